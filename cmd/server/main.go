@@ -5,20 +5,41 @@ import (
 	"errors"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"github.com/maxwellzp/golang-chat-api/internal/account"
+	"github.com/maxwellzp/golang-chat-api/internal/admin"
+	"github.com/maxwellzp/golang-chat-api/internal/apikey"
+	"github.com/maxwellzp/golang-chat-api/internal/audit"
 	"github.com/maxwellzp/golang-chat-api/internal/auth"
+	"github.com/maxwellzp/golang-chat-api/internal/command"
 	"github.com/maxwellzp/golang-chat-api/internal/config"
+	"github.com/maxwellzp/golang-chat-api/internal/contentfilter"
 	"github.com/maxwellzp/golang-chat-api/internal/db"
+	"github.com/maxwellzp/golang-chat-api/internal/event"
+	"github.com/maxwellzp/golang-chat-api/internal/export"
 	"github.com/maxwellzp/golang-chat-api/internal/logger"
+	"github.com/maxwellzp/golang-chat-api/internal/mailer"
 	"github.com/maxwellzp/golang-chat-api/internal/message"
 	appMiddleware "github.com/maxwellzp/golang-chat-api/internal/middleware"
+	"github.com/maxwellzp/golang-chat-api/internal/moderation"
+	"github.com/maxwellzp/golang-chat-api/internal/netguard"
+	"github.com/maxwellzp/golang-chat-api/internal/notification"
+	"github.com/maxwellzp/golang-chat-api/internal/oidc"
+	"github.com/maxwellzp/golang-chat-api/internal/presence"
+	"github.com/maxwellzp/golang-chat-api/internal/retention"
 	"github.com/maxwellzp/golang-chat-api/internal/room"
+	"github.com/maxwellzp/golang-chat-api/internal/schedule"
+	"github.com/maxwellzp/golang-chat-api/internal/typing"
 	"github.com/maxwellzp/golang-chat-api/internal/user"
 	validatorx "github.com/maxwellzp/golang-chat-api/internal/validatorx"
+	"github.com/maxwellzp/golang-chat-api/internal/webhook"
 	"go.uber.org/zap"
 	"net/http"
 	"os/signal"
 	"syscall"
 	"time"
+	// Notification quiet hours use IANA time zones even where the host has
+	// no zoneinfo installed.
+	_ "time/tzdata"
 )
 
 func main() {
@@ -52,30 +73,168 @@ func main() {
 	userRepo := user.NewUserRepository(dbInstance)
 	roomRepo := room.NewRoomRepository(dbInstance)
 	messageRepo := message.NewMessageRepository(dbInstance)
+	passwordResetRepo := auth.NewPasswordResetRepository(dbInstance)
+	identityRepo := user.NewIdentityRepository(dbInstance)
+	apiKeyRepo := apikey.NewAPIKeyRepository(dbInstance)
+	webhookRepo := webhook.NewWebhookRepository(dbInstance)
+	commandRepo := command.NewCommandRepository(dbInstance)
+	notificationRepo := notification.NewNotificationRepository(dbInstance)
+	incomingWebhookRepo := webhook.NewIncomingWebhookRepository(dbInstance)
+	presenceRepo := presence.NewPresenceRepository(dbInstance)
+	scheduleRepo := schedule.NewScheduleRepository(dbInstance)
+	retentionRepo := retention.NewRetentionRepository(dbInstance)
+	exportRepo := export.NewExportRepository(dbInstance)
+	accountRepo := account.NewAccountRepository(dbInstance)
+	moderationRepo := moderation.NewModerationRepository(dbInstance)
+	contentFilterRepo := contentfilter.NewContentFilterRepository(dbInstance)
+	auditRepo := audit.NewAuditRepository(dbInstance)
 	log.Debugw("Repositories initialized")
 
+	// In-process domain events (message.created, member.joined, ...)
+	bus := event.NewBus()
+
+	// Failed login counters
+	var attemptStore auth.AttemptStore
+	if cfg.Auth.LoginProtection.Store == "memory" {
+		attemptStore = auth.NewMemoryAttemptStore()
+	} else {
+		attemptStore = auth.NewPostgresAttemptStore(dbInstance)
+	}
+	loginGuard := auth.NewLoginGuard(attemptStore, cfg.Auth.LoginProtection)
+
+	mail := mailer.NewMailer(cfg, log)
+
+	// Keeps requests to user supplied URLs off the internal network
+	outboundGuard, err := netguard.New(cfg.Outbound.AllowedNetworks)
+	if err != nil {
+		log.Fatalw("Invalid outbound configuration",
+			"error", err,
+		)
+	}
+
 	// Instantiate business logic services
-	authService := auth.NewAuthService(userRepo, cfg.Auth.JwtSecret, log)
-	roomService := room.NewRoomService(roomRepo)
-	messageService := message.NewMessageService(messageRepo)
+	auditService := audit.NewAuditService(auditRepo, cfg.Audit, log)
+	authService := auth.NewAuthService(userRepo, passwordResetRepo, loginGuard, mail, auditService, cfg, log)
+	var oidcProviders []*oidc.Provider
+	for _, p := range cfg.OIDC.Providers {
+		oidcProviders = append(oidcProviders, oidc.NewProvider(oidc.Config{
+			Name:         p.Name,
+			Issuer:       p.Issuer,
+			ClientID:     p.ClientID,
+			ClientSecret: p.ClientSecret,
+			RedirectURL:  cfg.Application.BaseURL + "/auth/oidc/" + p.Name + "/callback",
+			Scopes:       p.Scopes,
+		}, nil))
+	}
+	oidcService := auth.NewOIDCService(oidcProviders, userRepo, identityRepo, authService, cfg.Auth.JwtSecret, log)
+	apiKeyService := apikey.NewAPIKeyService(apiKeyRepo, userRepo, log)
+	presenceService := presence.NewPresenceService(presenceRepo, cfg.Presence, log)
+	roomService := room.NewRoomService(roomRepo, presenceService, auditService, bus)
+	commandRegistry := command.NewRegistry(commandRepo, outboundGuard, log)
+	command.RegisterBuiltins(commandRegistry, roomService, userRepo)
+	commandService := command.NewCommandService(commandRepo, commandRegistry, roomService, outboundGuard)
+	contentFilters := contentfilter.NewChain(contentFilterRepo)
+	if err := contentfilter.RegisterBuiltins(contentFilters, cfg.ContentFilter); err != nil {
+		log.Fatalw("Invalid content filter configuration",
+			"error", err,
+		)
+	}
+	contentFilterService := contentfilter.NewContentFilterService(contentFilterRepo, contentFilters, roomService)
+	messageService := message.NewMessageService(messageRepo, roomRepo, commandRegistry, contentFilters, cfg.Message, auditService, bus)
+	webhookService := webhook.NewWebhookService(webhookRepo, roomService, outboundGuard, log)
+	bus.Subscribe(webhookService.HandleEvent)
+	notificationService := notification.NewNotificationService(notificationRepo, userRepo, roomRepo, outboundGuard, log)
+	bus.Subscribe(notificationService.HandleEvent)
+	scheduleService := schedule.NewScheduleService(scheduleRepo, roomService, userRepo)
+	retentionService := retention.NewRetentionService(retentionRepo, roomService, cfg.Retention)
+	accountService := account.NewAccountService(accountRepo, log)
+	moderationService := moderation.NewModerationService(moderationRepo, messageRepo, messageService, roomService, notificationService, userRepo, log)
+	bus.Subscribe(moderationService.HandleEvent)
+	adminService := admin.NewAdminService(userRepo, authService, cfg.Admin)
+	if err := adminService.PromoteConfiguredAdmins(context.Background()); err != nil {
+		log.Errorw("Failed to promote configured admins",
+			"err", err,
+		)
+	}
+	exportService := export.NewExportService(exportRepo, roomService, userRepo, cfg.Export, cfg.Application.BaseURL, cfg.Auth.JwtSecret)
+	schedule.RegisterCommands(commandRegistry, scheduleService)
+	incomingWebhookService := webhook.NewIncomingWebhookService(incomingWebhookRepo, roomService, messageService, cfg.Application.BaseURL)
+	var typingRelay typing.Relay
+	if cfg.Typing.Relay == "postgres" {
+		typingRelay = typing.NewPostgresRelay(dbInstance, cfg.Db.DSN(), log)
+	}
+	typingTracker := typing.NewTracker(typingRelay, cfg.Typing, log)
+	typingService := typing.NewTypingService(typingTracker, roomService, userRepo)
+	bus.Subscribe(typingService.HandleEvent)
 	log.Debugw("Business services initialized")
 
+	// Notification delivery channels; Web Push needs a VAPID key pair
+	notificationClient := outboundGuard.Client(cfg.Notification.Timeout)
+	notificationChannels := []notification.Channel{
+		notification.NewEmailChannel(mail),
+		notification.NewWebhookChannel(notificationClient),
+	}
+	vapidPublicKey := ""
+	if cfg.Notification.VAPIDPublicKey != "" && cfg.Notification.VAPIDPrivateKey != "" {
+		webPush, err := notification.NewWebPushChannel(notificationRepo, notificationClient,
+			cfg.Notification.VAPIDPublicKey, cfg.Notification.VAPIDPrivateKey, cfg.Notification.VAPIDSubject, log)
+		if err != nil {
+			log.Fatalw("Invalid Web Push configuration",
+				"error", err,
+			)
+		}
+		notificationChannels = append(notificationChannels, webPush)
+		vapidPublicKey = webPush.PublicKey()
+	}
+
 	// Validator
 	val := validatorx.NewValidator()
 
 	// REST API Handlers
 	authHandler := auth.NewAuthHandler(authService, val, log)
+	oidcHandler := auth.NewOIDCHandler(oidcService, cfg.Application.BaseURL, log)
 	roomHandler := room.NewRoomHandler(roomService, val, log)
 	messageHandler := message.NewMessageHandler(messageService, val, log)
+	apiKeyHandler := apikey.NewAPIKeyHandler(apiKeyService, val, log)
+	webhookHandler := webhook.NewWebhookHandler(webhookService, val, log)
+	commandHandler := command.NewCommandHandler(commandService, val, log)
+	notificationHandler := notification.NewNotificationHandler(notificationService, vapidPublicKey, val, log)
+	incomingWebhookHandler := webhook.NewIncomingWebhookHandler(incomingWebhookService, val, log)
+	presenceHandler := presence.NewPresenceHandler(presenceService, val, log)
+	typingHandler := typing.NewTypingHandler(typingService, log)
+	scheduleHandler := schedule.NewScheduleHandler(scheduleService, val, log)
+	retentionHandler := retention.NewRetentionHandler(retentionService, val, log)
+	exportHandler := export.NewExportHandler(exportService, val, log)
+	accountHandler := account.NewAccountHandler(accountService, log)
+	adminHandler := admin.NewAdminHandler(adminService, val, log)
+	moderationHandler := moderation.NewModerationHandler(moderationService, val, log)
+	contentFilterHandler := contentfilter.NewContentFilterHandler(contentFilterService, val, log)
+	auditHandler := audit.NewAuditHandler(auditService, log)
 	log.Debugw("API Handlers initialized")
 
 	// Middleware
-	jwtMiddleWare := appMiddleware.JWT(cfg.Auth.JwtSecret, log)
+	jwtMiddleWare := appMiddleware.JWT(cfg.Auth.JwtSecret, apiKeyService, authService, log)
+	scope := func(name string) func(http.Handler) http.Handler {
+		return appMiddleware.RequireScope(name, auditService, log)
+	}
+
+	var rateLimitStore appMiddleware.RateLimitStore
+	if cfg.RateLimit.Store == "memory" {
+		rateLimitStore = appMiddleware.NewMemoryRateLimitStore()
+	} else {
+		rateLimitStore = appMiddleware.NewPostgresRateLimitStore(dbInstance)
+	}
+	publicLimit := appMiddleware.RateLimit(rateLimitStore, "public", cfg.RateLimit.Public, log)
+	authLimit := appMiddleware.RateLimit(rateLimitStore, "auth", cfg.RateLimit.Auth, log)
+	apiLimit := appMiddleware.RateLimit(rateLimitStore, "api", cfg.RateLimit.Authenticated, log)
+	messageCreateLimit := appMiddleware.RateLimit(rateLimitStore, "messages.create", cfg.RateLimit.MessagesCreate, log)
 
 	r := chi.NewRouter()
 	log.Debugw("Router initialized")
 	r.Use(middleware.Recoverer)
-	log.Debugw("Middleware stack applied: Recoverer")
+	r.Use(middleware.RequestID)
+	r.Use(appMiddleware.RequestInfo)
+	log.Debugw("Middleware stack applied: Recoverer, RequestID, RequestInfo")
 
 	// Public routes
 	r.Group(func(r chi.Router) {
@@ -84,22 +243,41 @@ func main() {
 		r.Get("/healthz", func(w http.ResponseWriter, r *http.Request) {
 			w.Write([]byte("ok"))
 		})
-		r.Post("/login", authHandler.Login())
-		r.Post("/register", authHandler.Register())
-		r.Get("/rooms/list", roomHandler.List())
-		r.Get("/rooms/{id}", roomHandler.GetByID())
+
+		r.Group(func(r chi.Router) {
+			r.Use(publicLimit)
+
+			r.With(authLimit).Post("/login", authHandler.Login())
+			r.With(authLimit).Post("/register", authHandler.Register())
+			r.With(authLimit).Post("/password/forgot", authHandler.ForgotPassword())
+			r.With(authLimit).Post("/password/reset", authHandler.ResetPassword())
+			r.With(authLimit).Get("/auth/oidc/{provider}/start", oidcHandler.Start())
+			r.With(authLimit).Get("/auth/oidc/{provider}/callback", oidcHandler.Callback())
+			r.Get("/rooms/list", roomHandler.List())
+			r.Get("/rooms/{id}", roomHandler.GetByID())
+			r.Get("/push/vapid-key", notificationHandler.VAPIDKey())
+			// Incoming webhooks authenticate with the token in the path
+			r.With(messageCreateLimit).Post("/hooks/{token}", incomingWebhookHandler.Post())
+			r.Get("/exports/{id}/download", exportHandler.Download())
+		})
 	})
 
 	// Messages (all protected)
 	r.Route("/messages", func(r chi.Router) {
 		r.Use(jwtMiddleWare)
 		r.Use(appMiddleware.Logging(log))
+		r.Use(apiLimit)
 
-		r.Post("/create", messageHandler.Create())
-		r.Patch("/update/{id}", messageHandler.Update())
-		r.Delete("/delete/{id}", messageHandler.Delete())
-		r.Get("/{id}", messageHandler.GetByID())
-		r.Get("/list", messageHandler.List())
+		r.With(messageCreateLimit, scope(apikey.ScopeMessagesWrite)).Post("/create", messageHandler.Create())
+		r.With(scope(apikey.ScopeMessagesWrite)).Patch("/update/{id}", messageHandler.Update())
+		r.With(scope(apikey.ScopeMessagesWrite)).Delete("/delete/{id}", messageHandler.Delete())
+		r.With(scope(apikey.ScopeMessagesRead)).Get("/{id}", messageHandler.GetByID())
+		r.With(scope(apikey.ScopeMessagesRead)).Get("/list", messageHandler.List())
+		r.With(messageCreateLimit, scope(apikey.ScopeMessagesWrite)).Post("/schedule", scheduleHandler.Schedule())
+		r.With(scope(apikey.ScopeMessagesWrite)).Get("/scheduled", scheduleHandler.List())
+		r.With(scope(apikey.ScopeMessagesWrite)).Patch("/scheduled/{id}", scheduleHandler.Update())
+		r.With(scope(apikey.ScopeMessagesWrite)).Delete("/scheduled/{id}", scheduleHandler.Cancel())
+		r.With(scope(apikey.ScopeMessagesWrite)).Post("/{id}/report", moderationHandler.Report())
 	})
 
 	// Rooms (protected)
@@ -107,19 +285,182 @@ func main() {
 		r.Group(func(r chi.Router) {
 			r.Use(jwtMiddleWare)
 			r.Use(appMiddleware.Logging(log))
+			r.Use(apiLimit)
+
+			r.Group(func(r chi.Router) {
+				r.Use(scope(apikey.ScopeRoomsWrite))
+
+				r.Post("/create", roomHandler.Create())
+				r.Patch("/update/{id}", roomHandler.Update())
+				r.Delete("/delete/{id}", roomHandler.Delete())
+				r.Post("/{id}/join", roomHandler.Join())
+				r.Post("/{id}/leave", roomHandler.Leave())
+				r.Post("/{id}/members", roomHandler.AddMember())
+				r.Put("/{id}/members/{user_id}/role", roomHandler.UpdateMemberRole())
+				r.Post("/{id}/pins/{message_id}", messageHandler.Pin())
+				r.Delete("/{id}/pins/{message_id}", messageHandler.Unpin())
+				r.Put("/{id}/retention", retentionHandler.SetRoomPolicy())
+				r.Put("/{id}/content-filters/{filter}", contentFilterHandler.Save())
+				r.Delete("/{id}/content-filters/{filter}", contentFilterHandler.Delete())
+			})
+			r.With(scope(apikey.ScopeRoomsRead)).Get("/{id}/members", roomHandler.Members())
+			r.With(scope(apikey.ScopeRoomsRead)).Get("/{id}/retention", retentionHandler.RoomPolicy())
+			r.With(scope(apikey.ScopeRoomsRead)).Get("/{id}/content-filters", contentFilterHandler.List())
+			r.With(scope(apikey.ScopeMessagesRead)).Post("/{id}/exports", exportHandler.Create(false))
+			r.With(scope(apikey.ScopeMessagesRead)).Get("/{id}/pins", messageHandler.Pins())
+			r.With(scope(apikey.ScopeMessagesWrite)).Post("/{id}/typing", typingHandler.Signal(false))
+			r.With(scope(apikey.ScopeMessagesRead)).Get("/{id}/typing", typingHandler.Current(false))
+			r.With(scope(apikey.ScopeMessagesRead)).Get("/{id}/typing/stream", typingHandler.Stream(false))
+
+			// Webhooks are managed by room admins from a signed-in session
+			r.Route("/{id}/webhooks", func(r chi.Router) {
+				r.Use(appMiddleware.SessionOnly(log))
 
-			r.Post("/create", roomHandler.Create())
-			r.Patch("/update/{id}", roomHandler.Update())
-			r.Delete("/delete/{id}", roomHandler.Delete())
+				r.Post("/", webhookHandler.Create())
+				r.Get("/", webhookHandler.List())
+				r.Delete("/{wid}", webhookHandler.Delete())
+				r.Get("/{wid}/deliveries", webhookHandler.Deliveries())
+			})
+			r.Route("/{id}/commands", func(r chi.Router) {
+				r.Use(appMiddleware.SessionOnly(log))
+
+				r.Post("/", commandHandler.Create())
+				r.Get("/", commandHandler.List())
+				r.Delete("/{cid}", commandHandler.Delete())
+			})
+			r.Route("/{id}/incoming-webhooks", func(r chi.Router) {
+				r.Use(appMiddleware.SessionOnly(log))
+
+				r.Post("/", incomingWebhookHandler.Create())
+				r.Get("/", incomingWebhookHandler.List())
+				r.Post("/{hid}/rotate", incomingWebhookHandler.Rotate())
+				r.Delete("/{hid}", incomingWebhookHandler.Revoke())
+			})
 		})
 	})
 
-	log.Debugw("Routes registered: /login, /register, /messages/*, /rooms/*")
+	// Direct message conversations (protected)
+	r.Route("/conversations", func(r chi.Router) {
+		r.Use(jwtMiddleWare)
+		r.Use(appMiddleware.Logging(log))
+		r.Use(apiLimit)
+
+		r.With(scope(apikey.ScopeMessagesWrite)).Post("/{user_id}/typing", typingHandler.Signal(true))
+		r.With(scope(apikey.ScopeMessagesRead)).Get("/{user_id}/typing", typingHandler.Current(true))
+		r.With(scope(apikey.ScopeMessagesRead)).Get("/{user_id}/typing/stream", typingHandler.Stream(true))
+		r.With(scope(apikey.ScopeMessagesRead)).Post("/{user_id}/exports", exportHandler.Create(true))
+	})
+
+	// Slash commands available for autocompletion
+	r.Group(func(r chi.Router) {
+		r.Use(jwtMiddleWare)
+		r.Use(appMiddleware.Logging(log))
+		r.Use(apiLimit)
+
+		r.Get("/commands", commandHandler.Available())
+	})
+
+	// Presence (protected)
+	r.Group(func(r chi.Router) {
+		r.Use(jwtMiddleWare)
+		r.Use(appMiddleware.Logging(log))
+		r.Use(apiLimit)
+
+		r.With(scope(apikey.ScopePresenceRead)).Get("/users/{id}/presence", presenceHandler.Get())
+		r.With(scope(apikey.ScopePresenceRead)).Post("/presence/query", presenceHandler.Query())
+		r.With(scope(apikey.ScopePresenceWrite)).Post("/presence/heartbeat", presenceHandler.Heartbeat())
+		r.With(scope(apikey.ScopePresenceWrite)).Get("/presence/stream", presenceHandler.Stream())
+	})
+
+	// Current user (protected)
+	r.Route("/me", func(r chi.Router) {
+		r.Use(jwtMiddleWare)
+		r.Use(appMiddleware.Logging(log))
+		r.Use(apiLimit)
+
+		r.With(scope(apikey.ScopeMessagesRead)).Get("/mentions", messageHandler.Mentions())
+		r.With(scope(apikey.ScopeMessagesRead)).Post("/mentions/read", messageHandler.MarkAllMentionsRead())
+		r.With(scope(apikey.ScopeMessagesRead)).Post("/mentions/{message_id}/read", messageHandler.MarkMentionRead())
+		r.With(scope(apikey.ScopeMessagesRead)).Get("/notifications", notificationHandler.List())
+		r.With(scope(apikey.ScopeMessagesRead)).Post("/notifications/read", notificationHandler.MarkAllRead())
+		r.With(scope(apikey.ScopeMessagesRead)).Post("/notifications/{id}/read", notificationHandler.MarkRead())
+		r.With(scope(apikey.ScopeMessagesRead)).Get("/saved", messageHandler.Saved())
+		r.With(scope(apikey.ScopeMessagesWrite)).Post("/saved/{message_id}", messageHandler.Save())
+		r.With(scope(apikey.ScopeMessagesWrite)).Delete("/saved/{message_id}", messageHandler.Unsave())
+		r.With(scope(apikey.ScopePresenceWrite)).Put("/status", presenceHandler.SetStatus())
+		r.With(scope(apikey.ScopePresenceWrite)).Delete("/status", presenceHandler.ClearStatus())
+		r.With(scope(apikey.ScopeMessagesRead)).Get("/exports", exportHandler.List())
+		r.With(scope(apikey.ScopeMessagesRead)).Get("/exports/{id}", exportHandler.Get())
+
+		// API keys, bots, notification settings and the account itself can
+		// only be managed from a signed-in session
+		r.Group(func(r chi.Router) {
+			r.Use(appMiddleware.SessionOnly(log))
+
+			r.Post("/api-keys", apiKeyHandler.Create())
+			r.Get("/api-keys", apiKeyHandler.List())
+			r.Delete("/api-keys/{id}", apiKeyHandler.Revoke())
+			r.Post("/bots", apiKeyHandler.CreateBot())
+			r.Get("/bots", apiKeyHandler.ListBots())
+			r.Get("/notification-preferences", notificationHandler.Preferences())
+			r.Put("/notification-preferences", notificationHandler.UpdatePreferences())
+			r.Put("/notification-preferences/rooms/{room_id}", notificationHandler.SetRoomLevel())
+			r.Post("/push-subscriptions", notificationHandler.Subscribe())
+			r.Get("/push-subscriptions", notificationHandler.Subscriptions())
+			r.Delete("/push-subscriptions/{id}", notificationHandler.Unsubscribe())
+			r.Post("/export", exportHandler.CreateAccount())
+			r.Post("/identities/{provider}", oidcHandler.Link())
+			r.Delete("/", accountHandler.Delete())
+		})
+	})
+
+	// Report review, for room moderators and admins
+	r.Route("/moderation", func(r chi.Router) {
+		r.Use(jwtMiddleWare)
+		r.Use(appMiddleware.Logging(log))
+		r.Use(apiLimit)
+		r.Use(appMiddleware.SessionOnly(log))
+
+		r.Get("/reports", moderationHandler.ListReports())
+		r.Get("/reports/{id}", moderationHandler.GetReport())
+		r.Post("/reports/{id}/actions", moderationHandler.TakeAction())
+		r.Get("/actions", moderationHandler.ListActions())
+	})
+
+	// Administration, for users with the admin role
+	r.Route("/admin", func(r chi.Router) {
+		r.Use(jwtMiddleWare)
+		r.Use(appMiddleware.Logging(log))
+		r.Use(apiLimit)
+		r.Use(appMiddleware.SessionOnly(log))
+		r.Use(appMiddleware.RequireRole(user.RoleAdmin, userRepo, auditService, log))
+
+		r.Get("/users", adminHandler.ListUsers())
+		r.Get("/users/{id}", adminHandler.GetUser())
+		r.Put("/users/{id}/role", adminHandler.SetRole())
+		r.Put("/users/{id}/suspension", adminHandler.Suspend())
+		r.Delete("/users/{id}/suspension", adminHandler.Unsuspend())
+		r.Post("/users/{id}/password-reset", adminHandler.ForcePasswordReset())
+		r.Delete("/users/{id}/sessions", adminHandler.RevokeSessions())
+		r.Post("/users/{id}/impersonate", adminHandler.Impersonate())
+		r.Get("/retention/report", retentionHandler.Report())
+		r.Put("/legal-holds/rooms/{id}", retentionHandler.HoldRoom())
+		r.Delete("/legal-holds/rooms/{id}", retentionHandler.ReleaseRoom())
+		r.Put("/legal-holds/users/{id}", retentionHandler.HoldUser())
+		r.Delete("/legal-holds/users/{id}", retentionHandler.ReleaseUser())
+		r.Get("/audit-events", auditHandler.List())
+		r.Get("/audit-events/verify", auditHandler.Verify())
+	})
+
+	log.Debugw("Routes registered: /login, /register, /password/*, /auth/oidc/*, /hooks/*, /exports/*, /push/*, /commands, /presence/*, /users/*, /conversations/*, /messages/*, /rooms/*, /me/*, /moderation/*, /admin/*")
 
 	server := &http.Server{
 		Addr:    ":" + cfg.Server.Port,
 		Handler: r,
 	}
+	// Shutdown waits for active requests, so end open event streams
+	server.RegisterOnShutdown(presenceService.Shutdown)
+	server.RegisterOnShutdown(typingTracker.Shutdown)
 
 	log.Infow("Server running",
 		"port", cfg.Server.Port,
@@ -129,6 +470,25 @@ func main() {
 	shutdownCtx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
+	// Background workers stop with the server
+	go auditService.Run(shutdownCtx)
+	webhookDispatcher := webhook.NewDispatcher(webhookRepo, outboundGuard, cfg.Webhook, log)
+	go webhookDispatcher.Run(shutdownCtx)
+	notificationDispatcher := notification.NewDispatcher(notificationRepo, userRepo, notificationChannels, cfg.Notification, log)
+	go notificationDispatcher.Run(shutdownCtx)
+	go presenceService.Run(shutdownCtx)
+	reminderWorker := message.NewReminderWorker(messageRepo, bus, cfg.Message, log)
+	go reminderWorker.Run(shutdownCtx)
+	expiryReaper := message.NewExpiryReaper(messageRepo, bus, cfg.Message, log)
+	go expiryReaper.Run(shutdownCtx)
+	retentionPurger := retention.NewPurger(retentionRepo, cfg.Retention, log)
+	go retentionPurger.Run(shutdownCtx)
+	exporter := export.NewExporter(exportRepo, roomService, cfg.Export, log)
+	go exporter.Run(shutdownCtx)
+	scheduler := schedule.NewScheduler(scheduleRepo, messageService, authService, bus, cfg.Schedule, log)
+	go scheduler.Run(shutdownCtx)
+	go typingTracker.Run(shutdownCtx)
+
 	go func() {
 		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 			log.Fatalw("Failed to start server",
@@ -0,0 +1,52 @@
+package account
+
+import (
+	"github.com/maxwellzp/golang-chat-api/internal/httpx"
+	"github.com/maxwellzp/golang-chat-api/internal/logger"
+	"net/http"
+)
+
+type AccountHandler struct {
+	accountService *AccountService
+	logger         *logger.Logger
+}
+
+func NewAccountHandler(accountService *AccountService, logger *logger.Logger) *AccountHandler {
+	return &AccountHandler{
+		accountService: accountService,
+		logger:         logger,
+	}
+}
+
+// Delete erases the current user's account.
+func (h *AccountHandler) Delete() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := httpx.GetUserID(r.Context())
+		if err != nil {
+			h.logger.Warnw("Unauthorized request to delete account")
+			httpx.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		if _, ok := httpx.GetImpersonatorID(r.Context()); ok {
+			httpx.WriteError(w, http.StatusForbidden, "Accounts can't be deleted while impersonating")
+			return
+		}
+
+		err = h.accountService.Delete(r.Context(), userID)
+		switch {
+		case httpx.WriteServiceError(w, err):
+			return
+		case err != nil:
+			h.logger.Errorw("Failed to delete account",
+				"error", err,
+				"user_id", userID,
+			)
+			httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
+			return
+		}
+		h.logger.Infow("Account deleted",
+			"user_id", userID,
+		)
+		httpx.WriteJSON(w, http.StatusNoContent, nil)
+	}
+}
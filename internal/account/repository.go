@@ -0,0 +1,142 @@
+package account
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/maxwellzp/golang-chat-api/internal/db"
+	"github.com/maxwellzp/golang-chat-api/internal/user"
+	"time"
+)
+
+type AccountRepository struct {
+	database *db.Db
+}
+
+func NewAccountRepository(database *db.Db) *AccountRepository {
+	return &AccountRepository{database: database}
+}
+
+// LegalHold reports whether the user is under a legal hold. found is false
+// for unknown and already deleted users.
+func (r *AccountRepository) LegalHold(ctx context.Context, userID int64) (held bool, found bool, err error) {
+	err = r.database.QueryRowContext(ctx,
+		"SELECT legal_hold FROM users WHERE id = $1 AND deleted_at IS NULL", userID).Scan(&held)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, false, nil
+		}
+		return false, false, err
+	}
+	return held, true, nil
+}
+
+// Erase deletes the user's personal data in one transaction and returns the
+// paths of their export archives, which the caller deletes. Messages they
+// sent or received are reassigned to the deleted user placeholder so room
+// history and the other side of their conversations stay intact. Rooms they
+// owned pass to their longest-standing admin, or failing that, member, who
+// also becomes the room's creator so they can delete it. The user row itself
+// is kept, anonymized, so nothing referencing it cascades.
+func (r *AccountRepository) Erase(ctx context.Context, userID int64, now time.Time) ([]string, error) {
+	tx, err := r.database.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var placeholderID int64
+	err = tx.QueryRowContext(ctx,
+		"SELECT id FROM users WHERE username = $1 AND deleted_at IS NOT NULL", user.DeletedUsername).Scan(&placeholderID)
+	if err != nil {
+		return nil, fmt.Errorf("deleted user placeholder: %w", err)
+	}
+
+	rows, err := tx.QueryContext(ctx,
+		"DELETE FROM exports WHERE user_id = $1 OR peer_id = $1 RETURNING file_path", userID)
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for rows.Next() {
+		var path *string
+		if err := rows.Scan(&path); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if path != nil {
+			paths = append(paths, *path)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	statements := []string{
+		`UPDATE room_members rm SET role = 'owner'
+		 FROM (
+			SELECT DISTINCT ON (m.room_id) m.room_id, m.user_id
+			FROM room_members m
+			WHERE m.user_id <> $1
+			AND m.room_id IN (SELECT room_id FROM room_members WHERE user_id = $1 AND role = 'owner')
+			AND NOT EXISTS (SELECT 1 FROM room_members o WHERE o.room_id = m.room_id AND o.role = 'owner' AND o.user_id <> $1)
+			ORDER BY m.room_id, CASE m.role WHEN 'admin' THEN 0 WHEN 'moderator' THEN 1 ELSE 2 END, m.joined_at
+		 ) heir
+		 WHERE rm.room_id = heir.room_id AND rm.user_id = heir.user_id`,
+		"DELETE FROM room_members WHERE user_id = $1",
+		"DELETE FROM room_post_cooldowns WHERE user_id = $1",
+		"DELETE FROM user_mentions WHERE user_id = $1",
+		"DELETE FROM saved_messages WHERE user_id = $1",
+		"DELETE FROM message_reactions WHERE user_id = $1",
+		"DELETE FROM scheduled_messages WHERE user_id = $1 OR receiver_id = $1",
+		"DELETE FROM notifications WHERE user_id = $1",
+		"DELETE FROM notification_preferences WHERE user_id = $1",
+		"DELETE FROM notification_room_preferences WHERE user_id = $1",
+		"DELETE FROM push_subscriptions WHERE user_id = $1",
+		"DELETE FROM user_presence WHERE user_id = $1",
+		"DELETE FROM password_resets WHERE user_id = $1",
+		"DELETE FROM user_identities WHERE user_id = $1",
+		"DELETE FROM api_keys WHERE user_id = $1 OR created_by = $1",
+		"UPDATE message_mentions SET user_id = NULL WHERE user_id = $1",
+		"UPDATE notifications SET actor_id = NULL WHERE actor_id = $1",
+		"UPDATE pinned_messages SET pinned_by = NULL WHERE pinned_by = $1",
+		`UPDATE rooms r SET created_by = (
+			SELECT o.user_id FROM room_members o
+			WHERE o.room_id = r.id AND o.role = 'owner' AND o.user_id <> $1
+			ORDER BY o.joined_at LIMIT 1
+		 )
+		 WHERE r.created_by = $1`,
+		"UPDATE room_webhooks SET created_by = NULL WHERE created_by = $1",
+		"UPDATE incoming_webhooks SET created_by = NULL WHERE created_by = $1",
+		"UPDATE slash_commands SET created_by = NULL WHERE created_by = $1",
+		"UPDATE reports SET reporter_id = NULL WHERE reporter_id = $1",
+		"UPDATE room_bans SET banned_by = NULL WHERE banned_by = $1",
+	}
+	for _, statement := range statements {
+		if _, err := tx.ExecContext(ctx, statement, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		"UPDATE messages SET sender_id = $2 WHERE sender_id = $1", userID, placeholderID); err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx,
+		"UPDATE messages SET receiver_id = $2 WHERE receiver_id = $1", userID, placeholderID); err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`UPDATE users
+		 SET username = 'deleted-' || id, email = 'deleted-' || id || '@deleted.invalid', password = '',
+			deleted_at = $2, sessions_revoked_at = $2
+		 WHERE id = $1`,
+		userID, now)
+	if err != nil {
+		return nil, err
+	}
+	return paths, tx.Commit()
+}
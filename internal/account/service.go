@@ -0,0 +1,57 @@
+package account
+
+import (
+	"context"
+	"errors"
+	"github.com/maxwellzp/golang-chat-api/internal/apperr"
+	"github.com/maxwellzp/golang-chat-api/internal/logger"
+	"os"
+	"time"
+)
+
+var (
+	ErrAccountNotFound = apperr.NotFound("account_not_found", "account not found")
+	ErrLegalHold       = apperr.Conflict("legal_hold", "account is under a legal hold and can't be deleted")
+)
+
+type AccountService struct {
+	accountRepository *AccountRepository
+	logger            *logger.Logger
+}
+
+func NewAccountService(accountRepository *AccountRepository, logger *logger.Logger) *AccountService {
+	return &AccountService{
+		accountRepository: accountRepository,
+		logger:            logger,
+	}
+}
+
+// Delete erases the user's account. Every token issued to them stops
+// working immediately; see AuthService.CheckSession.
+func (s *AccountService) Delete(ctx context.Context, userID int64) error {
+	held, found, err := s.accountRepository.LegalHold(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrAccountNotFound
+	}
+	if held {
+		return ErrLegalHold
+	}
+
+	paths, err := s.accountRepository.Erase(ctx, userID, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			// The exporter's cleanup removes it once it is old enough
+			s.logger.Warnw("Failed to delete export archive",
+				"error", err,
+				"path", path,
+			)
+		}
+	}
+	return nil
+}
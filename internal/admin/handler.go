@@ -0,0 +1,321 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/maxwellzp/golang-chat-api/internal/httpx"
+	"github.com/maxwellzp/golang-chat-api/internal/logger"
+	"github.com/maxwellzp/golang-chat-api/internal/user"
+	"github.com/maxwellzp/golang-chat-api/internal/validatorx"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+type AdminHandler struct {
+	adminService *AdminService
+	validator    *validatorx.Validator
+	logger       *logger.Logger
+}
+
+func NewAdminHandler(adminService *AdminService, validator *validatorx.Validator, logger *logger.Logger) *AdminHandler {
+	return &AdminHandler{
+		adminService: adminService,
+		validator:    validator,
+		logger:       logger,
+	}
+}
+
+// ListUsers searches users. Filters: q (part of the username or email),
+// role and suspended (true or false).
+func (h *AdminHandler) ListUsers() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		limit, offset, err := httpx.ParsePagination(r, 50, 200)
+		if err != nil {
+			httpx.WriteValidationError(w, err)
+			return
+		}
+		query := r.URL.Query()
+		role := query.Get("role")
+		if role != "" && role != user.RoleUser && role != user.RoleAdmin {
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid role filter")
+			return
+		}
+		var suspended *bool
+		if raw := query.Get("suspended"); raw != "" {
+			value, err := strconv.ParseBool(raw)
+			if err != nil {
+				httpx.WriteError(w, http.StatusBadRequest, "Invalid suspended filter")
+				return
+			}
+			suspended = &value
+		}
+
+		users, err := h.adminService.ListUsers(r.Context(), query.Get("q"), role, suspended, limit, offset)
+		if err != nil {
+			h.logger.Errorw("Failed to list users",
+				"error", err,
+			)
+			httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
+			return
+		}
+		httpx.WriteJSON(w, http.StatusOK, users)
+	}
+}
+
+func (h *AdminHandler) GetUser() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		targetID, err := httpx.ParseInt64Param(r, "id")
+		if err != nil {
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid UserID")
+			return
+		}
+
+		u, err := h.adminService.GetUser(r.Context(), targetID)
+		if httpx.WriteServiceError(w, err) {
+			return
+		}
+		if err != nil {
+			h.logger.Errorw("Failed to get user",
+				"error", err,
+				"target_id", targetID,
+			)
+			httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
+			return
+		}
+		httpx.WriteJSON(w, http.StatusOK, u)
+	}
+}
+
+func (h *AdminHandler) SetRole() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := httpx.GetUserID(r.Context())
+		if err != nil {
+			h.logger.Warnw("Unauthorized request to set user role")
+			httpx.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		targetID, err := httpx.ParseInt64Param(r, "id")
+		if err != nil {
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid UserID")
+			return
+		}
+
+		var req SetRoleRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.logger.Warnw("Failed to decode SetRoleRequest",
+				"error", err,
+			)
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		if err := h.validator.Validate(&req); err != nil {
+			httpx.WriteValidationError(w, err)
+			return
+		}
+
+		u, err := h.adminService.SetRole(r.Context(), userID, targetID, req)
+		if httpx.WriteServiceError(w, err) {
+			return
+		}
+		if err != nil {
+			h.logger.Errorw("Failed to set user role",
+				"error", err,
+				"target_id", targetID,
+			)
+			httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
+			return
+		}
+		h.logger.Infow("User role changed",
+			"role", req.Role,
+			"target_id", targetID,
+			"user_id", userID,
+		)
+		httpx.WriteJSON(w, http.StatusOK, u)
+	}
+}
+
+// Suspend takes an optional body with the reason.
+func (h *AdminHandler) Suspend() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := httpx.GetUserID(r.Context())
+		if err != nil {
+			h.logger.Warnw("Unauthorized request to suspend user")
+			httpx.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		targetID, err := httpx.ParseInt64Param(r, "id")
+		if err != nil {
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid UserID")
+			return
+		}
+
+		var req SuspendUserRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+			h.logger.Warnw("Failed to decode SuspendUserRequest",
+				"error", err,
+			)
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		if err := h.validator.Validate(&req); err != nil {
+			httpx.WriteValidationError(w, err)
+			return
+		}
+
+		u, err := h.adminService.Suspend(r.Context(), userID, targetID, req)
+		if httpx.WriteServiceError(w, err) {
+			return
+		}
+		if err != nil {
+			h.logger.Errorw("Failed to suspend user",
+				"error", err,
+				"target_id", targetID,
+			)
+			httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
+			return
+		}
+		h.logger.Infow("User suspended",
+			"target_id", targetID,
+			"user_id", userID,
+		)
+		httpx.WriteJSON(w, http.StatusOK, u)
+	}
+}
+
+func (h *AdminHandler) Unsuspend() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := httpx.GetUserID(r.Context())
+		if err != nil {
+			h.logger.Warnw("Unauthorized request to unsuspend user")
+			httpx.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		targetID, err := httpx.ParseInt64Param(r, "id")
+		if err != nil {
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid UserID")
+			return
+		}
+
+		u, err := h.adminService.Unsuspend(r.Context(), targetID)
+		if httpx.WriteServiceError(w, err) {
+			return
+		}
+		if err != nil {
+			h.logger.Errorw("Failed to unsuspend user",
+				"error", err,
+				"target_id", targetID,
+			)
+			httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
+			return
+		}
+		h.logger.Infow("User unsuspended",
+			"target_id", targetID,
+			"user_id", userID,
+		)
+		httpx.WriteJSON(w, http.StatusOK, u)
+	}
+}
+
+func (h *AdminHandler) ForcePasswordReset() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := httpx.GetUserID(r.Context())
+		if err != nil {
+			h.logger.Warnw("Unauthorized request to force password reset")
+			httpx.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		targetID, err := httpx.ParseInt64Param(r, "id")
+		if err != nil {
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid UserID")
+			return
+		}
+
+		err = h.adminService.ForcePasswordReset(r.Context(), targetID)
+		if httpx.WriteServiceError(w, err) {
+			return
+		}
+		if err != nil {
+			h.logger.Errorw("Failed to force password reset",
+				"error", err,
+				"target_id", targetID,
+			)
+			httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
+			return
+		}
+		h.logger.Infow("Password reset forced",
+			"target_id", targetID,
+			"user_id", userID,
+		)
+		httpx.WriteJSON(w, http.StatusNoContent, nil)
+	}
+}
+
+func (h *AdminHandler) RevokeSessions() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := httpx.GetUserID(r.Context())
+		if err != nil {
+			h.logger.Warnw("Unauthorized request to revoke sessions")
+			httpx.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		targetID, err := httpx.ParseInt64Param(r, "id")
+		if err != nil {
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid UserID")
+			return
+		}
+
+		err = h.adminService.RevokeSessions(r.Context(), targetID)
+		if httpx.WriteServiceError(w, err) {
+			return
+		}
+		if err != nil {
+			h.logger.Errorw("Failed to revoke sessions",
+				"error", err,
+				"target_id", targetID,
+			)
+			httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
+			return
+		}
+		h.logger.Infow("Sessions revoked",
+			"target_id", targetID,
+			"user_id", userID,
+		)
+		httpx.WriteJSON(w, http.StatusNoContent, nil)
+	}
+}
+
+func (h *AdminHandler) Impersonate() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := httpx.GetUserID(r.Context())
+		if err != nil {
+			h.logger.Warnw("Unauthorized request to impersonate user")
+			httpx.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		targetID, err := httpx.ParseInt64Param(r, "id")
+		if err != nil {
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid UserID")
+			return
+		}
+
+		resp, err := h.adminService.Impersonate(r.Context(), userID, targetID)
+		if httpx.WriteServiceError(w, err) {
+			return
+		}
+		if err != nil {
+			h.logger.Errorw("Failed to impersonate user",
+				"error", err,
+				"target_id", targetID,
+			)
+			httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
+			return
+		}
+		h.logger.Infow("Impersonation token issued",
+			"expires_at", resp.ExpiresAt,
+			"target_id", targetID,
+			"user_id", userID,
+		)
+		httpx.WriteJSON(w, http.StatusOK, resp)
+	}
+}
@@ -0,0 +1,20 @@
+package admin
+
+import (
+	"github.com/maxwellzp/golang-chat-api/internal/user"
+	"time"
+)
+
+type SetRoleRequest struct {
+	Role string `json:"role" validate:"required,oneof=user admin"`
+}
+
+type SuspendUserRequest struct {
+	Reason string `json:"reason" validate:"max=500"`
+}
+
+type ImpersonationResponse struct {
+	Token     string     `json:"token"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	User      *user.User `json:"user"`
+}
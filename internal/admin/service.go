@@ -0,0 +1,174 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"github.com/maxwellzp/golang-chat-api/internal/apperr"
+	"github.com/maxwellzp/golang-chat-api/internal/auth"
+	"github.com/maxwellzp/golang-chat-api/internal/config"
+	"github.com/maxwellzp/golang-chat-api/internal/user"
+	"strings"
+	"time"
+)
+
+var (
+	ErrUserNotFound           = apperr.NotFound("user_not_found", "user not found")
+	ErrCannotTargetSelf       = apperr.Conflict("cannot_target_self", "admins can't do that to their own account")
+	ErrCannotImpersonateAdmin = apperr.Forbidden("cannot_impersonate_admin", "admins can't be impersonated")
+	ErrUserSuspended          = apperr.Conflict("user_suspended", "user is suspended")
+	ErrBotAccount             = apperr.Conflict("bot_account", "bots have no password to reset")
+)
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+type AdminService struct {
+	userRepository *user.UserRepository
+	authService    *auth.AuthService
+	cfg            config.AdminConfig
+}
+
+func NewAdminService(userRepository *user.UserRepository, authService *auth.AuthService, cfg config.AdminConfig) *AdminService {
+	return &AdminService{
+		userRepository: userRepository,
+		authService:    authService,
+		cfg:            cfg,
+	}
+}
+
+// PromoteConfiguredAdmins gives the users in ADMIN_USER_IDS the admin role.
+func (s *AdminService) PromoteConfiguredAdmins(ctx context.Context) error {
+	if len(s.cfg.UserIDs) == 0 {
+		return nil
+	}
+	return s.userRepository.PromoteAdmins(ctx, s.cfg.UserIDs)
+}
+
+// ListUsers searches active users by username or email. Empty filters match
+// everyone.
+func (s *AdminService) ListUsers(ctx context.Context, query string, role string, suspended *bool, limit int, offset int) ([]*user.User, error) {
+	return s.userRepository.Search(ctx, likeEscaper.Replace(strings.TrimSpace(query)), role, suspended, limit, offset)
+}
+
+func (s *AdminService) GetUser(ctx context.Context, id int64) (*user.User, error) {
+	u, err := s.userRepository.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil {
+		return nil, ErrUserNotFound
+	}
+	u.Password = ""
+	return u, nil
+}
+
+// SetRole changes the user's global role. Admins can't demote themselves,
+// so there is always one admin left who can undo a mistake. A demoted admin
+// is signed out everywhere, impersonation sessions included.
+func (s *AdminService) SetRole(ctx context.Context, adminID int64, targetID int64, req SetRoleRequest) (*user.User, error) {
+	if targetID == adminID {
+		return nil, ErrCannotTargetSelf
+	}
+	found, err := s.userRepository.SetRole(ctx, targetID, req.Role, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, ErrUserNotFound
+	}
+	return s.GetUser(ctx, targetID)
+}
+
+// Suspend blocks the user from signing in and ends their sessions. Their API
+// keys stop working until the suspension is lifted.
+func (s *AdminService) Suspend(ctx context.Context, adminID int64, targetID int64, req SuspendUserRequest) (*user.User, error) {
+	if targetID == adminID {
+		return nil, ErrCannotTargetSelf
+	}
+	var reason *string
+	if r := strings.TrimSpace(req.Reason); r != "" {
+		reason = &r
+	}
+	found, err := s.userRepository.Suspend(ctx, targetID, reason, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, ErrUserNotFound
+	}
+	return s.GetUser(ctx, targetID)
+}
+
+func (s *AdminService) Unsuspend(ctx context.Context, targetID int64) (*user.User, error) {
+	found, err := s.userRepository.Unsuspend(ctx, targetID)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, ErrUserNotFound
+	}
+	return s.GetUser(ctx, targetID)
+}
+
+// ForcePasswordReset ends the user's sessions, blocks password sign-in until
+// they choose a new password and emails them a reset link.
+func (s *AdminService) ForcePasswordReset(ctx context.Context, targetID int64) error {
+	u, err := s.activeUser(ctx, targetID, true)
+	if err != nil {
+		return err
+	}
+	if u.IsBot {
+		return ErrBotAccount
+	}
+	if err := s.userRepository.RequirePasswordReset(ctx, targetID, time.Now().UTC()); err != nil {
+		return err
+	}
+	return s.authService.ForgotPassword(ctx, u.Email)
+}
+
+// RevokeSessions invalidates every token issued to the user so far. API keys
+// are unaffected.
+func (s *AdminService) RevokeSessions(ctx context.Context, targetID int64) error {
+	if _, err := s.activeUser(ctx, targetID, true); err != nil {
+		return err
+	}
+	return s.userRepository.RevokeSessions(ctx, targetID, time.Now().UTC())
+}
+
+// Impersonate issues a short-lived token that acts as the user for support.
+// The token records the admin and stops working when the admin's own
+// sessions are revoked. Admins can't be impersonated, so impersonation never
+// grants more than the admin already has.
+func (s *AdminService) Impersonate(ctx context.Context, adminID int64, targetID int64) (*ImpersonationResponse, error) {
+	if targetID == adminID {
+		return nil, ErrCannotTargetSelf
+	}
+	u, err := s.activeUser(ctx, targetID, false)
+	if err != nil {
+		return nil, err
+	}
+	if u.Role == user.RoleAdmin {
+		return nil, ErrCannotImpersonateAdmin
+	}
+	token, expiresAt, err := s.authService.IssueImpersonationToken(ctx, u, adminID, s.cfg.ImpersonationTTL)
+	if err != nil {
+		return nil, err
+	}
+	return &ImpersonationResponse{Token: token, ExpiresAt: expiresAt, User: u}, nil
+}
+
+// activeUser returns the user unless they were deleted, or with
+// allowSuspended unset, suspended.
+func (s *AdminService) activeUser(ctx context.Context, id int64, allowSuspended bool) (*user.User, error) {
+	err := s.authService.CheckAccount(ctx, id)
+	switch {
+	case errors.Is(err, auth.ErrSessionRevoked):
+		return nil, ErrUserNotFound
+	case errors.Is(err, auth.ErrAccountSuspended):
+		if !allowSuspended {
+			return nil, ErrUserSuspended
+		}
+	case err != nil:
+		return nil, err
+	}
+	return s.GetUser(ctx, id)
+}
@@ -0,0 +1,204 @@
+package apikey
+
+import (
+	"encoding/json"
+	"github.com/maxwellzp/golang-chat-api/internal/httpx"
+	"github.com/maxwellzp/golang-chat-api/internal/logger"
+	"github.com/maxwellzp/golang-chat-api/internal/validatorx"
+	"net/http"
+)
+
+type APIKeyHandler struct {
+	apiKeyService *APIKeyService
+	validator     *validatorx.Validator
+	logger        *logger.Logger
+}
+
+func NewAPIKeyHandler(apiKeyService *APIKeyService, validator *validatorx.Validator, logger *logger.Logger) *APIKeyHandler {
+	return &APIKeyHandler{
+		apiKeyService: apiKeyService,
+		validator:     validator,
+		logger:        logger,
+	}
+}
+
+func (h *APIKeyHandler) Create() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := httpx.GetUserID(r.Context())
+		if err != nil {
+			h.logger.Warnw("Unauthorized request to create api key")
+			httpx.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		var req CreateAPIKeyRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.logger.Warnw("Failed to decode CreateAPIKeyRequest",
+				"error", err,
+			)
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+
+		if err := h.validator.Validate(&req); err != nil {
+			h.logger.Warnw("Validation failed for CreateAPIKeyRequest",
+				"error", err,
+				"user_id", userID,
+			)
+			httpx.WriteValidationError(w, err)
+			return
+		}
+
+		if err := req.Validate(); err != nil {
+			h.logger.Warnw("Custom validation failed for CreateAPIKeyRequest",
+				"error", err,
+				"user_id", userID,
+			)
+			httpx.WriteValidationError(w, err)
+			return
+		}
+
+		key, rawKey, err := h.apiKeyService.Create(r.Context(), userID, req)
+		if httpx.WriteServiceError(w, err) {
+			return
+		}
+		if err != nil {
+			h.logger.Errorw("Failed to create api key",
+				"error", err,
+				"user_id", userID,
+			)
+			httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
+			return
+		}
+		h.logger.Infow("API key created",
+			"api_key_id", key.ID,
+			"prefix", key.Prefix,
+			"key_user_id", key.UserID,
+			"user_id", userID,
+		)
+		httpx.WriteJSON(w, http.StatusCreated, CreateAPIKeyResponse{APIKey: key, Key: rawKey})
+	}
+}
+
+func (h *APIKeyHandler) List() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := httpx.GetUserID(r.Context())
+		if err != nil {
+			h.logger.Warnw("Unauthorized request to list api keys")
+			httpx.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		keys, err := h.apiKeyService.List(r.Context(), userID)
+		if err != nil {
+			h.logger.Errorw("Failed to list api keys",
+				"error", err,
+				"user_id", userID,
+			)
+			httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
+			return
+		}
+		httpx.WriteJSON(w, http.StatusOK, keys)
+	}
+}
+
+func (h *APIKeyHandler) Revoke() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := httpx.GetUserID(r.Context())
+		if err != nil {
+			h.logger.Warnw("Unauthorized request to revoke api key")
+			httpx.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		id, err := httpx.ParseInt64Param(r, "id")
+		if err != nil {
+			h.logger.Warnw("Invalid api key ID for revoke",
+				"error", err,
+			)
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid APIKeyID")
+			return
+		}
+		if err := h.apiKeyService.Revoke(r.Context(), id, userID); err != nil {
+			if httpx.WriteServiceError(w, err) {
+				return
+			}
+			h.logger.Errorw("Failed to revoke api key",
+				"error", err,
+				"user_id", userID,
+				"api_key_id", id,
+			)
+			httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
+			return
+		}
+		h.logger.Infow("API key revoked",
+			"api_key_id", id,
+			"user_id", userID,
+		)
+		httpx.WriteJSON(w, http.StatusNoContent, nil)
+	}
+}
+
+func (h *APIKeyHandler) CreateBot() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := httpx.GetUserID(r.Context())
+		if err != nil {
+			h.logger.Warnw("Unauthorized request to create bot")
+			httpx.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		var req CreateBotRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.logger.Warnw("Failed to decode CreateBotRequest",
+				"error", err,
+			)
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+
+		if err := h.validator.Validate(&req); err != nil {
+			h.logger.Warnw("Validation failed for CreateBotRequest",
+				"error", err,
+				"user_id", userID,
+			)
+			httpx.WriteValidationError(w, err)
+			return
+		}
+
+		bot, err := h.apiKeyService.CreateBot(r.Context(), userID, req)
+		if httpx.WriteServiceError(w, err) {
+			return
+		}
+		if err != nil {
+			h.logger.Errorw("Failed to create bot",
+				"error", err,
+				"user_id", userID,
+			)
+			httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
+			return
+		}
+		h.logger.Infow("Bot created",
+			"bot_id", bot.ID,
+			"user_id", userID,
+		)
+		httpx.WriteJSON(w, http.StatusCreated, bot)
+	}
+}
+
+func (h *APIKeyHandler) ListBots() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := httpx.GetUserID(r.Context())
+		if err != nil {
+			h.logger.Warnw("Unauthorized request to list bots")
+			httpx.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		bots, err := h.apiKeyService.ListBots(r.Context(), userID)
+		if err != nil {
+			h.logger.Errorw("Failed to list bots",
+				"error", err,
+				"user_id", userID,
+			)
+			httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
+			return
+		}
+		httpx.WriteJSON(w, http.StatusOK, bots)
+	}
+}
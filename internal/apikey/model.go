@@ -0,0 +1,37 @@
+package apikey
+
+import "time"
+
+const (
+	ScopeMessagesRead  = "messages:read"
+	ScopeMessagesWrite = "messages:write"
+	ScopeRoomsRead     = "rooms:read"
+	ScopeRoomsWrite    = "rooms:write"
+	ScopePresenceRead  = "presence:read"
+	ScopePresenceWrite = "presence:write"
+)
+
+var validScopes = map[string]bool{
+	ScopeMessagesRead:  true,
+	ScopeMessagesWrite: true,
+	ScopeRoomsRead:     true,
+	ScopeRoomsWrite:    true,
+	ScopePresenceRead:  true,
+	ScopePresenceWrite: true,
+}
+
+// APIKey is a long-lived credential for a user or one of their bots. Only a
+// hash of the secret is stored; Prefix identifies the key in listings.
+type APIKey struct {
+	ID         int64      `json:"id"`
+	UserID     int64      `json:"user_id"`
+	CreatedBy  int64      `json:"created_by"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	KeyHash    string     `json:"-"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
@@ -0,0 +1,39 @@
+package apikey
+
+import (
+	"github.com/maxwellzp/golang-chat-api/internal/httpx"
+	"time"
+)
+
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name" validate:"required,min=3,max=100"`
+	Scopes    []string   `json:"scopes" validate:"required,min=1"`
+	BotID     *int64     `json:"bot_id,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+func (r *CreateAPIKeyRequest) Validate() error {
+	for _, scope := range r.Scopes {
+		if !validScopes[scope] {
+			return httpx.ValidationErrorMap{
+				"scopes": "unknown scope " + scope,
+			}
+		}
+	}
+	if r.ExpiresAt != nil && !r.ExpiresAt.After(time.Now()) {
+		return httpx.ValidationErrorMap{
+			"expires_at": "expires_at must be in the future",
+		}
+	}
+	return nil
+}
+
+// CreateAPIKeyResponse is the only time the full key is returned.
+type CreateAPIKeyResponse struct {
+	*APIKey
+	Key string `json:"key"`
+}
+
+type CreateBotRequest struct {
+	Username string `json:"username" validate:"required,min=5,max=30,alphanumunicode"`
+}
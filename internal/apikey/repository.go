@@ -0,0 +1,104 @@
+package apikey
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/lib/pq"
+	"github.com/maxwellzp/golang-chat-api/internal/db"
+	"time"
+)
+
+type APIKeyRepository struct {
+	database *db.Db
+}
+
+func NewAPIKeyRepository(database *db.Db) *APIKeyRepository {
+	return &APIKeyRepository{database: database}
+}
+
+// Create stores the key. It returns errPrefixTaken if another key already
+// has the prefix.
+func (r *APIKeyRepository) Create(ctx context.Context, key *APIKey) error {
+	query := `INSERT INTO api_keys (user_id, created_by, name, prefix, key_hash, scopes, expires_at, created_at)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+				ON CONFLICT (prefix) DO NOTHING
+				RETURNING id, created_at`
+
+	row := r.database.QueryRowContext(ctx, query, key.UserID, key.CreatedBy, key.Name, key.Prefix,
+		key.KeyHash, pq.Array(key.Scopes), key.ExpiresAt, time.Now().UTC())
+	err := row.Scan(&key.ID, &key.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return errPrefixTaken
+	}
+	return err
+}
+
+func (r *APIKeyRepository) FindByPrefix(ctx context.Context, prefix string) (*APIKey, error) {
+	query := `SELECT id, user_id, created_by, name, prefix, key_hash, scopes,
+				expires_at, last_used_at, revoked_at, created_at
+			  FROM api_keys WHERE prefix = $1`
+	row := r.database.QueryRowContext(ctx, query, prefix)
+
+	var key APIKey
+	err := row.Scan(&key.ID, &key.UserID, &key.CreatedBy, &key.Name, &key.Prefix, &key.KeyHash,
+		pq.Array(&key.Scopes), &key.ExpiresAt, &key.LastUsedAt, &key.RevokedAt, &key.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &key, nil
+}
+
+// ListByCreator returns keys created by the user, for themselves or their bots.
+func (r *APIKeyRepository) ListByCreator(ctx context.Context, userID int64) ([]*APIKey, error) {
+	query := `SELECT id, user_id, created_by, name, prefix, scopes,
+				expires_at, last_used_at, revoked_at, created_at
+			  FROM api_keys WHERE created_by = $1 ORDER BY created_at DESC`
+	rows, err := r.database.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []*APIKey
+	for rows.Next() {
+		var key APIKey
+		err := rows.Scan(&key.ID, &key.UserID, &key.CreatedBy, &key.Name, &key.Prefix,
+			pq.Array(&key.Scopes), &key.ExpiresAt, &key.LastUsedAt, &key.RevokedAt, &key.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, &key)
+	}
+	return keys, rows.Err()
+}
+
+func (r *APIKeyRepository) Revoke(ctx context.Context, id int64, userID int64) error {
+	res, err := r.database.ExecContext(ctx,
+		"UPDATE api_keys SET revoked_at = $1 WHERE id = $2 AND created_by = $3 AND revoked_at IS NULL",
+		time.Now().UTC(), id, userID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// TouchLastUsed records usage at most once a minute per key to avoid a write
+// on every request.
+func (r *APIKeyRepository) TouchLastUsed(ctx context.Context, id int64, now time.Time) error {
+	_, err := r.database.ExecContext(ctx,
+		`UPDATE api_keys SET last_used_at = $1
+		 WHERE id = $2 AND (last_used_at IS NULL OR last_used_at < $3)`,
+		now, id, now.Add(-time.Minute))
+	return err
+}
@@ -0,0 +1,181 @@
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"github.com/maxwellzp/golang-chat-api/internal/apperr"
+	"github.com/maxwellzp/golang-chat-api/internal/logger"
+	"github.com/maxwellzp/golang-chat-api/internal/user"
+	"strings"
+	"time"
+)
+
+// KeyPrefix marks API keys so they can be told apart from JWTs in the
+// Authorization header.
+const KeyPrefix = "ck_"
+
+// createAttempts bounds the fresh keys tried when a generated prefix is
+// already taken. With 64-bit prefixes a second attempt is already unlikely.
+const createAttempts = 3
+
+// errPrefixTaken is returned by the repository when a key with the same
+// prefix exists.
+var errPrefixTaken = errors.New("api key prefix already in use")
+
+var (
+	ErrInvalidAPIKey  = apperr.Unauthorized("invalid_api_key", "invalid api key")
+	ErrAPIKeyNotFound = apperr.NotFound("api_key_not_found", "api key not found or not owned by you")
+	ErrNotBotOwner    = apperr.Forbidden("not_bot_owner", "bot not found or not owned by you")
+	ErrUsernameTaken  = apperr.Conflict("username_taken", "username already in use")
+)
+
+type APIKeyService struct {
+	apiKeyRepository *APIKeyRepository
+	userRepository   *user.UserRepository
+	logger           *logger.Logger
+}
+
+func NewAPIKeyService(apiKeyRepository *APIKeyRepository, userRepository *user.UserRepository, logger *logger.Logger) *APIKeyService {
+	return &APIKeyService{
+		apiKeyRepository: apiKeyRepository,
+		userRepository:   userRepository,
+		logger:           logger,
+	}
+}
+
+// Create issues a key for the caller, or for one of their bots when BotID is
+// set. The plaintext key is returned once and never stored.
+func (s *APIKeyService) Create(ctx context.Context, userID int64, req CreateAPIKeyRequest) (*APIKey, string, error) {
+	ownerID := userID
+	if req.BotID != nil {
+		bot, err := s.userRepository.FindByID(ctx, *req.BotID)
+		if err != nil {
+			return nil, "", err
+		}
+		if bot == nil || !bot.IsBot || bot.OwnerID == nil || *bot.OwnerID != userID {
+			return nil, "", ErrNotBotOwner
+		}
+		ownerID = bot.ID
+	}
+
+	key := &APIKey{
+		UserID:    ownerID,
+		CreatedBy: userID,
+		Name:      req.Name,
+		Scopes:    req.Scopes,
+	}
+	if req.ExpiresAt != nil {
+		expiresAt := req.ExpiresAt.UTC()
+		key.ExpiresAt = &expiresAt
+	}
+	for attempt := 1; ; attempt++ {
+		prefix, secret, err := generateKey()
+		if err != nil {
+			return nil, "", err
+		}
+		rawKey := KeyPrefix + prefix + "_" + secret
+		key.Prefix = prefix
+		key.KeyHash = hashKey(rawKey)
+
+		err = s.apiKeyRepository.Create(ctx, key)
+		if errors.Is(err, errPrefixTaken) && attempt < createAttempts {
+			s.logger.Warnw("API key prefix collision, generating another key",
+				"attempt", attempt,
+			)
+			continue
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		return key, rawKey, nil
+	}
+}
+
+func (s *APIKeyService) List(ctx context.Context, userID int64) ([]*APIKey, error) {
+	return s.apiKeyRepository.ListByCreator(ctx, userID)
+}
+
+func (s *APIKeyService) Revoke(ctx context.Context, id int64, userID int64) error {
+	return s.apiKeyRepository.Revoke(ctx, id, userID)
+}
+
+// Authenticate resolves a raw "ck_..." key to the user it acts as and the
+// scopes it grants.
+func (s *APIKeyService) Authenticate(ctx context.Context, rawKey string) (int64, []string, error) {
+	rest, ok := strings.CutPrefix(rawKey, KeyPrefix)
+	if !ok {
+		return 0, nil, ErrInvalidAPIKey
+	}
+	prefix, _, ok := strings.Cut(rest, "_")
+	if !ok || prefix == "" {
+		return 0, nil, ErrInvalidAPIKey
+	}
+
+	key, err := s.apiKeyRepository.FindByPrefix(ctx, prefix)
+	if err != nil {
+		return 0, nil, err
+	}
+	if key == nil || subtle.ConstantTimeCompare([]byte(key.KeyHash), []byte(hashKey(rawKey))) != 1 {
+		return 0, nil, ErrInvalidAPIKey
+	}
+
+	now := time.Now().UTC()
+	if key.RevokedAt != nil || (key.ExpiresAt != nil && !key.ExpiresAt.After(now)) {
+		return 0, nil, ErrInvalidAPIKey
+	}
+
+	if err := s.apiKeyRepository.TouchLastUsed(ctx, key.ID, now); err != nil {
+		s.logger.Warnw("Failed to record api key usage",
+			"api_key_id", key.ID,
+			"error", err,
+		)
+	}
+	return key.UserID, key.Scopes, nil
+}
+
+// CreateBot registers a bot user owned by the caller. Bots have no password
+// and a placeholder email, so they can only authenticate with API keys.
+func (s *APIKeyService) CreateBot(ctx context.Context, ownerID int64, req CreateBotRequest) (*user.User, error) {
+	username := strings.TrimSpace(req.Username)
+	exists, err := s.userRepository.ExistsByUsername(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, ErrUsernameTaken
+	}
+
+	bot := &user.User{
+		Username: username,
+		Email:    strings.ToLower(username) + "@bot.invalid",
+		IsBot:    true,
+		OwnerID:  &ownerID,
+	}
+	if err := s.userRepository.Create(ctx, bot); err != nil {
+		return nil, err
+	}
+	return bot, nil
+}
+
+func (s *APIKeyService) ListBots(ctx context.Context, ownerID int64) ([]*user.User, error) {
+	return s.userRepository.ListBotsByOwner(ctx, ownerID)
+}
+
+// generateKey returns a 16 character prefix, which identifies the key and
+// is stored in the clear, and a 64 character secret.
+func generateKey() (prefix string, secret string, err error) {
+	b := make([]byte, 40)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	return hex.EncodeToString(b[:8]), hex.EncodeToString(b[8:]), nil
+}
+
+func hashKey(rawKey string) string {
+	sum := sha256.Sum256([]byte(rawKey))
+	return hex.EncodeToString(sum[:])
+}
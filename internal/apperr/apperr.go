@@ -0,0 +1,77 @@
+package apperr
+
+import (
+	"errors"
+	"time"
+)
+
+// Kind says what went wrong in terms a client can act on. httpx maps each
+// kind to a status code.
+type Kind int
+
+const (
+	KindNotFound Kind = iota + 1
+	KindForbidden
+	KindConflict
+	KindRateLimited
+	KindValidation
+	KindUnauthorized
+)
+
+// Error is a domain error returned by services and repositories. Code is a
+// stable identifier clients can match on, such as "room_not_found"; Message
+// is for people and may change.
+type Error struct {
+	Kind    Kind
+	Code    string
+	Message string
+	// RetryAfter is set on rate limited errors.
+	RetryAfter time.Duration
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func NotFound(code string, message string) *Error {
+	return &Error{Kind: KindNotFound, Code: code, Message: message}
+}
+
+func Forbidden(code string, message string) *Error {
+	return &Error{Kind: KindForbidden, Code: code, Message: message}
+}
+
+func Conflict(code string, message string) *Error {
+	return &Error{Kind: KindConflict, Code: code, Message: message}
+}
+
+func RateLimited(code string, message string, retryAfter time.Duration) *Error {
+	return &Error{Kind: KindRateLimited, Code: code, Message: message, RetryAfter: retryAfter}
+}
+
+func Validation(code string, message string) *Error {
+	return &Error{Kind: KindValidation, Code: code, Message: message}
+}
+
+func Unauthorized(code string, message string) *Error {
+	return &Error{Kind: KindUnauthorized, Code: code, Message: message}
+}
+
+// describer is implemented by error types that carry their own details, such
+// as how long a user is muted for, and so build their Error when asked.
+type describer interface {
+	AppError() *Error
+}
+
+// As returns the domain error in err's chain. ok is false for errors that
+// aren't domain errors, such as database failures.
+func As(err error) (e *Error, ok bool) {
+	if errors.As(err, &e) {
+		return e, true
+	}
+	var d describer
+	if errors.As(err, &d) {
+		return d.AppError(), true
+	}
+	return nil, false
+}
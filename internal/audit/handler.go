@@ -0,0 +1,116 @@
+package audit
+
+import (
+	"github.com/maxwellzp/golang-chat-api/internal/httpx"
+	"github.com/maxwellzp/golang-chat-api/internal/logger"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+type AuditHandler struct {
+	auditService *AuditService
+	logger       *logger.Logger
+}
+
+func NewAuditHandler(auditService *AuditService, logger *logger.Logger) *AuditHandler {
+	return &AuditHandler{
+		auditService: auditService,
+		logger:       logger,
+	}
+}
+
+// List searches the audit log. Filters: actor_id, action, target_type,
+// target_id, and since and until as RFC 3339 times.
+func (h *AuditHandler) List() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		limit, offset, err := httpx.ParsePagination(r, 50, 200)
+		if err != nil {
+			httpx.WriteValidationError(w, err)
+			return
+		}
+		query := r.URL.Query()
+		filter := Filter{
+			Action:     query.Get("action"),
+			TargetType: query.Get("target_type"),
+		}
+		for name, dest := range map[string]**int64{
+			"actor_id":  &filter.ActorID,
+			"target_id": &filter.TargetID,
+		} {
+			if *dest, err = parseIDFilter(query, name); err != nil {
+				httpx.WriteError(w, http.StatusBadRequest, "Invalid "+name)
+				return
+			}
+		}
+		for name, dest := range map[string]**time.Time{
+			"since": &filter.Since,
+			"until": &filter.Until,
+		} {
+			if *dest, err = parseTimeFilter(query, name); err != nil {
+				httpx.WriteError(w, http.StatusBadRequest, "Invalid "+name+", use an RFC 3339 time")
+				return
+			}
+		}
+
+		events, err := h.auditService.List(r.Context(), filter, limit, offset)
+		if err != nil {
+			h.logger.Errorw("Failed to list audit events",
+				"error", err,
+			)
+			httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
+			return
+		}
+		httpx.WriteJSON(w, http.StatusOK, events)
+	}
+}
+
+// Verify checks the hash chain of the whole audit log.
+func (h *AuditHandler) Verify() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		v, err := h.auditService.Verify(r.Context())
+		if err != nil {
+			h.logger.Errorw("Failed to verify audit log",
+				"error", err,
+			)
+			httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
+			return
+		}
+		if !v.Valid {
+			h.logger.Errorw("Audit log hash chain is broken",
+				"broken_id", *v.BrokenID,
+				"checked", v.Checked,
+			)
+		}
+		httpx.WriteJSON(w, http.StatusOK, v)
+	}
+}
+
+// parseIDFilter reads an optional ID from the query string.
+func parseIDFilter(query url.Values, name string) (*int64, error) {
+	raw := query.Get(name)
+	if raw == "" {
+		return nil, nil
+	}
+	id, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &id, nil
+}
+
+// parseTimeFilter reads an optional time from the query string, in UTC to
+// match the stored timestamps.
+func parseTimeFilter(query url.Values, name string) (*time.Time, error) {
+	raw := query.Get(name)
+	if raw == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return nil, err
+	}
+	t = t.UTC()
+	return &t, nil
+}
@@ -0,0 +1,36 @@
+package audit
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestComputeHashIsKeyed(t *testing.T) {
+	actorID := int64(7)
+	e := &Event{
+		OccurredAt: time.Date(2025, 10, 19, 12, 0, 0, 0, time.UTC),
+		ActorID:    &actorID,
+		Action:     ActionLogin,
+		After:      json.RawMessage(`{"method":"password"}`),
+		PrevHash:   genesisHash,
+	}
+
+	hash := e.computeHash([]byte("secret-1"))
+	if hash != e.computeHash([]byte("secret-1")) {
+		t.Fatal("hash is not deterministic")
+	}
+	if hash == e.computeHash([]byte("secret-2")) {
+		t.Error("hash does not depend on the key")
+	}
+
+	e.PrevHash = hash
+	if e.computeHash([]byte("secret-1")) == hash {
+		t.Error("hash does not depend on the previous hash")
+	}
+	e.PrevHash = genesisHash
+	e.After = json.RawMessage(`{"method":"oidc"}`)
+	if e.computeHash([]byte("secret-1")) == hash {
+		t.Error("hash does not depend on the event fields")
+	}
+}
@@ -0,0 +1,175 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/maxwellzp/golang-chat-api/internal/db"
+)
+
+// sealLock is the advisory lock key held while sealing, so sealers on
+// different replicas never link two events to the same previous hash.
+const sealLock = 7_368_117
+
+type AuditRepository struct {
+	database *db.Db
+}
+
+func NewAuditRepository(database *db.Db) *AuditRepository {
+	return &AuditRepository{database: database}
+}
+
+const eventColumns = `id, seq, occurred_at, actor_id, impersonator_id, action, target_type, target_id, ip, user_agent,
+			request_id, before, after, COALESCE(prev_hash, ''), COALESCE(hash, '')`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanEvent(row rowScanner) (*Event, error) {
+	var e Event
+	var before, after []byte
+	err := row.Scan(&e.ID, &e.Seq, &e.OccurredAt, &e.ActorID, &e.ImpersonatorID, &e.Action, &e.TargetType, &e.TargetID,
+		&e.IP, &e.UserAgent, &e.RequestID, &before, &after, &e.PrevHash, &e.Hash)
+	if err != nil {
+		return nil, err
+	}
+	e.Before = before
+	e.After = after
+	return &e, nil
+}
+
+// Append stores the event unsealed and fills in its ID. It takes no locks,
+// so recording an event never waits on other writers.
+func (r *AuditRepository) Append(ctx context.Context, e *Event) error {
+	return r.database.QueryRowContext(ctx,
+		`INSERT INTO audit_events (occurred_at, actor_id, impersonator_id, action, target_type, target_id, ip,
+			user_agent, request_id, before, after)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		 RETURNING id`,
+		e.OccurredAt, e.ActorID, e.ImpersonatorID, e.Action, e.TargetType, e.TargetID, e.IP,
+		e.UserAgent, e.RequestID, nullJSON(e.Before), nullJSON(e.After)).Scan(&e.ID)
+}
+
+// Seal chains up to limit unsealed events, oldest first, onto the end of
+// the chain with hash and returns how many it sealed. It returns 0 without
+// waiting if another sealer holds the lock.
+func (r *AuditRepository) Seal(ctx context.Context, limit int, hash func(e *Event) string) (int, error) {
+	tx, err := r.database.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var locked bool
+	if err := tx.QueryRowContext(ctx, "SELECT pg_try_advisory_xact_lock($1)", sealLock).Scan(&locked); err != nil {
+		return 0, err
+	}
+	if !locked {
+		return 0, nil
+	}
+
+	var lastSeq int64
+	lastHash := genesisHash
+	err = tx.QueryRowContext(ctx,
+		"SELECT seq, hash FROM audit_events WHERE seq IS NOT NULL ORDER BY seq DESC LIMIT 1").Scan(&lastSeq, &lastHash)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return 0, err
+	}
+
+	rows, err := tx.QueryContext(ctx,
+		`SELECT `+eventColumns+`
+		 FROM audit_events
+		 WHERE seq IS NULL
+		 ORDER BY id
+		 LIMIT $1`,
+		limit)
+	if err != nil {
+		return 0, err
+	}
+	events, err := collectEvents(rows)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, e := range events {
+		lastSeq++
+		e.Seq = &lastSeq
+		e.PrevHash = lastHash
+		e.Hash = hash(e)
+		_, err := tx.ExecContext(ctx,
+			"UPDATE audit_events SET seq = $1, prev_hash = $2, hash = $3 WHERE id = $4",
+			lastSeq, e.PrevHash, e.Hash, e.ID)
+		if err != nil {
+			return 0, err
+		}
+		lastHash = e.Hash
+	}
+	return len(events), tx.Commit()
+}
+
+// List returns the events matching the filter, newest first.
+func (r *AuditRepository) List(ctx context.Context, filter Filter, limit int, offset int) ([]*Event, error) {
+	rows, err := r.database.QueryContext(ctx,
+		`SELECT `+eventColumns+`
+		 FROM audit_events
+		 WHERE ($1::int IS NULL OR actor_id = $1)
+		 AND ($2 = '' OR action = $2)
+		 AND ($3 = '' OR target_type = $3)
+		 AND ($4::int IS NULL OR target_id = $4)
+		 AND ($5::timestamp IS NULL OR occurred_at >= $5)
+		 AND ($6::timestamp IS NULL OR occurred_at < $6)
+		 ORDER BY id DESC
+		 LIMIT $7 OFFSET $8`,
+		filter.ActorID, filter.Action, filter.TargetType, filter.TargetID, filter.Since, filter.Until,
+		limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	return collectEvents(rows)
+}
+
+// ListAfter returns up to limit sealed events with seq above afterSeq, in
+// chain order.
+func (r *AuditRepository) ListAfter(ctx context.Context, afterSeq int64, limit int) ([]*Event, error) {
+	rows, err := r.database.QueryContext(ctx,
+		`SELECT `+eventColumns+`
+		 FROM audit_events
+		 WHERE seq > $1
+		 ORDER BY seq
+		 LIMIT $2`,
+		afterSeq, limit)
+	if err != nil {
+		return nil, err
+	}
+	return collectEvents(rows)
+}
+
+// CountUnsealed returns how many events are waiting for the sealer.
+func (r *AuditRepository) CountUnsealed(ctx context.Context) (int, error) {
+	var count int
+	err := r.database.QueryRowContext(ctx, "SELECT COUNT(*) FROM audit_events WHERE seq IS NULL").Scan(&count)
+	return count, err
+}
+
+func collectEvents(rows *sql.Rows) ([]*Event, error) {
+	defer rows.Close()
+
+	events := []*Event{}
+	for rows.Next() {
+		e, err := scanEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// nullJSON stores an absent diff as NULL rather than an empty string.
+func nullJSON(b []byte) any {
+	if b == nil {
+		return nil
+	}
+	return b
+}
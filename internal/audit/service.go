@@ -0,0 +1,175 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/maxwellzp/golang-chat-api/internal/config"
+	"github.com/maxwellzp/golang-chat-api/internal/httpx"
+	"github.com/maxwellzp/golang-chat-api/internal/logger"
+	"time"
+)
+
+// verifyBatch is how many events Verify reads at a time.
+const verifyBatch = 1000
+
+type AuditService struct {
+	auditRepository *AuditRepository
+	key             []byte
+	cfg             config.AuditConfig
+	logger          *logger.Logger
+}
+
+func NewAuditService(auditRepository *AuditRepository, cfg config.AuditConfig, logger *logger.Logger) *AuditService {
+	return &AuditService{
+		auditRepository: auditRepository,
+		key:             []byte(cfg.Secret),
+		cfg:             cfg,
+		logger:          logger,
+	}
+}
+
+// Record appends an event to the audit log, taking the IP, User-Agent,
+// request ID and impersonating admin from the request context. The event
+// is chained later by Run. The action it records has already happened, so
+// a failure to write is logged rather than returned.
+func (s *AuditService) Record(ctx context.Context, entry Entry) {
+	e := &Event{
+		OccurredAt: time.Now().UTC().Truncate(time.Microsecond),
+		ActorID:    entry.ActorID,
+		Action:     entry.Action,
+		TargetType: entry.TargetType,
+		TargetID:   entry.TargetID,
+		IP:         httpx.GetClientIP(ctx),
+		UserAgent:  truncate(httpx.GetUserAgent(ctx), 500),
+		RequestID:  truncate(httpx.GetRequestID(ctx), 100),
+	}
+	if id, ok := httpx.GetImpersonatorID(ctx); ok {
+		e.ImpersonatorID = &id
+	}
+	var err error
+	if e.Before, err = marshalFields(entry.Before); err == nil {
+		e.After, err = marshalFields(entry.After)
+	}
+	if err == nil {
+		// The event is written even if the request was cancelled.
+		err = s.auditRepository.Append(context.WithoutCancel(ctx), e)
+	}
+	if err != nil {
+		s.logger.Errorw("Failed to record audit event",
+			"action", entry.Action,
+			"actor_id", entry.ActorID,
+			"target_type", entry.TargetType,
+			"target_id", entry.TargetID,
+			"error", err,
+		)
+	}
+}
+
+// Run seals recorded events into the hash chain every SealInterval until
+// ctx is cancelled, then seals what is left.
+func (s *AuditService) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.SealInterval)
+	defer ticker.Stop()
+
+	for {
+		s.seal(ctx)
+		select {
+		case <-ctx.Done():
+			s.seal(context.WithoutCancel(ctx))
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// seal seals batches until no unsealed events are left or another replica
+// is sealing.
+func (s *AuditService) seal(ctx context.Context) {
+	for {
+		sealed, err := s.auditRepository.Seal(ctx, s.cfg.SealBatch, func(e *Event) string {
+			return e.computeHash(s.key)
+		})
+		if err != nil {
+			if ctx.Err() == nil {
+				s.logger.Errorw("Failed to seal audit events",
+					"error", err,
+				)
+			}
+			return
+		}
+		if sealed < s.cfg.SealBatch {
+			return
+		}
+	}
+}
+
+// List returns the audit events matching the filter, newest first.
+func (s *AuditService) List(ctx context.Context, filter Filter, limit int, offset int) ([]*Event, error) {
+	return s.auditRepository.List(ctx, filter, limit, offset)
+}
+
+// Verify walks the whole chain from the first event, recomputing each hash
+// and checking each link, and stops at the first event that doesn't match.
+// Events the sealer hasn't reached yet are only counted.
+func (s *AuditService) Verify(ctx context.Context) (*Verification, error) {
+	v := &Verification{Valid: true, LastHash: genesisHash}
+	for {
+		events, err := s.auditRepository.ListAfter(ctx, v.LastSeq, verifyBatch)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range events {
+			if *e.Seq != v.LastSeq+1 || e.PrevHash != v.LastHash || e.computeHash(s.key) != e.Hash {
+				v.Valid = false
+				v.BrokenID = &e.ID
+				return v, nil
+			}
+			v.Checked++
+			v.LastSeq = *e.Seq
+			v.LastID = e.ID
+			v.LastHash = e.Hash
+		}
+		if len(events) < verifyBatch {
+			break
+		}
+	}
+	pending, err := s.auditRepository.CountUnsealed(ctx)
+	if err != nil {
+		return nil, err
+	}
+	v.Pending = pending
+	return v, nil
+}
+
+// Changes keeps the fields whose values differ between before and after,
+// for an update's Entry.Before and Entry.After.
+func Changes(before map[string]any, after map[string]any) (map[string]any, map[string]any) {
+	changedBefore := map[string]any{}
+	changedAfter := map[string]any{}
+	for key, value := range after {
+		old, _ := json.Marshal(before[key])
+		updated, _ := json.Marshal(value)
+		if !bytes.Equal(old, updated) {
+			changedBefore[key] = before[key]
+			changedAfter[key] = value
+		}
+	}
+	return changedBefore, changedAfter
+}
+
+func marshalFields(fields map[string]any) (json.RawMessage, error) {
+	if fields == nil {
+		return nil, nil
+	}
+	return json.Marshal(fields)
+}
+
+// truncate cuts s to at most limit characters.
+func truncate(s string, limit int) string {
+	runes := []rune(s)
+	if len(runes) <= limit {
+		return s
+	}
+	return string(runes[:limit])
+}
@@ -0,0 +1,167 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/maxwellzp/golang-chat-api/internal/db"
+	"sync"
+	"time"
+)
+
+// Attempts is the failed-login state tracked for a single key (an account or
+// a client IP).
+type Attempts struct {
+	Failures      int
+	LastFailureAt time.Time
+	LockedUntil   *time.Time
+}
+
+// AttemptStore persists failed-login counters. Implementations must make
+// RecordFailure atomic so concurrent attempts are all counted.
+type AttemptStore interface {
+	Get(ctx context.Context, key string) (*Attempts, error)
+	RecordFailure(ctx context.Context, key string, now time.Time, window time.Duration) (*Attempts, error)
+	Lock(ctx context.Context, key string, until time.Time) error
+	Reset(ctx context.Context, key string) error
+}
+
+type MemoryAttemptStore struct {
+	mu        sync.Mutex
+	entries   map[string]*Attempts
+	lastSweep time.Time
+}
+
+func NewMemoryAttemptStore() *MemoryAttemptStore {
+	return &MemoryAttemptStore{entries: make(map[string]*Attempts)}
+}
+
+func (s *MemoryAttemptStore) Get(ctx context.Context, key string) (*Attempts, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a, ok := s.entries[key]
+	if !ok {
+		return nil, nil
+	}
+	copied := *a
+	return &copied, nil
+}
+
+func (s *MemoryAttemptStore) RecordFailure(ctx context.Context, key string, now time.Time, window time.Duration) (*Attempts, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sweep(now, window)
+
+	a, ok := s.entries[key]
+	if !ok || now.Sub(a.LastFailureAt) > window {
+		a = &Attempts{LockedUntil: lockedUntil(a, now)}
+		s.entries[key] = a
+	}
+	a.Failures++
+	a.LastFailureAt = now
+
+	copied := *a
+	return &copied, nil
+}
+
+func (s *MemoryAttemptStore) Lock(ctx context.Context, key string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a, ok := s.entries[key]
+	if !ok {
+		a = &Attempts{}
+		s.entries[key] = a
+	}
+	a.LockedUntil = &until
+	return nil
+}
+
+func (s *MemoryAttemptStore) Reset(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, key)
+	return nil
+}
+
+// sweep drops counters that have outlived the window and any lock, so the
+// map doesn't grow with every account and IP that ever failed to log in.
+func (s *MemoryAttemptStore) sweep(now time.Time, window time.Duration) {
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+	s.lastSweep = now
+	for key, a := range s.entries {
+		if now.Sub(a.LastFailureAt) > window && lockedUntil(a, now) == nil {
+			delete(s.entries, key)
+		}
+	}
+}
+
+// lockedUntil keeps an active lock when a stale counter is restarted.
+func lockedUntil(a *Attempts, now time.Time) *time.Time {
+	if a != nil && a.LockedUntil != nil && a.LockedUntil.After(now) {
+		return a.LockedUntil
+	}
+	return nil
+}
+
+type PostgresAttemptStore struct {
+	database *db.Db
+}
+
+func NewPostgresAttemptStore(database *db.Db) *PostgresAttemptStore {
+	return &PostgresAttemptStore{database: database}
+}
+
+func (s *PostgresAttemptStore) Get(ctx context.Context, key string) (*Attempts, error) {
+	query := `SELECT failures, last_failure_at, locked_until FROM login_attempts WHERE key = $1`
+	row := s.database.QueryRowContext(ctx, query, key)
+
+	var a Attempts
+	if err := row.Scan(&a.Failures, &a.LastFailureAt, &a.LockedUntil); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (s *PostgresAttemptStore) RecordFailure(ctx context.Context, key string, now time.Time, window time.Duration) (*Attempts, error) {
+	query := `
+		INSERT INTO login_attempts (key, failures, last_failure_at)
+		VALUES ($1, 1, $2)
+		ON CONFLICT (key) DO UPDATE SET
+			failures = CASE
+				WHEN login_attempts.last_failure_at < $3 THEN 1
+				ELSE login_attempts.failures + 1
+			END,
+			last_failure_at = EXCLUDED.last_failure_at
+		RETURNING failures, last_failure_at, locked_until;`
+
+	var a Attempts
+	err := s.database.QueryRowContext(ctx, query, key, now, now.Add(-window)).
+		Scan(&a.Failures, &a.LastFailureAt, &a.LockedUntil)
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (s *PostgresAttemptStore) Lock(ctx context.Context, key string, until time.Time) error {
+	query := `
+		INSERT INTO login_attempts (key, failures, last_failure_at, locked_until)
+		VALUES ($1, 0, $2, $3)
+		ON CONFLICT (key) DO UPDATE SET locked_until = EXCLUDED.locked_until;`
+	_, err := s.database.ExecContext(ctx, query, key, time.Now().UTC(), until)
+	return err
+}
+
+func (s *PostgresAttemptStore) Reset(ctx context.Context, key string) error {
+	_, err := s.database.ExecContext(ctx, "DELETE FROM login_attempts WHERE key = $1", key)
+	return err
+}
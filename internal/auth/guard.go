@@ -0,0 +1,150 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"github.com/maxwellzp/golang-chat-api/internal/apperr"
+	"github.com/maxwellzp/golang-chat-api/internal/config"
+	"time"
+)
+
+// LoginBlockedError is returned when a login attempt is refused before the
+// password is checked. Locked is set when the account itself is locked out,
+// as opposed to being throttled.
+type LoginBlockedError struct {
+	RetryAfter time.Duration
+	Locked     bool
+}
+
+func (e *LoginBlockedError) Error() string {
+	if e.Locked {
+		return "account temporarily locked due to too many failed login attempts; reset your password to unlock it"
+	}
+	return fmt.Sprintf("too many failed login attempts, retry in %s", e.RetryAfter.Round(time.Second))
+}
+
+// AppError describes both cases as rate limiting; the login handler answers
+// 423 Locked for a locked account.
+func (e *LoginBlockedError) AppError() *apperr.Error {
+	code := "login_throttled"
+	if e.Locked {
+		code = "account_locked"
+	}
+	return apperr.RateLimited(code, e.Error(), e.RetryAfter)
+}
+
+type LoginGuard struct {
+	store AttemptStore
+	cfg   config.LoginProtectionConfig
+	now   func() time.Time
+}
+
+func NewLoginGuard(store AttemptStore, cfg config.LoginProtectionConfig) *LoginGuard {
+	return &LoginGuard{store: store, cfg: cfg, now: utcNow}
+}
+
+// utcNow keeps stored timestamps comparable: the TIMESTAMP columns drop the
+// zone offset, so everything is written and compared in UTC.
+func utcNow() time.Time {
+	return time.Now().UTC()
+}
+
+// Check refuses the attempt when the account or the client IP is locked or
+// still inside its backoff period.
+func (g *LoginGuard) Check(ctx context.Context, email, ip string) error {
+	now := g.now()
+
+	account, err := g.store.Get(ctx, accountKey(email))
+	if err != nil {
+		return err
+	}
+	if account != nil {
+		if account.LockedUntil != nil && account.LockedUntil.After(now) {
+			return &LoginBlockedError{RetryAfter: account.LockedUntil.Sub(now), Locked: true}
+		}
+		if wait := g.remainingBackoff(account, now); wait > 0 {
+			return &LoginBlockedError{RetryAfter: wait}
+		}
+	}
+
+	if ip == "" {
+		return nil
+	}
+	client, err := g.store.Get(ctx, ipKey(ip))
+	if err != nil {
+		return err
+	}
+	if client != nil && client.LockedUntil != nil && client.LockedUntil.After(now) {
+		return &LoginBlockedError{RetryAfter: client.LockedUntil.Sub(now)}
+	}
+	return nil
+}
+
+// Fail records a failed attempt and locks the account or IP once its
+// threshold is reached.
+func (g *LoginGuard) Fail(ctx context.Context, email, ip string) error {
+	now := g.now()
+
+	account, err := g.store.RecordFailure(ctx, accountKey(email), now, g.cfg.Window)
+	if err != nil {
+		return err
+	}
+	if account.Failures >= g.cfg.MaxAccountFailures {
+		if err := g.store.Lock(ctx, accountKey(email), now.Add(g.cfg.LockoutDuration)); err != nil {
+			return err
+		}
+	}
+
+	if ip == "" {
+		return nil
+	}
+	client, err := g.store.RecordFailure(ctx, ipKey(ip), now, g.cfg.Window)
+	if err != nil {
+		return err
+	}
+	if client.Failures >= g.cfg.MaxIPFailures {
+		return g.store.Lock(ctx, ipKey(ip), now.Add(g.cfg.LockoutDuration))
+	}
+	return nil
+}
+
+// Succeed clears the account counters after a successful login. IP counters
+// are left to expire so one valid account can't be used to reset them.
+func (g *LoginGuard) Succeed(ctx context.Context, email string) error {
+	return g.store.Reset(ctx, accountKey(email))
+}
+
+// Unlock lifts an account lockout, e.g. after a password reset.
+func (g *LoginGuard) Unlock(ctx context.Context, email string) error {
+	return g.store.Reset(ctx, accountKey(email))
+}
+
+func (g *LoginGuard) remainingBackoff(a *Attempts, now time.Time) time.Duration {
+	if now.Sub(a.LastFailureAt) > g.cfg.Window {
+		return 0
+	}
+	return a.LastFailureAt.Add(g.backoff(a.Failures)).Sub(now)
+}
+
+func (g *LoginGuard) backoff(failures int) time.Duration {
+	excess := failures - g.cfg.FreeAttempts
+	if excess <= 0 {
+		return 0
+	}
+	wait := g.cfg.BaseBackoff
+	for i := 1; i < excess && wait < g.cfg.MaxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > g.cfg.MaxBackoff {
+		wait = g.cfg.MaxBackoff
+	}
+	return wait
+}
+
+func accountKey(email string) string {
+	return "account:" + email
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
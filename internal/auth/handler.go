@@ -2,6 +2,7 @@ package auth
 
 import (
 	"encoding/json"
+	"errors"
 	"github.com/maxwellzp/golang-chat-api/internal/httpx"
 	"github.com/maxwellzp/golang-chat-api/internal/logger"
 	"github.com/maxwellzp/golang-chat-api/internal/validatorx"
@@ -42,13 +43,26 @@ func (h *AuthHandler) Login() http.HandlerFunc {
 			return
 		}
 
-		user, token, err := h.authService.Login(r.Context(), req.Email, req.Password)
+		user, token, err := h.authService.Login(r.Context(), req.Email, req.Password, httpx.ClientIP(r))
+		var blocked *LoginBlockedError
+		if errors.As(err, &blocked) && blocked.Locked {
+			httpx.SetRetryAfter(w, blocked.RetryAfter)
+			httpx.WriteProblem(w, httpx.Problem{
+				Status: http.StatusLocked,
+				Detail: blocked.Error(),
+				Code:   blocked.AppError().Code,
+			})
+			return
+		}
 		if err != nil {
 			h.logger.Warnw("Login failed",
 				"email", req.Email,
 				"error", err,
 			)
-			httpx.WriteError(w, http.StatusUnauthorized, err.Error())
+			if httpx.WriteServiceError(w, err) {
+				return
+			}
+			httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
 			return
 		}
 		h.logger.Infow("User logged in successfully",
@@ -87,7 +101,10 @@ func (h *AuthHandler) Register() http.HandlerFunc {
 				"email", req.Email,
 				"error", err,
 			)
-			httpx.WriteError(w, http.StatusBadRequest, err.Error())
+			if httpx.WriteServiceError(w, err) {
+				return
+			}
+			httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
 			return
 		}
 		h.logger.Infow("User registered successfully",
@@ -97,3 +114,72 @@ func (h *AuthHandler) Register() http.HandlerFunc {
 		httpx.WriteJSON(w, http.StatusCreated, user)
 	}
 }
+
+func (h *AuthHandler) ForgotPassword() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req ForgotPasswordRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.logger.Warnw("Forgot password request JSON decode failed",
+				"error", err,
+			)
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+
+		if err := h.validator.Validate(&req); err != nil {
+			h.logger.Infow("Forgot password request validation failed",
+				"email", req.Email,
+				"errors", err,
+			)
+			httpx.WriteValidationError(w, err)
+			return
+		}
+
+		if err := h.authService.ForgotPassword(r.Context(), req.Email); err != nil {
+			h.logger.Errorw("Forgot password failed",
+				"email", req.Email,
+				"error", err,
+			)
+			httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
+			return
+		}
+		httpx.WriteJSON(w, http.StatusAccepted, map[string]string{
+			"message": "If the email is registered, a password reset link has been sent",
+		})
+	}
+}
+
+func (h *AuthHandler) ResetPassword() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req ResetPasswordRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.logger.Warnw("Reset password request JSON decode failed",
+				"error", err,
+			)
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+
+		if err := h.validator.Validate(&req); err != nil {
+			h.logger.Infow("Reset password request validation failed",
+				"errors", err,
+			)
+			httpx.WriteValidationError(w, err)
+			return
+		}
+
+		err := h.authService.ResetPassword(r.Context(), req.Token, req.Password)
+		if httpx.WriteServiceError(w, err) {
+			return
+		}
+		if err != nil {
+			h.logger.Errorw("Reset password failed",
+				"error", err,
+			)
+			httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
+			return
+		}
+		h.logger.Infow("Password reset successfully")
+		httpx.WriteJSON(w, http.StatusNoContent, nil)
+	}
+}
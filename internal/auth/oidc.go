@@ -0,0 +1,359 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/maxwellzp/golang-chat-api/internal/apperr"
+	"github.com/maxwellzp/golang-chat-api/internal/audit"
+	"github.com/maxwellzp/golang-chat-api/internal/logger"
+	"github.com/maxwellzp/golang-chat-api/internal/oidc"
+	"github.com/maxwellzp/golang-chat-api/internal/user"
+	"strings"
+	"time"
+	"unicode"
+)
+
+var (
+	ErrUnknownProvider  = apperr.NotFound("unknown_provider", "unknown identity provider")
+	ErrInvalidOIDCState = apperr.Validation("invalid_login_state", "invalid or expired login state")
+	// ErrAccountExists is returned when the provider's email belongs to a
+	// local account whose email was never verified. Linking it would let
+	// anyone who registered the address first take the account over, so the
+	// owner has to sign in with their password and link the provider.
+	ErrAccountExists = apperr.Conflict("account_exists",
+		"an account with this email already exists, sign in with your password and link the provider from your account")
+	ErrIdentityTaken = apperr.Conflict("identity_taken", "this identity is already linked to another account")
+)
+
+const oidcStateTTL = 10 * time.Minute
+
+// oidcState is carried in a signed cookie between the start and callback
+// requests, so no server-side session is needed.
+type oidcState struct {
+	jwt.RegisteredClaims
+	Provider     string `json:"provider"`
+	State        string `json:"state"`
+	Nonce        string `json:"nonce"`
+	CodeVerifier string `json:"code_verifier"`
+	// LinkUserID is set when a signed-in user links the provider to their
+	// account instead of signing in with it.
+	LinkUserID int64 `json:"link_user_id,omitempty"`
+}
+
+type OIDCService struct {
+	providers          map[string]*oidc.Provider
+	userRepository     *user.UserRepository
+	identityRepository *user.IdentityRepository
+	authService        *AuthService
+	stateSecret        []byte
+	logger             *logger.Logger
+}
+
+func NewOIDCService(
+	providers []*oidc.Provider,
+	userRepository *user.UserRepository,
+	identityRepository *user.IdentityRepository,
+	authService *AuthService,
+	stateSecret string,
+	logger *logger.Logger,
+) *OIDCService {
+	// The state key is derived from the JWT secret so a state cookie can
+	// never be replayed as an access token.
+	byName := make(map[string]*oidc.Provider, len(providers))
+	for _, p := range providers {
+		byName[p.Name] = p
+	}
+	return &OIDCService{
+		providers:          byName,
+		userRepository:     userRepository,
+		identityRepository: identityRepository,
+		authService:        authService,
+		stateSecret:        []byte("oidc-state:" + stateSecret),
+		logger:             logger,
+	}
+}
+
+// Start returns the provider authorization URL and the signed state that the
+// caller must hand back to Callback.
+func (s *OIDCService) Start(ctx context.Context, providerName string) (redirectURL string, signedState string, err error) {
+	return s.start(ctx, providerName, 0)
+}
+
+// StartLink is Start for a signed-in user: the callback links the external
+// identity to userID rather than signing in.
+func (s *OIDCService) StartLink(ctx context.Context, providerName string, userID int64) (redirectURL string, signedState string, err error) {
+	return s.start(ctx, providerName, userID)
+}
+
+func (s *OIDCService) start(ctx context.Context, providerName string, linkUserID int64) (redirectURL string, signedState string, err error) {
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return "", "", ErrUnknownProvider
+	}
+
+	state, err := oidc.RandomString()
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := oidc.RandomString()
+	if err != nil {
+		return "", "", err
+	}
+	verifier, err := oidc.RandomString()
+	if err != nil {
+		return "", "", err
+	}
+
+	redirectURL, err = provider.AuthCodeURL(ctx, state, nonce, oidc.CodeChallenge(verifier))
+	if err != nil {
+		s.logger.Errorw("OIDC authorization URL failed",
+			"provider", providerName,
+			"error", err,
+		)
+		return "", "", err
+	}
+
+	claims := oidcState{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(oidcStateTTL)),
+		},
+		Provider:     providerName,
+		State:        state,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		LinkUserID:   linkUserID,
+	}
+	signedState, err = jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.stateSecret)
+	if err != nil {
+		return "", "", err
+	}
+	return redirectURL, signedState, nil
+}
+
+// Callback completes the flow: it checks state, exchanges the code, verifies
+// the ID token and signs the user in, linking or creating a local account.
+func (s *OIDCService) Callback(ctx context.Context, providerName, code, state, signedState string) (*user.User, string, error) {
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return nil, "", ErrUnknownProvider
+	}
+
+	saved := &oidcState{}
+	_, err := jwt.ParseWithClaims(signedState, saved, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("unexpected signing method")
+		}
+		return s.stateSecret, nil
+	})
+	if err != nil || saved.Provider != providerName || saved.State == "" || saved.State != state {
+		s.logger.Warnw("OIDC callback with invalid state",
+			"provider", providerName,
+			"error", err,
+		)
+		return nil, "", ErrInvalidOIDCState
+	}
+
+	tokens, err := provider.Exchange(ctx, code, saved.CodeVerifier)
+	if err != nil {
+		s.logger.Warnw("OIDC code exchange failed",
+			"provider", providerName,
+			"error", err,
+		)
+		return nil, "", err
+	}
+	claims, err := provider.VerifyIDToken(ctx, tokens.IDToken, saved.Nonce)
+	if err != nil {
+		s.logger.Warnw("OIDC id_token verification failed",
+			"provider", providerName,
+			"error", err,
+		)
+		return nil, "", err
+	}
+
+	var u *user.User
+	if saved.LinkUserID != 0 {
+		u, err = s.linkUser(ctx, providerName, saved.LinkUserID, claims)
+	} else {
+		u, err = s.resolveUser(ctx, providerName, claims)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	if err := CheckLogin(u); err != nil {
+		s.logger.Warnw("OIDC login refused",
+			"user_id", u.ID,
+			"error", err,
+		)
+		s.authService.auditLoginFailure(ctx, &u.ID, u.Email, "suspended")
+		return nil, "", err
+	}
+	token, err := s.authService.IssueToken(u)
+	if err != nil {
+		s.logger.Errorw("JWT generation failed",
+			"user_id", u.ID,
+			"error", err,
+		)
+		return nil, "", err
+	}
+	s.authService.audit.Record(ctx, userEntry(audit.ActionLogin, u.ID, map[string]any{
+		"method":   "oidc",
+		"provider": providerName,
+	}))
+	u.Password = ""
+	return u, token, nil
+}
+
+// resolveUser finds the user linked to the external identity. An unlinked
+// identity is attached to the local account with the same email only when
+// both the provider and this server have verified that email; if no account
+// uses the email a new one is created.
+func (s *OIDCService) resolveUser(ctx context.Context, providerName string, claims *oidc.IDTokenClaims) (*user.User, error) {
+	identity, err := s.identityRepository.FindByProviderSubject(ctx, providerName, claims.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if identity != nil {
+		u, err := s.userRepository.FindByID(ctx, identity.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if u == nil {
+			return nil, errors.New("linked user no longer exists")
+		}
+		return u, nil
+	}
+
+	email := strings.TrimSpace(strings.ToLower(claims.Email))
+	if email == "" || !claims.EmailVerified {
+		return nil, errors.New("identity provider did not return a verified email")
+	}
+	u, err := s.userRepository.FindByEmail(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	if u != nil && u.EmailVerifiedAt == nil {
+		s.logger.Warnw("OIDC login matches an unverified account",
+			"user_id", u.ID,
+			"provider", providerName,
+		)
+		return nil, ErrAccountExists
+	}
+
+	if u == nil {
+		username, err := s.uniqueUsername(ctx, claims)
+		if err != nil {
+			return nil, err
+		}
+		// No usable password: the account signs in through the provider
+		// until the user sets one via password reset.
+		verifiedAt := time.Now().UTC()
+		u = &user.User{Username: username, Email: email, EmailVerifiedAt: &verifiedAt}
+		if err := s.userRepository.Create(ctx, u); err != nil {
+			s.logger.Errorw("OIDC user creation failed",
+				"provider", providerName,
+				"error", err,
+			)
+			return nil, err
+		}
+		s.logger.Infow("User created from OIDC identity",
+			"user_id", u.ID,
+			"provider", providerName,
+		)
+	}
+
+	if err := s.createIdentity(ctx, u.ID, providerName, claims.Subject, email); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// linkUser attaches the external identity to a signed-in user. The user
+// proved who they are when they started the flow, so the emails don't need
+// to match.
+func (s *OIDCService) linkUser(ctx context.Context, providerName string, userID int64, claims *oidc.IDTokenClaims) (*user.User, error) {
+	u, err := s.userRepository.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil {
+		return nil, ErrInvalidOIDCState
+	}
+
+	identity, err := s.identityRepository.FindByProviderSubject(ctx, providerName, claims.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if identity != nil {
+		if identity.UserID != u.ID {
+			return nil, ErrIdentityTaken
+		}
+		return u, nil
+	}
+
+	email := strings.TrimSpace(strings.ToLower(claims.Email))
+	if err := s.createIdentity(ctx, u.ID, providerName, claims.Subject, email); err != nil {
+		return nil, err
+	}
+	s.authService.audit.Record(ctx, userEntry(audit.ActionIdentityLink, u.ID, map[string]any{
+		"provider": providerName,
+	}))
+	return u, nil
+}
+
+func (s *OIDCService) createIdentity(ctx context.Context, userID int64, providerName, subject, email string) error {
+	err := s.identityRepository.Create(ctx, &user.Identity{
+		UserID:   userID,
+		Provider: providerName,
+		Subject:  subject,
+		Email:    email,
+	})
+	if err != nil {
+		s.logger.Errorw("OIDC identity link failed",
+			"user_id", userID,
+			"provider", providerName,
+			"error", err,
+		)
+		return err
+	}
+	s.logger.Infow("OIDC identity linked",
+		"user_id", userID,
+		"provider", providerName,
+	)
+	return nil
+}
+
+// uniqueUsername derives a username matching the registration rules
+// (5-30 alphanumeric characters) from the ID token claims.
+func (s *OIDCService) uniqueUsername(ctx context.Context, claims *oidc.IDTokenClaims) (string, error) {
+	base := claims.PreferredUsername
+	if base == "" {
+		base, _, _ = strings.Cut(claims.Email, "@")
+	}
+	base = strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, base)
+	if runes := []rune(base); len(runes) > 24 {
+		base = string(runes[:24])
+	}
+	for len([]rune(base)) < 5 {
+		base += "user"
+	}
+
+	candidate := base
+	for i := 1; i <= 50; i++ {
+		exists, err := s.userRepository.ExistsByUsername(ctx, candidate)
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			return candidate, nil
+		}
+		candidate = fmt.Sprintf("%s%d", base, i)
+	}
+	return "", errors.New("could not generate a unique username")
+}
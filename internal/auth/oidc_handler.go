@@ -0,0 +1,146 @@
+package auth
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/maxwellzp/golang-chat-api/internal/httpx"
+	"github.com/maxwellzp/golang-chat-api/internal/logger"
+	"net/http"
+	"strings"
+)
+
+const oidcStateCookie = "oidc_state"
+
+type OIDCHandler struct {
+	oidcService  *OIDCService
+	secureCookie bool
+	logger       *logger.Logger
+}
+
+func NewOIDCHandler(oidcService *OIDCService, baseURL string, logger *logger.Logger) *OIDCHandler {
+	return &OIDCHandler{
+		oidcService:  oidcService,
+		secureCookie: strings.HasPrefix(baseURL, "https://"),
+		logger:       logger,
+	}
+}
+
+func (h *OIDCHandler) Start() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		provider := chi.URLParam(r, "provider")
+
+		redirectURL, signedState, err := h.oidcService.Start(r.Context(), provider)
+		if errors.Is(err, ErrUnknownProvider) {
+			httpx.WriteServiceError(w, err)
+			return
+		}
+		if err != nil {
+			h.logger.Errorw("OIDC start failed",
+				"provider", provider,
+				"error", err,
+			)
+			httpx.WriteError(w, http.StatusBadGateway, "Identity provider is unavailable")
+			return
+		}
+
+		h.setStateCookie(w, signedState)
+		http.Redirect(w, r, redirectURL, http.StatusFound)
+	}
+}
+
+// Link starts the flow for a signed-in user who wants to attach the provider
+// to their account. It answers with the authorization URL instead of a
+// redirect, since the request carries a bearer token and comes from script.
+func (h *OIDCHandler) Link() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := httpx.GetUserID(r.Context())
+		if err != nil {
+			httpx.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		provider := chi.URLParam(r, "provider")
+
+		redirectURL, signedState, err := h.oidcService.StartLink(r.Context(), provider, userID)
+		if errors.Is(err, ErrUnknownProvider) {
+			httpx.WriteServiceError(w, err)
+			return
+		}
+		if err != nil {
+			h.logger.Errorw("OIDC link start failed",
+				"user_id", userID,
+				"provider", provider,
+				"error", err,
+			)
+			httpx.WriteError(w, http.StatusBadGateway, "Identity provider is unavailable")
+			return
+		}
+
+		h.setStateCookie(w, signedState)
+		httpx.WriteJSON(w, http.StatusOK, LinkResponse{AuthorizationURL: redirectURL})
+	}
+}
+
+func (h *OIDCHandler) setStateCookie(w http.ResponseWriter, signedState string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    signedState,
+		Path:     "/auth/oidc/",
+		MaxAge:   int(oidcStateTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   h.secureCookie,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func (h *OIDCHandler) Callback() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		provider := chi.URLParam(r, "provider")
+		q := r.URL.Query()
+
+		// Clear the state cookie whatever the outcome; it is single-use.
+		http.SetCookie(w, &http.Cookie{
+			Name:     oidcStateCookie,
+			Value:    "",
+			Path:     "/auth/oidc/",
+			MaxAge:   -1,
+			HttpOnly: true,
+			Secure:   h.secureCookie,
+			SameSite: http.SameSiteLaxMode,
+		})
+
+		if errCode := q.Get("error"); errCode != "" {
+			h.logger.Warnw("OIDC provider returned an error",
+				"provider", provider,
+				"error", errCode,
+				"description", q.Get("error_description"),
+			)
+			httpx.WriteError(w, http.StatusUnauthorized, "Sign-in was cancelled or denied by the identity provider")
+			return
+		}
+		if q.Get("code") == "" || q.Get("state") == "" {
+			httpx.WriteError(w, http.StatusBadRequest, "Missing code or state")
+			return
+		}
+
+		cookie, err := r.Cookie(oidcStateCookie)
+		if err != nil {
+			httpx.WriteServiceError(w, ErrInvalidOIDCState)
+			return
+		}
+
+		u, token, err := h.oidcService.Callback(r.Context(), provider, q.Get("code"), q.Get("state"), cookie.Value)
+		if err != nil {
+			if httpx.WriteServiceError(w, err) {
+				return
+			}
+			httpx.WriteError(w, http.StatusUnauthorized, "Sign-in with the identity provider failed")
+			return
+		}
+
+		h.logger.Infow("User logged in with OIDC",
+			"user_id", u.ID,
+			"provider", provider,
+		)
+		httpx.WriteJSON(w, http.StatusOK, LoginResponse{Token: token})
+	}
+}
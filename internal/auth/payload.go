@@ -9,6 +9,10 @@ type LoginResponse struct {
 	Token string `json:"token"`
 }
 
+type LinkResponse struct {
+	AuthorizationURL string `json:"authorization_url"`
+}
+
 type RegisterRequest struct {
 	Email    string `json:"email" validate:"required,email,max=255"`
 	Password string `json:"password" validate:"required,min=8,max=40,containsuppercase,containslowercase,containsnumber,containsspecial"`
@@ -18,3 +22,12 @@ type RegisterRequest struct {
 type RegisterResponse struct {
 	Token string `json:"token"`
 }
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" validate:"required,email,max=255"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required,min=8,max=40,containsuppercase,containslowercase,containsnumber,containsspecial"`
+}
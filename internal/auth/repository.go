@@ -0,0 +1,43 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/maxwellzp/golang-chat-api/internal/db"
+	"time"
+)
+
+type PasswordResetRepository struct {
+	database *db.Db
+}
+
+func NewPasswordResetRepository(database *db.Db) *PasswordResetRepository {
+	return &PasswordResetRepository{database: database}
+}
+
+func (r *PasswordResetRepository) Create(ctx context.Context, userID int64, tokenHash string, expiresAt time.Time) error {
+	query := `INSERT INTO password_resets (user_id, token_hash, expires_at, created_at)
+				VALUES ($1, $2, $3, $4)`
+	_, err := r.database.ExecContext(ctx, query, userID, tokenHash, expiresAt, time.Now().UTC())
+	return err
+}
+
+// Consume marks an unused, unexpired token as used and returns the owning
+// user ID, or 0 if the token is unknown, expired or already used.
+func (r *PasswordResetRepository) Consume(ctx context.Context, tokenHash string) (int64, error) {
+	query := `
+		UPDATE password_resets SET used_at = $2
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > $2
+		RETURNING user_id;`
+
+	var userID int64
+	err := r.database.QueryRowContext(ctx, query, tokenHash, time.Now().UTC()).Scan(&userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return userID, nil
+}
@@ -2,26 +2,65 @@ package auth
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
+	"fmt"
 	"github.com/golang-jwt/jwt/v4"
+	"github.com/maxwellzp/golang-chat-api/internal/apperr"
+	"github.com/maxwellzp/golang-chat-api/internal/audit"
+	"github.com/maxwellzp/golang-chat-api/internal/config"
 	"github.com/maxwellzp/golang-chat-api/internal/logger"
+	"github.com/maxwellzp/golang-chat-api/internal/mailer"
 	"github.com/maxwellzp/golang-chat-api/internal/user"
 	"golang.org/x/crypto/bcrypt"
+	"net/url"
 	"strings"
 	"time"
 )
 
+var (
+	ErrInvalidResetToken     = apperr.Validation("invalid_reset_token", "invalid or expired reset token")
+	ErrSessionRevoked        = apperr.Unauthorized("session_revoked", "session has been revoked")
+	ErrAccountSuspended      = apperr.Forbidden("account_suspended", "account is suspended")
+	ErrPasswordResetRequired = apperr.Forbidden("password_reset_required", "a password reset is required, use the link sent to your email")
+	ErrEmailTaken            = apperr.Conflict("email_taken", "email already in use")
+	ErrInvalidCredentials    = apperr.Unauthorized("invalid_credentials", "invalid credentials")
+)
+
 type AuthService struct {
-	userRepository *user.UserRepository
-	jwtSecret      string
-	logger         *logger.Logger
+	userRepository  *user.UserRepository
+	resetRepository *PasswordResetRepository
+	loginGuard      *LoginGuard
+	mailer          mailer.Mailer
+	audit           *audit.AuditService
+	jwtSecret       string
+	baseURL         string
+	resetTTL        time.Duration
+	logger          *logger.Logger
 }
 
-func NewAuthService(userRepository *user.UserRepository, jwtSecret string, logger *logger.Logger) *AuthService {
+func NewAuthService(
+	userRepository *user.UserRepository,
+	resetRepository *PasswordResetRepository,
+	loginGuard *LoginGuard,
+	mailer mailer.Mailer,
+	audit *audit.AuditService,
+	cfg *config.Config,
+	logger *logger.Logger,
+) *AuthService {
 	return &AuthService{
-		userRepository: userRepository,
-		jwtSecret:      jwtSecret,
-		logger:         logger,
+		userRepository:  userRepository,
+		resetRepository: resetRepository,
+		loginGuard:      loginGuard,
+		mailer:          mailer,
+		audit:           audit,
+		jwtSecret:       cfg.Auth.JwtSecret,
+		baseURL:         cfg.Application.BaseURL,
+		resetTTL:        cfg.Auth.PasswordResetTTL,
+		logger:          logger,
 	}
 }
 
@@ -41,7 +80,7 @@ func (as *AuthService) Register(ctx context.Context, username, email, password s
 		as.logger.Warnw("Registration failed: email already in use",
 			"email", email,
 		)
-		return nil, errors.New("email already in use")
+		return nil, ErrEmailTaken
 	}
 
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
@@ -64,12 +103,29 @@ func (as *AuthService) Register(ctx context.Context, username, email, password s
 		)
 		return nil, err
 	}
+	as.audit.Record(ctx, userEntry(audit.ActionRegister, u.ID, map[string]any{
+		"username": u.Username,
+		"email":    u.Email,
+	}))
 	u.Password = ""
 	return u, nil
 }
 
-func (as *AuthService) Login(ctx context.Context, email string, password string) (*user.User, string, error) {
+func (as *AuthService) Login(ctx context.Context, email string, password string, ip string) (*user.User, string, error) {
 	email = strings.TrimSpace(strings.ToLower(email))
+	if err := as.loginGuard.Check(ctx, email, ip); err != nil {
+		as.logger.Warnw("Login refused: too many failed attempts",
+			"email", email,
+			"ip", ip,
+			"error", err,
+		)
+		var blocked *LoginBlockedError
+		if errors.As(err, &blocked) {
+			as.auditLoginFailure(ctx, nil, email, "blocked")
+		}
+		return nil, "", err
+	}
+
 	existingUser, err := as.userRepository.FindByEmail(ctx, email)
 	if err != nil {
 		as.logger.Errorw("Login failed: DB error",
@@ -82,21 +138,41 @@ func (as *AuthService) Login(ctx context.Context, email string, password string)
 		as.logger.Warnw("Login failed: user not found",
 			"email", email,
 		)
-		return nil, "", errors.New("invalid credentials")
+		as.recordLoginFailure(ctx, email, ip)
+		as.auditLoginFailure(ctx, nil, email, "unknown_email")
+		return nil, "", ErrInvalidCredentials
 	}
 	if err := bcrypt.CompareHashAndPassword([]byte(existingUser.Password), []byte(password)); err != nil {
 		as.logger.Warnw("Login failed: incorrect password",
 			"email", email,
 		)
-		return nil, "", errors.New("invalid credentials")
+		as.recordLoginFailure(ctx, email, ip)
+		as.auditLoginFailure(ctx, &existingUser.ID, email, "wrong_password")
+		return nil, "", ErrInvalidCredentials
+	}
+	if err := as.loginGuard.Succeed(ctx, email); err != nil {
+		as.logger.Errorw("Failed to reset login attempts",
+			"email", email,
+			"error", err,
+		)
+	}
+	if err := CheckLogin(existingUser); err != nil {
+		as.logger.Warnw("Login refused",
+			"user_id", existingUser.ID,
+			"error", err,
+		)
+		as.auditLoginFailure(ctx, &existingUser.ID, email, "suspended")
+		return nil, "", err
+	}
+	if existingUser.PasswordResetRequired {
+		as.logger.Warnw("Login refused: password reset required",
+			"user_id", existingUser.ID,
+		)
+		as.auditLoginFailure(ctx, &existingUser.ID, email, "password_reset_required")
+		return nil, "", ErrPasswordResetRequired
 	}
 
-	// Generate JWT
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"user_id": existingUser.ID,
-		"exp":     time.Now().Add(24 * time.Hour).Unix(),
-	})
-	tokenString, err := token.SignedString([]byte(as.jwtSecret))
+	tokenString, err := as.IssueToken(existingUser)
 	if err != nil {
 		as.logger.Errorw("JWT generation failed",
 			"email", email,
@@ -104,7 +180,244 @@ func (as *AuthService) Login(ctx context.Context, email string, password string)
 		)
 		return nil, "", err
 	}
+	as.audit.Record(ctx, userEntry(audit.ActionLogin, existingUser.ID, map[string]any{"method": "password"}))
 
 	existingUser.Password = ""
 	return existingUser, tokenString, nil
 }
+
+// IssueToken signs an access token for an authenticated user.
+func (as *AuthService) IssueToken(u *user.User) (string, error) {
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id": u.ID,
+		"iat":     now.Unix(),
+		"exp":     now.Add(24 * time.Hour).Unix(),
+	})
+	return token.SignedString([]byte(as.jwtSecret))
+}
+
+// IssueImpersonationToken signs an access token for u on behalf of the admin
+// adminID. The token records the admin in its impersonated_by claim and
+// expires after ttl.
+func (as *AuthService) IssueImpersonationToken(ctx context.Context, u *user.User, adminID int64, ttl time.Duration) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(ttl)
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id":         u.ID,
+		"impersonated_by": adminID,
+		"iat":             now.Unix(),
+		"exp":             expiresAt.Unix(),
+	})
+	signed, err := token.SignedString([]byte(as.jwtSecret))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	as.audit.Record(ctx, audit.Entry{
+		Action:     audit.ActionImpersonate,
+		ActorID:    &adminID,
+		TargetType: audit.TargetUser,
+		TargetID:   &u.ID,
+		After:      map[string]any{"expires_at": expiresAt.UTC()},
+	})
+	return signed, expiresAt.UTC(), nil
+}
+
+// CheckLogin refuses to sign in suspended users, whichever way they
+// authenticate.
+func CheckLogin(u *user.User) error {
+	if u.SuspendedAt != nil {
+		return ErrAccountSuspended
+	}
+	return nil
+}
+
+// CheckSession rejects tokens of deleted and suspended users and tokens
+// issued before the user's sessions were last revoked. Revocation has second
+// precision, so a token issued in the same second is rejected too.
+func (as *AuthService) CheckSession(ctx context.Context, userID int64, issuedAt time.Time) error {
+	state, err := as.sessionState(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if state.RevokedAt != nil && issuedAt.Unix() <= state.RevokedAt.Unix() {
+		return ErrSessionRevoked
+	}
+	return nil
+}
+
+// CheckAccount rejects API keys of deleted and suspended users. Revoking
+// sessions leaves API keys alone; they are revoked one by one.
+func (as *AuthService) CheckAccount(ctx context.Context, userID int64) error {
+	_, err := as.sessionState(ctx, userID)
+	return err
+}
+
+func (as *AuthService) sessionState(ctx context.Context, userID int64) (*user.SessionState, error) {
+	state, err := as.userRepository.SessionState(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if state == nil || state.Deleted {
+		return nil, ErrSessionRevoked
+	}
+	if state.Suspended {
+		return nil, ErrAccountSuspended
+	}
+	return state, nil
+}
+
+func (as *AuthService) recordLoginFailure(ctx context.Context, email, ip string) {
+	if err := as.loginGuard.Fail(ctx, email, ip); err != nil {
+		as.logger.Errorw("Failed to record failed login attempt",
+			"email", email,
+			"ip", ip,
+			"error", err,
+		)
+	}
+}
+
+// auditLoginFailure writes a failed sign-in to the audit log. userID is nil
+// when no account has the email.
+func (as *AuthService) auditLoginFailure(ctx context.Context, userID *int64, email string, reason string) {
+	as.audit.Record(ctx, audit.Entry{
+		Action:     audit.ActionLoginFailed,
+		ActorID:    userID,
+		TargetType: audit.TargetUser,
+		TargetID:   userID,
+		After:      map[string]any{"email": email, "reason": reason},
+	})
+}
+
+// userEntry is an audit entry for an action users take on their own account.
+func userEntry(action string, userID int64, after map[string]any) audit.Entry {
+	return audit.Entry{
+		Action:     action,
+		ActorID:    &userID,
+		TargetType: audit.TargetUser,
+		TargetID:   &userID,
+		After:      after,
+	}
+}
+
+// ForgotPassword emails a single-use reset link. It succeeds silently for
+// unknown addresses so the endpoint can't be used to probe for accounts.
+func (as *AuthService) ForgotPassword(ctx context.Context, email string) error {
+	email = strings.TrimSpace(strings.ToLower(email))
+	existingUser, err := as.userRepository.FindByEmail(ctx, email)
+	if err != nil {
+		as.logger.Errorw("Password reset failed: DB error",
+			"email", email,
+			"error", err,
+		)
+		return err
+	}
+	if existingUser == nil {
+		as.logger.Infow("Password reset requested for unknown email",
+			"email", email,
+		)
+		as.audit.Record(ctx, audit.Entry{
+			Action: audit.ActionPasswordResetRequested,
+			After:  map[string]any{"email": email},
+		})
+		return nil
+	}
+
+	token, tokenHash, err := generateResetToken()
+	if err != nil {
+		as.logger.Errorw("Reset token generation failed",
+			"error", err,
+		)
+		return err
+	}
+	if err := as.resetRepository.Create(ctx, existingUser.ID, tokenHash, time.Now().UTC().Add(as.resetTTL)); err != nil {
+		as.logger.Errorw("Reset token creation failed",
+			"user_id", existingUser.ID,
+			"error", err,
+		)
+		return err
+	}
+
+	link := as.baseURL + "/password/reset?token=" + url.QueryEscape(token)
+	err = as.mailer.Send(ctx, mailer.Message{
+		To:      existingUser.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\n"+
+			"Use the link below to choose a new password. It expires in %s.\n"+
+			"Resetting your password also unlocks your account if it was locked "+
+			"after too many failed login attempts.\n\n%s\n",
+			existingUser.Username, as.resetTTL, link),
+	})
+	if err != nil {
+		as.logger.Errorw("Failed to send password reset email",
+			"user_id", existingUser.ID,
+			"error", err,
+		)
+		return err
+	}
+	as.audit.Record(ctx, userEntry(audit.ActionPasswordResetRequested, existingUser.ID, map[string]any{"email": email}))
+	return nil
+}
+
+// ResetPassword sets a new password using a token from ForgotPassword and
+// lifts any login lockout on the account.
+func (as *AuthService) ResetPassword(ctx context.Context, token, password string) error {
+	userID, err := as.resetRepository.Consume(ctx, hashResetToken(token))
+	if err != nil {
+		as.logger.Errorw("Password reset failed: DB error",
+			"error", err,
+		)
+		return err
+	}
+	if userID == 0 {
+		as.logger.Warnw("Password reset failed: invalid token")
+		as.audit.Record(ctx, audit.Entry{Action: audit.ActionPasswordResetFailed})
+		return ErrInvalidResetToken
+	}
+
+	existingUser, err := as.userRepository.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if existingUser == nil {
+		return ErrInvalidResetToken
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		as.logger.Errorw("Password hashing failed",
+			"error", err,
+		)
+		return err
+	}
+	if err := as.userRepository.UpdatePassword(ctx, userID, string(hashedPassword), time.Now().UTC()); err != nil {
+		as.logger.Errorw("Password update failed",
+			"user_id", userID,
+			"error", err,
+		)
+		return err
+	}
+	if err := as.loginGuard.Unlock(ctx, existingUser.Email); err != nil {
+		as.logger.Errorw("Failed to unlock account after password reset",
+			"user_id", userID,
+			"error", err,
+		)
+		return err
+	}
+	as.audit.Record(ctx, userEntry(audit.ActionPasswordReset, userID, nil))
+	return nil
+}
+
+func generateResetToken() (token string, tokenHash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, hashResetToken(token), nil
+}
+
+func hashResetToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,218 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"github.com/maxwellzp/golang-chat-api/internal/room"
+	"github.com/maxwellzp/golang-chat-api/internal/user"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultMuteDuration = 10 * time.Minute
+	maxMuteDuration     = 7 * 24 * time.Hour
+)
+
+type builtins struct {
+	roomService    *room.RoomService
+	userRepository *user.UserRepository
+}
+
+// RegisterBuiltins adds the commands implemented in Go.
+func RegisterBuiltins(r *Registry, roomService *room.RoomService, userRepository *user.UserRepository) {
+	b := &builtins{roomService: roomService, userRepository: userRepository}
+
+	r.Register(Command{
+		Name:        "me",
+		Description: "Post an action, e.g. /me waves",
+		Usage:       "/me <action>",
+	}, b.me)
+	r.Register(Command{
+		Name:        "topic",
+		Description: "Show the room topic, or set it (moderators)",
+		Usage:       "/topic [new topic]",
+	}, b.topic)
+	r.Register(Command{
+		Name:        "invite",
+		Description: "Add a user to the room (admins)",
+		Usage:       "/invite @username",
+	}, b.invite)
+	r.Register(Command{
+		Name:        "mute",
+		Description: "Stop a member from posting for a while (moderators)",
+		Usage:       "/mute @username [duration, e.g. 30m]",
+	}, b.mute)
+	r.Register(Command{
+		Name:        "unmute",
+		Description: "Let a muted member post again (moderators)",
+		Usage:       "/unmute @username",
+	}, b.unmute)
+	r.Register(Command{
+		Name:        "ban",
+		Description: "Remove a member and keep them out of the room (moderators)",
+		Usage:       "/ban @username",
+	}, b.ban)
+	r.Register(Command{
+		Name:        "unban",
+		Description: "Let a banned user join the room again (moderators)",
+		Usage:       "/unban @username",
+	}, b.unban)
+}
+
+func (b *builtins) me(ctx context.Context, inv Invocation) (*Response, error) {
+	if inv.Args == "" {
+		return &Response{Text: "Usage: /me <action>"}, nil
+	}
+	content := "_" + inv.Args + "_"
+	return &Response{Content: &content}, nil
+}
+
+func (b *builtins) topic(ctx context.Context, inv Invocation) (*Response, error) {
+	if inv.RoomID == nil {
+		return roomOnly(inv), nil
+	}
+	if inv.Args == "" {
+		rm, err := b.roomService.GetByID(ctx, *inv.RoomID)
+		if err != nil {
+			return nil, err
+		}
+		if rm == nil {
+			return nil, room.ErrRoomNotFound
+		}
+		if rm.Topic == "" {
+			return &Response{Text: "This room has no topic"}, nil
+		}
+		return &Response{Text: "Topic: " + rm.Topic}, nil
+	}
+
+	if len(inv.Args) > 250 {
+		return &Response{Text: "The topic can be at most 250 characters"}, nil
+	}
+	if err := b.roomService.SetTopic(ctx, *inv.RoomID, inv.UserID, inv.Args); err != nil {
+		return nil, err
+	}
+	content := "_set the topic to: " + inv.Args + "_"
+	return &Response{Content: &content}, nil
+}
+
+func (b *builtins) invite(ctx context.Context, inv Invocation) (*Response, error) {
+	if inv.RoomID == nil {
+		return roomOnly(inv), nil
+	}
+	target, resp, err := b.target(ctx, inv, "Usage: /invite @username")
+	if target == nil {
+		return resp, err
+	}
+	if err := b.roomService.AddMember(ctx, *inv.RoomID, inv.UserID, target.ID); err != nil {
+		return nil, err
+	}
+	return &Response{Text: "Added @" + target.Username + " to the room"}, nil
+}
+
+func (b *builtins) mute(ctx context.Context, inv Invocation) (*Response, error) {
+	if inv.RoomID == nil {
+		return roomOnly(inv), nil
+	}
+	const usage = "Usage: /mute @username [duration, e.g. 30m]"
+	target, resp, err := b.target(ctx, inv, usage)
+	if target == nil {
+		return resp, err
+	}
+
+	duration := defaultMuteDuration
+	if _, rest, _ := strings.Cut(inv.Args, " "); strings.TrimSpace(rest) != "" {
+		d, ok := parseDuration(strings.TrimSpace(rest))
+		if !ok {
+			return &Response{Text: usage}, nil
+		}
+		duration = d
+	}
+	if duration > maxMuteDuration {
+		duration = maxMuteDuration
+	}
+
+	until := time.Now().UTC().Add(duration)
+	if err := b.roomService.Mute(ctx, *inv.RoomID, inv.UserID, target.ID, &until); err != nil {
+		return nil, err
+	}
+	return &Response{Text: fmt.Sprintf("Muted @%s for %s", target.Username, duration)}, nil
+}
+
+func (b *builtins) unmute(ctx context.Context, inv Invocation) (*Response, error) {
+	if inv.RoomID == nil {
+		return roomOnly(inv), nil
+	}
+	target, resp, err := b.target(ctx, inv, "Usage: /unmute @username")
+	if target == nil {
+		return resp, err
+	}
+	if err := b.roomService.Mute(ctx, *inv.RoomID, inv.UserID, target.ID, nil); err != nil {
+		return nil, err
+	}
+	return &Response{Text: "Unmuted @" + target.Username}, nil
+}
+
+func (b *builtins) ban(ctx context.Context, inv Invocation) (*Response, error) {
+	if inv.RoomID == nil {
+		return roomOnly(inv), nil
+	}
+	target, resp, err := b.target(ctx, inv, "Usage: /ban @username")
+	if target == nil {
+		return resp, err
+	}
+	if err := b.roomService.Ban(ctx, *inv.RoomID, inv.UserID, target.ID); err != nil {
+		return nil, err
+	}
+	return &Response{Text: "Banned @" + target.Username}, nil
+}
+
+func (b *builtins) unban(ctx context.Context, inv Invocation) (*Response, error) {
+	if inv.RoomID == nil {
+		return roomOnly(inv), nil
+	}
+	target, resp, err := b.target(ctx, inv, "Usage: /unban @username")
+	if target == nil {
+		return resp, err
+	}
+	if err := b.roomService.Unban(ctx, *inv.RoomID, inv.UserID, target.ID); err != nil {
+		return nil, err
+	}
+	return &Response{Text: "Unbanned @" + target.Username}, nil
+}
+
+// target resolves the @username in the first argument. When it returns a nil
+// user, the response or error explains why.
+func (b *builtins) target(ctx context.Context, inv Invocation, usage string) (*user.User, *Response, error) {
+	first, _, _ := strings.Cut(inv.Args, " ")
+	username := strings.TrimPrefix(first, "@")
+	if username == "" {
+		return nil, &Response{Text: usage}, nil
+	}
+	u, err := b.userRepository.FindByUsername(ctx, username)
+	if err != nil {
+		return nil, nil, err
+	}
+	if u == nil {
+		return nil, &Response{Text: "No user named @" + username}, nil
+	}
+	return u, nil, nil
+}
+
+func roomOnly(inv Invocation) *Response {
+	return &Response{Text: fmt.Sprintf("/%s only works in rooms", inv.Name)}
+}
+
+// parseDuration accepts Go durations ("90s", "2h") or a bare number of
+// minutes.
+func parseDuration(s string) (time.Duration, bool) {
+	if minutes, err := strconv.Atoi(s); err == nil && minutes > 0 {
+		return time.Duration(minutes) * time.Minute, true
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, false
+	}
+	return d, true
+}
@@ -0,0 +1,68 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// dispatch POSTs the invocation to the command's endpoint. Requests are
+// signed like room webhooks: the X-Command-Signature header carries
+// "t=<timestamp>,v1=<hex HMAC-SHA256(secret, "<timestamp>.<body>")>".
+func (r *Registry) dispatch(ctx context.Context, cmd *ExternalCommand, inv Invocation) (*Response, error) {
+	now := time.Now()
+	body, err := json.Marshal(externalRequest{
+		Command:    "/" + cmd.Name,
+		Text:       inv.Args,
+		UserID:     inv.UserID,
+		RoomID:     cmd.RoomID,
+		IssuedAtMs: now.UnixMilli(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cmd.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "golang-chat-api-commands/1.0")
+	req.Header.Set("X-Command-Signature", "t="+timestamp+",v1="+sign(cmd.Secret, timestamp, body))
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("endpoint responded with status %d", resp.StatusCode)
+	}
+	var out externalResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&out); err != nil {
+		return nil, fmt.Errorf("invalid endpoint response: %w", err)
+	}
+
+	if out.ResponseType == "in_channel" && out.Text != "" {
+		return &Response{Content: &out.Text}, nil
+	}
+	return &Response{Text: out.Text}, nil
+}
+
+func sign(secret, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
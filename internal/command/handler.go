@@ -0,0 +1,200 @@
+package command
+
+import (
+	"encoding/json"
+	"github.com/maxwellzp/golang-chat-api/internal/httpx"
+	"github.com/maxwellzp/golang-chat-api/internal/logger"
+	"github.com/maxwellzp/golang-chat-api/internal/validatorx"
+	"net/http"
+	"strconv"
+)
+
+type CommandHandler struct {
+	commandService *CommandService
+	validator      *validatorx.Validator
+	logger         *logger.Logger
+}
+
+func NewCommandHandler(commandService *CommandService, validator *validatorx.Validator, logger *logger.Logger) *CommandHandler {
+	return &CommandHandler{
+		commandService: commandService,
+		validator:      validator,
+		logger:         logger,
+	}
+}
+
+// Available lists commands for autocompletion. Pass ?room_id= to include the
+// room's external commands.
+func (h *CommandHandler) Available() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := httpx.GetUserID(r.Context())
+		if err != nil {
+			h.logger.Warnw("Unauthorized request to list commands")
+			httpx.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+
+		var roomID *int64
+		if roomIDStr := r.URL.Query().Get("room_id"); roomIDStr != "" {
+			id, err := strconv.ParseInt(roomIDStr, 10, 64)
+			if err != nil {
+				h.logger.Warnw("Invalid room_id in query param",
+					"value", roomIDStr,
+				)
+				httpx.WriteError(w, http.StatusBadRequest, "Invalid room_id")
+				return
+			}
+			roomID = &id
+		}
+
+		cmds, err := h.commandService.Available(r.Context(), userID, roomID)
+		if httpx.WriteServiceError(w, err) {
+			return
+		}
+		if err != nil {
+			h.logger.Errorw("Failed to list commands",
+				"error", err,
+				"room_id", roomID,
+			)
+			httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
+			return
+		}
+		httpx.WriteJSON(w, http.StatusOK, cmds)
+	}
+}
+
+func (h *CommandHandler) Create() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := httpx.GetUserID(r.Context())
+		if err != nil {
+			h.logger.Warnw("Unauthorized request to create command")
+			httpx.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		roomID, err := httpx.ParseInt64Param(r, "id")
+		if err != nil {
+			h.logger.Warnw("Invalid room ID for command create",
+				"error", err,
+			)
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid RoomID")
+			return
+		}
+
+		var req CreateCommandRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.logger.Warnw("Failed to decode CreateCommandRequest",
+				"error", err,
+			)
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		if err := h.validator.Validate(&req); err != nil {
+			h.logger.Warnw("Validation failed for CreateCommandRequest",
+				"error", err,
+				"user_id", userID,
+			)
+			httpx.WriteValidationError(w, err)
+			return
+		}
+		if err := req.Validate(); err != nil {
+			h.logger.Warnw("Custom validation failed for CreateCommandRequest",
+				"error", err,
+				"user_id", userID,
+			)
+			httpx.WriteValidationError(w, err)
+			return
+		}
+
+		cmd, err := h.commandService.Create(r.Context(), roomID, userID, req)
+		if httpx.WriteServiceError(w, err) {
+			return
+		}
+		if err != nil {
+			h.logger.Errorw("Failed to create command",
+				"error", err,
+				"user_id", userID,
+				"room_id", roomID,
+			)
+			httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
+			return
+		}
+		h.logger.Infow("Command created",
+			"command_id", cmd.ID,
+			"name", cmd.Name,
+			"room_id", roomID,
+			"user_id", userID,
+		)
+		httpx.WriteJSON(w, http.StatusCreated, CreateCommandResponse{ExternalCommand: cmd, Secret: cmd.Secret})
+	}
+}
+
+func (h *CommandHandler) List() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := httpx.GetUserID(r.Context())
+		if err != nil {
+			h.logger.Warnw("Unauthorized request to list room commands")
+			httpx.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		roomID, err := httpx.ParseInt64Param(r, "id")
+		if err != nil {
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid RoomID")
+			return
+		}
+
+		cmds, err := h.commandService.List(r.Context(), roomID, userID)
+		if httpx.WriteServiceError(w, err) {
+			return
+		}
+		if err != nil {
+			h.logger.Errorw("Failed to list room commands",
+				"error", err,
+				"room_id", roomID,
+			)
+			httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
+			return
+		}
+		httpx.WriteJSON(w, http.StatusOK, cmds)
+	}
+}
+
+func (h *CommandHandler) Delete() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := httpx.GetUserID(r.Context())
+		if err != nil {
+			h.logger.Warnw("Unauthorized request to delete command")
+			httpx.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		roomID, err := httpx.ParseInt64Param(r, "id")
+		if err != nil {
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid RoomID")
+			return
+		}
+		id, err := httpx.ParseInt64Param(r, "cid")
+		if err != nil {
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid CommandID")
+			return
+		}
+
+		err = h.commandService.Delete(r.Context(), roomID, id, userID)
+		if httpx.WriteServiceError(w, err) {
+			return
+		}
+		if err != nil {
+			h.logger.Errorw("Failed to delete command",
+				"error", err,
+				"user_id", userID,
+				"command_id", id,
+			)
+			httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
+			return
+		}
+		h.logger.Infow("Command deleted",
+			"command_id", id,
+			"room_id", roomID,
+			"user_id", userID,
+		)
+		httpx.WriteJSON(w, http.StatusNoContent, nil)
+	}
+}
@@ -0,0 +1,46 @@
+package command
+
+import (
+	"context"
+	"time"
+)
+
+// Command describes a slash command for client autocompletion.
+type Command struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Usage       string `json:"usage"`
+	External    bool   `json:"external"`
+}
+
+// Invocation is a parsed slash command and where it was sent.
+type Invocation struct {
+	UserID     int64
+	RoomID     *int64
+	ReceiverID *int64
+	Name       string
+	Args       string
+}
+
+// Response is the outcome of a command. Text is shown only to the invoking
+// user. When Content is set it is posted as a regular message in place of
+// the command text.
+type Response struct {
+	Text    string
+	Content *string
+}
+
+type Handler func(ctx context.Context, inv Invocation) (*Response, error)
+
+// ExternalCommand is a room command dispatched to an HTTP endpoint.
+type ExternalCommand struct {
+	ID          int64     `json:"id"`
+	RoomID      int64     `json:"room_id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Usage       string    `json:"usage"`
+	URL         string    `json:"url"`
+	Secret      string    `json:"-"`
+	CreatedBy   *int64    `json:"created_by"`
+	CreatedAt   time.Time `json:"created_at"`
+}
@@ -0,0 +1,50 @@
+package command
+
+import (
+	"github.com/maxwellzp/golang-chat-api/internal/httpx"
+	"net/url"
+)
+
+type CreateCommandRequest struct {
+	Name        string `json:"name" validate:"required,min=1,max=32"`
+	Description string `json:"description" validate:"max=200"`
+	Usage       string `json:"usage" validate:"max=100"`
+	URL         string `json:"url" validate:"required,url,max=2000"`
+}
+
+func (r *CreateCommandRequest) Validate() error {
+	if !namePattern.MatchString(r.Name) {
+		return httpx.ValidationErrorMap{
+			"name": "name must start with a lowercase letter and contain only a-z, 0-9, - and _",
+		}
+	}
+	u, err := url.Parse(r.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return httpx.ValidationErrorMap{
+			"url": "url must be an absolute http or https URL",
+		}
+	}
+	return nil
+}
+
+// CreateCommandResponse is the only time the signing secret is returned.
+type CreateCommandResponse struct {
+	*ExternalCommand
+	Secret string `json:"secret"`
+}
+
+// externalRequest is the JSON body POSTed to an external command endpoint.
+type externalRequest struct {
+	Command    string `json:"command"`
+	Text       string `json:"text"`
+	UserID     int64  `json:"user_id"`
+	RoomID     int64  `json:"room_id"`
+	IssuedAtMs int64  `json:"issued_at_ms"`
+}
+
+// externalResponse is what an external endpoint replies with. "in_channel"
+// posts Text to the room; anything else is shown only to the sender.
+type externalResponse struct {
+	ResponseType string `json:"response_type"`
+	Text         string `json:"text"`
+}
@@ -0,0 +1,150 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/maxwellzp/golang-chat-api/internal/logger"
+	"github.com/maxwellzp/golang-chat-api/internal/netguard"
+	"github.com/maxwellzp/golang-chat-api/internal/room"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// externalTimeout bounds how long a message send waits on an external
+// command endpoint.
+const externalTimeout = 3 * time.Second
+
+var namePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]{0,31}$`)
+
+type builtin struct {
+	command Command
+	handler Handler
+}
+
+// Registry resolves slash commands: built-in ones registered in Go and
+// external ones stored per room.
+type Registry struct {
+	builtins          map[string]builtin
+	commandRepository *CommandRepository
+	client            *http.Client
+	logger            *logger.Logger
+}
+
+// NewRegistry creates an empty registry. External commands are called
+// through guard, so their endpoints can't be on the internal network.
+func NewRegistry(commandRepository *CommandRepository, guard *netguard.Guard, logger *logger.Logger) *Registry {
+	return &Registry{
+		builtins:          make(map[string]builtin),
+		commandRepository: commandRepository,
+		client:            guard.Client(externalTimeout),
+		logger:            logger,
+	}
+}
+
+// Register adds a built-in command, replacing any with the same name.
+func (r *Registry) Register(cmd Command, handler Handler) {
+	r.builtins[cmd.Name] = builtin{command: cmd, handler: handler}
+}
+
+// IsBuiltin reports whether name is taken by a built-in command.
+func (r *Registry) IsBuiltin(name string) bool {
+	_, ok := r.builtins[name]
+	return ok
+}
+
+// Parse splits "/name args" into its parts. Content that doesn't start with
+// a valid command name, such as "/tmp/file" or "//escaped", is not a command.
+func Parse(content string) (name string, args string, ok bool) {
+	rest, found := strings.CutPrefix(content, "/")
+	if !found {
+		return "", "", false
+	}
+	name, args, _ = strings.Cut(rest, " ")
+	name = strings.ToLower(name)
+	if !namePattern.MatchString(name) {
+		return "", "", false
+	}
+	return name, strings.TrimSpace(args), true
+}
+
+// Execute runs the command. Room permission errors and unknown commands are
+// reported to the sender in the response rather than returned.
+func (r *Registry) Execute(ctx context.Context, inv Invocation) (*Response, error) {
+	var resp *Response
+	var err error
+	if b, ok := r.builtins[inv.Name]; ok {
+		resp, err = b.handler(ctx, inv)
+	} else {
+		resp, err = r.executeExternal(ctx, inv)
+	}
+
+	switch {
+	case errors.Is(err, room.ErrForbidden), errors.Is(err, room.ErrRoomNotFound),
+		errors.Is(err, room.ErrNotMember), errors.Is(err, room.ErrPrivateRoom),
+		errors.Is(err, room.ErrBanned), errors.Is(err, room.ErrNotBanned):
+		return &Response{Text: fmt.Sprintf("/%s: %s", inv.Name, err.Error())}, nil
+	case err != nil:
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (r *Registry) executeExternal(ctx context.Context, inv Invocation) (*Response, error) {
+	unknown := &Response{Text: fmt.Sprintf("Unknown command /%s", inv.Name)}
+	if inv.RoomID == nil {
+		return unknown, nil
+	}
+	cmd, err := r.commandRepository.FindByName(ctx, *inv.RoomID, inv.Name)
+	if err != nil {
+		return nil, err
+	}
+	if cmd == nil {
+		return unknown, nil
+	}
+
+	resp, err := r.dispatch(ctx, cmd, inv)
+	if err != nil {
+		r.logger.Warnw("External command failed",
+			"command", cmd.Name,
+			"command_id", cmd.ID,
+			"room_id", cmd.RoomID,
+			"error", err,
+		)
+		return &Response{Text: fmt.Sprintf("/%s didn't respond, please try again later", cmd.Name)}, nil
+	}
+	return resp, nil
+}
+
+// List returns the built-in commands followed by the external commands of
+// roomID, if given, sorted by name.
+func (r *Registry) List(ctx context.Context, roomID *int64) ([]*Command, error) {
+	cmds := make([]*Command, 0, len(r.builtins))
+	for _, b := range r.builtins {
+		cmd := b.command
+		cmds = append(cmds, &cmd)
+	}
+
+	if roomID != nil {
+		external, err := r.commandRepository.ListByRoom(ctx, *roomID)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range external {
+			cmds = append(cmds, &Command{
+				Name:        e.Name,
+				Description: e.Description,
+				Usage:       e.Usage,
+				External:    true,
+			})
+		}
+	}
+
+	sort.Slice(cmds, func(i, j int) bool {
+		return cmds[i].Name < cmds[j].Name
+	})
+	return cmds, nil
+}
@@ -0,0 +1,82 @@
+package command
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/maxwellzp/golang-chat-api/internal/db"
+	"time"
+)
+
+type CommandRepository struct {
+	database *db.Db
+}
+
+func NewCommandRepository(database *db.Db) *CommandRepository {
+	return &CommandRepository{database: database}
+}
+
+func (r *CommandRepository) Create(ctx context.Context, cmd *ExternalCommand) error {
+	query := `INSERT INTO slash_commands (room_id, name, description, usage, url, secret, created_by, created_at)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+				RETURNING id, created_at`
+
+	row := r.database.QueryRowContext(ctx, query, cmd.RoomID, cmd.Name, cmd.Description, cmd.Usage,
+		cmd.URL, cmd.Secret, cmd.CreatedBy, time.Now().UTC())
+	return row.Scan(&cmd.ID, &cmd.CreatedAt)
+}
+
+func (r *CommandRepository) FindByName(ctx context.Context, roomID int64, name string) (*ExternalCommand, error) {
+	query := `SELECT id, room_id, name, description, usage, url, secret, created_by, created_at
+			  FROM slash_commands WHERE room_id = $1 AND name = $2`
+	row := r.database.QueryRowContext(ctx, query, roomID, name)
+
+	var cmd ExternalCommand
+	err := row.Scan(&cmd.ID, &cmd.RoomID, &cmd.Name, &cmd.Description, &cmd.Usage,
+		&cmd.URL, &cmd.Secret, &cmd.CreatedBy, &cmd.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &cmd, nil
+}
+
+func (r *CommandRepository) ListByRoom(ctx context.Context, roomID int64) ([]*ExternalCommand, error) {
+	query := `SELECT id, room_id, name, description, usage, url, secret, created_by, created_at
+			  FROM slash_commands WHERE room_id = $1 ORDER BY name`
+	rows, err := r.database.QueryContext(ctx, query, roomID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var cmds []*ExternalCommand
+	for rows.Next() {
+		var cmd ExternalCommand
+		err := rows.Scan(&cmd.ID, &cmd.RoomID, &cmd.Name, &cmd.Description, &cmd.Usage,
+			&cmd.URL, &cmd.Secret, &cmd.CreatedBy, &cmd.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		cmds = append(cmds, &cmd)
+	}
+	return cmds, rows.Err()
+}
+
+func (r *CommandRepository) Delete(ctx context.Context, roomID int64, id int64) error {
+	res, err := r.database.ExecContext(ctx,
+		"DELETE FROM slash_commands WHERE id = $1 AND room_id = $2", id, roomID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrCommandNotFound
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package command
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"github.com/maxwellzp/golang-chat-api/internal/apperr"
+	"github.com/maxwellzp/golang-chat-api/internal/httpx"
+	"github.com/maxwellzp/golang-chat-api/internal/netguard"
+	"github.com/maxwellzp/golang-chat-api/internal/room"
+)
+
+var (
+	ErrCommandNotFound = apperr.NotFound("command_not_found", "command not found")
+	ErrCommandExists   = apperr.Conflict("command_exists", "a command with this name already exists")
+)
+
+type CommandService struct {
+	commandRepository *CommandRepository
+	registry          *Registry
+	roomService       *room.RoomService
+	guard             *netguard.Guard
+}
+
+func NewCommandService(commandRepository *CommandRepository, registry *Registry, roomService *room.RoomService, guard *netguard.Guard) *CommandService {
+	return &CommandService{
+		commandRepository: commandRepository,
+		registry:          registry,
+		roomService:       roomService,
+		guard:             guard,
+	}
+}
+
+// Available lists the commands the user can run, including the external
+// commands of roomID when given. Private room commands are only listed for
+// members.
+func (s *CommandService) Available(ctx context.Context, userID int64, roomID *int64) ([]*Command, error) {
+	if roomID != nil {
+		rm, err := s.roomService.GetByID(ctx, *roomID)
+		if err != nil {
+			return nil, err
+		}
+		if rm == nil {
+			return nil, room.ErrRoomNotFound
+		}
+		if rm.IsPrivate {
+			role, err := s.roomService.MemberRole(ctx, *roomID, userID)
+			if err != nil {
+				return nil, err
+			}
+			if role == "" {
+				return nil, room.ErrForbidden
+			}
+		}
+	}
+	return s.registry.List(ctx, roomID)
+}
+
+// Create registers an external command on the room. The signing secret is
+// returned once on the command.
+func (s *CommandService) Create(ctx context.Context, roomID int64, userID int64, req CreateCommandRequest) (*ExternalCommand, error) {
+	if err := s.roomService.RequireRole(ctx, roomID, userID, room.RoleAdmin); err != nil {
+		return nil, err
+	}
+	if s.registry.IsBuiltin(req.Name) {
+		return nil, ErrCommandExists
+	}
+	existing, err := s.commandRepository.FindByName(ctx, roomID, req.Name)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, ErrCommandExists
+	}
+	if err := s.guard.CheckURL(ctx, req.URL); err != nil {
+		return nil, httpx.ValidationErrorMap{"url": err.Error()}
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	cmd := &ExternalCommand{
+		RoomID:      roomID,
+		Name:        req.Name,
+		Description: req.Description,
+		Usage:       req.Usage,
+		URL:         req.URL,
+		Secret:      hex.EncodeToString(secret),
+		CreatedBy:   &userID,
+	}
+	if err := s.commandRepository.Create(ctx, cmd); err != nil {
+		return nil, err
+	}
+	return cmd, nil
+}
+
+func (s *CommandService) List(ctx context.Context, roomID int64, userID int64) ([]*ExternalCommand, error) {
+	if err := s.roomService.RequireRole(ctx, roomID, userID, room.RoleAdmin); err != nil {
+		return nil, err
+	}
+	return s.commandRepository.ListByRoom(ctx, roomID)
+}
+
+func (s *CommandService) Delete(ctx context.Context, roomID int64, id int64, userID int64) error {
+	if err := s.roomService.RequireRole(ctx, roomID, userID, room.RoleAdmin); err != nil {
+		return err
+	}
+	return s.commandRepository.Delete(ctx, roomID, id)
+}
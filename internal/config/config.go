@@ -5,14 +5,45 @@ import (
 	"github.com/joho/godotenv"
 	"go.uber.org/zap"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type ApplicationConfig struct {
-	AppEnv string
+	AppEnv  string
+	BaseURL string
 }
 
 type AuthConfig struct {
-	JwtSecret string
+	JwtSecret        string
+	PasswordResetTTL time.Duration
+	LoginProtection  LoginProtectionConfig
+}
+
+// LoginProtectionConfig controls failed-login tracking. Failures are counted
+// per account (email) and per client IP within Window; each failure past
+// FreeAttempts doubles the wait before the next attempt, starting at
+// BaseBackoff and capped at MaxBackoff. Reaching MaxAccountFailures locks the
+// account for LockoutDuration.
+type LoginProtectionConfig struct {
+	Store              string
+	FreeAttempts       int
+	MaxAccountFailures int
+	MaxIPFailures      int
+	Window             time.Duration
+	BaseBackoff        time.Duration
+	MaxBackoff         time.Duration
+	LockoutDuration    time.Duration
+}
+
+type MailConfig struct {
+	Driver   string
+	From     string
+	SMTPHost string
+	SMTPPort string
+	Username string
+	Password string
 }
 
 type DbConfig struct {
@@ -32,11 +63,181 @@ type ServerConfig struct {
 	Port string
 }
 
+// RateLimit is a token bucket: Requests tokens are refilled evenly over
+// Period and at most Burst can be saved up.
+type RateLimit struct {
+	Requests int
+	Period   time.Duration
+	Burst    int
+}
+
+type RateLimitConfig struct {
+	Store          string
+	Public         RateLimit
+	Auth           RateLimit
+	Authenticated  RateLimit
+	MessagesCreate RateLimit
+}
+
+type OIDCProviderConfig struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+}
+
+type OIDCConfig struct {
+	Providers []OIDCProviderConfig
+}
+
+// WebhookConfig controls outgoing webhook delivery. Failed deliveries are
+// retried with exponential backoff from BaseBackoff up to MaxBackoff and are
+// marked dead after MaxAttempts.
+type WebhookConfig struct {
+	MaxAttempts  int
+	BaseBackoff  time.Duration
+	MaxBackoff   time.Duration
+	PollInterval time.Duration
+	Timeout      time.Duration
+	BatchSize    int
+}
+
+// NotificationConfig controls notification delivery. Web Push is only
+// enabled when both VAPID keys are set (base64url, as generated by common
+// web-push tooling: a 65-byte uncompressed public key and 32-byte private
+// key).
+type NotificationConfig struct {
+	PollInterval    time.Duration
+	BatchSize       int
+	Timeout         time.Duration
+	VAPIDPublicKey  string
+	VAPIDPrivateKey string
+	VAPIDSubject    string
+}
+
+// MessageConfig holds message limits. ReminderPollInterval is how often
+// due saved-message reminders are looked for, ExpiryPollInterval how often
+// expired messages are deleted, ExpiryBatchSize at most per statement.
+type MessageConfig struct {
+	MaxPinsPerRoom       int
+	ReminderPollInterval time.Duration
+	ExpiryPollInterval   time.Duration
+	ExpiryBatchSize      int
+}
+
+// ScheduleConfig controls the scheduled message worker. Posts that fail for
+// a temporary reason are retried with exponential backoff from RetryBackoff
+// and marked failed after MaxAttempts.
+type ScheduleConfig struct {
+	PollInterval time.Duration
+	BatchSize    int
+	MaxAttempts  int
+	RetryBackoff time.Duration
+}
+
+// RetentionConfig controls message retention. Messages older than
+// DefaultDays are purged unless their room overrides it; zero keeps them
+// forever. The purge runs every PollInterval and deletes BatchSize messages
+// per statement, pausing BatchPause between batches so it never holds locks
+// for long.
+type RetentionConfig struct {
+	DefaultDays  int
+	PollInterval time.Duration
+	BatchSize    int
+	BatchPause   time.Duration
+}
+
+// AdminConfig controls the admin API. UserIDs are given the admin role at
+// startup, which is how the first admin is made. Impersonation tokens expire
+// after ImpersonationTTL.
+type AdminConfig struct {
+	UserIDs          []int64
+	ImpersonationTTL time.Duration
+}
+
+// ExportConfig controls room and conversation exports. Archives are written
+// to Dir, which must be shared by all replicas, and deleted after FileTTL.
+// Download links are valid for LinkTTL. A job running for longer than
+// StaleAfter is assumed lost with its replica and started again.
+type ExportConfig struct {
+	Dir          string
+	PollInterval time.Duration
+	LinkTTL      time.Duration
+	FileTTL      time.Duration
+	StaleAfter   time.Duration
+}
+
+// PresenceConfig controls presence tracking. A user is online while they
+// hold an open presence stream or have sent a heartbeat within OnlineTTL.
+// Open streams refresh last-seen every RefreshInterval, which must be well
+// under OnlineTTL so other replicas keep seeing the user online.
+type PresenceConfig struct {
+	OnlineTTL       time.Duration
+	RefreshInterval time.Duration
+}
+
+// TypingConfig controls typing indicators. A typing signal lasts TTL;
+// repeated signals within CoalesceInterval only extend it. Relay is
+// "postgres" to share signals between replicas over LISTEN/NOTIFY, or "none"
+// for a single replica.
+type TypingConfig struct {
+	TTL              time.Duration
+	CoalesceInterval time.Duration
+	Relay            string
+}
+
+// AuditConfig controls the audit log. Events are written unhashed on the
+// request path and chained by a background sealer every SealInterval, up to
+// SealBatch at a time. Secret keys the chain's HMAC, so rewriting the log
+// needs the secret as well as database access; it defaults to one derived
+// from JWT_SECRET.
+type AuditConfig struct {
+	Secret       string
+	SealInterval time.Duration
+	SealBatch    int
+}
+
+// ContentFilterConfig sets the filters every message goes through. A room
+// can configure a stricter action for a filter, never a more lenient one. Words and DeniedDomains are
+// comma-separated lists that rooms can add to. Actions are "reject", "mask"
+// or "flag" (post the message and report it for review).
+type ContentFilterConfig struct {
+	Words         []string
+	WordsAction   string
+	DeniedDomains []string
+	LinksAction   string
+	SecretsAction string
+}
+
+// OutboundConfig controls requests to user supplied URLs such as webhooks.
+// They may not reach private, loopback or link-local addresses unless the
+// address is in one of AllowedNetworks (CIDR notation, comma-separated),
+// which is meant for local development and tests.
+type OutboundConfig struct {
+	AllowedNetworks []string
+}
+
 type Config struct {
-	Application ApplicationConfig
-	Db          DbConfig
-	Server      ServerConfig
-	Auth        AuthConfig
+	Application   ApplicationConfig
+	Db            DbConfig
+	Server        ServerConfig
+	Auth          AuthConfig
+	Mail          MailConfig
+	RateLimit     RateLimitConfig
+	OIDC          OIDCConfig
+	Message       MessageConfig
+	Schedule      ScheduleConfig
+	Webhook       WebhookConfig
+	Notification  NotificationConfig
+	Presence      PresenceConfig
+	Typing        TypingConfig
+	Retention     RetentionConfig
+	Export        ExportConfig
+	Admin         AdminConfig
+	Audit         AuditConfig
+	ContentFilter ContentFilterConfig
+	Outbound      OutboundConfig
 }
 
 func Load(logger *zap.SugaredLogger) *Config {
@@ -47,9 +248,17 @@ func Load(logger *zap.SugaredLogger) *Config {
 		logger.Warnw("No .env file found")
 	}
 
+	jwtSecret := mustGetEnv(logger, "JWT_SECRET")
+	// Not read through getEnv, which would log the derived default.
+	auditSecret, ok := os.LookupEnv("AUDIT_SECRET")
+	if !ok {
+		auditSecret = "audit:" + jwtSecret
+	}
+
 	return &Config{
 		Application: ApplicationConfig{
-			AppEnv: getEnv(logger, "APP_ENV", "prod"),
+			AppEnv:  getEnv(logger, "APP_ENV", "prod"),
+			BaseURL: getEnv(logger, "APP_BASE_URL", "http://localhost:8080"),
 		},
 		Db: DbConfig{
 			User:     mustGetEnv(logger, "POSTGRES_USER"),
@@ -62,9 +271,129 @@ func Load(logger *zap.SugaredLogger) *Config {
 			Port: getEnv(logger, "SERVER_PORT", "8080"),
 		},
 		Auth: AuthConfig{
-			JwtSecret: mustGetEnv(logger, "JWT_SECRET"),
+			JwtSecret:        jwtSecret,
+			PasswordResetTTL: getEnvDuration(logger, "PASSWORD_RESET_TTL", time.Hour),
+			LoginProtection: LoginProtectionConfig{
+				Store:              getEnv(logger, "LOGIN_ATTEMPT_STORE", "postgres"),
+				FreeAttempts:       getEnvInt(logger, "LOGIN_FREE_ATTEMPTS", 3),
+				MaxAccountFailures: getEnvInt(logger, "LOGIN_MAX_ACCOUNT_FAILURES", 10),
+				MaxIPFailures:      getEnvInt(logger, "LOGIN_MAX_IP_FAILURES", 50),
+				Window:             getEnvDuration(logger, "LOGIN_FAILURE_WINDOW", time.Hour),
+				BaseBackoff:        getEnvDuration(logger, "LOGIN_BASE_BACKOFF", time.Second),
+				MaxBackoff:         getEnvDuration(logger, "LOGIN_MAX_BACKOFF", 5*time.Minute),
+				LockoutDuration:    getEnvDuration(logger, "LOGIN_LOCKOUT_DURATION", 30*time.Minute),
+			},
+		},
+		Mail: MailConfig{
+			Driver:   getEnv(logger, "MAIL_DRIVER", "log"),
+			From:     getEnv(logger, "MAIL_FROM", "no-reply@localhost"),
+			SMTPHost: getEnv(logger, "SMTP_HOST", "localhost"),
+			SMTPPort: getEnv(logger, "SMTP_PORT", "25"),
+			Username: getEnv(logger, "SMTP_USERNAME", ""),
+			Password: getEnv(logger, "SMTP_PASSWORD", ""),
+		},
+		RateLimit: RateLimitConfig{
+			Store:          getEnv(logger, "RATE_LIMIT_STORE", "postgres"),
+			Public:         getEnvRateLimit(logger, "RATE_LIMIT_PUBLIC", "120/1m"),
+			Auth:           getEnvRateLimit(logger, "RATE_LIMIT_AUTH", "10/1m"),
+			Authenticated:  getEnvRateLimit(logger, "RATE_LIMIT_AUTHENTICATED", "300/1m"),
+			MessagesCreate: getEnvRateLimit(logger, "RATE_LIMIT_MESSAGES_CREATE", "30/1m"),
+		},
+		OIDC: OIDCConfig{
+			Providers: loadOIDCProviders(logger),
+		},
+		Message: MessageConfig{
+			MaxPinsPerRoom:       getEnvInt(logger, "MESSAGE_MAX_PINS_PER_ROOM", 50),
+			ReminderPollInterval: getEnvDuration(logger, "MESSAGE_REMINDER_POLL_INTERVAL", 30*time.Second),
+			ExpiryPollInterval:   getEnvDuration(logger, "MESSAGE_EXPIRY_POLL_INTERVAL", time.Minute),
+			ExpiryBatchSize:      getEnvInt(logger, "MESSAGE_EXPIRY_BATCH_SIZE", 500),
+		},
+		Schedule: ScheduleConfig{
+			PollInterval: getEnvDuration(logger, "SCHEDULE_POLL_INTERVAL", 5*time.Second),
+			BatchSize:    getEnvInt(logger, "SCHEDULE_BATCH_SIZE", 50),
+			MaxAttempts:  getEnvInt(logger, "SCHEDULE_MAX_ATTEMPTS", 5),
+			RetryBackoff: getEnvDuration(logger, "SCHEDULE_RETRY_BACKOFF", 30*time.Second),
 		},
+		Webhook: WebhookConfig{
+			MaxAttempts:  getEnvInt(logger, "WEBHOOK_MAX_ATTEMPTS", 8),
+			BaseBackoff:  getEnvDuration(logger, "WEBHOOK_BASE_BACKOFF", 30*time.Second),
+			MaxBackoff:   getEnvDuration(logger, "WEBHOOK_MAX_BACKOFF", time.Hour),
+			PollInterval: getEnvDuration(logger, "WEBHOOK_POLL_INTERVAL", 5*time.Second),
+			Timeout:      getEnvDuration(logger, "WEBHOOK_TIMEOUT", 10*time.Second),
+			BatchSize:    getEnvInt(logger, "WEBHOOK_BATCH_SIZE", 50),
+		},
+		Notification: NotificationConfig{
+			PollInterval:    getEnvDuration(logger, "NOTIFICATION_POLL_INTERVAL", 10*time.Second),
+			BatchSize:       getEnvInt(logger, "NOTIFICATION_BATCH_SIZE", 100),
+			Timeout:         getEnvDuration(logger, "NOTIFICATION_TIMEOUT", 10*time.Second),
+			VAPIDPublicKey:  getEnv(logger, "VAPID_PUBLIC_KEY", ""),
+			VAPIDPrivateKey: getEnv(logger, "VAPID_PRIVATE_KEY", ""),
+			VAPIDSubject:    getEnv(logger, "VAPID_SUBJECT", "mailto:admin@localhost"),
+		},
+		Presence: PresenceConfig{
+			OnlineTTL:       getEnvDuration(logger, "PRESENCE_ONLINE_TTL", 90*time.Second),
+			RefreshInterval: getEnvDuration(logger, "PRESENCE_REFRESH_INTERVAL", 30*time.Second),
+		},
+		Typing: TypingConfig{
+			TTL:              getEnvDuration(logger, "TYPING_TTL", 6*time.Second),
+			CoalesceInterval: getEnvDuration(logger, "TYPING_COALESCE_INTERVAL", 3*time.Second),
+			Relay:            getEnv(logger, "TYPING_RELAY", "postgres"),
+		},
+		Retention: RetentionConfig{
+			DefaultDays:  getEnvInt(logger, "RETENTION_DEFAULT_DAYS", 0),
+			PollInterval: getEnvDuration(logger, "RETENTION_POLL_INTERVAL", time.Hour),
+			BatchSize:    getEnvInt(logger, "RETENTION_BATCH_SIZE", 1000),
+			BatchPause:   getEnvDuration(logger, "RETENTION_BATCH_PAUSE", 200*time.Millisecond),
+		},
+		Export: ExportConfig{
+			Dir:          getEnv(logger, "EXPORT_DIR", "exports"),
+			PollInterval: getEnvDuration(logger, "EXPORT_POLL_INTERVAL", 5*time.Second),
+			LinkTTL:      getEnvDuration(logger, "EXPORT_LINK_TTL", 15*time.Minute),
+			FileTTL:      getEnvDuration(logger, "EXPORT_FILE_TTL", 7*24*time.Hour),
+			StaleAfter:   getEnvDuration(logger, "EXPORT_STALE_AFTER", 30*time.Minute),
+		},
+		Admin: AdminConfig{
+			UserIDs:          getEnvInt64List(logger, "ADMIN_USER_IDS"),
+			ImpersonationTTL: getEnvDuration(logger, "ADMIN_IMPERSONATION_TTL", time.Hour),
+		},
+		Audit: AuditConfig{
+			Secret:       auditSecret,
+			SealInterval: getEnvDuration(logger, "AUDIT_SEAL_INTERVAL", time.Second),
+			SealBatch:    getEnvInt(logger, "AUDIT_SEAL_BATCH", 500),
+		},
+		ContentFilter: ContentFilterConfig{
+			Words:         getEnvList(logger, "CONTENT_FILTER_WORDS"),
+			WordsAction:   getEnv(logger, "CONTENT_FILTER_WORDS_ACTION", "mask"),
+			DeniedDomains: getEnvList(logger, "CONTENT_FILTER_DENIED_DOMAINS"),
+			LinksAction:   getEnv(logger, "CONTENT_FILTER_LINKS_ACTION", "reject"),
+			SecretsAction: getEnv(logger, "CONTENT_FILTER_SECRETS_ACTION", "reject"),
+		},
+		Outbound: OutboundConfig{
+			AllowedNetworks: getEnvList(logger, "OUTBOUND_ALLOWED_NETWORKS"),
+		},
+	}
+}
+
+// loadOIDCProviders reads OIDC_PROVIDERS (a comma-separated list of names)
+// and, for each name, OIDC_<NAME>_ISSUER, OIDC_<NAME>_CLIENT_ID,
+// OIDC_<NAME>_CLIENT_SECRET and optionally OIDC_<NAME>_SCOPES.
+func loadOIDCProviders(logger *zap.SugaredLogger) []OIDCProviderConfig {
+	var providers []OIDCProviderConfig
+	for _, name := range strings.Split(getEnv(logger, "OIDC_PROVIDERS", ""), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		prefix := "OIDC_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		providers = append(providers, OIDCProviderConfig{
+			Name:         name,
+			Issuer:       mustGetEnv(logger, prefix+"ISSUER"),
+			ClientID:     mustGetEnv(logger, prefix+"CLIENT_ID"),
+			ClientSecret: getEnv(logger, prefix+"CLIENT_SECRET", ""),
+			Scopes:       strings.Fields(getEnv(logger, prefix+"SCOPES", "openid email profile")),
+		})
 	}
+	return providers
 }
 
 func getEnv(logger *zap.SugaredLogger, key, defaultVal string) string {
@@ -87,3 +416,98 @@ func mustGetEnv(logger *zap.SugaredLogger, key string) string {
 	}
 	return value
 }
+
+func getEnvInt(logger *zap.SugaredLogger, key string, defaultVal int) int {
+	raw := getEnv(logger, key, strconv.Itoa(defaultVal))
+	value, err := strconv.Atoi(raw)
+	if err != nil {
+		logger.Fatalw("Environment variable must be an integer",
+			"key", key,
+			"value", raw,
+		)
+	}
+	return value
+}
+
+func getEnvDuration(logger *zap.SugaredLogger, key string, defaultVal time.Duration) time.Duration {
+	raw := getEnv(logger, key, defaultVal.String())
+	value, err := time.ParseDuration(raw)
+	if err != nil {
+		logger.Fatalw("Environment variable must be a duration",
+			"key", key,
+			"value", raw,
+		)
+	}
+	return value
+}
+
+// getEnvInt64List parses a comma-separated list of integers, empty by
+// default.
+func getEnvInt64List(logger *zap.SugaredLogger, key string) []int64 {
+	var values []int64
+	for _, raw := range strings.Split(getEnv(logger, key, ""), ",") {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+		value, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			logger.Fatalw("Environment variable must be a comma-separated list of integers",
+				"key", key,
+				"value", raw,
+			)
+		}
+		values = append(values, value)
+	}
+	return values
+}
+
+// getEnvList parses a comma-separated list, empty by default.
+func getEnvList(logger *zap.SugaredLogger, key string) []string {
+	var values []string
+	for _, raw := range strings.Split(getEnv(logger, key, ""), ",") {
+		if raw = strings.TrimSpace(raw); raw != "" {
+			values = append(values, raw)
+		}
+	}
+	return values
+}
+
+// getEnvRateLimit parses limits written as "<requests>/<period>", e.g.
+// "30/1m", with an optional burst suffix: "30/1m/60".
+func getEnvRateLimit(logger *zap.SugaredLogger, key string, defaultVal string) RateLimit {
+	raw := getEnv(logger, key, defaultVal)
+	parts := strings.Split(raw, "/")
+	if len(parts) != 2 && len(parts) != 3 {
+		logger.Fatalw("Environment variable must look like <requests>/<period>[/<burst>]",
+			"key", key,
+			"value", raw,
+		)
+	}
+
+	requests, err := strconv.Atoi(parts[0])
+	if err != nil || requests <= 0 {
+		logger.Fatalw("Invalid request count in rate limit",
+			"key", key,
+			"value", raw,
+		)
+	}
+	period, err := time.ParseDuration(parts[1])
+	if err != nil || period <= 0 {
+		logger.Fatalw("Invalid period in rate limit",
+			"key", key,
+			"value", raw,
+		)
+	}
+	burst := requests
+	if len(parts) == 3 {
+		burst, err = strconv.Atoi(parts[2])
+		if err != nil || burst <= 0 {
+			logger.Fatalw("Invalid burst in rate limit",
+				"key", key,
+				"value", raw,
+			)
+		}
+	}
+	return RateLimit{Requests: requests, Period: period, Burst: burst}
+}
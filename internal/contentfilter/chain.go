@@ -0,0 +1,173 @@
+package contentfilter
+
+import (
+	"context"
+	"fmt"
+	"github.com/maxwellzp/golang-chat-api/internal/config"
+	"github.com/maxwellzp/golang-chat-api/internal/httpx"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Chain runs every message through the registered filters before it is
+// stored.
+type Chain struct {
+	contentFilterRepository *ContentFilterRepository
+	filters                 []Filter
+	defaults                map[string]*Rule
+}
+
+func NewChain(contentFilterRepository *ContentFilterRepository) *Chain {
+	return &Chain{
+		contentFilterRepository: contentFilterRepository,
+		defaults:                map[string]*Rule{},
+	}
+}
+
+// Register adds a filter to the end of the chain. defaults is the server's
+// rule for it; with nil defaults the filter only runs in rooms that
+// configure it.
+func (c *Chain) Register(f Filter, defaults *Rule) {
+	c.filters = append(c.filters, f)
+	if defaults != nil {
+		c.defaults[f.Name()] = defaults
+	}
+}
+
+// Has reports whether a filter with the name is registered.
+func (c *Chain) Has(name string) bool {
+	for _, f := range c.filters {
+		if f.Name() == name {
+			return true
+		}
+	}
+	return false
+}
+
+// RegisterBuiltins adds the words, regex, links and secrets filters with the
+// server's rules from cfg.
+func RegisterBuiltins(c *Chain, cfg config.ContentFilterConfig) error {
+	for name, action := range map[string]string{
+		"CONTENT_FILTER_WORDS_ACTION":   cfg.WordsAction,
+		"CONTENT_FILTER_LINKS_ACTION":   cfg.LinksAction,
+		"CONTENT_FILTER_SECRETS_ACTION": cfg.SecretsAction,
+	} {
+		if actionRank[action] == 0 {
+			return fmt.Errorf("%s must be reject, mask or flag, got %q", name, action)
+		}
+	}
+
+	c.Register(wordFilter{}, &Rule{Filter: FilterWords, Action: cfg.WordsAction, Terms: cfg.Words})
+	c.Register(newRegexFilter(), nil)
+	c.Register(linkFilter{}, &Rule{Filter: FilterLinks, Action: cfg.LinksAction, Terms: normalizeDomains(cfg.DeniedDomains)})
+	c.Register(secretFilter{}, &Rule{Filter: FilterSecrets, Action: cfg.SecretsAction})
+	return nil
+}
+
+// Apply runs the content through the filters that apply in the room, or for
+// a nil roomID, in direct messages. A rejecting filter fails with a
+// validation error; otherwise the result has the masked content and the
+// filters that flagged it.
+func (c *Chain) Apply(ctx context.Context, roomID *int64, content string) (*Result, error) {
+	rules := c.defaults
+	if roomID != nil {
+		roomRules, err := c.contentFilterRepository.ListByRoom(ctx, *roomID)
+		if err != nil {
+			return nil, err
+		}
+		if len(roomRules) > 0 {
+			rules = make(map[string]*Rule, len(c.defaults)+len(roomRules))
+			for name, rule := range c.defaults {
+				rules[name] = rule
+			}
+			for _, rule := range roomRules {
+				rules[rule.Filter] = merge(c.defaults[rule.Filter], rule)
+			}
+		}
+	}
+
+	result := &Result{Content: content}
+	var masked []Match
+	for _, f := range c.filters {
+		rule := rules[f.Name()]
+		if rule == nil {
+			continue
+		}
+		matches := f.Find(content, rule)
+		if len(matches) == 0 {
+			continue
+		}
+		switch rule.Action {
+		case ActionReject:
+			return nil, httpx.ValidationErrorMap{
+				"content": fmt.Sprintf("content was blocked by the %s filter", f.Name()),
+			}
+		case ActionMask:
+			masked = append(masked, matches...)
+		case ActionFlag:
+			result.Flagged = append(result.Flagged, f.Name())
+		}
+	}
+	if len(masked) > 0 {
+		result.Content = mask(content, masked)
+	}
+	return result, nil
+}
+
+// merge applies a room's rule on top of the server's. The stricter of the
+// two actions wins, so a room can't let through what the server rejects.
+func merge(defaults *Rule, rule *Rule) *Rule {
+	if defaults == nil {
+		return rule
+	}
+	merged := *rule
+	if actionRank[defaults.Action] > actionRank[rule.Action] {
+		merged.Action = defaults.Action
+	}
+	merged.Terms = append(append([]string{}, defaults.Terms...), rule.Terms...)
+	if len(merged.AllowedDomains) == 0 {
+		merged.AllowedDomains = defaults.AllowedDomains
+	}
+	return &merged
+}
+
+// mask replaces the matched parts of content with asterisks, one per
+// character. Whitespace is kept, so masked blocks keep their line breaks.
+func mask(content string, matches []Match) string {
+	sort.Slice(matches, func(i, j int) bool { return matches[i].Start < matches[j].Start })
+
+	var b strings.Builder
+	pos := 0
+	for _, m := range matches {
+		if m.End <= pos {
+			continue
+		}
+		start := max(m.Start, pos)
+		b.WriteString(content[pos:start])
+		for _, r := range content[start:m.End] {
+			if unicode.IsSpace(r) {
+				b.WriteRune(r)
+			} else {
+				b.WriteByte('*')
+			}
+		}
+		pos = m.End
+	}
+	b.WriteString(content[pos:])
+	return b.String()
+}
+
+// normalizeDomains lowercases the domains and strips wildcards and dots
+// around them: "*.Example.com." becomes "example.com".
+func normalizeDomains(domains []string) []string {
+	normalized := make([]string, 0, len(domains))
+	for _, domain := range domains {
+		domain = strings.ToLower(strings.TrimSpace(domain))
+		domain = strings.Trim(strings.TrimPrefix(domain, "*."), ".")
+		if domain != "" {
+			normalized = append(normalized, domain)
+		}
+	}
+	return normalized
+}
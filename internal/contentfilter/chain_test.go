@@ -0,0 +1,29 @@
+package contentfilter
+
+import "testing"
+
+func TestMergeKeepsStricterAction(t *testing.T) {
+	tests := []struct {
+		server string
+		room   string
+		want   string
+	}{
+		{server: ActionReject, room: ActionFlag, want: ActionReject},
+		{server: ActionReject, room: ActionMask, want: ActionReject},
+		{server: ActionMask, room: ActionFlag, want: ActionMask},
+		{server: ActionFlag, room: ActionReject, want: ActionReject},
+		{server: ActionMask, room: ActionMask, want: ActionMask},
+	}
+	for _, tt := range tests {
+		defaults := &Rule{Filter: FilterWords, Action: tt.server, Terms: []string{"server"}}
+		rule := &Rule{RoomID: 1, Filter: FilterWords, Action: tt.room, Terms: []string{"room"}}
+
+		merged := merge(defaults, rule)
+		if merged.Action != tt.want {
+			t.Errorf("merge(%s, %s) action = %s, want %s", tt.server, tt.room, merged.Action, tt.want)
+		}
+		if len(merged.Terms) != 2 {
+			t.Errorf("merge(%s, %s) terms = %v, want both lists", tt.server, tt.room, merged.Terms)
+		}
+	}
+}
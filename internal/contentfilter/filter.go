@@ -0,0 +1,239 @@
+package contentfilter
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// Filter finds the parts of a message that break one kind of rule.
+type Filter interface {
+	Name() string
+	Find(content string, rule *Rule) []Match
+}
+
+// wordFilter matches whole words from the list, seeing through letter case
+// and leet-speak: "H3LL0" matches "hello". Letters commonly swapped for one
+// another are folded together on both sides, so "l", "1" and "i" are the
+// same letter.
+type wordFilter struct{}
+
+func (wordFilter) Name() string { return FilterWords }
+
+var leet = map[rune]rune{
+	'0': 'o',
+	'1': 'i', '!': 'i', '|': 'i', 'l': 'i',
+	'3': 'e',
+	'4': 'a', '@': 'a',
+	'5': 's', '$': 's',
+	'7': 't', '+': 't',
+	'8': 'b',
+	'9': 'g',
+}
+
+func fold(r rune) rune {
+	r = unicode.ToLower(r)
+	if folded, ok := leet[r]; ok {
+		return folded
+	}
+	return r
+}
+
+func foldString(s string) string {
+	return strings.Map(fold, s)
+}
+
+// inWord reports whether r can be part of a word, leet symbols included.
+func inWord(r rune) bool {
+	_, isLeet := leet[r]
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || isLeet
+}
+
+func (wordFilter) Find(content string, rule *Rule) []Match {
+	if len(rule.Terms) == 0 {
+		return nil
+	}
+	words := make(map[string]bool, len(rule.Terms))
+	for _, term := range rule.Terms {
+		words[foldString(strings.TrimSpace(term))] = true
+	}
+
+	var matches []Match
+	start := -1
+	check := func(end int) {
+		if start < 0 {
+			return
+		}
+		// Symbols at the edges are more likely punctuation than leet,
+		// as in "hello!", so try without them too.
+		s, e := start, end
+		if !words[foldString(content[s:e])] {
+			s, e = trimSymbols(content, s, e)
+			if s == e || !words[foldString(content[s:e])] {
+				return
+			}
+		}
+		matches = append(matches, Match{Start: s, End: e})
+	}
+	for i, r := range content {
+		if inWord(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		check(i)
+		start = -1
+	}
+	check(len(content))
+	return matches
+}
+
+// trimSymbols narrows content[start:end] to start and end with a letter or
+// digit.
+func trimSymbols(content string, start int, end int) (int, int) {
+	word := content[start:end]
+	trimmed := strings.TrimLeftFunc(word, notAlphanumeric)
+	start += len(word) - len(trimmed)
+	return start, start + len(strings.TrimRightFunc(trimmed, notAlphanumeric))
+}
+
+func notAlphanumeric(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
+
+// regexFilter matches the rule's regular expressions. Patterns are checked
+// when a room saves them, so invalid ones are skipped here. Compiled
+// patterns are cached per room until the room's rule changes.
+type regexFilter struct {
+	mu    sync.Mutex
+	rooms map[int64]*compiledPatterns
+}
+
+type compiledPatterns struct {
+	updatedAt time.Time
+	patterns  []*regexp.Regexp
+}
+
+func newRegexFilter() *regexFilter {
+	return &regexFilter{rooms: make(map[int64]*compiledPatterns)}
+}
+
+func (*regexFilter) Name() string { return FilterRegex }
+
+func (f *regexFilter) Find(content string, rule *Rule) []Match {
+	var matches []Match
+	for _, re := range f.compiled(rule) {
+		for _, loc := range re.FindAllStringIndex(content, -1) {
+			if loc[0] < loc[1] {
+				matches = append(matches, Match{Start: loc[0], End: loc[1]})
+			}
+		}
+	}
+	return matches
+}
+
+// compiled returns the rule's patterns compiled, from the cache if the
+// room's rule hasn't been updated since.
+func (f *regexFilter) compiled(rule *Rule) []*regexp.Regexp {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if cached, ok := f.rooms[rule.RoomID]; ok && cached.updatedAt.Equal(rule.UpdatedAt) {
+		return cached.patterns
+	}
+	patterns := make([]*regexp.Regexp, 0, len(rule.Terms))
+	for _, pattern := range rule.Terms {
+		if re, err := regexp.Compile(pattern); err == nil {
+			patterns = append(patterns, re)
+		}
+	}
+	f.rooms[rule.RoomID] = &compiledPatterns{updatedAt: rule.UpdatedAt, patterns: patterns}
+	return patterns
+}
+
+// linkFilter matches links to denied domains, and with an allow list, links
+// to any domain not on it. A domain covers its subdomains.
+type linkFilter struct{}
+
+func (linkFilter) Name() string { return FilterLinks }
+
+var urlPattern = regexp.MustCompile(`(?i)\b(?:https?://|www\.)[^\s<>"]+`)
+
+func (linkFilter) Find(content string, rule *Rule) []Match {
+	if len(rule.Terms) == 0 && len(rule.AllowedDomains) == 0 {
+		return nil
+	}
+	var matches []Match
+	for _, loc := range urlPattern.FindAllStringIndex(content, -1) {
+		host := linkHost(content[loc[0]:loc[1]])
+		if host == "" {
+			continue
+		}
+		denied := inDomains(host, rule.Terms)
+		if len(rule.AllowedDomains) > 0 && !inDomains(host, rule.AllowedDomains) {
+			denied = true
+		}
+		if denied {
+			matches = append(matches, Match{Start: loc[0], End: loc[1]})
+		}
+	}
+	return matches
+}
+
+func linkHost(link string) string {
+	if !strings.Contains(link, "://") {
+		link = "http://" + link
+	}
+	u, err := url.Parse(link)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+}
+
+func inDomains(host string, domains []string) bool {
+	for _, domain := range domains {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// secretFilter matches credentials that should never be posted: private
+// keys and API tokens with a recognizable format.
+type secretFilter struct{}
+
+func (secretFilter) Name() string { return FilterSecrets }
+
+var secretPatterns = []*regexp.Regexp{
+	// A private key block, to its end or the end of the message.
+	regexp.MustCompile(`-----BEGIN[A-Z ]* PRIVATE KEY-----[\s\S]*?(?:-----END[A-Z ]* PRIVATE KEY-----|$)`),
+	// AWS access key IDs
+	regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`),
+	// GitHub tokens
+	regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,}\b`),
+	regexp.MustCompile(`\bgithub_pat_[A-Za-z0-9_]{22,}\b`),
+	// Slack tokens
+	regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}`),
+	// Stripe live keys
+	regexp.MustCompile(`\b[rs]k_live_[A-Za-z0-9]{20,}\b`),
+	// Google API keys
+	regexp.MustCompile(`\bAIza[0-9A-Za-z_\-]{35}`),
+	// This server's own API keys
+	regexp.MustCompile(`\bck_[0-9a-f]{8,16}_[0-9a-f]{64}\b`),
+}
+
+func (secretFilter) Find(content string, rule *Rule) []Match {
+	var matches []Match
+	for _, re := range secretPatterns {
+		for _, loc := range re.FindAllStringIndex(content, -1) {
+			matches = append(matches, Match{Start: loc[0], End: loc[1]})
+		}
+	}
+	return matches
+}
@@ -0,0 +1,144 @@
+package contentfilter
+
+import (
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"github.com/maxwellzp/golang-chat-api/internal/httpx"
+	"github.com/maxwellzp/golang-chat-api/internal/logger"
+	"github.com/maxwellzp/golang-chat-api/internal/validatorx"
+	"net/http"
+)
+
+type ContentFilterHandler struct {
+	contentFilterService *ContentFilterService
+	validator            *validatorx.Validator
+	logger               *logger.Logger
+}
+
+func NewContentFilterHandler(contentFilterService *ContentFilterService, validator *validatorx.Validator, logger *logger.Logger) *ContentFilterHandler {
+	return &ContentFilterHandler{
+		contentFilterService: contentFilterService,
+		validator:            validator,
+		logger:               logger,
+	}
+}
+
+// List returns the room's own rules; filters without one follow the
+// server's rules.
+func (h *ContentFilterHandler) List() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := httpx.GetUserID(r.Context())
+		if err != nil {
+			h.logger.Warnw("Unauthorized request to list content filters")
+			httpx.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		roomID, err := httpx.ParseInt64Param(r, "id")
+		if err != nil {
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid RoomID")
+			return
+		}
+
+		rules, err := h.contentFilterService.Rules(r.Context(), roomID, userID)
+		if httpx.WriteServiceError(w, err) {
+			return
+		}
+		if err != nil {
+			h.logger.Errorw("Failed to list content filters",
+				"error", err,
+				"room_id", roomID,
+			)
+			httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
+			return
+		}
+		httpx.WriteJSON(w, http.StatusOK, rules)
+	}
+}
+
+func (h *ContentFilterHandler) Save() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := httpx.GetUserID(r.Context())
+		if err != nil {
+			h.logger.Warnw("Unauthorized request to save content filter")
+			httpx.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		roomID, err := httpx.ParseInt64Param(r, "id")
+		if err != nil {
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid RoomID")
+			return
+		}
+		filter := chi.URLParam(r, "filter")
+
+		var req SaveRuleRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.logger.Warnw("Failed to decode SaveRuleRequest",
+				"error", err,
+			)
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		if err := h.validator.Validate(&req); err != nil {
+			httpx.WriteValidationError(w, err)
+			return
+		}
+
+		rule, err := h.contentFilterService.SaveRule(r.Context(), roomID, userID, filter, req)
+		if httpx.WriteServiceError(w, err) {
+			return
+		}
+		if err != nil {
+			h.logger.Errorw("Failed to save content filter",
+				"error", err,
+				"filter", filter,
+				"room_id", roomID,
+			)
+			httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
+			return
+		}
+		h.logger.Infow("Content filter saved",
+			"action", rule.Action,
+			"filter", filter,
+			"room_id", roomID,
+			"user_id", userID,
+		)
+		httpx.WriteJSON(w, http.StatusOK, rule)
+	}
+}
+
+func (h *ContentFilterHandler) Delete() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := httpx.GetUserID(r.Context())
+		if err != nil {
+			h.logger.Warnw("Unauthorized request to delete content filter")
+			httpx.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		roomID, err := httpx.ParseInt64Param(r, "id")
+		if err != nil {
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid RoomID")
+			return
+		}
+		filter := chi.URLParam(r, "filter")
+
+		err = h.contentFilterService.DeleteRule(r.Context(), roomID, userID, filter)
+		if httpx.WriteServiceError(w, err) {
+			return
+		}
+		if err != nil {
+			h.logger.Errorw("Failed to delete content filter",
+				"error", err,
+				"filter", filter,
+				"room_id", roomID,
+			)
+			httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
+			return
+		}
+		h.logger.Infow("Content filter deleted",
+			"filter", filter,
+			"room_id", roomID,
+			"user_id", userID,
+		)
+		httpx.WriteJSON(w, http.StatusNoContent, nil)
+	}
+}
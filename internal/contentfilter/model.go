@@ -0,0 +1,55 @@
+package contentfilter
+
+import "time"
+
+// Names of the built-in filters.
+const (
+	FilterWords   = "words"
+	FilterRegex   = "regex"
+	FilterLinks   = "links"
+	FilterSecrets = "secrets"
+)
+
+// What happens to a message a filter matches. When several filters match,
+// reject wins over mask, and mask over flag.
+const (
+	ActionReject = "reject"
+	ActionMask   = "mask"
+	ActionFlag   = "flag"
+)
+
+var actionRank = map[string]int{
+	ActionFlag:   1,
+	ActionMask:   2,
+	ActionReject: 3,
+}
+
+// Rule configures one filter. The server's rules apply everywhere; a room's
+// rule for a filter can make the action stricter but not more lenient, and
+// adds its terms to the server's.
+// Terms are words for the words filter, patterns for the regex filter and
+// denied domains for the links filter; the secrets filter has none.
+// AllowedDomains only applies to the links filter: when set, links to any
+// other domain match.
+type Rule struct {
+	RoomID         int64     `json:"room_id"`
+	Filter         string    `json:"filter"`
+	Action         string    `json:"action"`
+	Terms          []string  `json:"terms"`
+	AllowedDomains []string  `json:"allowed_domains"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// Match is a part of a message that breaks a rule, as byte offsets into the
+// content.
+type Match struct {
+	Start int
+	End   int
+}
+
+// Result is a message that passed the filters. Content has the masked parts
+// replaced; Flagged names the filters that want the message reviewed.
+type Result struct {
+	Content string
+	Flagged []string
+}
@@ -0,0 +1,9 @@
+package contentfilter
+
+// SaveRuleRequest sets a room's rule for one filter. See Rule for what Terms
+// hold.
+type SaveRuleRequest struct {
+	Action         string   `json:"action" validate:"required,oneof=reject mask flag"`
+	Terms          []string `json:"terms" validate:"max=500,dive,min=1,max=200"`
+	AllowedDomains []string `json:"allowed_domains" validate:"max=100,dive,min=1,max=253"`
+}
@@ -0,0 +1,65 @@
+package contentfilter
+
+import (
+	"context"
+	"github.com/lib/pq"
+	"github.com/maxwellzp/golang-chat-api/internal/db"
+)
+
+type ContentFilterRepository struct {
+	database *db.Db
+}
+
+func NewContentFilterRepository(database *db.Db) *ContentFilterRepository {
+	return &ContentFilterRepository{database: database}
+}
+
+func (r *ContentFilterRepository) ListByRoom(ctx context.Context, roomID int64) ([]*Rule, error) {
+	rows, err := r.database.QueryContext(ctx,
+		`SELECT room_id, filter, action, terms, allowed_domains, updated_at
+		 FROM room_content_filters
+		 WHERE room_id = $1
+		 ORDER BY filter`, roomID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules := []*Rule{}
+	for rows.Next() {
+		var rule Rule
+		err := rows.Scan(&rule.RoomID, &rule.Filter, &rule.Action, pq.Array(&rule.Terms),
+			pq.Array(&rule.AllowedDomains), &rule.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, &rule)
+	}
+	return rules, rows.Err()
+}
+
+// Save creates or replaces the room's rule for the filter.
+func (r *ContentFilterRepository) Save(ctx context.Context, rule *Rule) error {
+	_, err := r.database.ExecContext(ctx,
+		`INSERT INTO room_content_filters (room_id, filter, action, terms, allowed_domains, updated_at)
+		 VALUES ($1, $2, $3, $4, $5, $6)
+		 ON CONFLICT (room_id, filter) DO UPDATE
+		 SET action = EXCLUDED.action, terms = EXCLUDED.terms,
+		     allowed_domains = EXCLUDED.allowed_domains, updated_at = EXCLUDED.updated_at`,
+		rule.RoomID, rule.Filter, rule.Action, pq.Array(rule.Terms), pq.Array(rule.AllowedDomains), rule.UpdatedAt)
+	return err
+}
+
+// Delete reports false if the room had no rule for the filter.
+func (r *ContentFilterRepository) Delete(ctx context.Context, roomID int64, filter string) (bool, error) {
+	res, err := r.database.ExecContext(ctx,
+		"DELETE FROM room_content_filters WHERE room_id = $1 AND filter = $2", roomID, filter)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected > 0, nil
+}
@@ -0,0 +1,98 @@
+package contentfilter
+
+import (
+	"context"
+	"fmt"
+	"github.com/maxwellzp/golang-chat-api/internal/apperr"
+	"github.com/maxwellzp/golang-chat-api/internal/httpx"
+	"github.com/maxwellzp/golang-chat-api/internal/room"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	ErrUnknownFilter = apperr.NotFound("unknown_filter", "no such content filter")
+	ErrRuleNotFound  = apperr.NotFound("rule_not_found", "this room has no rule for the filter")
+)
+
+// ContentFilterService manages rooms' filter rules. Moderators can see them;
+// only room admins can change them.
+type ContentFilterService struct {
+	contentFilterRepository *ContentFilterRepository
+	chain                   *Chain
+	roomService             *room.RoomService
+}
+
+func NewContentFilterService(contentFilterRepository *ContentFilterRepository, chain *Chain, roomService *room.RoomService) *ContentFilterService {
+	return &ContentFilterService{
+		contentFilterRepository: contentFilterRepository,
+		chain:                   chain,
+		roomService:             roomService,
+	}
+}
+
+func (s *ContentFilterService) Rules(ctx context.Context, roomID int64, userID int64) ([]*Rule, error) {
+	if err := s.roomService.RequireRole(ctx, roomID, userID, room.RoleModerator); err != nil {
+		return nil, err
+	}
+	return s.contentFilterRepository.ListByRoom(ctx, roomID)
+}
+
+// SaveRule replaces the room's rule for the filter. Regex patterns must
+// compile; domains are stored lowercase without wildcards.
+func (s *ContentFilterService) SaveRule(ctx context.Context, roomID int64, userID int64, filter string, req SaveRuleRequest) (*Rule, error) {
+	if err := s.roomService.RequireRole(ctx, roomID, userID, room.RoleAdmin); err != nil {
+		return nil, err
+	}
+	if !s.chain.Has(filter) {
+		return nil, ErrUnknownFilter
+	}
+
+	terms := make([]string, 0, len(req.Terms))
+	for _, term := range req.Terms {
+		if term = strings.TrimSpace(term); term != "" {
+			terms = append(terms, term)
+		}
+	}
+	switch filter {
+	case FilterRegex:
+		for _, pattern := range terms {
+			if _, err := regexp.Compile(pattern); err != nil {
+				return nil, httpx.ValidationErrorMap{
+					"terms": fmt.Sprintf("invalid pattern %q: %v", pattern, err),
+				}
+			}
+		}
+	case FilterLinks:
+		terms = normalizeDomains(terms)
+	}
+
+	rule := &Rule{
+		RoomID:         roomID,
+		Filter:         filter,
+		Action:         req.Action,
+		Terms:          terms,
+		AllowedDomains: normalizeDomains(req.AllowedDomains),
+		UpdatedAt:      time.Now().UTC(),
+	}
+	if err := s.contentFilterRepository.Save(ctx, rule); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+// DeleteRule returns the filter to the server's rule in the room.
+func (s *ContentFilterService) DeleteRule(ctx context.Context, roomID int64, userID int64, filter string) error {
+	if err := s.roomService.RequireRole(ctx, roomID, userID, room.RoleAdmin); err != nil {
+		return err
+	}
+	found, err := s.contentFilterRepository.Delete(ctx, roomID, filter)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrRuleNotFound
+	}
+	return nil
+}
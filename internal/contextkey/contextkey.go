@@ -3,3 +3,16 @@ package contextkey
 type Key string
 
 const UserID Key = "user_id"
+
+// Scopes holds the []string granted by an API key. It is absent for
+// interactive (JWT) sessions, which are not scope-restricted.
+const Scopes Key = "scopes"
+
+// ImpersonatorID holds the int64 ID of the admin acting as the user, on
+// requests made with an impersonation token.
+const ImpersonatorID Key = "impersonator_id"
+
+// ClientIP and UserAgent hold the strings describing where a request came
+// from, for code that only has the context.
+const ClientIP Key = "client_ip"
+const UserAgent Key = "user_agent"
@@ -0,0 +1,74 @@
+package event
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	MessageCreated = "message.created"
+	MessageUpdated = "message.updated"
+	MessageDeleted = "message.deleted"
+	// MessagePinned and MessageUnpinned carry the message as Data.
+	MessagePinned   = "message.pinned"
+	MessageUnpinned = "message.unpinned"
+	MemberJoined    = "member.joined"
+	// MentionCreated is published once per user newly mentioned by a
+	// message; Data is a MentionData.
+	MentionCreated = "mention.created"
+	// SavedMessageReminder is published when a bookmark's reminder is due;
+	// ActorID is the user who saved it and Data is a SavedMessage.
+	SavedMessageReminder = "saved_message.reminder"
+	// ReminderDue is published after a /remind reminder has been posted to
+	// the user's own DMs; Data is the posted message.
+	ReminderDue = "reminder.due"
+	// MessageFlagged is published after a content filter flagged a new or
+	// edited message for review; Data is a FlaggedData.
+	MessageFlagged = "message.flagged"
+)
+
+// Event is a domain event published by the services. Exactly one of RoomID
+// and ReceiverID is set for message events; member events carry RoomID.
+type Event struct {
+	Type       string    `json:"type"`
+	RoomID     *int64    `json:"room_id,omitempty"`
+	ReceiverID *int64    `json:"receiver_id,omitempty"`
+	ActorID    int64     `json:"actor_id"`
+	Data       any       `json:"data"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+type Handler func(ctx context.Context, e Event)
+
+// Bus is an in-process publish/subscribe hub. Handlers run synchronously in
+// the publisher's goroutine, so they must be quick and hand slow work off
+// (e.g. to an outbox table or a buffered channel).
+type Bus struct {
+	mu       sync.RWMutex
+	handlers []Handler
+}
+
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+func (b *Bus) Subscribe(h Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers = append(b.handlers, h)
+}
+
+func (b *Bus) Publish(ctx context.Context, e Event) {
+	if e.OccurredAt.IsZero() {
+		e.OccurredAt = time.Now().UTC()
+	}
+
+	b.mu.RLock()
+	handlers := b.handlers
+	b.mu.RUnlock()
+
+	for _, h := range handlers {
+		h(ctx, e)
+	}
+}
@@ -0,0 +1,209 @@
+package export
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/maxwellzp/golang-chat-api/internal/config"
+	"github.com/maxwellzp/golang-chat-api/internal/logger"
+	"github.com/maxwellzp/golang-chat-api/internal/room"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Exporter runs queued exports and deletes archives past their expiry.
+// Several exporters (one per replica) can run against the same database as
+// long as they share the export directory.
+type Exporter struct {
+	exportRepository *ExportRepository
+	roomService      *room.RoomService
+	cfg              config.ExportConfig
+	logger           *logger.Logger
+}
+
+func NewExporter(exportRepository *ExportRepository, roomService *room.RoomService, cfg config.ExportConfig, logger *logger.Logger) *Exporter {
+	return &Exporter{
+		exportRepository: exportRepository,
+		roomService:      roomService,
+		cfg:              cfg,
+		logger:           logger,
+	}
+}
+
+// Run processes exports until ctx is cancelled.
+func (x *Exporter) Run(ctx context.Context) {
+	if err := os.MkdirAll(x.cfg.Dir, 0o750); err != nil {
+		x.logger.Errorw("Failed to create export directory",
+			"error", err,
+			"dir", x.cfg.Dir,
+		)
+		return
+	}
+	ticker := time.NewTicker(x.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		for x.RunNext(ctx) {
+		}
+		x.Cleanup(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunNext runs one queued export. It reports false when there was nothing
+// to run.
+func (x *Exporter) RunNext(ctx context.Context) bool {
+	now := time.Now().UTC()
+	e, err := x.exportRepository.Claim(ctx, now, now.Add(-x.cfg.StaleAfter))
+	if err != nil {
+		if ctx.Err() == nil {
+			x.logger.Errorw("Failed to claim export",
+				"error", err,
+			)
+		}
+		return false
+	}
+	if e == nil {
+		return false
+	}
+
+	if err := x.run(ctx, e); err != nil {
+		if ctx.Err() != nil {
+			// Picked up again once StaleAfter has passed
+			return false
+		}
+		reason := "Export failed, please try again"
+		if errors.Is(err, room.ErrForbidden) || errors.Is(err, room.ErrRoomNotFound) {
+			reason = err.Error()
+		}
+		x.logger.Errorw("Export failed",
+			"error", err,
+			"export_id", e.ID,
+		)
+		if err := x.exportRepository.Fail(ctx, e.ID, reason); err != nil {
+			x.logger.Errorw("Failed to mark export failed",
+				"error", err,
+				"export_id", e.ID,
+			)
+		}
+		return true
+	}
+	x.logger.Infow("Export finished",
+		"export_id", e.ID,
+		"messages", e.MessageCount,
+		"size_bytes", e.SizeBytes,
+	)
+	return true
+}
+
+// run writes the archive to a temporary file and renames it into place once
+// complete, so a download never sees a partial archive.
+func (x *Exporter) run(ctx context.Context, e *Export) error {
+	manifest := &Manifest{
+		ExportID:   e.ID,
+		RoomID:     e.RoomID,
+		PeerID:     e.PeerID,
+		ExportedBy: e.UserID,
+		ExportedAt: time.Now().UTC(),
+		Format:     e.Format,
+	}
+	if e.RoomID != nil {
+		if err := x.roomService.RequireRole(ctx, *e.RoomID, e.UserID, room.RoleMember); err != nil {
+			return err
+		}
+		rm, err := x.roomService.GetByID(ctx, *e.RoomID)
+		if err != nil {
+			return err
+		}
+		if rm == nil {
+			return room.ErrRoomNotFound
+		}
+		manifest.RoomName = rm.Name
+	}
+	var extra map[string]any
+	if e.RoomID == nil && e.PeerID == nil {
+		profile, err := x.exportRepository.AccountProfile(ctx, e.UserID)
+		if err != nil {
+			return err
+		}
+		if profile == nil {
+			return ErrPeerNotFound
+		}
+		extra = map[string]any{"profile.json": profile}
+	}
+
+	tmp, err := os.CreateTemp(x.cfg.Dir, fmt.Sprintf("export-%d-*.tmp", e.ID))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	count, err := writeArchive(tmp, manifest, extra, func(fn func(*ExportedMessage) error) error {
+		return x.exportRepository.StreamMessages(ctx, e, fn)
+	})
+	if err != nil {
+		return err
+	}
+	info, err := tmp.Stat()
+	if err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	path := filepath.Join(x.cfg.Dir, fmt.Sprintf("export-%d.zip", e.ID))
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+
+	finishedAt := time.Now().UTC()
+	expiresAt := finishedAt.Add(x.cfg.FileTTL)
+	e.FilePath = &path
+	e.SizeBytes = info.Size()
+	e.MessageCount = count
+	e.FinishedAt = &finishedAt
+	e.ExpiresAt = &expiresAt
+	return x.exportRepository.Finish(ctx, e)
+}
+
+// Cleanup expires finished exports and deletes their archives. Leftover
+// files older than FileTTL, e.g. of exports whose room was deleted, are
+// removed too.
+func (x *Exporter) Cleanup(ctx context.Context) {
+	now := time.Now().UTC()
+	paths, err := x.exportRepository.ExpireFinished(ctx, now)
+	if err != nil {
+		if ctx.Err() == nil {
+			x.logger.Errorw("Failed to expire exports",
+				"error", err,
+			)
+		}
+		return
+	}
+	for _, path := range paths {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			x.logger.Warnw("Failed to delete export archive",
+				"error", err,
+				"path", path,
+			)
+		}
+	}
+
+	entries, err := os.ReadDir(x.cfg.Dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || entry.IsDir() || now.Sub(info.ModTime()) < x.cfg.FileTTL+x.cfg.StaleAfter {
+			continue
+		}
+		_ = os.Remove(filepath.Join(x.cfg.Dir, entry.Name()))
+	}
+}
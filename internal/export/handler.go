@@ -0,0 +1,220 @@
+package export
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/maxwellzp/golang-chat-api/internal/httpx"
+	"github.com/maxwellzp/golang-chat-api/internal/logger"
+	"github.com/maxwellzp/golang-chat-api/internal/validatorx"
+	"io"
+	"net/http"
+)
+
+type ExportHandler struct {
+	exportService *ExportService
+	validator     *validatorx.Validator
+	logger        *logger.Logger
+}
+
+func NewExportHandler(exportService *ExportService, validator *validatorx.Validator, logger *logger.Logger) *ExportHandler {
+	return &ExportHandler{
+		exportService: exportService,
+		validator:     validator,
+		logger:        logger,
+	}
+}
+
+// Create queues an export of the room, or with conversation set, of the
+// direct messages with the user in the path. Poll the returned export until
+// its status is done.
+func (h *ExportHandler) Create(conversation bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := httpx.GetUserID(r.Context())
+		if err != nil {
+			h.logger.Warnw("Unauthorized request to create export")
+			httpx.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		param, invalid := "id", "Invalid RoomID"
+		if conversation {
+			param, invalid = "user_id", "Invalid UserID"
+		}
+		targetID, err := httpx.ParseInt64Param(r, param)
+		if err != nil {
+			httpx.WriteError(w, http.StatusBadRequest, invalid)
+			return
+		}
+
+		var req CreateExportRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.logger.Warnw("Failed to decode CreateExportRequest",
+				"error", err,
+			)
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		if err := h.validator.Validate(&req); err != nil {
+			httpx.WriteValidationError(w, err)
+			return
+		}
+
+		var e *Export
+		if conversation {
+			e, err = h.exportService.ExportConversation(r.Context(), userID, targetID, req)
+		} else {
+			e, err = h.exportService.ExportRoom(r.Context(), userID, targetID, req)
+		}
+		if httpx.WriteServiceError(w, err) {
+			return
+		}
+		if err != nil {
+			h.logger.Errorw("Failed to create export",
+				"error", err,
+				"conversation", conversation,
+				"target_id", targetID,
+			)
+			httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
+			return
+		}
+		h.logger.Infow("Export queued",
+			"export_id", e.ID,
+			"conversation", conversation,
+			"target_id", targetID,
+			"format", e.Format,
+			"user_id", userID,
+		)
+		httpx.WriteJSON(w, http.StatusAccepted, e)
+	}
+}
+
+func (h *ExportHandler) Get() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := httpx.GetUserID(r.Context())
+		if err != nil {
+			h.logger.Warnw("Unauthorized request to get export")
+			httpx.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		id, err := httpx.ParseInt64Param(r, "id")
+		if err != nil {
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid ExportID")
+			return
+		}
+
+		e, err := h.exportService.Get(r.Context(), userID, id)
+		if httpx.WriteServiceError(w, err) {
+			return
+		}
+		if err != nil {
+			h.logger.Errorw("Failed to get export",
+				"error", err,
+				"export_id", id,
+			)
+			httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
+			return
+		}
+		httpx.WriteJSON(w, http.StatusOK, e)
+	}
+}
+
+// List returns the current user's exports, newest first.
+func (h *ExportHandler) List() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := httpx.GetUserID(r.Context())
+		if err != nil {
+			h.logger.Warnw("Unauthorized request to list exports")
+			httpx.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		limit, offset, err := httpx.ParsePagination(r, 50, 100)
+		if err != nil {
+			httpx.WriteValidationError(w, err)
+			return
+		}
+
+		exports, err := h.exportService.List(r.Context(), userID, limit, offset)
+		if err != nil {
+			h.logger.Errorw("Failed to list exports",
+				"error", err,
+				"user_id", userID,
+			)
+			httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
+			return
+		}
+		httpx.WriteJSON(w, http.StatusOK, exports)
+	}
+}
+
+// Download serves the archive. The signed link is the credential, so the
+// route needs no session.
+func (h *ExportHandler) Download() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := httpx.ParseInt64Param(r, "id")
+		if err != nil {
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid ExportID")
+			return
+		}
+		query := r.URL.Query()
+
+		e, f, err := h.exportService.Open(r.Context(), id, query.Get("expires"), query.Get("signature"))
+		if httpx.WriteServiceError(w, err) {
+			return
+		}
+		if err != nil {
+			h.logger.Errorw("Failed to open export",
+				"error", err,
+				"export_id", id,
+			)
+			httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
+			return
+		}
+		defer f.Close()
+
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+FileName(e)+`"`)
+		w.Header().Set("Cache-Control", "private, no-store")
+		http.ServeContent(w, r, FileName(e), *e.FinishedAt, f)
+	}
+}
+
+// CreateAccount queues an export of all of the current user's data. The body
+// is optional; the format defaults to JSON.
+func (h *ExportHandler) CreateAccount() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := httpx.GetUserID(r.Context())
+		if err != nil {
+			h.logger.Warnw("Unauthorized request to export account")
+			httpx.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+
+		req := CreateExportRequest{Format: FormatJSON}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+			h.logger.Warnw("Failed to decode CreateExportRequest",
+				"error", err,
+			)
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		if err := h.validator.Validate(&req); err != nil {
+			httpx.WriteValidationError(w, err)
+			return
+		}
+
+		e, err := h.exportService.ExportAccount(r.Context(), userID, req)
+		if err != nil {
+			h.logger.Errorw("Failed to create account export",
+				"error", err,
+				"user_id", userID,
+			)
+			httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
+			return
+		}
+		h.logger.Infow("Account export queued",
+			"export_id", e.ID,
+			"format", e.Format,
+			"user_id", userID,
+		)
+		httpx.WriteJSON(w, http.StatusAccepted, e)
+	}
+}
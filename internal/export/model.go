@@ -0,0 +1,115 @@
+package export
+
+import (
+	"time"
+)
+
+const (
+	FormatJSON = "json"
+	FormatCSV  = "csv"
+	FormatHTML = "html"
+)
+
+const (
+	StatusPending = "pending"
+	StatusRunning = "running"
+	StatusDone    = "done"
+	StatusFailed  = "failed"
+	// StatusExpired exports have had their archive deleted.
+	StatusExpired = "expired"
+)
+
+// Export is an export job for a room (RoomID set), for the requester's
+// direct messages with PeerID, or with neither set, for all of the
+// requester's own data. DownloadURL is set on finished exports and is
+// valid until DownloadExpiresAt.
+type Export struct {
+	ID                int64      `json:"id"`
+	UserID            int64      `json:"user_id"`
+	RoomID            *int64     `json:"room_id,omitempty"`
+	PeerID            *int64     `json:"peer_id,omitempty"`
+	Format            string     `json:"format"`
+	Status            string     `json:"status"`
+	Error             *string    `json:"error,omitempty"`
+	FilePath          *string    `json:"-"`
+	SizeBytes         int64      `json:"size_bytes"`
+	MessageCount      int        `json:"message_count"`
+	CreatedAt         time.Time  `json:"created_at"`
+	StartedAt         *time.Time `json:"started_at"`
+	FinishedAt        *time.Time `json:"finished_at"`
+	ExpiresAt         *time.Time `json:"expires_at"`
+	DownloadURL       string     `json:"download_url,omitempty"`
+	DownloadExpiresAt *time.Time `json:"download_expires_at,omitempty"`
+}
+
+// ExportedMessage is one message as written to an archive. SenderName is
+// the webhook display name when set, otherwise the author's username.
+// Edits holds the earlier versions of the content, oldest first.
+type ExportedMessage struct {
+	ID         int64               `json:"id"`
+	SenderID   int64               `json:"sender_id"`
+	SenderName string              `json:"sender_name"`
+	RoomID     *int64              `json:"room_id,omitempty"`
+	ReceiverID *int64              `json:"receiver_id,omitempty"`
+	ParentID   *int64              `json:"parent_id,omitempty"`
+	Content    string              `json:"content"`
+	CreatedAt  time.Time           `json:"created_at"`
+	EditedAt   *time.Time          `json:"edited_at"`
+	Pinned     bool                `json:"pinned"`
+	Edits      []*ExportedEdit     `json:"edits,omitempty"`
+	Reactions  []*ExportedReaction `json:"reactions,omitempty"`
+}
+
+// ExportedEdit is the content a message had until EditedAt.
+type ExportedEdit struct {
+	Content  string    `json:"content"`
+	EditedAt time.Time `json:"edited_at"`
+}
+
+type ExportedReaction struct {
+	Emoji string `json:"emoji"`
+	Count int    `json:"count"`
+}
+
+// Manifest describes the archive and is stored next to the messages.
+type Manifest struct {
+	ExportID     int64     `json:"export_id"`
+	RoomID       *int64    `json:"room_id,omitempty"`
+	RoomName     string    `json:"room_name,omitempty"`
+	PeerID       *int64    `json:"peer_id,omitempty"`
+	ExportedBy   int64     `json:"exported_by"`
+	ExportedAt   time.Time `json:"exported_at"`
+	Format       string    `json:"format"`
+	MessageCount int       `json:"message_count"`
+}
+
+// AccountProfile is the personal data written to account exports next to
+// the user's messages.
+type AccountProfile struct {
+	ID         int64                `json:"id"`
+	Username   string               `json:"username"`
+	Email      string               `json:"email"`
+	CreatedAt  time.Time            `json:"created_at"`
+	Identities []*AccountIdentity   `json:"identities"`
+	Rooms      []*AccountMembership `json:"rooms"`
+	Bots       []*AccountBot        `json:"bots"`
+}
+
+type AccountIdentity struct {
+	Provider  string    `json:"provider"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type AccountMembership struct {
+	RoomID   int64     `json:"room_id"`
+	RoomName string    `json:"room_name"`
+	Role     string    `json:"role"`
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+type AccountBot struct {
+	ID        int64     `json:"id"`
+	Username  string    `json:"username"`
+	CreatedAt time.Time `json:"created_at"`
+}
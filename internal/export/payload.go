@@ -0,0 +1,5 @@
+package export
+
+type CreateExportRequest struct {
+	Format string `json:"format" validate:"required,oneof=json csv html"`
+}
@@ -0,0 +1,293 @@
+package export
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"github.com/maxwellzp/golang-chat-api/internal/db"
+	"time"
+)
+
+type ExportRepository struct {
+	database *db.Db
+}
+
+func NewExportRepository(database *db.Db) *ExportRepository {
+	return &ExportRepository{database: database}
+}
+
+// exportColumns is the select list matching scanExport.
+const exportColumns = `id, user_id, room_id, peer_id, format, status, error, file_path, size_bytes, message_count,
+	created_at, started_at, finished_at, expires_at`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanExport(row rowScanner) (*Export, error) {
+	var e Export
+	err := row.Scan(
+		&e.ID,
+		&e.UserID,
+		&e.RoomID,
+		&e.PeerID,
+		&e.Format,
+		&e.Status,
+		&e.Error,
+		&e.FilePath,
+		&e.SizeBytes,
+		&e.MessageCount,
+		&e.CreatedAt,
+		&e.StartedAt,
+		&e.FinishedAt,
+		&e.ExpiresAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+func (r *ExportRepository) Create(ctx context.Context, e *Export) error {
+	query := `INSERT INTO exports (user_id, room_id, peer_id, format, status, created_at)
+			  VALUES ($1, $2, $3, $4, $5, $6)
+			  RETURNING id, created_at`
+	e.Status = StatusPending
+	return r.database.QueryRowContext(ctx, query, e.UserID, e.RoomID, e.PeerID, e.Format, e.Status, time.Now().UTC()).
+		Scan(&e.ID, &e.CreatedAt)
+}
+
+func (r *ExportRepository) GetByID(ctx context.Context, id int64) (*Export, error) {
+	e, err := scanExport(r.database.QueryRowContext(ctx, `SELECT `+exportColumns+` FROM exports WHERE id = $1`, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return e, nil
+}
+
+// ListByUser returns the user's exports, newest first.
+func (r *ExportRepository) ListByUser(ctx context.Context, userID int64, limit int, offset int) ([]*Export, error) {
+	query := `SELECT ` + exportColumns + `
+			  FROM exports
+			  WHERE user_id = $1
+			  ORDER BY created_at DESC, id DESC
+			  LIMIT $2 OFFSET $3`
+	rows, err := r.database.QueryContext(ctx, query, userID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	exports := []*Export{}
+	for rows.Next() {
+		e, err := scanExport(rows)
+		if err != nil {
+			return nil, err
+		}
+		exports = append(exports, e)
+	}
+	return exports, rows.Err()
+}
+
+// Claim marks the oldest pending export, or one whose replica has been
+// running it since before staleBefore, as running and returns it. Rows
+// locked by another replica are skipped. It returns nil when there is
+// nothing to do.
+func (r *ExportRepository) Claim(ctx context.Context, now time.Time, staleBefore time.Time) (*Export, error) {
+	query := `UPDATE exports SET status = 'running', started_at = $1, error = NULL
+			  WHERE id = (
+				SELECT id FROM exports
+				WHERE status = 'pending' OR (status = 'running' AND started_at < $2)
+				ORDER BY created_at, id
+				LIMIT 1
+				FOR UPDATE SKIP LOCKED
+			  )
+			  RETURNING ` + exportColumns
+	e, err := scanExport(r.database.QueryRowContext(ctx, query, now, staleBefore))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return e, nil
+}
+
+// Finish records a written archive.
+func (r *ExportRepository) Finish(ctx context.Context, e *Export) error {
+	_, err := r.database.ExecContext(ctx,
+		`UPDATE exports SET status = 'done', file_path = $2, size_bytes = $3, message_count = $4, finished_at = $5, expires_at = $6
+		 WHERE id = $1`,
+		e.ID, e.FilePath, e.SizeBytes, e.MessageCount, e.FinishedAt, e.ExpiresAt)
+	return err
+}
+
+func (r *ExportRepository) Fail(ctx context.Context, id int64, reason string) error {
+	_, err := r.database.ExecContext(ctx,
+		"UPDATE exports SET status = 'failed', error = $2, finished_at = $3 WHERE id = $1",
+		id, reason, time.Now().UTC())
+	return err
+}
+
+// ExpireFinished marks finished exports past their expiry as expired and
+// returns the archive paths to delete.
+func (r *ExportRepository) ExpireFinished(ctx context.Context, now time.Time) ([]string, error) {
+	query := `WITH due AS (
+				SELECT id, file_path FROM exports
+				WHERE status = 'done' AND expires_at <= $1
+				FOR UPDATE SKIP LOCKED
+			  )
+			  UPDATE exports e SET status = 'expired', file_path = NULL
+			  FROM due
+			  WHERE e.id = due.id
+			  RETURNING due.file_path`
+	rows, err := r.database.QueryContext(ctx, query, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var paths []string
+	for rows.Next() {
+		var path *string
+		if err := rows.Scan(&path); err != nil {
+			return nil, err
+		}
+		if path != nil {
+			paths = append(paths, *path)
+		}
+	}
+	return paths, rows.Err()
+}
+
+// StreamMessages calls fn for each message of the export, oldest first,
+// with its edit history and reaction counts. Room exports cover the whole
+// room, conversation exports the direct messages between the requester and
+// the peer, and account exports every message the requester sent or
+// received directly. Expired messages are left out.
+func (r *ExportRepository) StreamMessages(ctx context.Context, e *Export, fn func(*ExportedMessage) error) error {
+	// Timestamps are converted to timestamptz so the JSON carries a zone.
+	query := `SELECT m.id, m.sender_id, COALESCE(m.display_name, u.username, 'deleted user'), m.room_id, m.receiver_id, m.parent_id,
+				m.content, m.created_at,
+				CASE WHEN m.updated_at > m.created_at + INTERVAL '1 second' THEN m.updated_at END,
+				EXISTS (SELECT 1 FROM pinned_messages pm WHERE pm.message_id = m.id),
+				(SELECT json_agg(json_build_object('content', me.content, 'edited_at', me.edited_at AT TIME ZONE 'UTC')
+					ORDER BY me.edited_at, me.id)
+				 FROM message_edits me WHERE me.message_id = m.id),
+				(SELECT json_agg(mr ORDER BY mr.emoji)
+				 FROM (SELECT emoji, COUNT(*) AS count FROM message_reactions
+					   WHERE message_id = m.id GROUP BY emoji) mr)
+			  FROM messages m
+			  LEFT JOIN users u ON u.id = m.sender_id
+			  WHERE (m.expires_at IS NULL OR m.expires_at > (NOW() AT TIME ZONE 'UTC'))
+			  AND CASE
+				WHEN $1::int IS NOT NULL THEN m.room_id = $1
+				WHEN $3::int IS NOT NULL THEN m.room_id IS NULL
+					AND ((m.sender_id = $2 AND m.receiver_id = $3) OR (m.sender_id = $3 AND m.receiver_id = $2))
+				ELSE m.sender_id = $2 OR m.receiver_id = $2
+			  END
+			  ORDER BY m.created_at, m.id`
+	rows, err := r.database.QueryContext(ctx, query, e.RoomID, e.UserID, e.PeerID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var msg ExportedMessage
+		var edits, reactions []byte
+		err := rows.Scan(&msg.ID, &msg.SenderID, &msg.SenderName, &msg.RoomID, &msg.ReceiverID, &msg.ParentID,
+			&msg.Content, &msg.CreatedAt, &msg.EditedAt, &msg.Pinned, &edits, &reactions)
+		if err != nil {
+			return err
+		}
+		if edits != nil {
+			if err := json.Unmarshal(edits, &msg.Edits); err != nil {
+				return err
+			}
+		}
+		if reactions != nil {
+			if err := json.Unmarshal(reactions, &msg.Reactions); err != nil {
+				return err
+			}
+		}
+		if err := fn(&msg); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// AccountProfile returns nil if the user doesn't exist or was deleted.
+func (r *ExportRepository) AccountProfile(ctx context.Context, userID int64) (*AccountProfile, error) {
+	var p AccountProfile
+	err := r.database.QueryRowContext(ctx,
+		"SELECT id, username, email, created_at FROM users WHERE id = $1 AND deleted_at IS NULL", userID).
+		Scan(&p.ID, &p.Username, &p.Email, &p.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	rows, err := r.database.QueryContext(ctx,
+		"SELECT provider, email, created_at FROM user_identities WHERE user_id = $1 ORDER BY id", userID)
+	if err != nil {
+		return nil, err
+	}
+	p.Identities = []*AccountIdentity{}
+	for rows.Next() {
+		var identity AccountIdentity
+		if err := rows.Scan(&identity.Provider, &identity.Email, &identity.CreatedAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		p.Identities = append(p.Identities, &identity)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = r.database.QueryContext(ctx,
+		`SELECT m.room_id, r.name, m.role, m.joined_at
+		 FROM room_members m JOIN rooms r ON r.id = m.room_id
+		 WHERE m.user_id = $1 ORDER BY m.joined_at`, userID)
+	if err != nil {
+		return nil, err
+	}
+	p.Rooms = []*AccountMembership{}
+	for rows.Next() {
+		var membership AccountMembership
+		if err := rows.Scan(&membership.RoomID, &membership.RoomName, &membership.Role, &membership.JoinedAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		p.Rooms = append(p.Rooms, &membership)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = r.database.QueryContext(ctx,
+		"SELECT id, username, created_at FROM users WHERE is_bot AND owner_id = $1 ORDER BY id", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	p.Bots = []*AccountBot{}
+	for rows.Next() {
+		var bot AccountBot
+		if err := rows.Scan(&bot.ID, &bot.Username, &bot.CreatedAt); err != nil {
+			return nil, err
+		}
+		p.Bots = append(p.Bots, &bot)
+	}
+	return &p, rows.Err()
+}
@@ -0,0 +1,171 @@
+package export
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/maxwellzp/golang-chat-api/internal/apperr"
+	"github.com/maxwellzp/golang-chat-api/internal/config"
+	"github.com/maxwellzp/golang-chat-api/internal/room"
+	"github.com/maxwellzp/golang-chat-api/internal/user"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrExportNotFound      = apperr.NotFound("export_not_found", "export not found")
+	ErrPeerNotFound        = apperr.NotFound("user_not_found", "user not found")
+	ErrExportNotReady      = apperr.Conflict("export_not_ready", "export is not ready for download")
+	ErrInvalidDownloadLink = apperr.Forbidden("invalid_download_link", "download link is invalid or has expired")
+)
+
+type ExportService struct {
+	exportRepository *ExportRepository
+	roomService      *room.RoomService
+	userRepository   *user.UserRepository
+	cfg              config.ExportConfig
+	baseURL          string
+	secret           []byte
+}
+
+// NewExportService signs download links with a key derived from secret.
+func NewExportService(exportRepository *ExportRepository, roomService *room.RoomService, userRepository *user.UserRepository, cfg config.ExportConfig, baseURL string, secret string) *ExportService {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("export-download-links"))
+	return &ExportService{
+		exportRepository: exportRepository,
+		roomService:      roomService,
+		userRepository:   userRepository,
+		cfg:              cfg,
+		baseURL:          baseURL,
+		secret:           mac.Sum(nil),
+	}
+}
+
+// ExportRoom queues an export of the room. Only members can export it, and
+// they must still be members when the export runs.
+func (s *ExportService) ExportRoom(ctx context.Context, userID int64, roomID int64, req CreateExportRequest) (*Export, error) {
+	if err := s.roomService.RequireRole(ctx, roomID, userID, room.RoleMember); err != nil {
+		return nil, err
+	}
+	e := &Export{UserID: userID, RoomID: &roomID, Format: req.Format}
+	if err := s.exportRepository.Create(ctx, e); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// ExportConversation queues an export of the user's direct messages with
+// peerID.
+func (s *ExportService) ExportConversation(ctx context.Context, userID int64, peerID int64, req CreateExportRequest) (*Export, error) {
+	peer, err := s.userRepository.FindByID(ctx, peerID)
+	if err != nil {
+		return nil, err
+	}
+	if peer == nil {
+		return nil, ErrPeerNotFound
+	}
+	e := &Export{UserID: userID, PeerID: &peerID, Format: req.Format}
+	if err := s.exportRepository.Create(ctx, e); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// ExportAccount queues an export of everything the user has stored: their
+// profile, linked identities, room memberships, bots and every message they
+// sent or received directly.
+func (s *ExportService) ExportAccount(ctx context.Context, userID int64, req CreateExportRequest) (*Export, error) {
+	e := &Export{UserID: userID, Format: req.Format}
+	if err := s.exportRepository.Create(ctx, e); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Get returns one of the user's exports, with a fresh download link when it
+// is done.
+func (s *ExportService) Get(ctx context.Context, userID int64, id int64) (*Export, error) {
+	e, err := s.exportRepository.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if e == nil || e.UserID != userID {
+		return nil, ErrExportNotFound
+	}
+	s.attachLink(e)
+	return e, nil
+}
+
+// List returns the user's exports, newest first.
+func (s *ExportService) List(ctx context.Context, userID int64, limit int, offset int) ([]*Export, error) {
+	exports, err := s.exportRepository.ListByUser(ctx, userID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range exports {
+		s.attachLink(e)
+	}
+	return exports, nil
+}
+
+// Open checks a download link and opens the archive. The caller closes the
+// file.
+func (s *ExportService) Open(ctx context.Context, id int64, expires string, signature string) (*Export, *os.File, error) {
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > unix || !hmac.Equal([]byte(signature), []byte(s.sign(id, unix))) {
+		return nil, nil, ErrInvalidDownloadLink
+	}
+	e, err := s.exportRepository.GetByID(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if e == nil {
+		return nil, nil, ErrExportNotFound
+	}
+	if e.Status != StatusDone || e.FilePath == nil {
+		return nil, nil, ErrExportNotReady
+	}
+	f, err := os.Open(*e.FilePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	return e, f, nil
+}
+
+// FileName is the name the archive is downloaded as.
+func FileName(e *Export) string {
+	switch {
+	case e.RoomID != nil:
+		return fmt.Sprintf("room-%d-export-%d.zip", *e.RoomID, e.ID)
+	case e.PeerID != nil:
+		return fmt.Sprintf("conversation-%d-export-%d.zip", *e.PeerID, e.ID)
+	}
+	return fmt.Sprintf("account-%d-export-%d.zip", e.UserID, e.ID)
+}
+
+func (s *ExportService) attachLink(e *Export) {
+	if e.Status != StatusDone {
+		return
+	}
+	expiresAt := time.Now().UTC().Add(s.cfg.LinkTTL).Truncate(time.Second)
+	if e.ExpiresAt != nil && e.ExpiresAt.Before(expiresAt) {
+		expiresAt = *e.ExpiresAt
+	}
+	query := url.Values{
+		"expires":   {strconv.FormatInt(expiresAt.Unix(), 10)},
+		"signature": {s.sign(e.ID, expiresAt.Unix())},
+	}
+	e.DownloadURL = fmt.Sprintf("%s/exports/%d/download?%s", s.baseURL, e.ID, query.Encode())
+	e.DownloadExpiresAt = &expiresAt
+}
+
+func (s *ExportService) sign(id int64, expires int64) string {
+	mac := hmac.New(sha256.New, s.secret)
+	fmt.Fprintf(mac, "%d:%d", id, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
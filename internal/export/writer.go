@@ -0,0 +1,259 @@
+package export
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// messageWriter streams messages into one archive entry.
+type messageWriter interface {
+	Write(msg *ExportedMessage) error
+	// Close finishes the entry but not the underlying writer.
+	Close() error
+}
+
+// writeArchive writes the messages produced by stream into a zip with a
+// messages.<format> entry and a manifest.json, and returns how many
+// messages it wrote. Each of extra is written as another JSON entry.
+func writeArchive(w io.Writer, manifest *Manifest, extra map[string]any, stream func(fn func(*ExportedMessage) error) error) (int, error) {
+	zw := zip.NewWriter(w)
+	for name, value := range extra {
+		if err := writeJSONEntry(zw, name, value); err != nil {
+			return 0, err
+		}
+	}
+
+	entry, err := zw.Create("messages." + manifest.Format)
+	if err != nil {
+		return 0, err
+	}
+	var mw messageWriter
+	switch manifest.Format {
+	case FormatCSV:
+		mw, err = newCSVWriter(entry)
+	case FormatHTML:
+		mw, err = newHTMLWriter(entry, manifest)
+	default:
+		mw, err = newJSONWriter(entry)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	err = stream(func(msg *ExportedMessage) error {
+		count++
+		return mw.Write(msg)
+	})
+	if err != nil {
+		return 0, err
+	}
+	if err := mw.Close(); err != nil {
+		return 0, err
+	}
+
+	manifest.MessageCount = count
+	if err := writeJSONEntry(zw, "manifest.json", manifest); err != nil {
+		return 0, err
+	}
+	return count, zw.Close()
+}
+
+func writeJSONEntry(zw *zip.Writer, name string, value any) error {
+	entry, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(entry)
+	enc.SetIndent("", "  ")
+	return enc.Encode(value)
+}
+
+// jsonWriter writes a JSON array one element at a time.
+type jsonWriter struct {
+	w     io.Writer
+	first bool
+}
+
+func newJSONWriter(w io.Writer) (*jsonWriter, error) {
+	if _, err := io.WriteString(w, "["); err != nil {
+		return nil, err
+	}
+	return &jsonWriter{w: w, first: true}, nil
+}
+
+func (jw *jsonWriter) Write(msg *ExportedMessage) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	sep := ",\n  "
+	if jw.first {
+		sep, jw.first = "\n  ", false
+	}
+	if _, err := io.WriteString(jw.w, sep); err != nil {
+		return err
+	}
+	_, err = jw.w.Write(data)
+	return err
+}
+
+func (jw *jsonWriter) Close() error {
+	_, err := io.WriteString(jw.w, "\n]\n")
+	return err
+}
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func newCSVWriter(w io.Writer) (*csvWriter, error) {
+	cw := csv.NewWriter(w)
+	err := cw.Write([]string{"id", "sender_id", "sender_name", "room_id", "receiver_id", "parent_id", "content", "created_at", "edited_at", "pinned",
+		"edits", "reactions"})
+	return &csvWriter{w: cw}, err
+}
+
+// Write puts a message on one row. Edits and reactions don't fit a flat
+// row, so they are written as JSON arrays.
+func (cw *csvWriter) Write(msg *ExportedMessage) error {
+	roomID, receiverID, parentID, editedAt, edits, reactions := "", "", "", "", "", ""
+	if msg.RoomID != nil {
+		roomID = strconv.FormatInt(*msg.RoomID, 10)
+	}
+	if msg.ReceiverID != nil {
+		receiverID = strconv.FormatInt(*msg.ReceiverID, 10)
+	}
+	if msg.ParentID != nil {
+		parentID = strconv.FormatInt(*msg.ParentID, 10)
+	}
+	if msg.EditedAt != nil {
+		editedAt = msg.EditedAt.Format(time.RFC3339)
+	}
+	if len(msg.Edits) > 0 {
+		data, err := json.Marshal(msg.Edits)
+		if err != nil {
+			return err
+		}
+		edits = string(data)
+	}
+	if len(msg.Reactions) > 0 {
+		data, err := json.Marshal(msg.Reactions)
+		if err != nil {
+			return err
+		}
+		reactions = string(data)
+	}
+	return cw.w.Write([]string{
+		strconv.FormatInt(msg.ID, 10),
+		strconv.FormatInt(msg.SenderID, 10),
+		msg.SenderName,
+		roomID,
+		receiverID,
+		parentID,
+		msg.Content,
+		msg.CreatedAt.Format(time.RFC3339),
+		editedAt,
+		strconv.FormatBool(msg.Pinned),
+		edits,
+		reactions,
+	})
+}
+
+func (cw *csvWriter) Close() error {
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+// htmlWriter writes a self-contained page: styles are inline and nothing is
+// loaded from elsewhere, so the archive can be opened offline.
+type htmlWriter struct {
+	w io.Writer
+}
+
+const htmlHead = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>%s</title>
+<style>
+body { font-family: system-ui, sans-serif; max-width: 860px; margin: 2em auto; padding: 0 1em; color: #1d1c1d; }
+header { border-bottom: 1px solid #ddd; margin-bottom: 1em; }
+.message { padding: .5em 0; border-bottom: 1px solid #f0f0f0; }
+.author { font-weight: 600; }
+.meta { color: #616061; font-size: .85em; margin-left: .5em; }
+.content { white-space: pre-wrap; word-wrap: break-word; margin-top: .25em; }
+.pinned { border-left: 3px solid #e8912d; padding-left: .5em; }
+.edits { color: #616061; font-size: .85em; margin-top: .25em; }
+.edits .content { margin: .25em 0 .25em 1em; }
+.reactions { font-size: .85em; margin-top: .25em; }
+</style>
+</head>
+<body>
+<header><h1>%s</h1><p class="meta">Exported %s</p></header>
+<main>
+`
+
+func newHTMLWriter(w io.Writer, manifest *Manifest) (*htmlWriter, error) {
+	title := "Conversation export"
+	switch {
+	case manifest.RoomName != "":
+		title = "#" + manifest.RoomName
+	case manifest.PeerID == nil:
+		title = "Account export"
+	}
+	title = html.EscapeString(title)
+	_, err := fmt.Fprintf(w, htmlHead, title, title, manifest.ExportedAt.Format(time.RFC1123))
+	return &htmlWriter{w: w}, err
+}
+
+func (hw *htmlWriter) Write(msg *ExportedMessage) error {
+	class := "message"
+	if msg.Pinned {
+		class += " pinned"
+	}
+	meta := msg.CreatedAt.Format("2006-01-02 15:04:05 MST")
+	if msg.EditedAt != nil {
+		meta += " (edited)"
+	}
+	if msg.ParentID != nil {
+		meta += fmt.Sprintf(" <a href=\"#m%d\">in reply</a>", *msg.ParentID)
+	}
+
+	var extra strings.Builder
+	if len(msg.Edits) > 0 {
+		fmt.Fprintf(&extra, "<details class=\"edits\"><summary>%d earlier version(s)</summary>", len(msg.Edits))
+		for _, edit := range msg.Edits {
+			fmt.Fprintf(&extra, "<div>Until %s</div><div class=\"content\">%s</div>",
+				edit.EditedAt.Format("2006-01-02 15:04:05 MST"), html.EscapeString(edit.Content))
+		}
+		extra.WriteString("</details>")
+	}
+	if len(msg.Reactions) > 0 {
+		extra.WriteString("<div class=\"reactions\">")
+		for i, r := range msg.Reactions {
+			if i > 0 {
+				extra.WriteString(" ")
+			}
+			fmt.Fprintf(&extra, "%s %d", html.EscapeString(r.Emoji), r.Count)
+		}
+		extra.WriteString("</div>")
+	}
+
+	_, err := fmt.Fprintf(hw.w,
+		"<div class=\"%s\" id=\"m%d\"><span class=\"author\">%s</span><span class=\"meta\">%s</span><div class=\"content\">%s</div>%s</div>\n",
+		class, msg.ID, html.EscapeString(msg.SenderName), meta, html.EscapeString(msg.Content), extra.String())
+	return err
+}
+
+func (hw *htmlWriter) Close() error {
+	_, err := io.WriteString(hw.w, "</main>\n</body>\n</html>\n")
+	return err
+}
@@ -3,6 +3,7 @@ package httpx
 import (
 	"context"
 	"errors"
+	"github.com/go-chi/chi/v5/middleware"
 	"github.com/maxwellzp/golang-chat-api/internal/contextkey"
 )
 
@@ -14,3 +15,36 @@ func GetUserID(ctx context.Context) (int64, error) {
 	}
 	return id, nil
 }
+
+// GetScopes returns the API key scopes of the request. ok is false for JWT
+// sessions, which carry no scope restrictions.
+func GetScopes(ctx context.Context) (scopes []string, ok bool) {
+	scopes, ok = ctx.Value(contextkey.Scopes).([]string)
+	return scopes, ok
+}
+
+// GetImpersonatorID returns the admin acting as the user. ok is false unless
+// the request was made with an impersonation token.
+func GetImpersonatorID(ctx context.Context) (id int64, ok bool) {
+	id, ok = ctx.Value(contextkey.ImpersonatorID).(int64)
+	return id, ok
+}
+
+// GetClientIP returns the IP the request came from, or "" outside a request.
+func GetClientIP(ctx context.Context) string {
+	ip, _ := ctx.Value(contextkey.ClientIP).(string)
+	return ip
+}
+
+// GetUserAgent returns the User-Agent of the request, or "" outside a
+// request.
+func GetUserAgent(ctx context.Context) string {
+	ua, _ := ctx.Value(contextkey.UserAgent).(string)
+	return ua
+}
+
+// GetRequestID returns the ID middleware.RequestID gave the request, or ""
+// outside a request.
+func GetRequestID(ctx context.Context) string {
+	return middleware.GetReqID(ctx)
+}
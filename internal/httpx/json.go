@@ -15,6 +15,8 @@ func WriteJSON(w http.ResponseWriter, status int, data any) {
 	_ = json.NewEncoder(w).Encode(data)
 }
 
+// WriteError writes a problem with a code derived from the status, for
+// errors that don't come from a domain error.
 func WriteError(w http.ResponseWriter, status int, message string) {
-	WriteJSON(w, status, map[string]string{"error": message})
+	WriteProblem(w, Problem{Status: status, Detail: message})
 }
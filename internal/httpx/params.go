@@ -10,3 +10,25 @@ func ParseInt64Param(r *http.Request, name string) (int64, error) {
 	raw := chi.URLParam(r, name)
 	return strconv.ParseInt(raw, 10, 64)
 }
+
+// ParsePagination reads ?limit= and ?offset=. A missing limit defaults to
+// defaultLimit and larger limits are capped at maxLimit.
+func ParsePagination(r *http.Request, defaultLimit int, maxLimit int) (limit int, offset int, err error) {
+	limit = defaultLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		limit, err = strconv.Atoi(raw)
+		if err != nil || limit < 1 {
+			return 0, 0, ValidationErrorMap{"limit": "limit must be a positive integer"}
+		}
+	}
+	if limit > maxLimit {
+		limit = maxLimit
+	}
+	if raw := r.URL.Query().Get("offset"); raw != "" {
+		offset, err = strconv.Atoi(raw)
+		if err != nil || offset < 0 {
+			return 0, 0, ValidationErrorMap{"offset": "offset must be a non-negative integer"}
+		}
+	}
+	return limit, offset, nil
+}
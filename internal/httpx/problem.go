@@ -0,0 +1,109 @@
+package httpx
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/go-playground/validator/v10"
+	"github.com/maxwellzp/golang-chat-api/internal/apperr"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Problem is an RFC 7807 error body. Code is a stable identifier clients can
+// match on; Detail is for people and may change.
+type Problem struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail,omitempty"`
+	Code      string `json:"code"`
+	RequestID string `json:"request_id,omitempty"`
+	// Errors holds per-field messages of validation problems.
+	Errors map[string]string `json:"errors,omitempty"`
+	// RetryAfter repeats the Retry-After header of rate limited problems,
+	// in seconds.
+	RetryAfter int `json:"retry_after,omitempty"`
+}
+
+// statuses maps domain error kinds to status codes.
+var statuses = map[apperr.Kind]int{
+	apperr.KindNotFound:     http.StatusNotFound,
+	apperr.KindForbidden:    http.StatusForbidden,
+	apperr.KindConflict:     http.StatusConflict,
+	apperr.KindRateLimited:  http.StatusTooManyRequests,
+	apperr.KindValidation:   http.StatusUnprocessableEntity,
+	apperr.KindUnauthorized: http.StatusUnauthorized,
+}
+
+// codeValidationFailed is the code of every request rejected field by field.
+const codeValidationFailed = "validation_failed"
+
+// WriteProblem writes an application/problem+json response. The request ID
+// comes from the X-Request-Id header middleware.RequestInfo sets on every
+// response.
+func WriteProblem(w http.ResponseWriter, p Problem) {
+	if p.Type == "" {
+		p.Type = "about:blank"
+	}
+	if p.Title == "" {
+		p.Title = http.StatusText(p.Status)
+	}
+	if p.Code == "" {
+		p.Code = statusCode(p.Status)
+	}
+	p.RequestID = w.Header().Get("X-Request-Id")
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(p.Status)
+	_ = json.NewEncoder(w).Encode(p)
+}
+
+// WriteServiceError writes err as a problem if it is a domain error or a
+// validation error, and reports whether it did. Anything else is unexpected:
+// the caller logs it and answers 500.
+func WriteServiceError(w http.ResponseWriter, err error) bool {
+	var invalid ValidationErrorMap
+	if errors.As(err, &invalid) {
+		WriteValidationError(w, invalid)
+		return true
+	}
+	e, ok := apperr.As(err)
+	if !ok {
+		return false
+	}
+	status, ok := statuses[e.Kind]
+	if !ok {
+		return false
+	}
+	p := Problem{Status: status, Detail: e.Message, Code: e.Code}
+	if e.RetryAfter > 0 {
+		SetRetryAfter(w, e.RetryAfter)
+		p.RetryAfter, _ = strconv.Atoi(w.Header().Get("Retry-After"))
+	}
+	WriteProblem(w, p)
+	return true
+}
+
+// statusCode is the code of problems written without one: the status text
+// in snake case, e.g. "not_found".
+func statusCode(status int) string {
+	text := strings.ToLower(http.StatusText(status))
+	if text == "" {
+		return "error"
+	}
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, text)
+}
+
+func validationErrors(err validator.ValidationErrors) map[string]string {
+	errorMap := make(map[string]string)
+	for _, ve := range err {
+		field := toSnakeCase(ve.Field())
+		errorMap[field] = validationMessage(ve.Tag(), field, ve.Param())
+	}
+	return errorMap
+}
@@ -16,20 +16,18 @@ func (v ValidationErrorMap) Error() string {
 func WriteValidationError(w http.ResponseWriter, err error) {
 	switch e := err.(type) {
 	case validator.ValidationErrors:
-		errorMap := make(map[string]string)
-		for _, ve := range e {
-			field := toSnakeCase(ve.Field())
-			message := validationMessage(ve.Tag(), field, ve.Param())
-			errorMap[field] = message
-		}
-		WriteJSON(w, http.StatusUnprocessableEntity, map[string]any{
-			"message": "Validation failed",
-			"errors":  errorMap,
+		WriteProblem(w, Problem{
+			Status: http.StatusUnprocessableEntity,
+			Detail: "Validation failed",
+			Code:   codeValidationFailed,
+			Errors: validationErrors(e),
 		})
 	case ValidationErrorMap:
-		WriteJSON(w, http.StatusUnprocessableEntity, map[string]any{
-			"message": "Validation failed",
-			"errors":  e,
+		WriteProblem(w, Problem{
+			Status: http.StatusUnprocessableEntity,
+			Detail: "Validation failed",
+			Code:   codeValidationFailed,
+			Errors: e,
 		})
 	default:
 		WriteError(w, http.StatusBadRequest, "Invalid input")
@@ -0,0 +1,47 @@
+package httpx
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// SSEWriter writes a text/event-stream response, flushing after each event.
+type SSEWriter struct {
+	w  http.ResponseWriter
+	rc *http.ResponseController
+}
+
+// NewSSEWriter sends the stream headers. It fails if the response writer
+// can't flush.
+func NewSSEWriter(w http.ResponseWriter) (*SSEWriter, error) {
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		return nil, err
+	}
+	return &SSEWriter{w: w, rc: rc}, nil
+}
+
+// Event writes one event with data encoded as JSON.
+func (s *SSEWriter) Event(name string, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", name, payload); err != nil {
+		return err
+	}
+	return s.rc.Flush()
+}
+
+// Ping writes a comment line so proxies don't close an idle stream.
+func (s *SSEWriter) Ping() error {
+	if _, err := fmt.Fprint(s.w, ": ping\n\n"); err != nil {
+		return err
+	}
+	return s.rc.Flush()
+}
@@ -0,0 +1,175 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+type discordUser struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	IsBot bool   `json:"isBot"`
+}
+
+// discordExport is one channel as written by DiscordChatExporter in JSON
+// mode.
+type discordExport struct {
+	Channel struct {
+		ID    string `json:"id"`
+		Type  string `json:"type"`
+		Name  string `json:"name"`
+		Topic string `json:"topic"`
+	} `json:"channel"`
+	Messages []struct {
+		ID              string        `json:"id"`
+		Type            string        `json:"type"`
+		Timestamp       time.Time     `json:"timestamp"`
+		TimestampEdited *time.Time    `json:"timestampEdited"`
+		Content         string        `json:"content"`
+		Author          discordUser   `json:"author"`
+		Mentions        []discordUser `json:"mentions"`
+		Reference       *struct {
+			MessageID string `json:"messageId"`
+		} `json:"reference"`
+		Reactions []struct {
+			Emoji struct {
+				Name string `json:"name"`
+			} `json:"emoji"`
+			Count int           `json:"count"`
+			Users []discordUser `json:"users"`
+		} `json:"reactions"`
+	} `json:"messages"`
+}
+
+// OpenDiscord reads a DiscordChatExporter JSON export: either one channel
+// file or a directory of them. Every channel becomes a room; direct and
+// group chats become private rooms, since Discord exports don't list a
+// conversation's members.
+func OpenDiscord(name string) (*Archive, error) {
+	files := []string{name}
+	if info, err := os.Stat(name); err != nil {
+		return nil, err
+	} else if info.IsDir() {
+		if files, err = filepath.Glob(filepath.Join(name, "*.json")); err != nil {
+			return nil, err
+		}
+		sort.Strings(files)
+	}
+
+	a := &Archive{Source: SourceDiscord, Text: discordText}
+	seen := map[string]bool{}
+	addUser := func(u discordUser) {
+		if u.ID == "" || seen[u.ID] {
+			return
+		}
+		seen[u.ID] = true
+		a.Users = append(a.Users, &User{ExternalID: u.ID, Name: u.Name, IsBot: u.IsBot})
+	}
+
+	// The users of a channel are only known from its messages, so each file
+	// is read in full here.
+	for _, file := range files {
+		export, err := readDiscordFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		var messages []*Message
+		members := map[string]bool{}
+		var memberIDs []string
+		for _, m := range export.Messages {
+			if m.Type != "Default" && m.Type != "Reply" {
+				continue
+			}
+			addUser(m.Author)
+			for _, u := range m.Mentions {
+				addUser(u)
+			}
+			msg := &Message{
+				ExternalID: m.ID,
+				UserID:     m.Author.ID,
+				Text:       m.Content,
+				CreatedAt:  m.Timestamp.UTC(),
+			}
+			if m.Reference != nil {
+				msg.ParentID = m.Reference.MessageID
+			}
+			if m.TimestampEdited != nil {
+				editedAt := m.TimestampEdited.UTC()
+				msg.EditedAt = &editedAt
+			}
+			for _, r := range m.Reactions {
+				reaction := &Reaction{Emoji: r.Emoji.Name, Count: r.Count}
+				for _, u := range r.Users {
+					addUser(u)
+					reaction.UserIDs = append(reaction.UserIDs, u.ID)
+				}
+				msg.Reactions = append(msg.Reactions, reaction)
+			}
+			messages = append(messages, msg)
+			if !members[m.Author.ID] {
+				members[m.Author.ID] = true
+				memberIDs = append(memberIDs, m.Author.ID)
+			}
+		}
+		sort.SliceStable(messages, func(i, j int) bool {
+			return messages[i].CreatedAt.Before(messages[j].CreatedAt)
+		})
+
+		channel := &Channel{
+			ExternalID: export.Channel.ID,
+			Name:       export.Channel.Name,
+			Topic:      export.Channel.Topic,
+			IsPrivate:  strings.HasPrefix(export.Channel.Type, "Direct"),
+			MemberIDs:  memberIDs,
+			Messages: func() ([]*Message, error) {
+				return messages, nil
+			},
+		}
+		if len(messages) > 0 {
+			channel.CreatedAt = messages[0].CreatedAt
+		}
+		a.Channels = append(a.Channels, channel)
+	}
+	return a, nil
+}
+
+func readDiscordFile(name string) (*discordExport, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var export discordExport
+	if err := json.NewDecoder(f).Decode(&export); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	if export.Channel.ID == "" {
+		return nil, fmt.Errorf("%s: not a DiscordChatExporter JSON export", name)
+	}
+	return &export, nil
+}
+
+// discordEntity matches user mentions (<@123>, <@!123>) and custom emoji
+// (<:name:123>, <a:name:123>).
+var discordEntity = regexp.MustCompile(`<@!?(\d+)>|<a?:(\w+):\d+>`)
+
+func discordText(raw string, username func(externalID string) string) string {
+	return discordEntity.ReplaceAllStringFunc(raw, func(entity string) string {
+		m := discordEntity.FindStringSubmatch(entity)
+		if m[2] != "" {
+			return ":" + m[2] + ":"
+		}
+		if name := username(m[1]); name != "" {
+			return "@" + name
+		}
+		return entity
+	})
+}
@@ -0,0 +1,326 @@
+package importer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Importer writes an archive into the database. Every user, room and
+// message is recorded in import_mappings, so running the same import again
+// only adds what is new.
+type Importer struct {
+	importRepository *ImportRepository
+	batchSize        int
+	dryRun           bool
+	progress         io.Writer
+}
+
+// NewImporter returns an importer that inserts messages batchSize at a time
+// and reports progress to progress. With dryRun set it only reads the
+// database and reports what it would do.
+func NewImporter(importRepository *ImportRepository, batchSize int, dryRun bool, progress io.Writer) *Importer {
+	return &Importer{
+		importRepository: importRepository,
+		batchSize:        batchSize,
+		dryRun:           dryRun,
+		progress:         progress,
+	}
+}
+
+// importedUser is where an external user ended up.
+type importedUser struct {
+	// ID is 0 for users a dry run would create.
+	ID       int64
+	Username string
+}
+
+func (imp *Importer) Run(ctx context.Context, a *Archive) (*Report, error) {
+	report := &Report{}
+	users, err := imp.importUsers(ctx, a, report)
+	if err != nil {
+		return nil, err
+	}
+	username := func(externalID string) string {
+		if u := users[externalID]; u != nil {
+			return u.Username
+		}
+		return ""
+	}
+
+	for i, c := range a.Channels {
+		label := "#" + c.Name
+		if c.Direct {
+			label = "direct messages " + c.ExternalID
+		}
+		fmt.Fprintf(imp.progress, "[%d/%d] %s\n", i+1, len(a.Channels), label)
+		if err := imp.importChannel(ctx, a, c, users, username, report); err != nil {
+			return nil, fmt.Errorf("%s: %w", label, err)
+		}
+	}
+	return report, nil
+}
+
+// importUsers maps every user of the archive to an account: the one it was
+// imported as before, an existing account with the same email, or a new
+// placeholder account nobody can sign in to until a password reset.
+func (imp *Importer) importUsers(ctx context.Context, a *Archive, report *Report) (map[string]*importedUser, error) {
+	externalIDs := make([]string, len(a.Users))
+	for i, u := range a.Users {
+		externalIDs[i] = u.ExternalID
+	}
+	mapped, err := imp.importRepository.Mapped(ctx, a.Source, kindUser, externalIDs)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]int64, 0, len(mapped))
+	for _, id := range mapped {
+		ids = append(ids, id)
+	}
+	usernames, err := imp.importRepository.Usernames(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	users := map[string]*importedUser{}
+	for _, u := range a.Users {
+		if id, ok := mapped[u.ExternalID]; ok {
+			users[u.ExternalID] = &importedUser{ID: id, Username: usernames[id]}
+			report.UsersExisting++
+			continue
+		}
+
+		if u.Email != "" {
+			id, name, err := imp.importRepository.UserByEmail(ctx, u.Email)
+			if err != nil {
+				return nil, err
+			}
+			if id != 0 {
+				if !imp.dryRun {
+					if err := imp.importRepository.Map(ctx, a.Source, kindUser, u.ExternalID, id); err != nil {
+						return nil, err
+					}
+				}
+				users[u.ExternalID] = &importedUser{ID: id, Username: name}
+				report.UsersMatched++
+				continue
+			}
+		}
+
+		name := usernameFor(u)
+		email := u.Email
+		if email == "" {
+			email = strings.ToLower(a.Source + "-" + u.ExternalID + "@import.invalid")
+		}
+		var id int64
+		if !imp.dryRun {
+			if id, name, err = imp.importRepository.CreateUser(ctx, a.Source, u, name, email); err != nil {
+				return nil, err
+			}
+		}
+		users[u.ExternalID] = &importedUser{ID: id, Username: name}
+		report.UsersCreated++
+	}
+	fmt.Fprintf(imp.progress, "users: %d created, %d matched by email, %d already imported\n",
+		report.UsersCreated, report.UsersMatched, report.UsersExisting)
+	return users, nil
+}
+
+func (imp *Importer) importChannel(ctx context.Context, a *Archive, c *Channel, users map[string]*importedUser, username func(string) string, report *Report) error {
+	// Direct messages go from the author to the other member. Anything but
+	// a pair of known users is imported as a private room instead.
+	var roomID *int64
+	var pair [2]*importedUser
+	direct := c.Direct && len(c.MemberIDs) == 2 && users[c.MemberIDs[0]] != nil && users[c.MemberIDs[1]] != nil
+	if direct {
+		pair = [2]*importedUser{users[c.MemberIDs[0]], users[c.MemberIDs[1]]}
+	} else {
+		id, err := imp.importRoom(ctx, a, c, users, report)
+		if err != nil {
+			return err
+		}
+		roomID = &id
+	}
+
+	messages, err := c.Messages()
+	if err != nil {
+		return err
+	}
+	// localIDs maps the channel's messages to their local IDs as they are
+	// found to be imported or get inserted, so replies can point at their
+	// parent. Parents come before their replies in timestamp order. In a
+	// dry run nothing gets an ID, so the map only records what is known.
+	localIDs := map[string]int64{}
+	pending := map[string]*importedMessage{}
+	for start := 0; start < len(messages); start += imp.batchSize {
+		batch := messages[start:min(start+imp.batchSize, len(messages))]
+		externalIDs := make([]string, len(batch))
+		for i, m := range batch {
+			externalIDs[i] = m.ExternalID
+		}
+		mapped, err := imp.importRepository.Mapped(ctx, a.Source, kindMessage, externalIDs)
+		if err != nil {
+			return err
+		}
+
+		var rows []*importedMessage
+		for _, m := range batch {
+			if id, ok := mapped[m.ExternalID]; ok {
+				localIDs[m.ExternalID] = id
+				report.MessagesExisting++
+				continue
+			}
+			sender := users[m.UserID]
+			content := strings.TrimSpace(a.Text(m.Text, username))
+			if sender == nil || content == "" {
+				report.MessagesSkipped++
+				continue
+			}
+			row := &importedMessage{
+				ExternalID: m.ExternalID,
+				SenderID:   sender.ID,
+				RoomID:     roomID,
+				Content:    content,
+				CreatedAt:  m.CreatedAt,
+				UpdatedAt:  m.CreatedAt,
+			}
+			if m.EditedAt != nil && m.EditedAt.After(m.CreatedAt) {
+				row.UpdatedAt = *m.EditedAt
+			}
+			if direct {
+				switch sender {
+				case pair[0]:
+					row.ReceiverID = &pair[1].ID
+				case pair[1]:
+					row.ReceiverID = &pair[0].ID
+				default:
+					report.MessagesSkipped++
+					continue
+				}
+			}
+			if m.ParentID != "" {
+				if id, ok := localIDs[m.ParentID]; ok {
+					row.ParentID = &id
+					report.RepliesLinked++
+				} else if parent, ok := pending[m.ParentID]; ok {
+					row.Parent = parent
+					report.RepliesLinked++
+				} else {
+					report.RepliesOrphaned++
+				}
+			}
+			for _, r := range m.Reactions {
+				stored := 0
+				for _, externalID := range r.UserIDs {
+					if u := users[externalID]; u != nil {
+						row.Reactions = append(row.Reactions, importedReaction{UserID: u.ID, Emoji: r.Emoji})
+						stored++
+					}
+				}
+				report.ReactionsImported += stored
+				report.ReactionsSkipped += max(r.Count, len(r.UserIDs)) - stored
+			}
+			rows = append(rows, row)
+			pending[m.ExternalID] = row
+		}
+
+		if len(rows) > 0 && !imp.dryRun {
+			if err := imp.importRepository.InsertMessages(ctx, a.Source, rows); err != nil {
+				return err
+			}
+			for _, row := range rows {
+				localIDs[row.ExternalID] = row.ID
+				delete(pending, row.ExternalID)
+			}
+		}
+		report.MessagesImported += len(rows)
+		fmt.Fprintf(imp.progress, "  %d/%d messages\n", start+len(batch), len(messages))
+	}
+	return nil
+}
+
+// importRoom finds or creates the channel's room and adds the channel's
+// members to it. A dry run returns 0 for a room it would create.
+func (imp *Importer) importRoom(ctx context.Context, a *Archive, c *Channel, users map[string]*importedUser, report *Report) (int64, error) {
+	mapped, err := imp.importRepository.Mapped(ctx, a.Source, kindRoom, []string{c.ExternalID})
+	if err != nil {
+		return 0, err
+	}
+	var ownerID *int64
+	if u := users[c.CreatorID]; u != nil {
+		ownerID = &u.ID
+	}
+
+	id, ok := mapped[c.ExternalID]
+	if ok {
+		report.RoomsExisting++
+	} else {
+		report.RoomsCreated++
+	}
+	if imp.dryRun {
+		return id, nil
+	}
+	if !ok {
+		name := roomName(c)
+		if id, err = imp.importRepository.CreateRoom(ctx, a.Source, c, name, ownerID); err != nil {
+			return 0, err
+		}
+	}
+
+	memberIDs := make([]int64, 0, len(c.MemberIDs)+1)
+	if ownerID != nil {
+		memberIDs = append(memberIDs, *ownerID)
+	}
+	for _, externalID := range c.MemberIDs {
+		if u := users[externalID]; u != nil {
+			memberIDs = append(memberIDs, u.ID)
+		}
+	}
+	return id, imp.importRepository.AddMembers(ctx, id, ownerID, memberIDs)
+}
+
+// usernameFor turns an external name into a username mentions can refer
+// to: letters, digits, underscores and hyphens.
+func usernameFor(u *User) string {
+	name := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '-' {
+			return r
+		}
+		return '_'
+	}, u.Name)
+	name = strings.Trim(name, "_")
+	if name == "" {
+		name = "user_" + u.ExternalID
+	}
+	return truncate(name, 50)
+}
+
+func roomName(c *Channel) string {
+	name := strings.TrimSpace(c.Name)
+	if name == "" {
+		name = "imported-" + c.ExternalID
+	}
+	return truncate(name, 100)
+}
+
+// numbered returns name for the first attempt and name-<attempt> after
+// that, within limit characters.
+func numbered(name string, attempt int, limit int) string {
+	if attempt == 1 {
+		return truncate(name, limit)
+	}
+	suffix := "-" + strconv.Itoa(attempt)
+	return truncate(name, limit-len(suffix)) + suffix
+}
+
+// truncate cuts s to at most limit characters.
+func truncate(s string, limit int) string {
+	runes := []rune(s)
+	if len(runes) <= limit {
+		return s
+	}
+	return string(runes[:limit])
+}
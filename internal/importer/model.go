@@ -0,0 +1,108 @@
+package importer
+
+import "time"
+
+// Sources an archive can be read from. The source is stored with every
+// mapping, so the same external ID from two sources never collides.
+const (
+	SourceSlack   = "slack"
+	SourceDiscord = "discord"
+)
+
+// Kinds of imported objects.
+const (
+	kindUser    = "user"
+	kindRoom    = "room"
+	kindMessage = "message"
+)
+
+// Archive is an export read into the source-independent shape the importer
+// works on.
+type Archive struct {
+	Source   string
+	Users    []*User
+	Channels []*Channel
+
+	// Text turns a message from the source's markup into plain text,
+	// resolving user mentions through username.
+	Text func(raw string, username func(externalID string) string) string
+
+	close func() error
+}
+
+// Close releases the export file.
+func (a *Archive) Close() error {
+	if a.close == nil {
+		return nil
+	}
+	return a.close()
+}
+
+type User struct {
+	ExternalID string
+	Name       string
+	// Email is empty when the export doesn't have it.
+	Email string
+	IsBot bool
+}
+
+type Channel struct {
+	ExternalID string
+	Name       string
+	Topic      string
+	IsPrivate  bool
+	// Direct channels are one-to-one conversations. They are imported as
+	// direct messages between their two members rather than as a room.
+	Direct    bool
+	CreatorID string
+	MemberIDs []string
+	CreatedAt time.Time
+
+	// Messages loads the channel's messages, oldest first. Channels are
+	// loaded one at a time, so a large export never has to fit in memory.
+	Messages func() ([]*Message, error)
+}
+
+type Message struct {
+	ExternalID string
+	UserID     string
+	Text       string
+	CreatedAt  time.Time
+	EditedAt   *time.Time
+	// ParentID is the external ID of the message this one replies to, set on
+	// thread replies.
+	ParentID  string
+	Reactions []*Reaction
+}
+
+// Reaction is one emoji on a message. UserIDs can be shorter than Count
+// when the export only lists some of the users who reacted.
+type Reaction struct {
+	Emoji   string
+	UserIDs []string
+	Count   int
+}
+
+// Report counts what an import did, or with a dry run, would do.
+type Report struct {
+	UsersCreated int
+	// UsersMatched were linked to existing accounts with the same email.
+	UsersMatched  int
+	UsersExisting int
+
+	RoomsCreated  int
+	RoomsExisting int
+
+	MessagesImported int
+	MessagesExisting int
+	// MessagesSkipped had no text, or an author or direct message peer
+	// missing from the export.
+	MessagesSkipped int
+	RepliesLinked   int
+	// RepliesOrphaned reply to a message missing from the import and were
+	// imported as plain channel messages.
+	RepliesOrphaned   int
+	ReactionsImported int
+	// ReactionsSkipped were by users the export doesn't list.
+	ReactionsSkipped int
+}
@@ -0,0 +1,254 @@
+package importer
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"github.com/maxwellzp/golang-chat-api/internal/db"
+	"time"
+)
+
+// uniqueAttempts bounds the numbered suffixes tried when a username or room
+// name is taken.
+const uniqueAttempts = 100
+
+type ImportRepository struct {
+	database *db.Db
+}
+
+func NewImportRepository(database *db.Db) *ImportRepository {
+	return &ImportRepository{database: database}
+}
+
+// Mapped returns the local IDs of the objects among externalIDs that were
+// already imported.
+func (r *ImportRepository) Mapped(ctx context.Context, source string, kind string, externalIDs []string) (map[string]int64, error) {
+	rows, err := r.database.QueryContext(ctx,
+		`SELECT external_id, local_id FROM import_mappings
+		 WHERE source = $1 AND kind = $2 AND external_id = ANY($3)`,
+		source, kind, pq.Array(externalIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	mapped := map[string]int64{}
+	for rows.Next() {
+		var externalID string
+		var localID int64
+		if err := rows.Scan(&externalID, &localID); err != nil {
+			return nil, err
+		}
+		mapped[externalID] = localID
+	}
+	return mapped, rows.Err()
+}
+
+// Usernames returns the usernames of the users by ID.
+func (r *ImportRepository) Usernames(ctx context.Context, ids []int64) (map[int64]string, error) {
+	rows, err := r.database.QueryContext(ctx, "SELECT id, username FROM users WHERE id = ANY($1)", pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	usernames := map[int64]string{}
+	for rows.Next() {
+		var id int64
+		var username string
+		if err := rows.Scan(&id, &username); err != nil {
+			return nil, err
+		}
+		usernames[id] = username
+	}
+	return usernames, rows.Err()
+}
+
+// UserByEmail returns the ID and username of the active account with the
+// email, or 0 if there is none.
+func (r *ImportRepository) UserByEmail(ctx context.Context, email string) (int64, string, error) {
+	var id int64
+	var username string
+	err := r.database.QueryRowContext(ctx,
+		"SELECT id, username FROM users WHERE LOWER(email) = LOWER($1) AND deleted_at IS NULL", email).
+		Scan(&id, &username)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, "", nil
+		}
+		return 0, "", err
+	}
+	return id, username, nil
+}
+
+// Map records that the external object was imported as localID.
+func (r *ImportRepository) Map(ctx context.Context, source string, kind string, externalID string, localID int64) error {
+	_, err := r.database.ExecContext(ctx,
+		`INSERT INTO import_mappings (source, kind, external_id, local_id, created_at)
+		 VALUES ($1, $2, $3, $4, $5)
+		 ON CONFLICT DO NOTHING`,
+		source, kind, externalID, localID, time.Now().UTC())
+	return err
+}
+
+// CreateUser creates a placeholder account with no password and maps the
+// external user to it. A taken username gets a numbered suffix. It returns
+// the new ID and the username it got.
+func (r *ImportRepository) CreateUser(ctx context.Context, source string, u *User, username string, email string) (int64, string, error) {
+	tx, err := r.database.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, "", err
+	}
+	defer tx.Rollback()
+
+	for attempt := 1; attempt <= uniqueAttempts; attempt++ {
+		name := numbered(username, attempt, 50)
+		var id int64
+		err := tx.QueryRowContext(ctx,
+			`INSERT INTO users (username, email, password, is_bot, created_at)
+			 VALUES ($1, $2, '', $3, $4)
+			 ON CONFLICT DO NOTHING
+			 RETURNING id`,
+			name, email, u.IsBot, time.Now().UTC()).Scan(&id)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return 0, "", err
+		}
+		if err := mapTx(ctx, tx, source, kindUser, u.ExternalID, id); err != nil {
+			return 0, "", err
+		}
+		return id, name, tx.Commit()
+	}
+	return 0, "", fmt.Errorf("no free username or email for %q <%s>", username, email)
+}
+
+// CreateRoom creates the room and maps the channel to it. A taken name gets
+// a numbered suffix.
+func (r *ImportRepository) CreateRoom(ctx context.Context, source string, c *Channel, name string, createdBy *int64) (int64, error) {
+	tx, err := r.database.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	createdAt := c.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now().UTC()
+	}
+	for attempt := 1; attempt <= uniqueAttempts; attempt++ {
+		var id int64
+		err := tx.QueryRowContext(ctx,
+			`INSERT INTO rooms (name, is_private, created_by, created_at, topic)
+			 VALUES ($1, $2, $3, $4, $5)
+			 ON CONFLICT DO NOTHING
+			 RETURNING id`,
+			numbered(name, attempt, 100), c.IsPrivate, createdBy, createdAt, truncate(c.Topic, 250)).Scan(&id)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		if err := mapTx(ctx, tx, source, kindRoom, c.ExternalID, id); err != nil {
+			return 0, err
+		}
+		return id, tx.Commit()
+	}
+	return 0, fmt.Errorf("no free room name for %q", name)
+}
+
+// AddMembers adds the users to the room, the owner if not nil as its owner.
+// Existing memberships are left as they are.
+func (r *ImportRepository) AddMembers(ctx context.Context, roomID int64, ownerID *int64, userIDs []int64) error {
+	_, err := r.database.ExecContext(ctx,
+		`INSERT INTO room_members (room_id, user_id, role, joined_at)
+		 SELECT $1, u.id, CASE WHEN u.id = $2 THEN 'owner' ELSE 'member' END, $4
+		 FROM unnest($3::int[]) AS u(id)
+		 ON CONFLICT DO NOTHING`,
+		roomID, ownerID, pq.Array(userIDs), time.Now().UTC())
+	return err
+}
+
+// importedMessage is a message ready to insert. InsertMessages sets ID.
+type importedMessage struct {
+	ID         int64
+	ExternalID string
+	SenderID   int64
+	RoomID     *int64
+	ReceiverID *int64
+	// A reply has either ParentID, if its parent is already stored, or
+	// Parent, if the parent is inserted earlier in the same run.
+	ParentID  *int64
+	Parent    *importedMessage
+	Content   string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Reactions []importedReaction
+}
+
+type importedReaction struct {
+	UserID int64
+	Emoji  string
+}
+
+// InsertMessages inserts a batch of messages and their mappings in one
+// transaction, so an interrupted import resumes after the last whole batch.
+func (r *ImportRepository) InsertMessages(ctx context.Context, source string, messages []*importedMessage) error {
+	tx, err := r.database.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx,
+		`INSERT INTO messages (sender_id, room_id, receiver_id, parent_id, content, created_at, updated_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7)
+		 RETURNING id`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	// Reactions carry no time of their own in the exports, so they are
+	// dated with their message.
+	reactionStmt, err := tx.PrepareContext(ctx,
+		`INSERT INTO message_reactions (message_id, user_id, emoji, created_at)
+		 VALUES ($1, $2, $3, $4)
+		 ON CONFLICT DO NOTHING`)
+	if err != nil {
+		return err
+	}
+	defer reactionStmt.Close()
+
+	for _, msg := range messages {
+		parentID := msg.ParentID
+		if parentID == nil && msg.Parent != nil && msg.Parent.ID != 0 {
+			parentID = &msg.Parent.ID
+		}
+		err := stmt.QueryRowContext(ctx, msg.SenderID, msg.RoomID, msg.ReceiverID, parentID, msg.Content, msg.CreatedAt, msg.UpdatedAt).Scan(&msg.ID)
+		if err != nil {
+			return err
+		}
+		if err := mapTx(ctx, tx, source, kindMessage, msg.ExternalID, msg.ID); err != nil {
+			return err
+		}
+		for _, r := range msg.Reactions {
+			if _, err := reactionStmt.ExecContext(ctx, msg.ID, r.UserID, truncate(r.Emoji, 100), msg.CreatedAt); err != nil {
+				return err
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+func mapTx(ctx context.Context, tx *sql.Tx, source string, kind string, externalID string, localID int64) error {
+	_, err := tx.ExecContext(ctx,
+		`INSERT INTO import_mappings (source, kind, external_id, local_id, created_at)
+		 VALUES ($1, $2, $3, $4, $5)`,
+		source, kind, externalID, localID, time.Now().UTC())
+	return err
+}
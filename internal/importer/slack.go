@@ -0,0 +1,262 @@
+package importer
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// slackSubtypes are the message subtypes that carry user content. Joins,
+// topic changes and the like are left out.
+var slackSubtypes = map[string]bool{
+	"":                 true,
+	"bot_message":      true,
+	"me_message":       true,
+	"file_share":       true,
+	"thread_broadcast": true,
+}
+
+type slackUser struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	IsBot   bool   `json:"is_bot"`
+	Profile struct {
+		Email string `json:"email"`
+		BotID string `json:"bot_id"`
+	} `json:"profile"`
+}
+
+type slackChannel struct {
+	ID      string   `json:"id"`
+	Name    string   `json:"name"`
+	Created int64    `json:"created"`
+	Creator string   `json:"creator"`
+	Members []string `json:"members"`
+	Topic   struct {
+		Value string `json:"value"`
+	} `json:"topic"`
+	Purpose struct {
+		Value string `json:"value"`
+	} `json:"purpose"`
+}
+
+type slackMessage struct {
+	Type     string `json:"type"`
+	Subtype  string `json:"subtype"`
+	User     string `json:"user"`
+	BotID    string `json:"bot_id"`
+	Text     string `json:"text"`
+	TS       string `json:"ts"`
+	ThreadTS string `json:"thread_ts"`
+	Edited   *struct {
+		TS string `json:"ts"`
+	} `json:"edited"`
+	Reactions []struct {
+		Name  string   `json:"name"`
+		Users []string `json:"users"`
+		Count int      `json:"count"`
+	} `json:"reactions"`
+}
+
+// OpenSlack reads a Slack workspace export zip. Public channels, private
+// channels and group conversations become rooms; direct messages stay
+// direct messages. Close the archive when done.
+func OpenSlack(name string) (*Archive, error) {
+	zr, err := zip.OpenReader(name)
+	if err != nil {
+		return nil, err
+	}
+	a, err := readSlack(&zr.Reader)
+	if err != nil {
+		zr.Close()
+		return nil, err
+	}
+	a.close = zr.Close
+	return a, nil
+}
+
+func readSlack(zr *zip.Reader) (*Archive, error) {
+	a := &Archive{Source: SourceSlack, Text: slackText}
+
+	var users []*slackUser
+	if err := readSlackJSON(zr, "users.json", &users); err != nil {
+		return nil, err
+	}
+	bots := map[string]string{}
+	for _, u := range users {
+		a.Users = append(a.Users, &User{ExternalID: u.ID, Name: u.Name, Email: u.Profile.Email, IsBot: u.IsBot})
+		if u.Profile.BotID != "" {
+			bots[u.Profile.BotID] = u.ID
+		}
+	}
+
+	// The export names a channel's directory after the channel, except for
+	// direct messages, which have no name.
+	lists := []struct {
+		file    string
+		private bool
+		direct  bool
+	}{
+		{file: "channels.json"},
+		{file: "groups.json", private: true},
+		{file: "mpims.json", private: true},
+		{file: "dms.json", private: true, direct: true},
+	}
+	for _, list := range lists {
+		var channels []*slackChannel
+		if err := readSlackJSON(zr, list.file, &channels); err != nil {
+			return nil, err
+		}
+		for _, c := range channels {
+			dir := c.Name
+			if list.direct {
+				dir = c.ID
+			}
+			topic := c.Topic.Value
+			if topic == "" {
+				topic = c.Purpose.Value
+			}
+			a.Channels = append(a.Channels, &Channel{
+				ExternalID: c.ID,
+				Name:       c.Name,
+				Topic:      topic,
+				IsPrivate:  list.private,
+				Direct:     list.direct,
+				CreatorID:  c.Creator,
+				MemberIDs:  c.Members,
+				CreatedAt:  time.Unix(c.Created, 0).UTC(),
+				Messages: func() ([]*Message, error) {
+					return readSlackMessages(zr, c.ID, dir, bots)
+				},
+			})
+		}
+	}
+	return a, nil
+}
+
+// readSlackJSON decodes one of the top-level lists. Lists the workspace had
+// nothing for are missing from the export.
+func readSlackJSON(zr *zip.Reader, name string, v any) error {
+	f, err := zr.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := json.NewDecoder(f).Decode(v); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+// readSlackMessages reads the channel's per-day files.
+func readSlackMessages(zr *zip.Reader, channelID string, dir string, bots map[string]string) ([]*Message, error) {
+	var messages []*Message
+	for _, f := range zr.File {
+		if path.Dir(f.Name) != dir || path.Ext(f.Name) != ".json" {
+			continue
+		}
+		var day []*slackMessage
+		if err := readSlackJSON(zr, f.Name, &day); err != nil {
+			return nil, err
+		}
+		for _, m := range day {
+			if m.Type != "message" || !slackSubtypes[m.Subtype] {
+				continue
+			}
+			createdAt, err := slackTime(m.TS)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", f.Name, err)
+			}
+			userID := m.User
+			if userID == "" {
+				userID = bots[m.BotID]
+			}
+			msg := &Message{
+				ExternalID: channelID + "/" + m.TS,
+				UserID:     userID,
+				Text:       m.Text,
+				CreatedAt:  createdAt,
+			}
+			if m.ThreadTS != "" && m.ThreadTS != m.TS {
+				msg.ParentID = channelID + "/" + m.ThreadTS
+			}
+			if m.Edited != nil {
+				if editedAt, err := slackTime(m.Edited.TS); err == nil {
+					msg.EditedAt = &editedAt
+				}
+			}
+			for _, r := range m.Reactions {
+				msg.Reactions = append(msg.Reactions, &Reaction{Emoji: ":" + r.Name + ":", UserIDs: r.Users, Count: r.Count})
+			}
+			messages = append(messages, msg)
+		}
+	}
+	sort.SliceStable(messages, func(i, j int) bool {
+		return messages[i].CreatedAt.Before(messages[j].CreatedAt)
+	})
+	return messages, nil
+}
+
+// slackTime parses a message timestamp such as "1508284197.000015", which
+// is seconds and microseconds since the epoch.
+func slackTime(ts string) (time.Time, error) {
+	sec, frac, _ := strings.Cut(ts, ".")
+	s, err := strconv.ParseInt(sec, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid timestamp %q", ts)
+	}
+	var us int64
+	if frac != "" {
+		if us, err = strconv.ParseInt((frac + "000000")[:6], 10, 64); err != nil {
+			return time.Time{}, fmt.Errorf("invalid timestamp %q", ts)
+		}
+	}
+	return time.Unix(s, us*1000).UTC(), nil
+}
+
+// slackEntity matches Slack's <...> markup: <@U123>, <#C123|general>,
+// <!here> and <https://example.com|label>.
+var slackEntity = regexp.MustCompile(`<([^<>|]+)(?:\|([^<>]*))?>`)
+
+func slackText(raw string, username func(externalID string) string) string {
+	text := slackEntity.ReplaceAllStringFunc(raw, func(entity string) string {
+		m := slackEntity.FindStringSubmatch(entity)
+		target, label := m[1], m[2]
+		switch {
+		case strings.HasPrefix(target, "@"):
+			if name := username(target[1:]); name != "" {
+				return "@" + name
+			}
+			if label == "" {
+				label = target[1:]
+			}
+			return "@" + label
+		case strings.HasPrefix(target, "#"):
+			if label == "" {
+				label = target[1:]
+			}
+			return "#" + label
+		case strings.HasPrefix(target, "!"):
+			if label != "" {
+				return label
+			}
+			return "@" + strings.TrimPrefix(target, "!")
+		case label != "" && label != target:
+			return label + " (" + target + ")"
+		}
+		return target
+	})
+	return html.UnescapeString(text)
+}
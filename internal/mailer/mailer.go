@@ -0,0 +1,78 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"github.com/maxwellzp/golang-chat-api/internal/config"
+	"github.com/maxwellzp/golang-chat-api/internal/logger"
+	"net/smtp"
+	"strings"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// NewMailer picks the delivery driver from config: "smtp" sends real mail,
+// anything else writes the message to the log (useful in development).
+func NewMailer(cfg *config.Config, log *logger.Logger) Mailer {
+	if cfg.Mail.Driver == "smtp" {
+		return NewSMTPMailer(cfg.Mail)
+	}
+	return NewLogMailer(cfg.Mail.From, log)
+}
+
+type LogMailer struct {
+	from   string
+	logger *logger.Logger
+}
+
+func NewLogMailer(from string, logger *logger.Logger) *LogMailer {
+	return &LogMailer{from: from, logger: logger}
+}
+
+func (m *LogMailer) Send(ctx context.Context, msg Message) error {
+	m.logger.Infow("Email (log driver)",
+		"from", m.from,
+		"to", msg.To,
+		"subject", msg.Subject,
+		"body", msg.Body,
+	)
+	return nil
+}
+
+type SMTPMailer struct {
+	addr string
+	from string
+	auth smtp.Auth
+}
+
+func NewSMTPMailer(cfg config.MailConfig) *SMTPMailer {
+	var auth smtp.Auth
+	if cfg.Username != "" {
+		auth = smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.SMTPHost)
+	}
+	return &SMTPMailer{
+		addr: cfg.SMTPHost + ":" + cfg.SMTPPort,
+		from: cfg.From,
+		auth: auth,
+	}
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", m.from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", msg.Subject)
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=\"utf-8\"\r\n\r\n")
+	b.WriteString(msg.Body)
+
+	return smtp.SendMail(m.addr, m.auth, m.from, []string{msg.To}, []byte(b.String()))
+}
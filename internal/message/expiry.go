@@ -0,0 +1,78 @@
+package message
+
+import (
+	"context"
+	"github.com/maxwellzp/golang-chat-api/internal/config"
+	"github.com/maxwellzp/golang-chat-api/internal/event"
+	"github.com/maxwellzp/golang-chat-api/internal/logger"
+	"time"
+)
+
+// ExpiryReaper deletes expired messages together with their mentions, pins
+// and bookmarks (all cascade) and publishes message.deleted for each, so
+// webhook subscribers drop them. Reads already hide expired messages; the
+// reaper reclaims the rows. Several reapers (one per replica) can run
+// against the same database.
+type ExpiryReaper struct {
+	messageRepository *MessageRepository
+	bus               *event.Bus
+	cfg               config.MessageConfig
+	logger            *logger.Logger
+}
+
+func NewExpiryReaper(messageRepository *MessageRepository, bus *event.Bus, cfg config.MessageConfig, logger *logger.Logger) *ExpiryReaper {
+	return &ExpiryReaper{
+		messageRepository: messageRepository,
+		bus:               bus,
+		cfg:               cfg,
+		logger:            logger,
+	}
+}
+
+// Run reaps expired messages until ctx is cancelled.
+func (w *ExpiryReaper) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.cfg.ExpiryPollInterval)
+	defer ticker.Stop()
+
+	for {
+		w.DeleteExpired(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DeleteExpired deletes expired messages in batches until none are left.
+// The published messages carry only their ids, destinations and expiry.
+func (w *ExpiryReaper) DeleteExpired(ctx context.Context) {
+	for {
+		deleted, err := w.messageRepository.DeleteExpired(ctx, time.Now().UTC(), w.cfg.ExpiryBatchSize)
+		if err != nil {
+			if ctx.Err() == nil {
+				w.logger.Errorw("Failed to delete expired messages",
+					"error", err,
+				)
+			}
+			return
+		}
+		for _, msg := range deleted {
+			w.bus.Publish(ctx, event.Event{
+				Type:       event.MessageDeleted,
+				RoomID:     msg.RoomID,
+				ReceiverID: msg.ReceiverID,
+				ActorID:    msg.SenderID,
+				Data:       msg,
+			})
+		}
+		if len(deleted) > 0 {
+			w.logger.Infow("Deleted expired messages",
+				"count", len(deleted),
+			)
+		}
+		if len(deleted) < w.cfg.ExpiryBatchSize || ctx.Err() != nil {
+			return
+		}
+	}
+}
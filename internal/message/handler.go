@@ -2,9 +2,11 @@ package message
 
 import (
 	"encoding/json"
+	"errors"
 	"github.com/maxwellzp/golang-chat-api/internal/httpx"
 	"github.com/maxwellzp/golang-chat-api/internal/logger"
 	"github.com/maxwellzp/golang-chat-api/internal/validatorx"
+	"io"
 	"net/http"
 	"strconv"
 )
@@ -59,6 +61,17 @@ func (h *MessageHandler) Create() http.HandlerFunc {
 		}
 
 		msg, err := h.messageService.Create(r.Context(), userID, req)
+		var slowMode *SlowModeError
+		if errors.As(err, &slowMode) {
+			h.logger.Infow("Message rejected by slow mode",
+				"user_id", userID,
+				"room_id", req.RoomID,
+				"retry_after", slowMode.RetryAfter,
+			)
+		}
+		if httpx.WriteServiceError(w, err) {
+			return
+		}
 		if err != nil {
 			h.logger.Errorw("Failed to create message",
 				"error", err,
@@ -67,6 +80,10 @@ func (h *MessageHandler) Create() http.HandlerFunc {
 			httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
 			return
 		}
+		if msg.Ephemeral {
+			httpx.WriteJSON(w, http.StatusOK, msg)
+			return
+		}
 		h.logger.Infow("Message created",
 			"message_id", msg.ID,
 			"user_id", userID,
@@ -111,7 +128,11 @@ func (h *MessageHandler) Update() http.HandlerFunc {
 			return
 		}
 
-		if err := h.messageService.Update(r.Context(), id, userID, req); err != nil {
+		err = h.messageService.Update(r.Context(), id, userID, req)
+		if httpx.WriteServiceError(w, err) {
+			return
+		}
+		if err != nil {
 			h.logger.Errorw("Failed to update message",
 				"error", err,
 				"user_id", userID,
@@ -145,6 +166,9 @@ func (h *MessageHandler) Delete() http.HandlerFunc {
 			return
 		}
 		if err := h.messageService.Delete(r.Context(), id, userID); err != nil {
+			if httpx.WriteServiceError(w, err) {
+				return
+			}
 			h.logger.Errorw("Failed to delete message",
 				"error", err,
 				"user_id", userID,
@@ -205,6 +229,12 @@ func (h *MessageHandler) GetByID() http.HandlerFunc {
 
 func (h *MessageHandler) List() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := httpx.GetUserID(r.Context())
+		if err != nil {
+			h.logger.Warnw("Unauthorized request to list messages")
+			httpx.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
 		roomIDStr := r.URL.Query().Get("room_id")
 		receiverIDStr := r.URL.Query().Get("receiver_id")
 
@@ -236,10 +266,11 @@ func (h *MessageHandler) List() http.HandlerFunc {
 		}
 
 		h.logger.Infow("Listing messages",
+			"user_id", userID,
 			"room_id", roomID,
 			"receiver_id", receiverID,
 		)
-		messages, err := h.messageService.List(r.Context(), roomID, receiverID)
+		messages, err := h.messageService.List(r.Context(), userID, roomID, receiverID)
 		if err != nil {
 			h.logger.Errorw("Failed to list messages",
 				"error", err,
@@ -253,3 +284,280 @@ func (h *MessageHandler) List() http.HandlerFunc {
 		httpx.WriteJSON(w, http.StatusOK, messages)
 	}
 }
+
+// Mentions lists the messages that mentioned the current user. Pass
+// ?unread=true to only get unread ones.
+func (h *MessageHandler) Mentions() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := httpx.GetUserID(r.Context())
+		if err != nil {
+			h.logger.Warnw("Unauthorized request to list mentions")
+			httpx.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		limit, offset, err := httpx.ParsePagination(r, 50, 100)
+		if err != nil {
+			httpx.WriteValidationError(w, err)
+			return
+		}
+		unreadOnly := r.URL.Query().Get("unread") == "true"
+
+		mentions, err := h.messageService.Mentions(r.Context(), userID, unreadOnly, limit, offset)
+		if err != nil {
+			h.logger.Errorw("Failed to list mentions",
+				"error", err,
+				"user_id", userID,
+			)
+			httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
+			return
+		}
+		httpx.WriteJSON(w, http.StatusOK, mentions)
+	}
+}
+
+func (h *MessageHandler) MarkMentionRead() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := httpx.GetUserID(r.Context())
+		if err != nil {
+			h.logger.Warnw("Unauthorized request to mark mention read")
+			httpx.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		messageID, err := httpx.ParseInt64Param(r, "message_id")
+		if err != nil {
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid MessageID")
+			return
+		}
+
+		if _, err := h.messageService.MarkMentionsRead(r.Context(), userID, &messageID); err != nil {
+			h.logger.Errorw("Failed to mark mention read",
+				"error", err,
+				"user_id", userID,
+				"message_id", messageID,
+			)
+			httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
+			return
+		}
+		httpx.WriteJSON(w, http.StatusNoContent, nil)
+	}
+}
+
+func (h *MessageHandler) MarkAllMentionsRead() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := httpx.GetUserID(r.Context())
+		if err != nil {
+			h.logger.Warnw("Unauthorized request to mark mentions read")
+			httpx.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+
+		count, err := h.messageService.MarkMentionsRead(r.Context(), userID, nil)
+		if err != nil {
+			h.logger.Errorw("Failed to mark mentions read",
+				"error", err,
+				"user_id", userID,
+			)
+			httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
+			return
+		}
+		httpx.WriteJSON(w, http.StatusOK, map[string]int64{"marked_read": count})
+	}
+}
+
+func (h *MessageHandler) Pin() http.HandlerFunc {
+	return h.changePin(true)
+}
+
+func (h *MessageHandler) Unpin() http.HandlerFunc {
+	return h.changePin(false)
+}
+
+func (h *MessageHandler) changePin(pin bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := httpx.GetUserID(r.Context())
+		if err != nil {
+			h.logger.Warnw("Unauthorized request to change pin")
+			httpx.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		roomID, err := httpx.ParseInt64Param(r, "id")
+		if err != nil {
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid RoomID")
+			return
+		}
+		messageID, err := httpx.ParseInt64Param(r, "message_id")
+		if err != nil {
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid MessageID")
+			return
+		}
+
+		if pin {
+			err = h.messageService.Pin(r.Context(), roomID, messageID, userID)
+		} else {
+			err = h.messageService.Unpin(r.Context(), roomID, messageID, userID)
+		}
+		if httpx.WriteServiceError(w, err) {
+			return
+		}
+		if err != nil {
+			h.logger.Errorw("Failed to change pin",
+				"error", err,
+				"pin", pin,
+				"room_id", roomID,
+				"message_id", messageID,
+			)
+			httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
+			return
+		}
+		h.logger.Infow("Pin changed",
+			"pin", pin,
+			"room_id", roomID,
+			"message_id", messageID,
+			"user_id", userID,
+		)
+		httpx.WriteJSON(w, http.StatusNoContent, nil)
+	}
+}
+
+// Pins lists the room's pinned messages, most recently pinned first.
+func (h *MessageHandler) Pins() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := httpx.GetUserID(r.Context())
+		if err != nil {
+			h.logger.Warnw("Unauthorized request to list pins")
+			httpx.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		roomID, err := httpx.ParseInt64Param(r, "id")
+		if err != nil {
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid RoomID")
+			return
+		}
+
+		pins, err := h.messageService.Pins(r.Context(), roomID, userID)
+		if httpx.WriteServiceError(w, err) {
+			return
+		}
+		if err != nil {
+			h.logger.Errorw("Failed to list pins",
+				"error", err,
+				"room_id", roomID,
+			)
+			httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
+			return
+		}
+		httpx.WriteJSON(w, http.StatusOK, pins)
+	}
+}
+
+// Save bookmarks a message for the current user. The body is optional.
+func (h *MessageHandler) Save() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := httpx.GetUserID(r.Context())
+		if err != nil {
+			h.logger.Warnw("Unauthorized request to save message")
+			httpx.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		messageID, err := httpx.ParseInt64Param(r, "message_id")
+		if err != nil {
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid MessageID")
+			return
+		}
+
+		var req SaveMessageRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+			h.logger.Warnw("Failed to decode SaveMessageRequest",
+				"error", err,
+			)
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		if err := h.validator.Validate(&req); err != nil {
+			httpx.WriteValidationError(w, err)
+			return
+		}
+		if err := req.Validate(); err != nil {
+			httpx.WriteValidationError(w, err)
+			return
+		}
+
+		saved, created, err := h.messageService.Save(r.Context(), userID, messageID, req)
+		if httpx.WriteServiceError(w, err) {
+			return
+		}
+		if err != nil {
+			h.logger.Errorw("Failed to save message",
+				"error", err,
+				"user_id", userID,
+				"message_id", messageID,
+			)
+			httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
+			return
+		}
+		if created {
+			httpx.WriteJSON(w, http.StatusCreated, saved)
+			return
+		}
+		httpx.WriteJSON(w, http.StatusOK, saved)
+	}
+}
+
+func (h *MessageHandler) Unsave() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := httpx.GetUserID(r.Context())
+		if err != nil {
+			h.logger.Warnw("Unauthorized request to unsave message")
+			httpx.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		messageID, err := httpx.ParseInt64Param(r, "message_id")
+		if err != nil {
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid MessageID")
+			return
+		}
+
+		err = h.messageService.Unsave(r.Context(), userID, messageID)
+		if httpx.WriteServiceError(w, err) {
+			return
+		}
+		if err != nil {
+			h.logger.Errorw("Failed to unsave message",
+				"error", err,
+				"user_id", userID,
+				"message_id", messageID,
+			)
+			httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
+			return
+		}
+		httpx.WriteJSON(w, http.StatusNoContent, nil)
+	}
+}
+
+// Saved lists the current user's bookmarks, newest first.
+func (h *MessageHandler) Saved() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := httpx.GetUserID(r.Context())
+		if err != nil {
+			h.logger.Warnw("Unauthorized request to list saved messages")
+			httpx.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		limit, offset, err := httpx.ParsePagination(r, 50, 100)
+		if err != nil {
+			httpx.WriteValidationError(w, err)
+			return
+		}
+
+		saved, err := h.messageService.Saved(r.Context(), userID, limit, offset)
+		if err != nil {
+			h.logger.Errorw("Failed to list saved messages",
+				"error", err,
+				"user_id", userID,
+			)
+			httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
+			return
+		}
+		httpx.WriteJSON(w, http.StatusOK, saved)
+	}
+}
@@ -0,0 +1,70 @@
+package message
+
+import (
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	MentionUser = "user"
+	MentionHere = "here"
+	MentionRoom = "room"
+)
+
+// mentionPattern matches @name at the start of the content or after a
+// character that can't be part of a word, so emails don't count.
+var mentionPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_@])@([\p{L}\p{N}_-]+)`)
+
+// Mention is a resolved mention span. Start and End are character (rune)
+// offsets into the message content, End exclusive, covering the "@".
+type Mention struct {
+	Kind     string `json:"kind"`
+	UserID   *int64 `json:"user_id,omitempty"`
+	Username string `json:"username,omitempty"`
+	Start    int    `json:"start"`
+	End      int    `json:"end"`
+}
+
+// MentionedMessage is an entry in a user's mentions inbox.
+type MentionedMessage struct {
+	*Message
+	MentionKind string     `json:"mention_kind"`
+	Read        bool       `json:"read"`
+	ReadAt      *time.Time `json:"read_at"`
+	MentionedAt time.Time  `json:"mentioned_at"`
+}
+
+// MentionData is the payload of a mention.created event.
+type MentionData struct {
+	UserID  int64    `json:"user_id"`
+	Kind    string   `json:"kind"`
+	Message *Message `json:"message"`
+}
+
+// parseMentions returns the unresolved mentions in content. @here and @room
+// are recognised case-insensitively; anything else is a username.
+func parseMentions(content string) []*Mention {
+	var mentions []*Mention
+	for _, m := range mentionPattern.FindAllStringSubmatchIndex(content, -1) {
+		nameStart, nameEnd := m[2], m[3]
+		name := content[nameStart:nameEnd]
+
+		mention := &Mention{
+			Kind:  MentionUser,
+			Start: utf8.RuneCountInString(content[:nameStart-1]),
+		}
+		mention.End = mention.Start + 1 + utf8.RuneCountInString(name)
+		switch strings.ToLower(name) {
+		case MentionHere:
+			mention.Kind = MentionHere
+		case MentionRoom:
+			mention.Kind = MentionRoom
+		default:
+			mention.Username = name
+		}
+		mentions = append(mentions, mention)
+	}
+	return mentions
+}
@@ -5,11 +5,35 @@ import (
 )
 
 type Message struct {
-	ID         int64     `json:"id"`
-	SenderID   int64     `json:"sender_id"`
-	RoomID     *int64    `json:"room_id,omitempty"`
-	ReceiverID *int64    `json:"receiver_id,omitempty"`
-	Content    string    `json:"content"`
-	CreatedAt  time.Time `json:"created_at"`
-	UpdatedAt  time.Time `json:"updated_at"`
+	ID         int64  `json:"id"`
+	SenderID   int64  `json:"sender_id"`
+	RoomID     *int64 `json:"room_id,omitempty"`
+	ReceiverID *int64 `json:"receiver_id,omitempty"`
+	// ParentID is set on replies in a thread.
+	ParentID *int64 `json:"parent_id,omitempty"`
+	Content  string `json:"content"`
+	// Display overrides, set on messages posted through incoming webhooks.
+	DisplayName *string   `json:"display_name,omitempty"`
+	IconURL     *string   `json:"icon_url,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	Pinned      bool      `json:"pinned"`
+	// ExpiresAt is set on expiring messages. Expired messages are hidden
+	// from reads and deleted by the ExpiryReaper.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+
+	Mentions []*Mention `json:"mentions,omitempty"`
+
+	// Ephemeral responses (e.g. slash command output) are shown only to the
+	// sender and never stored.
+	Ephemeral bool `json:"ephemeral,omitempty"`
+}
+
+// PinnedMessage is a message pinned to its room. PinnedBy is nil if the user
+// who pinned it has since been deleted.
+type PinnedMessage struct {
+	*Message
+	PinnedBy         *int64    `json:"pinned_by"`
+	PinnedByUsername *string   `json:"pinned_by_username"`
+	PinnedAt         time.Time `json:"pinned_at"`
 }
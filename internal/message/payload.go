@@ -1,11 +1,24 @@
 package message
 
-import "github.com/maxwellzp/golang-chat-api/internal/httpx"
+import (
+	"github.com/maxwellzp/golang-chat-api/internal/httpx"
+	"time"
+)
 
 type CreateMessageRequest struct {
 	RoomID     *int64 `json:"room_id,omitempty"`
 	ReceiverID *int64 `json:"receiver_id,omitempty"`
 	Content    string `json:"content" validate:"required,min=3"`
+	// TTLSeconds makes the message expire. The room's default TTL still
+	// applies if it is shorter.
+	TTLSeconds *int `json:"ttl_seconds,omitempty" validate:"omitempty,min=1,max=2592000"`
+
+	// Set only by internal callers such as incoming webhooks; never decoded
+	// from client requests.
+	DisplayName *string `json:"-"`
+	IconURL     *string `json:"-"`
+	// Verbatim stores the content as-is, without running slash commands.
+	Verbatim bool `json:"-"`
 }
 
 func (r *CreateMessageRequest) Validate() error {
@@ -28,3 +41,17 @@ func (r *CreateMessageRequest) Validate() error {
 type UpdateMessageRequest struct {
 	Content string `json:"content" validate:"required,min=3"`
 }
+
+// SaveMessageRequest is optional. Saving an already saved message replaces
+// its note and reminder.
+type SaveMessageRequest struct {
+	Note     *string    `json:"note,omitempty" validate:"omitempty,max=500"`
+	RemindAt *time.Time `json:"remind_at,omitempty"`
+}
+
+func (r *SaveMessageRequest) Validate() error {
+	if r.RemindAt != nil && !r.RemindAt.After(time.Now()) {
+		return httpx.ValidationErrorMap{"remind_at": "remind_at must be in the future"}
+	}
+	return nil
+}
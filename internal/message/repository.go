@@ -4,7 +4,9 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"github.com/lib/pq"
 	"github.com/maxwellzp/golang-chat-api/internal/db"
+	"strings"
 	"time"
 )
 
@@ -12,33 +14,140 @@ type MessageRepository struct {
 	database *db.Db
 }
 
+// messageColumns is the select list matching scanMessage, for queries that
+// alias messages as m.
+const messageColumns = `m.id, m.sender_id, m.room_id, m.receiver_id, m.parent_id, m.content, m.display_name, m.icon_url, m.created_at, m.updated_at,
+	m.expires_at, EXISTS (SELECT 1 FROM pinned_messages pm WHERE pm.message_id = m.id)`
+
+// notExpired hides messages m whose expiry has passed but which the
+// ExpiryReaper hasn't deleted yet. Timestamps are stored in UTC.
+const notExpired = `(m.expires_at IS NULL OR m.expires_at > (NOW() AT TIME ZONE 'UTC'))`
+
+// readableBy is a condition limiting messages m, with their room left
+// joined as r, to those userExpr can read now: DMs they sent or received,
+// public room messages and messages in private rooms they belong to.
+func readableBy(userExpr string) string {
+	return `((m.room_id IS NULL AND (m.sender_id = ` + userExpr + ` OR m.receiver_id = ` + userExpr + `))
+		OR (m.room_id IS NOT NULL AND (NOT r.is_private OR EXISTS (
+			SELECT 1 FROM room_members rm WHERE rm.room_id = m.room_id AND rm.user_id = ` + userExpr + `))))`
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanMessage(row rowScanner) (*Message, error) {
+	var msg Message
+	err := row.Scan(
+		&msg.ID,
+		&msg.SenderID,
+		&msg.RoomID,
+		&msg.ReceiverID,
+		&msg.ParentID,
+		&msg.Content,
+		&msg.DisplayName,
+		&msg.IconURL,
+		&msg.CreatedAt,
+		&msg.UpdatedAt,
+		&msg.ExpiresAt,
+		&msg.Pinned,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
 func NewMessageRepository(database *db.Db) *MessageRepository {
 	return &MessageRepository{database: database}
 }
 
-func (r *MessageRepository) Create(ctx context.Context, msg *Message) error {
+// Create inserts the message. A positive cooldown applies slow mode: the
+// sender's cooldown row for the room is claimed in the same transaction, so
+// concurrent posts can't both get through, and a *SlowModeError is returned
+// if the previous post is too recent.
+func (r *MessageRepository) Create(ctx context.Context, msg *Message, cooldown time.Duration) error {
+	tx, err := r.database.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+	if cooldown > 0 && msg.RoomID != nil {
+		if err := claimCooldown(ctx, tx, msg.SenderID, *msg.RoomID, now, cooldown); err != nil {
+			return err
+		}
+	}
+
 	query := `
-			INSERT INTO messages (sender_id, room_id, receiver_id, content, created_at, updated_at) 
-			VALUES($1, $2, $3, $4, $5, $6) 
+			INSERT INTO messages (sender_id, room_id, receiver_id, content, display_name, icon_url, created_at, updated_at, expires_at) 
+			VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9) 
 			RETURNING id, created_at, updated_at;`
-	err := r.database.QueryRowContext(ctx, query,
+	err = tx.QueryRowContext(ctx, query,
 		msg.SenderID,
 		msg.RoomID,
 		msg.ReceiverID,
 		msg.Content,
-		time.Now(),
-		time.Now()).Scan(&msg.ID, &msg.CreatedAt, &msg.UpdatedAt)
+		msg.DisplayName,
+		msg.IconURL,
+		now,
+		now,
+		msg.ExpiresAt).Scan(&msg.ID, &msg.CreatedAt, &msg.UpdatedAt)
 	if err != nil {
 		return err
 	}
-	return nil
+	return tx.Commit()
+}
+
+// claimCooldown moves the sender's last post time in the room to now, unless
+// it is less than cooldown ago. The upsert locks the row, so of two
+// concurrent posts only the first claims it.
+func claimCooldown(ctx context.Context, tx *sql.Tx, senderID int64, roomID int64, now time.Time, cooldown time.Duration) error {
+	since := now.Add(-cooldown)
+	var claimed time.Time
+	err := tx.QueryRowContext(ctx,
+		`INSERT INTO room_post_cooldowns (room_id, user_id, last_posted_at) VALUES ($1, $2, $3)
+		 ON CONFLICT (room_id, user_id) DO UPDATE SET last_posted_at = EXCLUDED.last_posted_at
+		 WHERE room_post_cooldowns.last_posted_at <= $4
+		 RETURNING last_posted_at`,
+		roomID, senderID, now, since).Scan(&claimed)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	// The subtraction happens in SQL so both timestamps are compared in the
+	// same time zone.
+	var seconds float64
+	err = tx.QueryRowContext(ctx,
+		`SELECT EXTRACT(EPOCH FROM (last_posted_at - $3::timestamp))
+		 FROM room_post_cooldowns WHERE room_id = $1 AND user_id = $2`,
+		roomID, senderID, since).Scan(&seconds)
+	if err != nil {
+		return err
+	}
+	return &SlowModeError{RetryAfter: time.Duration(seconds * float64(time.Second))}
 }
 
+// Update replaces the message's content and keeps the old content in its
+// edit history, in one statement.
 func (r *MessageRepository) Update(ctx context.Context, messageID int64, senderID int64, content string) error {
 	query := `
-		UPDATE messages SET content = $1, updated_at = $2 WHERE id = $3 AND sender_id = $4;
-`
-	res, err := r.database.ExecContext(ctx, query, content, time.Now(), messageID, senderID)
+		WITH old AS (
+			SELECT m.id, m.content FROM messages m
+			WHERE m.id = $3 AND m.sender_id = $4 AND ` + notExpired + `
+			FOR UPDATE
+		), updated AS (
+			UPDATE messages m SET content = $1, updated_at = $2
+			FROM old WHERE m.id = old.id
+			RETURNING m.id
+		)
+		INSERT INTO message_edits (message_id, content, edited_at)
+		SELECT old.id, old.content, $2 FROM old JOIN updated ON updated.id = old.id`
+	res, err := r.database.ExecContext(ctx, query, content, time.Now().UTC(), messageID, senderID)
 	if err != nil {
 		return err
 	}
@@ -48,7 +157,7 @@ func (r *MessageRepository) Update(ctx context.Context, messageID int64, senderI
 		return err
 	}
 	if rowsAffected == 0 {
-		return errors.New("no message found or permission denied")
+		return ErrNotSender
 	}
 
 	return nil
@@ -65,57 +174,532 @@ func (r *MessageRepository) Delete(ctx context.Context, messageID int64, senderI
 		return err
 	}
 	if rowsAffected == 0 {
-		return errors.New("no message found or permission denied")
+		return ErrNotSender
 	}
 	return nil
 }
 
 func (r *MessageRepository) GetByID(ctx context.Context, messageID int64, senderID int64) (*Message, error) {
-	query := `SELECT id, sender_id, room_id, receiver_id, content, created_at, updated_at 
-			  FROM messages WHERE id = $1 AND sender_id = $2;`
+	query := `SELECT ` + messageColumns + ` 
+			  FROM messages m WHERE m.id = $1 AND m.sender_id = $2 AND ` + notExpired
 	row := r.database.QueryRowContext(ctx, query, messageID, senderID)
 
-	var msg Message
-	err := row.Scan(&msg.ID, &msg.SenderID, &msg.RoomID, &msg.ReceiverID, &msg.Content, &msg.CreatedAt, &msg.UpdatedAt)
+	msg, err := scanMessage(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if err := r.attachMentions(ctx, []*Message{msg}); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
 
+// FindByID returns the message whoever sent it.
+func (r *MessageRepository) FindByID(ctx context.Context, messageID int64) (*Message, error) {
+	query := `SELECT ` + messageColumns + ` FROM messages m WHERE m.id = $1 AND ` + notExpired
+	msg, err := scanMessage(r.database.QueryRowContext(ctx, query, messageID))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil
 		}
 		return nil, err
 	}
-	return &msg, nil
+	if err := r.attachMentions(ctx, []*Message{msg}); err != nil {
+		return nil, err
+	}
+	return msg, nil
 }
 
-func (r *MessageRepository) List(ctx context.Context, roomID *int64, receiverID *int64) ([]*Message, error) {
-	query := `SELECT id, sender_id, room_id, receiver_id, content, created_at, updated_at 
-				FROM messages
-			WHERE ($1::int IS NULL OR room_id = $1)
-          	AND ($2::int IS NULL OR receiver_id = $2)
-        	ORDER BY created_at ASC
+// List returns the messages userID can read, optionally limited to a room
+// or a receiver.
+func (r *MessageRepository) List(ctx context.Context, userID int64, roomID *int64, receiverID *int64) ([]*Message, error) {
+	query := `SELECT ` + messageColumns + ` 
+				FROM messages m
+				LEFT JOIN rooms r ON r.id = m.room_id
+			WHERE ($2::int IS NULL OR m.room_id = $2)
+          	AND ($3::int IS NULL OR m.receiver_id = $3)
+          	AND ` + notExpired + `
+          	AND ` + readableBy("$1") + `
+        	ORDER BY m.created_at ASC
 `
-	rows, err := r.database.QueryContext(ctx, query, roomID, receiverID)
+	rows, err := r.database.QueryContext(ctx, query, userID, roomID, receiverID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
 	var messages []*Message
+	for rows.Next() {
+		msg, err := scanMessage(rows)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, msg)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if err := r.attachMentions(ctx, messages); err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// ResolveUsernames looks up users by case-insensitive username and returns
+// them keyed by lowercased username.
+func (r *MessageRepository) ResolveUsernames(ctx context.Context, usernames []string) (map[string]Mention, error) {
+	resolved := make(map[string]Mention)
+	if len(usernames) == 0 {
+		return resolved, nil
+	}
+	rows, err := r.database.QueryContext(ctx,
+		"SELECT id, username FROM users WHERE LOWER(username) = ANY($1)", pq.Array(usernames))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		var username string
+		if err := rows.Scan(&id, &username); err != nil {
+			return nil, err
+		}
+		resolved[strings.ToLower(username)] = Mention{Kind: MentionUser, UserID: &id, Username: username}
+	}
+	return resolved, rows.Err()
+}
+
+// SaveMentions replaces the message's mention spans and its recipients. Read
+// state is kept for users who are still mentioned. It returns the users who
+// were not mentioned by the message before.
+func (r *MessageRepository) SaveMentions(ctx context.Context, messageID int64, mentions []*Mention, recipients map[int64]string) ([]int64, error) {
+	tx, err := r.database.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM message_mentions WHERE message_id = $1", messageID); err != nil {
+		return nil, err
+	}
+	for _, m := range mentions {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO message_mentions (message_id, kind, user_id, username, start_pos, end_pos)
+			 VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6)`,
+			messageID, m.Kind, m.UserID, m.Username, m.Start, m.End)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	userIDs := make([]int64, 0, len(recipients))
+	for userID := range recipients {
+		userIDs = append(userIDs, userID)
+	}
+	_, err = tx.ExecContext(ctx,
+		"DELETE FROM user_mentions WHERE message_id = $1 AND NOT (user_id = ANY($2))",
+		messageID, pq.Array(userIDs))
+	if err != nil {
+		return nil, err
+	}
+
+	var added []int64
+	now := time.Now().UTC()
+	for userID, kind := range recipients {
+		var inserted bool
+		err := tx.QueryRowContext(ctx,
+			`INSERT INTO user_mentions (message_id, user_id, kind, created_at) VALUES ($1, $2, $3, $4)
+			 ON CONFLICT (message_id, user_id) DO UPDATE SET kind = EXCLUDED.kind
+			 RETURNING (xmax = 0)`,
+			messageID, userID, kind, now).Scan(&inserted)
+		if err != nil {
+			return nil, err
+		}
+		if inserted {
+			added = append(added, userID)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return added, nil
+}
+
+// attachMentions loads the mention spans of msgs in one query.
+func (r *MessageRepository) attachMentions(ctx context.Context, msgs []*Message) error {
+	if len(msgs) == 0 {
+		return nil
+	}
+	byID := make(map[int64]*Message, len(msgs))
+	ids := make([]int64, 0, len(msgs))
+	for _, msg := range msgs {
+		byID[msg.ID] = msg
+		ids = append(ids, msg.ID)
+	}
+
+	rows, err := r.database.QueryContext(ctx,
+		`SELECT message_id, kind, user_id, COALESCE(username, ''), start_pos, end_pos
+		 FROM message_mentions WHERE message_id = ANY($1) ORDER BY message_id, start_pos`,
+		pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var messageID int64
+		var m Mention
+		if err := rows.Scan(&messageID, &m.Kind, &m.UserID, &m.Username, &m.Start, &m.End); err != nil {
+			return err
+		}
+		if msg, ok := byID[messageID]; ok {
+			msg.Mentions = append(msg.Mentions, &m)
+		}
+	}
+	return rows.Err()
+}
+
+// ListMentions returns the messages that mentioned the user, newest first.
+// Access is re-checked: mentions in private rooms the user has since left are
+// skipped.
+func (r *MessageRepository) ListMentions(ctx context.Context, userID int64, unreadOnly bool, limit int, offset int) ([]*MentionedMessage, error) {
+	query := `SELECT ` + messageColumns + `, um.kind, um.read_at, um.created_at
+			  FROM user_mentions um
+			  JOIN messages m ON m.id = um.message_id
+			  LEFT JOIN rooms r ON r.id = m.room_id
+			  WHERE um.user_id = $1
+			  AND (NOT $2::boolean OR um.read_at IS NULL)
+			  AND ` + notExpired + `
+			  AND (m.room_id IS NULL OR NOT r.is_private OR EXISTS (
+				SELECT 1 FROM room_members rm WHERE rm.room_id = m.room_id AND rm.user_id = $1
+			  ))
+			  ORDER BY um.created_at DESC, m.id DESC
+			  LIMIT $3 OFFSET $4`
+	rows, err := r.database.QueryContext(ctx, query, userID, unreadOnly, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var mentioned []*MentionedMessage
+	var msgs []*Message
 	for rows.Next() {
 		var msg Message
+		entry := &MentionedMessage{Message: &msg}
 		err := rows.Scan(
 			&msg.ID,
 			&msg.SenderID,
 			&msg.RoomID,
 			&msg.ReceiverID,
 			&msg.Content,
+			&msg.DisplayName,
+			&msg.IconURL,
 			&msg.CreatedAt,
 			&msg.UpdatedAt,
+			&msg.ExpiresAt,
+			&msg.Pinned,
+			&entry.MentionKind,
+			&entry.ReadAt,
+			&entry.MentionedAt,
 		)
 		if err != nil {
 			return nil, err
 		}
-		messages = append(messages, &msg)
+		entry.Read = entry.ReadAt != nil
+		mentioned = append(mentioned, entry)
+		msgs = append(msgs, &msg)
 	}
-	return messages, nil
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if err := r.attachMentions(ctx, msgs); err != nil {
+		return nil, err
+	}
+	return mentioned, nil
+}
+
+// MarkMentionsRead marks one mention (messageID set) or all of the user's
+// unread mentions as read, returning how many changed.
+func (r *MessageRepository) MarkMentionsRead(ctx context.Context, userID int64, messageID *int64) (int64, error) {
+	res, err := r.database.ExecContext(ctx,
+		`UPDATE user_mentions SET read_at = $1
+		 WHERE user_id = $2 AND read_at IS NULL AND ($3::int IS NULL OR message_id = $3)`,
+		time.Now().UTC(), userID, messageID)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// Pin pins a message to its room unless the room already has maxPins pinned
+// messages. The room row is locked so concurrent pins can't exceed the cap.
+// It reports false if the message was already pinned.
+func (r *MessageRepository) Pin(ctx context.Context, roomID int64, messageID int64, pinnedBy int64, maxPins int) (bool, error) {
+	tx, err := r.database.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "SELECT id FROM rooms WHERE id = $1 FOR UPDATE", roomID); err != nil {
+		return false, err
+	}
+	var pinned bool
+	var count int
+	err = tx.QueryRowContext(ctx,
+		`SELECT COUNT(*), COALESCE(BOOL_OR(message_id = $2), FALSE) FROM pinned_messages WHERE room_id = $1`,
+		roomID, messageID).Scan(&count, &pinned)
+	if err != nil {
+		return false, err
+	}
+	if pinned {
+		return false, nil
+	}
+	if count >= maxPins {
+		return false, ErrPinLimitReached
+	}
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO pinned_messages (message_id, room_id, pinned_by, pinned_at) VALUES ($1, $2, $3, $4)",
+		messageID, roomID, pinnedBy, time.Now().UTC())
+	if err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// Unpin reports false if the message wasn't pinned to the room.
+func (r *MessageRepository) Unpin(ctx context.Context, roomID int64, messageID int64) (bool, error) {
+	res, err := r.database.ExecContext(ctx,
+		"DELETE FROM pinned_messages WHERE room_id = $1 AND message_id = $2", roomID, messageID)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected > 0, nil
+}
+
+// ListPins returns the room's pinned messages, most recently pinned first.
+func (r *MessageRepository) ListPins(ctx context.Context, roomID int64) ([]*PinnedMessage, error) {
+	query := `SELECT ` + messageColumns + `, p.pinned_by, u.username, p.pinned_at
+			  FROM pinned_messages p
+			  JOIN messages m ON m.id = p.message_id
+			  LEFT JOIN users u ON u.id = p.pinned_by
+			  WHERE p.room_id = $1 AND ` + notExpired + `
+			  ORDER BY p.pinned_at DESC, p.message_id DESC`
+	rows, err := r.database.QueryContext(ctx, query, roomID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	pins := []*PinnedMessage{}
+	var msgs []*Message
+	for rows.Next() {
+		var msg Message
+		pin := &PinnedMessage{Message: &msg}
+		err := rows.Scan(
+			&msg.ID,
+			&msg.SenderID,
+			&msg.RoomID,
+			&msg.ReceiverID,
+			&msg.Content,
+			&msg.DisplayName,
+			&msg.IconURL,
+			&msg.CreatedAt,
+			&msg.UpdatedAt,
+			&msg.ExpiresAt,
+			&msg.Pinned,
+			&pin.PinnedBy,
+			&pin.PinnedByUsername,
+			&pin.PinnedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		pins = append(pins, pin)
+		msgs = append(msgs, &msg)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if err := r.attachMentions(ctx, msgs); err != nil {
+		return nil, err
+	}
+	return pins, nil
+}
+
+// FindReadable returns the message if userID can read it, nil otherwise.
+func (r *MessageRepository) FindReadable(ctx context.Context, userID int64, messageID int64) (*Message, error) {
+	query := `SELECT ` + messageColumns + `
+			  FROM messages m
+			  LEFT JOIN rooms r ON r.id = m.room_id
+			  WHERE m.id = $2 AND ` + notExpired + ` AND ` + readableBy("$1")
+	msg, err := scanMessage(r.database.QueryRowContext(ctx, query, userID, messageID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return msg, nil
+}
+
+// SaveForUser bookmarks the message, replacing the note and reminder if it
+// was already saved. A changed reminder time is armed again. It reports
+// whether the bookmark is new.
+func (r *MessageRepository) SaveForUser(ctx context.Context, saved *SavedMessage) (bool, error) {
+	query := `INSERT INTO saved_messages (user_id, message_id, note, remind_at, created_at)
+			  VALUES ($1, $2, $3, $4, $5)
+			  ON CONFLICT (user_id, message_id) DO UPDATE SET
+				note = EXCLUDED.note,
+				remind_at = EXCLUDED.remind_at,
+				reminded_at = CASE WHEN saved_messages.remind_at IS DISTINCT FROM EXCLUDED.remind_at
+					THEN NULL ELSE saved_messages.reminded_at END
+			  RETURNING reminded_at, created_at, (xmax = 0)`
+	var inserted bool
+	err := r.database.QueryRowContext(ctx, query, saved.UserID, saved.ID, saved.Note, saved.RemindAt, time.Now().UTC()).
+		Scan(&saved.RemindedAt, &saved.SavedAt, &inserted)
+	return inserted, err
+}
+
+// Unsave reports false if the message wasn't saved.
+func (r *MessageRepository) Unsave(ctx context.Context, userID int64, messageID int64) (bool, error) {
+	res, err := r.database.ExecContext(ctx,
+		"DELETE FROM saved_messages WHERE user_id = $1 AND message_id = $2", userID, messageID)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected > 0, nil
+}
+
+// ListSaved returns the user's bookmarks, newest first. Bookmarks of
+// messages the user can no longer read are skipped but kept, so they come
+// back if the user rejoins the room.
+func (r *MessageRepository) ListSaved(ctx context.Context, userID int64, limit int, offset int) ([]*SavedMessage, error) {
+	query := `SELECT ` + messageColumns + `, s.user_id, s.note, s.remind_at, s.reminded_at, s.created_at
+			  FROM saved_messages s
+			  JOIN messages m ON m.id = s.message_id
+			  LEFT JOIN rooms r ON r.id = m.room_id
+			  WHERE s.user_id = $1 AND ` + notExpired + ` AND ` + readableBy("$1") + `
+			  ORDER BY s.created_at DESC, s.message_id DESC
+			  LIMIT $2 OFFSET $3`
+	rows, err := r.database.QueryContext(ctx, query, userID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	return r.scanSaved(ctx, rows)
+}
+
+// ClaimDueReminders marks up to limit due reminders as sent and returns the
+// ones whose message the user can still read. Rows locked by another
+// replica are skipped.
+func (r *MessageRepository) ClaimDueReminders(ctx context.Context, now time.Time, limit int) ([]*SavedMessage, error) {
+	query := `WITH due AS (
+				SELECT user_id, message_id FROM saved_messages
+				WHERE remind_at <= $1 AND reminded_at IS NULL
+				ORDER BY remind_at
+				LIMIT $2
+				FOR UPDATE SKIP LOCKED
+			  ), claimed AS (
+				UPDATE saved_messages s SET reminded_at = $1
+				FROM due
+				WHERE s.user_id = due.user_id AND s.message_id = due.message_id
+				RETURNING s.user_id, s.message_id, s.note, s.remind_at, s.reminded_at, s.created_at
+			  )
+			  SELECT ` + messageColumns + `, s.user_id, s.note, s.remind_at, s.reminded_at, s.created_at
+			  FROM claimed s
+			  JOIN messages m ON m.id = s.message_id
+			  LEFT JOIN rooms r ON r.id = m.room_id
+			  WHERE ` + notExpired + ` AND ` + readableBy("s.user_id")
+	rows, err := r.database.QueryContext(ctx, query, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	return r.scanSaved(ctx, rows)
+}
+
+func (r *MessageRepository) scanSaved(ctx context.Context, rows *sql.Rows) ([]*SavedMessage, error) {
+	defer rows.Close()
+
+	saved := []*SavedMessage{}
+	var msgs []*Message
+	for rows.Next() {
+		var msg Message
+		entry := &SavedMessage{Message: &msg}
+		err := rows.Scan(
+			&msg.ID,
+			&msg.SenderID,
+			&msg.RoomID,
+			&msg.ReceiverID,
+			&msg.Content,
+			&msg.DisplayName,
+			&msg.IconURL,
+			&msg.CreatedAt,
+			&msg.UpdatedAt,
+			&msg.ExpiresAt,
+			&msg.Pinned,
+			&entry.UserID,
+			&entry.Note,
+			&entry.RemindAt,
+			&entry.RemindedAt,
+			&entry.SavedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		saved = append(saved, entry)
+		msgs = append(msgs, &msg)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if err := r.attachMentions(ctx, msgs); err != nil {
+		return nil, err
+	}
+	return saved, nil
+}
+
+// DeleteExpired deletes up to limit messages that expired at or before now
+// and returns their ids and destinations. Messages under legal hold (their
+// room's or a participant's) are kept. Rows locked by another replica are
+// skipped.
+func (r *MessageRepository) DeleteExpired(ctx context.Context, now time.Time, limit int) ([]*Message, error) {
+	query := `DELETE FROM messages WHERE id IN (
+				SELECT m.id FROM messages m
+				LEFT JOIN rooms r ON r.id = m.room_id
+				WHERE m.expires_at <= $1
+				AND NOT COALESCE(r.legal_hold, FALSE)
+				AND NOT EXISTS (SELECT 1 FROM users h WHERE h.legal_hold AND h.id IN (m.sender_id, m.receiver_id))
+				ORDER BY m.expires_at
+				LIMIT $2
+				FOR UPDATE OF m SKIP LOCKED
+			  )
+			  RETURNING id, sender_id, room_id, receiver_id, expires_at`
+	rows, err := r.database.QueryContext(ctx, query, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deleted []*Message
+	for rows.Next() {
+		var msg Message
+		if err := rows.Scan(&msg.ID, &msg.SenderID, &msg.RoomID, &msg.ReceiverID, &msg.ExpiresAt); err != nil {
+			return nil, err
+		}
+		deleted = append(deleted, &msg)
+	}
+	return deleted, rows.Err()
 }
@@ -0,0 +1,66 @@
+package message
+
+import (
+	"fmt"
+	"github.com/maxwellzp/golang-chat-api/internal/apperr"
+	"github.com/maxwellzp/golang-chat-api/internal/httpx"
+	"github.com/maxwellzp/golang-chat-api/internal/room"
+	"regexp"
+	"time"
+	"unicode/utf8"
+)
+
+// ErrRoomNotFound is room.ErrRoomNotFound, for callers that only import
+// this package.
+var ErrRoomNotFound = room.ErrRoomNotFound
+
+var linkPattern = regexp.MustCompile(`(?i)\b(?:https?://|www\.)\S+`)
+
+// SlowModeError is returned when a user posts to a slow-mode room before
+// their cooldown has elapsed.
+type SlowModeError struct {
+	RetryAfter time.Duration
+}
+
+func (e *SlowModeError) Error() string {
+	return fmt.Sprintf("slow mode is enabled in this room, you can post again in %s",
+		e.RetryAfter.Round(time.Second))
+}
+
+func (e *SlowModeError) AppError() *apperr.Error {
+	return apperr.RateLimited("slow_mode", e.Error(), e.RetryAfter)
+}
+
+// FlaggedData is the payload of a message.flagged event.
+type FlaggedData struct {
+	Message *Message `json:"message"`
+	Filters []string `json:"filters"`
+}
+
+// MutedError is returned when a muted member posts to the room.
+type MutedError struct {
+	Until time.Time
+}
+
+func (e *MutedError) Error() string {
+	return fmt.Sprintf("you are muted in this room until %s", e.Until.Format(time.RFC3339))
+}
+
+func (e *MutedError) AppError() *apperr.Error {
+	return apperr.Forbidden("muted", e.Error())
+}
+
+// checkContentRules applies the room's length and link restrictions.
+func checkContentRules(rm *room.Room, content string) error {
+	if rm.MaxMessageLength > 0 && utf8.RuneCountInString(content) > rm.MaxMessageLength {
+		return httpx.ValidationErrorMap{
+			"content": fmt.Sprintf("content must be at most %d characters in this room", rm.MaxMessageLength),
+		}
+	}
+	if !rm.AllowLinks && linkPattern.MatchString(content) {
+		return httpx.ValidationErrorMap{
+			"content": "links are not allowed in this room",
+		}
+	}
+	return nil
+}
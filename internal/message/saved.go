@@ -0,0 +1,76 @@
+package message
+
+import (
+	"context"
+	"github.com/maxwellzp/golang-chat-api/internal/config"
+	"github.com/maxwellzp/golang-chat-api/internal/event"
+	"github.com/maxwellzp/golang-chat-api/internal/logger"
+	"time"
+)
+
+// SavedMessage is an entry in a user's bookmarks.
+type SavedMessage struct {
+	*Message
+	UserID     int64      `json:"-"`
+	Note       *string    `json:"note"`
+	RemindAt   *time.Time `json:"remind_at"`
+	RemindedAt *time.Time `json:"reminded_at"`
+	SavedAt    time.Time  `json:"saved_at"`
+}
+
+// ReminderWorker publishes a saved_message.reminder event for each bookmark
+// whose reminder is due. Several workers (one per replica) can run against
+// the same database.
+type ReminderWorker struct {
+	messageRepository *MessageRepository
+	bus               *event.Bus
+	cfg               config.MessageConfig
+	logger            *logger.Logger
+}
+
+func NewReminderWorker(messageRepository *MessageRepository, bus *event.Bus, cfg config.MessageConfig, logger *logger.Logger) *ReminderWorker {
+	return &ReminderWorker{
+		messageRepository: messageRepository,
+		bus:               bus,
+		cfg:               cfg,
+		logger:            logger,
+	}
+}
+
+// Run polls for due reminders until ctx is cancelled.
+func (w *ReminderWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.cfg.ReminderPollInterval)
+	defer ticker.Stop()
+
+	for {
+		w.SendDue(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// SendDue claims one batch of due reminders. Reminders for messages the
+// user can no longer read are claimed but not sent.
+func (w *ReminderWorker) SendDue(ctx context.Context) {
+	due, err := w.messageRepository.ClaimDueReminders(ctx, time.Now().UTC(), 100)
+	if err != nil {
+		if ctx.Err() == nil {
+			w.logger.Errorw("Failed to claim saved message reminders",
+				"error", err,
+			)
+		}
+		return
+	}
+	for _, saved := range due {
+		w.bus.Publish(ctx, event.Event{
+			Type:       event.SavedMessageReminder,
+			RoomID:     saved.RoomID,
+			ReceiverID: saved.ReceiverID,
+			ActorID:    saved.UserID,
+			Data:       saved,
+		})
+	}
+}
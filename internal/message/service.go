@@ -2,42 +2,575 @@ package message
 
 import (
 	"context"
+	"github.com/maxwellzp/golang-chat-api/internal/apperr"
+	"github.com/maxwellzp/golang-chat-api/internal/audit"
+	"github.com/maxwellzp/golang-chat-api/internal/command"
+	"github.com/maxwellzp/golang-chat-api/internal/config"
+	"github.com/maxwellzp/golang-chat-api/internal/contentfilter"
+	"github.com/maxwellzp/golang-chat-api/internal/event"
+	"github.com/maxwellzp/golang-chat-api/internal/room"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	ErrMessageNotFound = apperr.NotFound("message_not_found", "message not found in this room")
+	ErrNotPinned       = apperr.NotFound("not_pinned", "message is not pinned")
+	ErrNotSaved        = apperr.NotFound("not_saved", "message is not saved")
+	ErrNotSender       = apperr.NotFound("message_not_found", "message not found or not sent by you")
+	// ErrMessageNotReadable hides whether the message exists.
+	ErrMessageNotReadable = apperr.NotFound("message_not_found", "message not found")
+	ErrPinLimitReached    = apperr.Conflict("pin_limit_reached", "this room has reached its limit of pinned messages, unpin one first")
 )
 
 type MessageService struct {
 	messageRepository *MessageRepository
+	roomRepository    *room.RoomRepository
+	commands          *command.Registry
+	filters           *contentfilter.Chain
+	cfg               config.MessageConfig
+	audit             *audit.AuditService
+	bus               *event.Bus
 }
 
-func NewMessageService(messageRepository *MessageRepository) *MessageService {
-	return &MessageService{messageRepository: messageRepository}
+func NewMessageService(messageRepository *MessageRepository, roomRepository *room.RoomRepository, commands *command.Registry, filters *contentfilter.Chain, cfg config.MessageConfig, audit *audit.AuditService, bus *event.Bus) *MessageService {
+	return &MessageService{
+		messageRepository: messageRepository,
+		roomRepository:    roomRepository,
+		commands:          commands,
+		filters:           filters,
+		cfg:               cfg,
+		audit:             audit,
+		bus:               bus,
+	}
 }
 
+// Create posts a message. Content starting with a slash command is run
+// through the command registry first: the command may rewrite the content, or
+// answer with an ephemeral message that is returned to the sender but never
+// stored. A leading "//" escapes a literal slash. The content filters run
+// last, on what would be stored.
 func (ms *MessageService) Create(ctx context.Context, userID int64, req CreateMessageRequest) (*Message, error) {
+	// Access is checked before any command runs, so outsiders can't make
+	// the room's external commands send requests.
+	var rm *room.Room
+	if req.RoomID != nil {
+		var err error
+		if rm, err = ms.checkRoomAccess(ctx, *req.RoomID, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	if !req.Verbatim {
+		if name, args, ok := command.Parse(req.Content); ok {
+			resp, err := ms.commands.Execute(ctx, command.Invocation{
+				UserID:     userID,
+				RoomID:     req.RoomID,
+				ReceiverID: req.ReceiverID,
+				Name:       name,
+				Args:       args,
+			})
+			if err != nil {
+				return nil, err
+			}
+			if resp.Content == nil {
+				return &Message{
+					RoomID:     req.RoomID,
+					ReceiverID: req.ReceiverID,
+					Content:    resp.Text,
+					Ephemeral:  true,
+					CreatedAt:  time.Now().UTC(),
+					UpdatedAt:  time.Now().UTC(),
+				}, nil
+			}
+			req.Content = *resp.Content
+		} else if strings.HasPrefix(req.Content, "//") {
+			req.Content = req.Content[1:]
+		}
+	}
+
+	var cooldown time.Duration
+	if rm != nil {
+		if err := ms.checkRoomRules(ctx, rm, userID, req.Content); err != nil {
+			return nil, err
+		}
+		var err error
+		if cooldown, err = ms.slowModeCooldown(ctx, rm, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	filtered, err := ms.filters.Apply(ctx, req.RoomID, req.Content)
+	if err != nil {
+		return nil, err
+	}
+
 	msg := &Message{
-		SenderID:   userID,
-		RoomID:     req.RoomID,
-		ReceiverID: req.ReceiverID,
-		Content:    req.Content,
+		SenderID:    userID,
+		RoomID:      req.RoomID,
+		ReceiverID:  req.ReceiverID,
+		Content:     filtered.Content,
+		DisplayName: req.DisplayName,
+		IconURL:     req.IconURL,
+	}
+	if ttl := messageTTL(req.TTLSeconds, rm); ttl > 0 {
+		expiresAt := time.Now().UTC().Add(ttl)
+		msg.ExpiresAt = &expiresAt
 	}
 
-	if err := ms.messageRepository.Create(ctx, msg); err != nil {
+	if err := ms.messageRepository.Create(ctx, msg, cooldown); err != nil {
+		return nil, err
+	}
+	if err := ms.recordMentions(ctx, msg); err != nil {
 		return nil, err
 	}
+	ms.publish(ctx, event.MessageCreated, userID, msg)
+	ms.publishFlagged(ctx, userID, msg, filtered)
 	return msg, nil
 }
 
+// Update edits a message. Room messages go through the same access checks
+// and rules as new posts, so banned and muted users can't change what they
+// said; slow mode doesn't apply to edits.
 func (ms *MessageService) Update(ctx context.Context, id int64, userID int64, req UpdateMessageRequest) error {
-	return ms.messageRepository.Update(ctx, id, userID, req.Content)
+	existing, err := ms.messageRepository.GetByID(ctx, id, userID)
+	if err != nil {
+		return err
+	}
+	var roomID *int64
+	if existing != nil {
+		roomID = existing.RoomID
+	}
+	if roomID != nil {
+		rm, err := ms.checkRoomAccess(ctx, *roomID, userID)
+		if err != nil {
+			return err
+		}
+		if err := ms.checkRoomRules(ctx, rm, userID, req.Content); err != nil {
+			return err
+		}
+	}
+	filtered, err := ms.filters.Apply(ctx, roomID, req.Content)
+	if err != nil {
+		return err
+	}
+	if err := ms.messageRepository.Update(ctx, id, userID, filtered.Content); err != nil {
+		return err
+	}
+
+	updated, err := ms.messageRepository.GetByID(ctx, id, userID)
+	if err != nil {
+		return err
+	}
+	if updated != nil {
+		if err := ms.recordMentions(ctx, updated); err != nil {
+			return err
+		}
+		ms.publish(ctx, event.MessageUpdated, userID, updated)
+		ms.publishFlagged(ctx, userID, updated, filtered)
+	}
+	return nil
 }
 
 func (ms *MessageService) Delete(ctx context.Context, messageID, senderID int64) error {
-	return ms.messageRepository.Delete(ctx, messageID, senderID)
+	existing, err := ms.messageRepository.GetByID(ctx, messageID, senderID)
+	if err != nil {
+		return err
+	}
+	if err := ms.messageRepository.Delete(ctx, messageID, senderID); err != nil {
+		return err
+	}
+	if existing != nil {
+		ms.record(ctx, audit.ActionMessageDelete, senderID, existing)
+		ms.publish(ctx, event.MessageDeleted, senderID, existing)
+	}
+	return nil
+}
+
+// Remove deletes a message posted in the room on behalf of a moderator.
+func (ms *MessageService) Remove(ctx context.Context, roomID int64, messageID int64, actorID int64) error {
+	msg, err := ms.roomMessage(ctx, roomID, messageID, actorID)
+	if err != nil {
+		return err
+	}
+	if err := ms.messageRepository.Delete(ctx, msg.ID, msg.SenderID); err != nil {
+		return err
+	}
+	ms.record(ctx, audit.ActionMessageRemove, actorID, msg)
+	ms.publish(ctx, event.MessageDeleted, actorID, msg)
+	return nil
 }
 
 func (ms *MessageService) GetByID(ctx context.Context, messageID int64, senderID int64) (*Message, error) {
 	return ms.messageRepository.GetByID(ctx, messageID, senderID)
 }
 
-func (ms *MessageService) List(ctx context.Context, roomID *int64, receiverID *int64) ([]*Message, error) {
-	return ms.messageRepository.List(ctx, roomID, receiverID)
+// List returns the messages of a room or conversation that userID can read:
+// nothing from private rooms they aren't a member of, and only DMs they sent
+// or received.
+func (ms *MessageService) List(ctx context.Context, userID int64, roomID *int64, receiverID *int64) ([]*Message, error) {
+	return ms.messageRepository.List(ctx, userID, roomID, receiverID)
+}
+
+// Mentions lists the messages that mentioned the user, newest first.
+func (ms *MessageService) Mentions(ctx context.Context, userID int64, unreadOnly bool, limit int, offset int) ([]*MentionedMessage, error) {
+	return ms.messageRepository.ListMentions(ctx, userID, unreadOnly, limit, offset)
+}
+
+// MarkMentionsRead marks one mention, or all when messageID is nil, as read.
+func (ms *MessageService) MarkMentionsRead(ctx context.Context, userID int64, messageID *int64) (int64, error) {
+	return ms.messageRepository.MarkMentionsRead(ctx, userID, messageID)
+}
+
+// recordMentions resolves the mentions in msg.Content, stores them and
+// notifies newly mentioned users. Users are only notified if they can read
+// the message: the DM receiver, or room members (anyone for public rooms).
+// @here and @room notify every member of the room.
+func (ms *MessageService) recordMentions(ctx context.Context, msg *Message) error {
+	mentions := parseMentions(msg.Content)
+	if len(mentions) == 0 && len(msg.Mentions) == 0 {
+		return nil
+	}
+
+	var usernames []string
+	for _, m := range mentions {
+		if m.Kind == MentionUser {
+			usernames = append(usernames, strings.ToLower(m.Username))
+		}
+	}
+	users, err := ms.messageRepository.ResolveUsernames(ctx, usernames)
+	if err != nil {
+		return err
+	}
+
+	var rm *room.Room
+	members := make(map[int64]bool)
+	if msg.RoomID != nil && len(mentions) > 0 {
+		rm, err = ms.roomRepository.GetByID(ctx, *msg.RoomID)
+		if err != nil {
+			return err
+		}
+		list, err := ms.roomRepository.ListMembers(ctx, *msg.RoomID)
+		if err != nil {
+			return err
+		}
+		for _, member := range list {
+			members[member.UserID] = true
+		}
+	}
+
+	resolved := make([]*Mention, 0, len(mentions))
+	recipients := make(map[int64]string)
+	for _, m := range mentions {
+		if m.Kind != MentionUser {
+			continue
+		}
+		u, ok := users[strings.ToLower(m.Username)]
+		if !ok {
+			continue
+		}
+		m.UserID, m.Username = u.UserID, u.Username
+		resolved = append(resolved, m)
+
+		userID := *u.UserID
+		canRead := false
+		switch {
+		case msg.ReceiverID != nil:
+			canRead = userID == *msg.ReceiverID
+		case rm != nil:
+			canRead = !rm.IsPrivate || members[userID]
+		}
+		if canRead && userID != msg.SenderID {
+			recipients[userID] = MentionUser
+		}
+	}
+	for _, m := range mentions {
+		if m.Kind == MentionUser || rm == nil {
+			continue
+		}
+		resolved = append(resolved, m)
+		for userID := range members {
+			if _, ok := recipients[userID]; !ok && userID != msg.SenderID {
+				recipients[userID] = m.Kind
+			}
+		}
+	}
+	sort.Slice(resolved, func(i, j int) bool {
+		return resolved[i].Start < resolved[j].Start
+	})
+
+	added, err := ms.messageRepository.SaveMentions(ctx, msg.ID, resolved, recipients)
+	if err != nil {
+		return err
+	}
+	msg.Mentions = resolved
+
+	for _, userID := range added {
+		ms.bus.Publish(ctx, event.Event{
+			Type:       event.MentionCreated,
+			RoomID:     msg.RoomID,
+			ReceiverID: msg.ReceiverID,
+			ActorID:    msg.SenderID,
+			Data: MentionData{
+				UserID:  userID,
+				Kind:    recipients[userID],
+				Message: msg,
+			},
+		})
+	}
+	return nil
+}
+
+// Pin pins a message to its room. Moderators and above may pin; pinning an
+// already pinned message does nothing.
+func (ms *MessageService) Pin(ctx context.Context, roomID int64, messageID int64, actorID int64) error {
+	msg, err := ms.roomMessage(ctx, roomID, messageID, actorID)
+	if err != nil {
+		return err
+	}
+	pinned, err := ms.messageRepository.Pin(ctx, roomID, messageID, actorID, ms.cfg.MaxPinsPerRoom)
+	if err != nil {
+		return err
+	}
+	if pinned {
+		msg.Pinned = true
+		ms.record(ctx, audit.ActionMessagePin, actorID, msg)
+		ms.publish(ctx, event.MessagePinned, actorID, msg)
+	}
+	return nil
+}
+
+// Unpin removes a pin. Moderators and above may unpin.
+func (ms *MessageService) Unpin(ctx context.Context, roomID int64, messageID int64, actorID int64) error {
+	msg, err := ms.roomMessage(ctx, roomID, messageID, actorID)
+	if err != nil {
+		return err
+	}
+	unpinned, err := ms.messageRepository.Unpin(ctx, roomID, messageID)
+	if err != nil {
+		return err
+	}
+	if !unpinned {
+		return ErrNotPinned
+	}
+	msg.Pinned = false
+	ms.record(ctx, audit.ActionMessageUnpin, actorID, msg)
+	ms.publish(ctx, event.MessageUnpinned, actorID, msg)
+	return nil
+}
+
+// roomMessage checks that actorID moderates the room and returns the
+// message, which must have been posted there.
+func (ms *MessageService) roomMessage(ctx context.Context, roomID int64, messageID int64, actorID int64) (*Message, error) {
+	rm, err := ms.roomRepository.GetByID(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+	if rm == nil {
+		return nil, ErrRoomNotFound
+	}
+	role, err := ms.roomRepository.MemberRole(ctx, roomID, actorID)
+	if err != nil {
+		return nil, err
+	}
+	if !room.RoleAtLeast(role, room.RoleModerator) {
+		ms.audit.Record(ctx, audit.Entry{
+			Action:     audit.ActionPermDenied,
+			ActorID:    &actorID,
+			TargetType: audit.TargetRoom,
+			TargetID:   &roomID,
+			After:      map[string]any{"role": role, "required_role": room.RoleModerator, "message_id": messageID},
+		})
+		return nil, room.ErrForbidden
+	}
+
+	msg, err := ms.messageRepository.FindByID(ctx, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if msg == nil || msg.RoomID == nil || *msg.RoomID != roomID {
+		return nil, ErrMessageNotFound
+	}
+	return msg, nil
+}
+
+// Pins lists the room's pinned messages, most recently pinned first. Pins of
+// private rooms are only visible to members.
+func (ms *MessageService) Pins(ctx context.Context, roomID int64, userID int64) ([]*PinnedMessage, error) {
+	rm, err := ms.roomRepository.GetByID(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+	if rm == nil {
+		return nil, ErrRoomNotFound
+	}
+	if rm.IsPrivate {
+		role, err := ms.roomRepository.MemberRole(ctx, roomID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if role == "" {
+			return nil, room.ErrForbidden
+		}
+	}
+	return ms.messageRepository.ListPins(ctx, roomID)
+}
+
+// Save bookmarks a message the user can read. It reports whether the
+// bookmark is new.
+func (ms *MessageService) Save(ctx context.Context, userID int64, messageID int64, req SaveMessageRequest) (*SavedMessage, bool, error) {
+	msg, err := ms.messageRepository.FindReadable(ctx, userID, messageID)
+	if err != nil {
+		return nil, false, err
+	}
+	if msg == nil {
+		return nil, false, ErrMessageNotReadable
+	}
+	saved := &SavedMessage{
+		Message:  msg,
+		UserID:   userID,
+		Note:     req.Note,
+		RemindAt: req.RemindAt,
+	}
+	created, err := ms.messageRepository.SaveForUser(ctx, saved)
+	if err != nil {
+		return nil, false, err
+	}
+	return saved, created, nil
+}
+
+func (ms *MessageService) Unsave(ctx context.Context, userID int64, messageID int64) error {
+	removed, err := ms.messageRepository.Unsave(ctx, userID, messageID)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return ErrNotSaved
+	}
+	return nil
+}
+
+// Saved lists the user's bookmarks that they can still read, newest first.
+func (ms *MessageService) Saved(ctx context.Context, userID int64, limit int, offset int) ([]*SavedMessage, error) {
+	return ms.messageRepository.ListSaved(ctx, userID, limit, offset)
+}
+
+func (ms *MessageService) publish(ctx context.Context, eventType string, actorID int64, msg *Message) {
+	ms.bus.Publish(ctx, event.Event{
+		Type:       eventType,
+		RoomID:     msg.RoomID,
+		ReceiverID: msg.ReceiverID,
+		ActorID:    actorID,
+		Data:       msg,
+	})
+}
+
+// record writes a moderation or deletion of msg to the audit log. Message
+// content is left out, the log records who did what, not what was said.
+func (ms *MessageService) record(ctx context.Context, action string, actorID int64, msg *Message) {
+	ms.audit.Record(ctx, audit.Entry{
+		Action:     action,
+		ActorID:    &actorID,
+		TargetType: audit.TargetMessage,
+		TargetID:   &msg.ID,
+		Before: map[string]any{
+			"sender_id":   msg.SenderID,
+			"room_id":     msg.RoomID,
+			"receiver_id": msg.ReceiverID,
+		},
+	})
+}
+
+// publishFlagged asks for a review of the message if a filter flagged it.
+func (ms *MessageService) publishFlagged(ctx context.Context, actorID int64, msg *Message, filtered *contentfilter.Result) {
+	if len(filtered.Flagged) == 0 {
+		return
+	}
+	ms.bus.Publish(ctx, event.Event{
+		Type:       event.MessageFlagged,
+		RoomID:     msg.RoomID,
+		ReceiverID: msg.ReceiverID,
+		ActorID:    actorID,
+		Data:       &FlaggedData{Message: msg, Filters: filtered.Flagged},
+	})
+}
+
+// messageTTL returns the shorter of the requested TTL and the room's default,
+// or zero if neither is set.
+func messageTTL(requested *int, rm *room.Room) time.Duration {
+	seconds := 0
+	if requested != nil {
+		seconds = *requested
+	}
+	if rm != nil && rm.MessageTTLSeconds > 0 && (seconds == 0 || rm.MessageTTLSeconds < seconds) {
+		seconds = rm.MessageTTLSeconds
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// checkRoomAccess checks that userID may post in the room: it exists, they
+// are a member if it's private and they aren't banned. It returns the room.
+func (ms *MessageService) checkRoomAccess(ctx context.Context, roomID int64, userID int64) (*room.Room, error) {
+	rm, err := ms.roomRepository.GetByID(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+	if rm == nil {
+		return nil, ErrRoomNotFound
+	}
+	if rm.IsPrivate {
+		role, err := ms.roomRepository.MemberRole(ctx, roomID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if role == "" {
+			ms.audit.Record(ctx, audit.Entry{
+				Action:     audit.ActionPermDenied,
+				ActorID:    &userID,
+				TargetType: audit.TargetRoom,
+				TargetID:   &roomID,
+				After:      map[string]any{"role": role, "required_role": room.RoleMember},
+			})
+			return nil, room.ErrForbidden
+		}
+	}
+
+	banned, err := ms.roomRepository.IsBanned(ctx, roomID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if banned {
+		return nil, room.ErrBanned
+	}
+	return rm, nil
+}
+
+// checkRoomRules enforces mutes and the room's content restrictions.
+func (ms *MessageService) checkRoomRules(ctx context.Context, rm *room.Room, userID int64, content string) error {
+	mutedUntil, err := ms.roomRepository.MutedUntil(ctx, rm.ID, userID)
+	if err != nil {
+		return err
+	}
+	if mutedUntil != nil && mutedUntil.After(time.Now().UTC()) {
+		return &MutedError{Until: *mutedUntil}
+	}
+	return checkContentRules(rm, content)
+}
+
+// slowModeCooldown returns the room's slow mode cooldown for userID, or zero
+// if slow mode is off. Moderators and above are exempt. The cooldown itself
+// is claimed when the message is inserted.
+func (ms *MessageService) slowModeCooldown(ctx context.Context, rm *room.Room, userID int64) (time.Duration, error) {
+	if rm.SlowModeSeconds <= 0 {
+		return 0, nil
+	}
+	role, err := ms.roomRepository.MemberRole(ctx, rm.ID, userID)
+	if err != nil {
+		return 0, err
+	}
+	if room.RoleAtLeast(role, room.RoleModerator) {
+		return 0, nil
+	}
+	return time.Duration(rm.SlowModeSeconds) * time.Second, nil
 }
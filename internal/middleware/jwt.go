@@ -4,14 +4,30 @@ import (
 	"context"
 	"errors"
 	"github.com/golang-jwt/jwt/v4"
+	"github.com/maxwellzp/golang-chat-api/internal/apikey"
 	"github.com/maxwellzp/golang-chat-api/internal/contextkey"
 	"github.com/maxwellzp/golang-chat-api/internal/httpx"
 	"github.com/maxwellzp/golang-chat-api/internal/logger"
 	"net/http"
 	"strings"
+	"time"
 )
 
-func JWT(secret string, log *logger.Logger) func(http.Handler) http.Handler {
+type APIKeyAuthenticator interface {
+	Authenticate(ctx context.Context, key string) (userID int64, scopes []string, err error)
+}
+
+// SessionChecker rejects valid JWTs whose session has since been revoked,
+// and JWTs and API keys of accounts that were suspended or deleted.
+type SessionChecker interface {
+	CheckSession(ctx context.Context, userID int64, issuedAt time.Time) error
+	CheckAccount(ctx context.Context, userID int64) error
+}
+
+// JWT authenticates requests with either a JWT or an API key
+// ("Bearer ck_..."). API key requests also carry the key's scopes in the
+// context; see RequireScope.
+func JWT(secret string, apiKeys APIKeyAuthenticator, sessions SessionChecker, log *logger.Logger) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			authHeader := r.Header.Get("Authorization")
@@ -33,6 +49,33 @@ func JWT(secret string, log *logger.Logger) func(http.Handler) http.Handler {
 			}
 
 			tokenStr := parts[1]
+			if strings.HasPrefix(tokenStr, apikey.KeyPrefix) {
+				userID, scopes, err := apiKeys.Authenticate(r.Context(), tokenStr)
+				if err != nil {
+					log.Warnw("Invalid API key",
+						"error", err,
+					)
+					httpx.WriteError(w, http.StatusUnauthorized, "Invalid or revoked API key")
+					return
+				}
+				if err := sessions.CheckAccount(r.Context(), userID); err != nil {
+					log.Warnw("Rejected API key of inactive account",
+						"user_id", userID,
+						"error", err,
+					)
+					httpx.WriteError(w, http.StatusUnauthorized, "Invalid or revoked API key")
+					return
+				}
+				log.Infow("Authenticated request with API key",
+					"user_id", userID,
+					"path", r.URL.Path,
+				)
+				ctx := context.WithValue(r.Context(), contextkey.UserID, userID)
+				ctx = context.WithValue(ctx, contextkey.Scopes, scopes)
+				next.ServeHTTP(w, r.WithContext(ctx))
+				return
+			}
+
 			claims := jwt.MapClaims{}
 
 			token, err := jwt.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (interface{}, error) {
@@ -62,11 +105,45 @@ func JWT(secret string, log *logger.Logger) func(http.Handler) http.Handler {
 				httpx.WriteError(w, http.StatusUnauthorized, "Invalid user_id in token")
 				return
 			}
+			// Tokens issued before iat was added count as issued at the epoch
+			issuedAt, _ := claims["iat"].(float64)
+			if err := sessions.CheckSession(r.Context(), int64(userIDFloat), time.Unix(int64(issuedAt), 0)); err != nil {
+				log.Warnw("Rejected token of revoked session",
+					"user_id", int64(userIDFloat),
+					"error", err,
+				)
+				httpx.WriteError(w, http.StatusUnauthorized, "Invalid or expired token")
+				return
+			}
+			ctx := context.WithValue(r.Context(), contextkey.UserID, int64(userIDFloat))
+
+			// Impersonation tokens die with the admin's own session
+			if impersonatorFloat, ok := claims["impersonated_by"].(float64); ok {
+				impersonatorID := int64(impersonatorFloat)
+				if err := sessions.CheckSession(r.Context(), impersonatorID, time.Unix(int64(issuedAt), 0)); err != nil {
+					log.Warnw("Rejected impersonation token of revoked admin session",
+						"user_id", int64(userIDFloat),
+						"impersonated_by", impersonatorID,
+						"error", err,
+					)
+					httpx.WriteError(w, http.StatusUnauthorized, "Invalid or expired token")
+					return
+				}
+				log.Infow("Authenticated impersonated request",
+					"user_id", int64(userIDFloat),
+					"impersonated_by", impersonatorID,
+					"method", r.Method,
+					"path", r.URL.Path,
+				)
+				ctx = context.WithValue(ctx, contextkey.ImpersonatorID, impersonatorID)
+				next.ServeHTTP(w, r.WithContext(ctx))
+				return
+			}
+
 			log.Infow("Authenticated request",
 				"user_id", int64(userIDFloat),
 				"path", r.URL.Path,
 			)
-			ctx := context.WithValue(r.Context(), contextkey.UserID, int64(userIDFloat))
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
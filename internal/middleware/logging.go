@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/maxwellzp/golang-chat-api/internal/contextkey"
+	"github.com/maxwellzp/golang-chat-api/internal/httpx"
 	"github.com/maxwellzp/golang-chat-api/internal/logger"
 )
 
@@ -19,6 +20,12 @@ func (rw *responseWriter) WriteHeader(code int) {
 	rw.ResponseWriter.WriteHeader(code)
 }
 
+// Unwrap lets http.ResponseController reach the underlying writer, e.g. to
+// flush event streams.
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
 func Logging(log *logger.Logger) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -41,6 +48,7 @@ func Logging(log *logger.Logger) func(http.Handler) http.Handler {
 				"user_id", userID,
 				"user_agent", r.UserAgent(),
 				"ip", r.RemoteAddr,
+				"request_id", httpx.GetRequestID(r.Context()),
 				"duration", time.Since(start).String(),
 			)
 		})
@@ -0,0 +1,144 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"github.com/maxwellzp/golang-chat-api/internal/config"
+	"github.com/maxwellzp/golang-chat-api/internal/contextkey"
+	"github.com/maxwellzp/golang-chat-api/internal/httpx"
+	"github.com/maxwellzp/golang-chat-api/internal/logger"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type RateLimitResult struct {
+	Allowed    bool
+	Remaining  int
+	RetryAfter time.Duration
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+}
+
+// RateLimitStore holds token buckets. Use the PostgreSQL store when running
+// more than one replica so every instance draws from the same bucket.
+type RateLimitStore interface {
+	Take(ctx context.Context, key string, limit config.RateLimit, now time.Time) (RateLimitResult, error)
+}
+
+// RateLimit throttles requests with a token bucket per caller. Authenticated
+// requests are keyed by user ID, so it must run after JWT on protected
+// routes; anything else is keyed by client IP. scope keeps buckets of
+// different routes apart.
+func RateLimit(store RateLimitStore, scope string, limit config.RateLimit, log *logger.Logger) func(http.Handler) http.Handler {
+	policy := fmt.Sprintf("%d;w=%d", limit.Requests, int(limit.Period.Seconds()))
+	if limit.Burst != limit.Requests {
+		policy += ";burst=" + strconv.Itoa(limit.Burst)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := scope + ":ip:" + httpx.ClientIP(r)
+			if id, ok := r.Context().Value(contextkey.UserID).(int64); ok {
+				key = scope + ":user:" + strconv.FormatInt(id, 10)
+			}
+
+			res, err := store.Take(r.Context(), key, limit, time.Now().UTC())
+			if err != nil {
+				// Fail open: an unavailable store shouldn't take the API down.
+				log.Errorw("Rate limit store failed",
+					"key", key,
+					"error", err,
+				)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("RateLimit-Policy", policy)
+			w.Header().Set("RateLimit-Limit", strconv.Itoa(limit.Burst))
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+			w.Header().Set("RateLimit-Reset", strconv.Itoa(int(math.Ceil(res.Reset.Seconds()))))
+
+			if !res.Allowed {
+				log.Warnw("Rate limit exceeded",
+					"key", key,
+					"path", r.URL.Path,
+				)
+				httpx.SetRetryAfter(w, res.RetryAfter)
+				httpx.WriteError(w, http.StatusTooManyRequests, "Too many requests")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// takeToken refills a bucket that held tokens at last and tries to spend one.
+func takeToken(tokens float64, last, now time.Time, limit config.RateLimit) (float64, RateLimitResult) {
+	rate := float64(limit.Requests) / limit.Period.Seconds()
+	capacity := float64(limit.Burst)
+
+	if elapsed := now.Sub(last).Seconds(); elapsed > 0 {
+		tokens = math.Min(capacity, tokens+elapsed*rate)
+	}
+
+	res := RateLimitResult{}
+	if tokens >= 1 {
+		tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = time.Duration((1 - tokens) / rate * float64(time.Second))
+	}
+	res.Remaining = int(math.Floor(tokens))
+	res.Reset = time.Duration((capacity - tokens) / rate * float64(time.Second))
+	return tokens, res
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+type MemoryRateLimitStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{buckets: make(map[string]*bucket)}
+}
+
+func (s *MemoryRateLimitStore) Take(ctx context.Context, key string, limit config.RateLimit, now time.Time) (RateLimitResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sweep(now)
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), updated: now}
+		s.buckets[key] = b
+	}
+
+	tokens, res := takeToken(b.tokens, b.updated, now, limit)
+	b.tokens = tokens
+	b.updated = now
+	return res, nil
+}
+
+// sweep drops buckets that have been idle long enough to be full again, so
+// the map doesn't grow with every client ever seen.
+func (s *MemoryRateLimitStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+	s.lastSweep = now
+	for key, b := range s.buckets {
+		if now.Sub(b.updated) > time.Hour {
+			delete(s.buckets, key)
+		}
+	}
+}
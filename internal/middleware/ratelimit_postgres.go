@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"context"
+	"github.com/maxwellzp/golang-chat-api/internal/config"
+	"github.com/maxwellzp/golang-chat-api/internal/db"
+	"sync"
+	"time"
+)
+
+type PostgresRateLimitStore struct {
+	database  *db.Db
+	mu        sync.Mutex
+	lastSweep time.Time
+}
+
+func NewPostgresRateLimitStore(database *db.Db) *PostgresRateLimitStore {
+	return &PostgresRateLimitStore{database: database}
+}
+
+func (s *PostgresRateLimitStore) Take(ctx context.Context, key string, limit config.RateLimit, now time.Time) (RateLimitResult, error) {
+	s.sweep(ctx, now)
+
+	tx, err := s.database.BeginTx(ctx, nil)
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO rate_limit_buckets (key, tokens, updated_at) VALUES ($1, $2, $3)
+		ON CONFLICT (key) DO NOTHING;`, key, float64(limit.Burst), now)
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+
+	var tokens float64
+	var updated time.Time
+	err = tx.QueryRowContext(ctx,
+		"SELECT tokens, updated_at FROM rate_limit_buckets WHERE key = $1 FOR UPDATE", key).
+		Scan(&tokens, &updated)
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+
+	tokens, res := takeToken(tokens, updated, now, limit)
+	_, err = tx.ExecContext(ctx,
+		"UPDATE rate_limit_buckets SET tokens = $1, updated_at = $2 WHERE key = $3", tokens, now, key)
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return RateLimitResult{}, err
+	}
+	return res, nil
+}
+
+// sweep occasionally deletes buckets that have been idle for a day; they
+// would be full by now and are recreated on demand.
+func (s *PostgresRateLimitStore) sweep(ctx context.Context, now time.Time) {
+	s.mu.Lock()
+	if now.Sub(s.lastSweep) < 10*time.Minute {
+		s.mu.Unlock()
+		return
+	}
+	s.lastSweep = now
+	s.mu.Unlock()
+
+	_, _ = s.database.ExecContext(ctx,
+		"DELETE FROM rate_limit_buckets WHERE updated_at < $1", now.Add(-24*time.Hour))
+}
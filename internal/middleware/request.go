@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"context"
+	"github.com/maxwellzp/golang-chat-api/internal/contextkey"
+	"github.com/maxwellzp/golang-chat-api/internal/httpx"
+	"net/http"
+)
+
+// RequestInfo puts the client IP and User-Agent into the request context,
+// so services can record where a change came from, and echoes the request
+// ID in the X-Request-Id response header. Must run after chi's RequestID.
+func RequestInfo(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if id := httpx.GetRequestID(r.Context()); id != "" {
+			w.Header().Set("X-Request-Id", id)
+		}
+		ctx := context.WithValue(r.Context(), contextkey.ClientIP, httpx.ClientIP(r))
+		ctx = context.WithValue(ctx, contextkey.UserAgent, r.UserAgent())
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"github.com/maxwellzp/golang-chat-api/internal/httpx"
+	"github.com/maxwellzp/golang-chat-api/internal/logger"
+	"net/http"
+	"slices"
+)
+
+// RequireScope rejects API key requests whose key lacks scope, and audits
+// the denial. JWT sessions pass through unchanged. Must run after JWT.
+func RequireScope(scope string, recorder AuditRecorder, log *logger.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			scopes, ok := httpx.GetScopes(r.Context())
+			if ok && !slices.Contains(scopes, scope) {
+				log.Warnw("API key missing required scope",
+					"scope", scope,
+					"path", r.URL.Path,
+				)
+				if userID, err := httpx.GetUserID(r.Context()); err == nil {
+					recordDenied(r, recorder, userID, map[string]any{"scopes": scopes, "required_scope": scope})
+				}
+				httpx.WriteError(w, http.StatusForbidden, "API key lacks required scope: "+scope)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// SessionOnly rejects API key requests and requests made while impersonating
+// someone, for endpoints such as key management and account deletion that
+// must only be reachable by the signed-in user themselves. Must run after JWT.
+func SessionOnly(log *logger.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := httpx.GetScopes(r.Context()); ok {
+				log.Warnw("API key used on session-only endpoint",
+					"path", r.URL.Path,
+				)
+				httpx.WriteError(w, http.StatusForbidden, "This endpoint cannot be used with an API key")
+				return
+			}
+			if impersonatorID, ok := httpx.GetImpersonatorID(r.Context()); ok {
+				log.Warnw("Impersonation token used on session-only endpoint",
+					"impersonated_by", impersonatorID,
+					"path", r.URL.Path,
+				)
+				httpx.WriteError(w, http.StatusForbidden, "This endpoint cannot be used while impersonating")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
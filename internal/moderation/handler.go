@@ -0,0 +1,256 @@
+package moderation
+
+import (
+	"encoding/json"
+	"github.com/maxwellzp/golang-chat-api/internal/httpx"
+	"github.com/maxwellzp/golang-chat-api/internal/logger"
+	"github.com/maxwellzp/golang-chat-api/internal/validatorx"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+type ModerationHandler struct {
+	moderationService *ModerationService
+	validator         *validatorx.Validator
+	logger            *logger.Logger
+}
+
+func NewModerationHandler(moderationService *ModerationService, validator *validatorx.Validator, logger *logger.Logger) *ModerationHandler {
+	return &ModerationHandler{
+		moderationService: moderationService,
+		validator:         validator,
+		logger:            logger,
+	}
+}
+
+// Report flags a message for the moderators.
+func (h *ModerationHandler) Report() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := httpx.GetUserID(r.Context())
+		if err != nil {
+			h.logger.Warnw("Unauthorized request to report message")
+			httpx.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		messageID, err := httpx.ParseInt64Param(r, "id")
+		if err != nil {
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid MessageID")
+			return
+		}
+
+		var req CreateReportRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.logger.Warnw("Failed to decode CreateReportRequest",
+				"error", err,
+			)
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		if err := h.validator.Validate(&req); err != nil {
+			httpx.WriteValidationError(w, err)
+			return
+		}
+
+		rep, err := h.moderationService.Report(r.Context(), userID, messageID, req)
+		if httpx.WriteServiceError(w, err) {
+			return
+		}
+		if err != nil {
+			h.logger.Errorw("Failed to report message",
+				"error", err,
+				"message_id", messageID,
+				"user_id", userID,
+			)
+			httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
+			return
+		}
+		h.logger.Infow("Message reported",
+			"message_id", messageID,
+			"reason", rep.Reason,
+			"report_id", rep.ID,
+			"user_id", userID,
+		)
+		httpx.WriteJSON(w, http.StatusCreated, rep)
+	}
+}
+
+// ListReports is the review queue, oldest first. Filters: status, reason and
+// room_id.
+func (h *ModerationHandler) ListReports() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := httpx.GetUserID(r.Context())
+		if err != nil {
+			h.logger.Warnw("Unauthorized request to list reports")
+			httpx.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		limit, offset, err := httpx.ParsePagination(r, 50, 200)
+		if err != nil {
+			httpx.WriteValidationError(w, err)
+			return
+		}
+		query := r.URL.Query()
+		filter := ReportFilter{Status: query.Get("status"), Reason: query.Get("reason")}
+		if filter.Status != "" && !statuses[filter.Status] {
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid status filter")
+			return
+		}
+		if filter.Reason != "" && !reasons[filter.Reason] {
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid reason filter")
+			return
+		}
+		if filter.RoomID, err = parseIDFilter(query, "room_id"); err != nil {
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid room_id")
+			return
+		}
+
+		reports, err := h.moderationService.Reports(r.Context(), userID, filter, limit, offset)
+		if err != nil {
+			h.logger.Errorw("Failed to list reports",
+				"error", err,
+				"user_id", userID,
+			)
+			httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
+			return
+		}
+		httpx.WriteJSON(w, http.StatusOK, reports)
+	}
+}
+
+func (h *ModerationHandler) GetReport() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := httpx.GetUserID(r.Context())
+		if err != nil {
+			h.logger.Warnw("Unauthorized request to get report")
+			httpx.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		reportID, err := httpx.ParseInt64Param(r, "id")
+		if err != nil {
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid ReportID")
+			return
+		}
+
+		rep, err := h.moderationService.GetReport(r.Context(), userID, reportID)
+		if httpx.WriteServiceError(w, err) {
+			return
+		}
+		if err != nil {
+			h.logger.Errorw("Failed to get report",
+				"error", err,
+				"report_id", reportID,
+				"user_id", userID,
+			)
+			httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
+			return
+		}
+		httpx.WriteJSON(w, http.StatusOK, rep)
+	}
+}
+
+func (h *ModerationHandler) TakeAction() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := httpx.GetUserID(r.Context())
+		if err != nil {
+			h.logger.Warnw("Unauthorized request to act on report")
+			httpx.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		reportID, err := httpx.ParseInt64Param(r, "id")
+		if err != nil {
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid ReportID")
+			return
+		}
+
+		var req TakeActionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.logger.Warnw("Failed to decode TakeActionRequest",
+				"error", err,
+			)
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		if err := h.validator.Validate(&req); err != nil {
+			httpx.WriteValidationError(w, err)
+			return
+		}
+
+		action, err := h.moderationService.TakeAction(r.Context(), userID, reportID, req)
+		if httpx.WriteServiceError(w, err) {
+			return
+		}
+		if err != nil {
+			h.logger.Errorw("Failed to act on report",
+				"error", err,
+				"action", req.Action,
+				"report_id", reportID,
+				"user_id", userID,
+			)
+			httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
+			return
+		}
+		h.logger.Infow("Moderation action taken",
+			"action", action.Action,
+			"report_id", reportID,
+			"target_id", action.TargetUserID,
+			"user_id", userID,
+		)
+		httpx.WriteJSON(w, http.StatusCreated, action)
+	}
+}
+
+// ListActions is the moderation log, newest first. Filters: room_id,
+// report_id, moderator_id and target_user_id.
+func (h *ModerationHandler) ListActions() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := httpx.GetUserID(r.Context())
+		if err != nil {
+			h.logger.Warnw("Unauthorized request to list moderation actions")
+			httpx.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		limit, offset, err := httpx.ParsePagination(r, 50, 200)
+		if err != nil {
+			httpx.WriteValidationError(w, err)
+			return
+		}
+		query := r.URL.Query()
+		var filter ActionFilter
+		for name, dest := range map[string]**int64{
+			"room_id":        &filter.RoomID,
+			"report_id":      &filter.ReportID,
+			"moderator_id":   &filter.ModeratorID,
+			"target_user_id": &filter.TargetUserID,
+		} {
+			if *dest, err = parseIDFilter(query, name); err != nil {
+				httpx.WriteError(w, http.StatusBadRequest, "Invalid "+name)
+				return
+			}
+		}
+
+		actions, err := h.moderationService.Actions(r.Context(), userID, filter, limit, offset)
+		if err != nil {
+			h.logger.Errorw("Failed to list moderation actions",
+				"error", err,
+				"user_id", userID,
+			)
+			httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
+			return
+		}
+		httpx.WriteJSON(w, http.StatusOK, actions)
+	}
+}
+
+// parseIDFilter reads an optional ID from the query string.
+func parseIDFilter(query url.Values, name string) (*int64, error) {
+	raw := query.Get(name)
+	if raw == "" {
+		return nil, nil
+	}
+	id, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &id, nil
+}
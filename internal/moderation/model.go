@@ -0,0 +1,99 @@
+package moderation
+
+import "time"
+
+// Reasons a message can be reported for.
+const (
+	ReasonSpam       = "spam"
+	ReasonHarassment = "harassment"
+	ReasonHate       = "hate"
+	ReasonViolence   = "violence"
+	ReasonSexual     = "sexual"
+	ReasonSelfHarm   = "self_harm"
+	ReasonOther      = "other"
+	// ReasonFilter marks reports filed by a content filter. They have no
+	// reporter, and users can't pick the reason themselves.
+	ReasonFilter = "filter"
+)
+
+// Report statuses. A report stays open until a moderator dismisses it or
+// takes any other action on it.
+const (
+	StatusOpen      = "open"
+	StatusDismissed = "dismissed"
+	StatusActioned  = "actioned"
+)
+
+// Actions a moderator can take on a report.
+const (
+	ActionDismiss       = "dismiss"
+	ActionDeleteMessage = "delete_message"
+	ActionWarn          = "warn"
+	ActionMute          = "mute"
+	ActionBan           = "ban"
+)
+
+var reasons = map[string]bool{
+	ReasonSpam:       true,
+	ReasonHarassment: true,
+	ReasonHate:       true,
+	ReasonViolence:   true,
+	ReasonSexual:     true,
+	ReasonSelfHarm:   true,
+	ReasonOther:      true,
+	ReasonFilter:     true,
+}
+
+var statuses = map[string]bool{
+	StatusOpen:      true,
+	StatusDismissed: true,
+	StatusActioned:  true,
+}
+
+// Report is a user's flag on a message. Content is a copy of the message
+// taken when it was reported, so the report can still be reviewed after the
+// message is edited or deleted. RoomID is nil for direct messages.
+type Report struct {
+	ID         int64      `json:"id"`
+	MessageID  int64      `json:"message_id"`
+	RoomID     *int64     `json:"room_id"`
+	SenderID   *int64     `json:"sender_id"`
+	ReporterID *int64     `json:"reporter_id"`
+	Reason     string     `json:"reason"`
+	Details    *string    `json:"details"`
+	Content    string     `json:"content"`
+	Status     string     `json:"status"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ResolvedBy *int64     `json:"resolved_by"`
+	ResolvedAt *time.Time `json:"resolved_at"`
+}
+
+// Action is an entry of the moderation log. Entries are never changed or
+// removed.
+type Action struct {
+	ID           int64      `json:"id"`
+	ReportID     *int64     `json:"report_id"`
+	RoomID       *int64     `json:"room_id"`
+	ModeratorID  int64      `json:"moderator_id"`
+	Action       string     `json:"action"`
+	TargetUserID *int64     `json:"target_user_id"`
+	MessageID    *int64     `json:"message_id"`
+	Note         *string    `json:"note"`
+	MutedUntil   *time.Time `json:"muted_until,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// ReportFilter narrows the review queue. Empty fields match every report.
+type ReportFilter struct {
+	Status string
+	Reason string
+	RoomID *int64
+}
+
+// ActionFilter narrows the moderation log. Nil fields match every entry.
+type ActionFilter struct {
+	RoomID       *int64
+	ReportID     *int64
+	ModeratorID  *int64
+	TargetUserID *int64
+}
@@ -0,0 +1,14 @@
+package moderation
+
+type CreateReportRequest struct {
+	Reason  string  `json:"reason" validate:"required,oneof=spam harassment hate violence sexual self_harm other"`
+	Details *string `json:"details" validate:"omitempty,max=1000"`
+}
+
+// TakeActionRequest acts on a report. DurationMinutes only applies to mutes
+// and defaults to an hour.
+type TakeActionRequest struct {
+	Action          string  `json:"action" validate:"required,oneof=dismiss delete_message warn mute ban"`
+	Note            *string `json:"note" validate:"omitempty,max=500"`
+	DurationMinutes int     `json:"duration_minutes" validate:"omitempty,min=1,max=10080"`
+}
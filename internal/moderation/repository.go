@@ -0,0 +1,193 @@
+package moderation
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/lib/pq"
+	"github.com/maxwellzp/golang-chat-api/internal/db"
+	"github.com/maxwellzp/golang-chat-api/internal/room"
+	"time"
+)
+
+// moderatorRoles are the room roles that can see and act on a room's reports.
+var moderatorRoles = pq.Array([]string{room.RoleOwner, room.RoleAdmin, room.RoleModerator})
+
+type ModerationRepository struct {
+	database *db.Db
+}
+
+func NewModerationRepository(database *db.Db) *ModerationRepository {
+	return &ModerationRepository{database: database}
+}
+
+const reportColumns = `id, message_id, room_id, sender_id, reporter_id, reason, details, content, status,
+			created_at, resolved_by, resolved_at`
+
+const actionColumns = `id, report_id, room_id, moderator_id, action, target_user_id, message_id, note,
+			muted_until, created_at`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanReport(row rowScanner) (*Report, error) {
+	var rep Report
+	err := row.Scan(&rep.ID, &rep.MessageID, &rep.RoomID, &rep.SenderID, &rep.ReporterID, &rep.Reason,
+		&rep.Details, &rep.Content, &rep.Status, &rep.CreatedAt, &rep.ResolvedBy, &rep.ResolvedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &rep, nil
+}
+
+func scanAction(row rowScanner) (*Action, error) {
+	var a Action
+	err := row.Scan(&a.ID, &a.ReportID, &a.RoomID, &a.ModeratorID, &a.Action, &a.TargetUserID, &a.MessageID,
+		&a.Note, &a.MutedUntil, &a.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// CreateReport stores the report. It reports false, without error, if the
+// reporter already reported the message.
+func (r *ModerationRepository) CreateReport(ctx context.Context, rep *Report) (bool, error) {
+	err := r.database.QueryRowContext(ctx,
+		`INSERT INTO reports (message_id, room_id, sender_id, reporter_id, reason, details, content, status, created_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		 ON CONFLICT (message_id, reporter_id) DO NOTHING
+		 RETURNING id`,
+		rep.MessageID, rep.RoomID, rep.SenderID, rep.ReporterID, rep.Reason, rep.Details, rep.Content,
+		rep.Status, rep.CreatedAt).Scan(&rep.ID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (r *ModerationRepository) FindReport(ctx context.Context, id int64) (*Report, error) {
+	rep, err := scanReport(r.database.QueryRowContext(ctx,
+		`SELECT `+reportColumns+` FROM reports WHERE id = $1`, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return rep, nil
+}
+
+// ListReports returns the reports matching the filter, oldest first. With
+// moderatorID set, only reports from rooms that user moderates are listed.
+func (r *ModerationRepository) ListReports(ctx context.Context, filter ReportFilter, moderatorID *int64, limit int, offset int) ([]*Report, error) {
+	rows, err := r.database.QueryContext(ctx,
+		`SELECT `+reportColumns+`
+		 FROM reports
+		 WHERE ($1 = '' OR status = $1)
+		 AND ($2 = '' OR reason = $2)
+		 AND ($3::int IS NULL OR room_id = $3)
+		 AND ($4::int IS NULL OR room_id IN (
+		     SELECT room_id FROM room_members WHERE user_id = $4 AND role = ANY($5)))
+		 ORDER BY created_at, id
+		 LIMIT $6 OFFSET $7`,
+		filter.Status, filter.Reason, filter.RoomID, moderatorID, moderatorRoles, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reports := []*Report{}
+	for rows.Next() {
+		rep, err := scanReport(rows)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, rep)
+	}
+	return reports, rows.Err()
+}
+
+// Resolve logs the action and sets the report's status in one transaction.
+// With closeSiblings set, the other open reports of the same message are
+// marked actioned too.
+func (r *ModerationRepository) Resolve(ctx context.Context, rep *Report, action *Action, status string, closeSiblings bool) error {
+	tx, err := r.database.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+	err = tx.QueryRowContext(ctx,
+		`INSERT INTO moderation_actions (report_id, room_id, moderator_id, action, target_user_id, message_id,
+			note, muted_until, created_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		 RETURNING id, created_at`,
+		action.ReportID, action.RoomID, action.ModeratorID, action.Action, action.TargetUserID, action.MessageID,
+		action.Note, action.MutedUntil, now).Scan(&action.ID, &action.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"UPDATE reports SET status = $1, resolved_by = $2, resolved_at = $3 WHERE id = $4",
+		status, action.ModeratorID, now, rep.ID)
+	if err != nil {
+		return err
+	}
+	if closeSiblings {
+		_, err = tx.ExecContext(ctx,
+			`UPDATE reports SET status = $1, resolved_by = $2, resolved_at = $3
+			 WHERE message_id = $4 AND status = $5`,
+			StatusActioned, action.ModeratorID, now, rep.MessageID, StatusOpen)
+		if err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	rep.Status = status
+	rep.ResolvedBy = &action.ModeratorID
+	rep.ResolvedAt = &now
+	return nil
+}
+
+// ListActions returns the moderation log matching the filter, newest first.
+// With moderatorID set, only entries from rooms that user moderates are
+// listed.
+func (r *ModerationRepository) ListActions(ctx context.Context, filter ActionFilter, moderatorID *int64, limit int, offset int) ([]*Action, error) {
+	rows, err := r.database.QueryContext(ctx,
+		`SELECT `+actionColumns+`
+		 FROM moderation_actions
+		 WHERE ($1::int IS NULL OR room_id = $1)
+		 AND ($2::int IS NULL OR report_id = $2)
+		 AND ($3::int IS NULL OR moderator_id = $3)
+		 AND ($4::int IS NULL OR target_user_id = $4)
+		 AND ($5::int IS NULL OR room_id IN (
+		     SELECT room_id FROM room_members WHERE user_id = $5 AND role = ANY($6)))
+		 ORDER BY created_at DESC, id DESC
+		 LIMIT $7 OFFSET $8`,
+		filter.RoomID, filter.ReportID, filter.ModeratorID, filter.TargetUserID, moderatorID, moderatorRoles,
+		limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	actions := []*Action{}
+	for rows.Next() {
+		a, err := scanAction(rows)
+		if err != nil {
+			return nil, err
+		}
+		actions = append(actions, a)
+	}
+	return actions, rows.Err()
+}
@@ -0,0 +1,261 @@
+package moderation
+
+import (
+	"context"
+	"github.com/maxwellzp/golang-chat-api/internal/apperr"
+	"github.com/maxwellzp/golang-chat-api/internal/event"
+	"github.com/maxwellzp/golang-chat-api/internal/logger"
+	"github.com/maxwellzp/golang-chat-api/internal/message"
+	"github.com/maxwellzp/golang-chat-api/internal/notification"
+	"github.com/maxwellzp/golang-chat-api/internal/room"
+	"github.com/maxwellzp/golang-chat-api/internal/user"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrReportNotFound also hides reports the user can't moderate.
+	ErrReportNotFound  = apperr.NotFound("report_not_found", "report not found")
+	ErrOwnMessage      = apperr.Conflict("own_message", "you can't report your own message")
+	ErrAlreadyReported = apperr.Conflict("already_reported", "you already reported this message")
+	ErrReportClosed    = apperr.Conflict("report_closed", "report was already resolved")
+	ErrRoomOnly        = apperr.Conflict("room_only", "this action only applies to messages posted in rooms")
+	ErrSenderGone      = apperr.Conflict("sender_gone", "the sender of the reported message no longer exists")
+)
+
+const defaultMuteDuration = time.Hour
+
+// ModerationService handles message reports. Room moderators review the
+// reports of the rooms they moderate; global admins review every report,
+// including those on direct messages. Actions go through the room and
+// message services, so they are subject to the same role checks as
+// everywhere else, and each one is written to the moderation log.
+type ModerationService struct {
+	moderationRepository *ModerationRepository
+	messageRepository    *message.MessageRepository
+	messageService       *message.MessageService
+	roomService          *room.RoomService
+	notificationService  *notification.NotificationService
+	userRepository       *user.UserRepository
+	logger               *logger.Logger
+}
+
+func NewModerationService(
+	moderationRepository *ModerationRepository,
+	messageRepository *message.MessageRepository,
+	messageService *message.MessageService,
+	roomService *room.RoomService,
+	notificationService *notification.NotificationService,
+	userRepository *user.UserRepository,
+	logger *logger.Logger,
+) *ModerationService {
+	return &ModerationService{
+		moderationRepository: moderationRepository,
+		messageRepository:    messageRepository,
+		messageService:       messageService,
+		roomService:          roomService,
+		notificationService:  notificationService,
+		userRepository:       userRepository,
+		logger:               logger,
+	}
+}
+
+// Report flags a message the reporter can read. Each user can report a
+// message once.
+func (s *ModerationService) Report(ctx context.Context, reporterID int64, messageID int64, req CreateReportRequest) (*Report, error) {
+	msg, err := s.messageRepository.FindReadable(ctx, reporterID, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if msg == nil {
+		return nil, message.ErrMessageNotReadable
+	}
+	if msg.SenderID == reporterID {
+		return nil, ErrOwnMessage
+	}
+
+	rep := &Report{
+		MessageID:  msg.ID,
+		RoomID:     msg.RoomID,
+		SenderID:   &msg.SenderID,
+		ReporterID: &reporterID,
+		Reason:     req.Reason,
+		Details:    req.Details,
+		Content:    msg.Content,
+		Status:     StatusOpen,
+		CreatedAt:  time.Now().UTC(),
+	}
+	created, err := s.moderationRepository.CreateReport(ctx, rep)
+	if err != nil {
+		return nil, err
+	}
+	if !created {
+		return nil, ErrAlreadyReported
+	}
+	return rep, nil
+}
+
+// HandleEvent files a report for messages flagged by a content filter. It is
+// registered on the event bus.
+func (s *ModerationService) HandleEvent(ctx context.Context, e event.Event) {
+	if e.Type != event.MessageFlagged {
+		return
+	}
+	data, ok := e.Data.(*message.FlaggedData)
+	if !ok {
+		return
+	}
+	details := "Flagged by the " + strings.Join(data.Filters, ", ") + " filter"
+	rep := &Report{
+		MessageID: data.Message.ID,
+		RoomID:    data.Message.RoomID,
+		SenderID:  &data.Message.SenderID,
+		Reason:    ReasonFilter,
+		Details:   &details,
+		Content:   data.Message.Content,
+		Status:    StatusOpen,
+		CreatedAt: time.Now().UTC(),
+	}
+	if _, err := s.moderationRepository.CreateReport(ctx, rep); err != nil {
+		s.logger.Errorw("Failed to report flagged message",
+			"message_id", data.Message.ID,
+			"error", err,
+		)
+	}
+}
+
+// Reports lists the reports userID may review.
+func (s *ModerationService) Reports(ctx context.Context, userID int64, filter ReportFilter, limit int, offset int) ([]*Report, error) {
+	moderatorID, err := s.scope(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.moderationRepository.ListReports(ctx, filter, moderatorID, limit, offset)
+}
+
+func (s *ModerationService) GetReport(ctx context.Context, userID int64, reportID int64) (*Report, error) {
+	return s.report(ctx, userID, reportID)
+}
+
+// TakeAction applies the action to the report's message or its sender and
+// logs it. Dismissing needs an open report; any other action marks the
+// report actioned, and deleting the message also closes the other reports
+// about it.
+func (s *ModerationService) TakeAction(ctx context.Context, userID int64, reportID int64, req TakeActionRequest) (*Action, error) {
+	rep, err := s.report(ctx, userID, reportID)
+	if err != nil {
+		return nil, err
+	}
+
+	action := &Action{
+		ReportID:    &rep.ID,
+		RoomID:      rep.RoomID,
+		ModeratorID: userID,
+		Action:      req.Action,
+		MessageID:   &rep.MessageID,
+		Note:        req.Note,
+	}
+	if req.Action != ActionDismiss && req.Action != ActionDeleteMessage {
+		if rep.SenderID == nil {
+			return nil, ErrSenderGone
+		}
+		action.TargetUserID = rep.SenderID
+	}
+	if rep.RoomID == nil && (req.Action == ActionDeleteMessage || req.Action == ActionMute || req.Action == ActionBan) {
+		return nil, ErrRoomOnly
+	}
+
+	status := StatusActioned
+	closeSiblings := false
+	switch req.Action {
+	case ActionDismiss:
+		if rep.Status != StatusOpen {
+			return nil, ErrReportClosed
+		}
+		status = StatusDismissed
+	case ActionDeleteMessage:
+		if err := s.messageService.Remove(ctx, *rep.RoomID, rep.MessageID, userID); err != nil {
+			return nil, err
+		}
+		action.TargetUserID = rep.SenderID
+		closeSiblings = true
+	case ActionWarn:
+		note := ""
+		if req.Note != nil {
+			note = *req.Note
+		}
+		if err := s.notificationService.Warn(ctx, *rep.SenderID, userID, rep.RoomID, note); err != nil {
+			return nil, err
+		}
+	case ActionMute:
+		duration := defaultMuteDuration
+		if req.DurationMinutes > 0 {
+			duration = time.Duration(req.DurationMinutes) * time.Minute
+		}
+		until := time.Now().UTC().Add(duration)
+		if err := s.roomService.Mute(ctx, *rep.RoomID, userID, *rep.SenderID, &until); err != nil {
+			return nil, err
+		}
+		action.MutedUntil = &until
+	case ActionBan:
+		if err := s.roomService.Ban(ctx, *rep.RoomID, userID, *rep.SenderID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.moderationRepository.Resolve(ctx, rep, action, status, closeSiblings); err != nil {
+		return nil, err
+	}
+	return action, nil
+}
+
+// Actions lists the moderation log entries userID may see.
+func (s *ModerationService) Actions(ctx context.Context, userID int64, filter ActionFilter, limit int, offset int) ([]*Action, error) {
+	moderatorID, err := s.scope(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.moderationRepository.ListActions(ctx, filter, moderatorID, limit, offset)
+}
+
+// report returns the report if userID may review it.
+func (s *ModerationService) report(ctx context.Context, userID int64, reportID int64) (*Report, error) {
+	rep, err := s.moderationRepository.FindReport(ctx, reportID)
+	if err != nil {
+		return nil, err
+	}
+	if rep == nil {
+		return nil, ErrReportNotFound
+	}
+	moderatorID, err := s.scope(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if moderatorID == nil {
+		return rep, nil
+	}
+	if rep.RoomID == nil {
+		return nil, ErrReportNotFound
+	}
+	role, err := s.roomService.MemberRole(ctx, *rep.RoomID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !room.RoleAtLeast(role, room.RoleModerator) {
+		return nil, ErrReportNotFound
+	}
+	return rep, nil
+}
+
+// scope returns nil for global admins, who see everything, and userID
+// otherwise, limiting them to the rooms they moderate.
+func (s *ModerationService) scope(ctx context.Context, userID int64) (*int64, error) {
+	role, err := s.userRepository.Role(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if role == user.RoleAdmin {
+		return nil, nil
+	}
+	return &userID, nil
+}
@@ -0,0 +1,118 @@
+package netguard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+var (
+	ErrInvalidURL     = errors.New("url must be an absolute http or https URL")
+	ErrPrivateAddress = errors.New("url must not point to a private, loopback or link-local address")
+)
+
+// blocked lists ranges that aren't covered by the netip predicates but are
+// still not on the public internet.
+var blocked = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+}
+
+// Guard keeps outgoing requests to user supplied URLs (webhooks, external
+// commands, notification endpoints) away from the internal network and
+// cloud metadata services. Addresses are checked when a URL is saved and
+// again on every connection, so a host that later resolves to a private
+// address is still refused.
+type Guard struct {
+	allowed []netip.Prefix
+}
+
+// New creates a guard. allowed lists networks in CIDR notation that are let
+// through anyway, such as 127.0.0.1/32 for a local test receiver.
+func New(allowed []string) (*Guard, error) {
+	g := &Guard{}
+	for _, raw := range allowed {
+		prefix, err := netip.ParsePrefix(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid allowed network %q: %w", raw, err)
+		}
+		g.allowed = append(g.allowed, prefix.Masked())
+	}
+	return g, nil
+}
+
+// CheckURL checks that rawURL is an absolute http or https URL whose host
+// only resolves to public addresses.
+func (g *Guard) CheckURL(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return ErrInvalidURL
+	}
+	host := u.Hostname()
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return g.check(addr)
+	}
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return fmt.Errorf("could not resolve %s", host)
+	}
+	for _, addr := range addrs {
+		if err := g.check(addr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Control is a net.Dialer.Control hook that refuses connections to
+// addresses CheckURL would reject. It runs after DNS resolution, so it also
+// stops DNS rebinding.
+func (g *Guard) Control(network string, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	return g.check(addrPort.Addr())
+}
+
+// Client returns an HTTP client whose connections go through Control. It
+// ignores proxy settings, which would otherwise hide the real destination
+// from the check.
+func (g *Guard) Client(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   g.Control,
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
+
+func (g *Guard) check(addr netip.Addr) error {
+	addr = addr.Unmap()
+	for _, prefix := range g.allowed {
+		if prefix.Contains(addr) {
+			return nil
+		}
+	}
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return ErrPrivateAddress
+	}
+	for _, prefix := range blocked {
+		if prefix.Contains(addr) {
+			return ErrPrivateAddress
+		}
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package netguard
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestCheckURL(t *testing.T) {
+	guard, err := New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		url     string
+		wantErr error
+	}{
+		{url: "https://93.184.216.34/hook", wantErr: nil},
+		{url: "https://[2606:4700::1111]/hook", wantErr: nil},
+		{url: "ftp://93.184.216.34/hook", wantErr: ErrInvalidURL},
+		{url: "/relative", wantErr: ErrInvalidURL},
+		{url: "http://127.0.0.1:8080/", wantErr: ErrPrivateAddress},
+		{url: "http://[::1]/", wantErr: ErrPrivateAddress},
+		{url: "http://10.0.0.5/", wantErr: ErrPrivateAddress},
+		{url: "http://172.16.0.1/", wantErr: ErrPrivateAddress},
+		{url: "http://192.168.1.1/", wantErr: ErrPrivateAddress},
+		{url: "http://169.254.169.254/latest/meta-data/", wantErr: ErrPrivateAddress},
+		{url: "http://[fe80::1]/", wantErr: ErrPrivateAddress},
+		{url: "http://[fd00::1]/", wantErr: ErrPrivateAddress},
+		{url: "http://[::ffff:127.0.0.1]/", wantErr: ErrPrivateAddress},
+		{url: "http://0.0.0.0/", wantErr: ErrPrivateAddress},
+		{url: "http://100.64.0.1/", wantErr: ErrPrivateAddress},
+	}
+	for _, tt := range tests {
+		if err := guard.CheckURL(context.Background(), tt.url); !errors.Is(err, tt.wantErr) {
+			t.Errorf("CheckURL(%q) = %v, want %v", tt.url, err, tt.wantErr)
+		}
+	}
+}
+
+func TestAllowedNetworks(t *testing.T) {
+	guard, err := New([]string{"127.0.0.1/32"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := guard.CheckURL(context.Background(), "http://127.0.0.1:8080/"); err != nil {
+		t.Errorf("allowed address rejected: %v", err)
+	}
+	if err := guard.CheckURL(context.Background(), "http://127.0.0.2/"); !errors.Is(err, ErrPrivateAddress) {
+		t.Errorf("address outside the allowlist: got %v", err)
+	}
+	if _, err := New([]string{"not-a-network"}); err == nil {
+		t.Error("expected an invalid network to be rejected")
+	}
+}
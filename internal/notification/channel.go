@@ -0,0 +1,112 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/maxwellzp/golang-chat-api/internal/mailer"
+	"github.com/maxwellzp/golang-chat-api/internal/user"
+	"github.com/maxwellzp/golang-chat-api/internal/webhook"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Channel delivers a batch of notifications to one user. A batch holds a
+// single notification unless the user asked for digests.
+type Channel interface {
+	Name() string
+	// Enabled reports whether the user wants deliveries on this channel.
+	Enabled(prefs *Preferences) bool
+	Send(ctx context.Context, u *user.User, prefs *Preferences, batch []*Notification) error
+}
+
+// EmailChannel sends notifications through a mailer.Mailer.
+type EmailChannel struct {
+	mailer mailer.Mailer
+}
+
+func NewEmailChannel(mailer mailer.Mailer) *EmailChannel {
+	return &EmailChannel{mailer: mailer}
+}
+
+func (c *EmailChannel) Name() string { return "email" }
+
+func (c *EmailChannel) Enabled(prefs *Preferences) bool { return prefs.EmailEnabled }
+
+func (c *EmailChannel) Send(ctx context.Context, u *user.User, prefs *Preferences, batch []*Notification) error {
+	subject := batch[0].Title
+	if len(batch) > 1 {
+		subject = fmt.Sprintf("You have %d new notifications", len(batch))
+	}
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "Hi %s,\n\n", u.Username)
+	for _, n := range batch {
+		body.WriteString(n.Title)
+		body.WriteString("\n")
+		if n.Body != "" {
+			body.WriteString("  ")
+			body.WriteString(n.Body)
+			body.WriteString("\n")
+		}
+		body.WriteString("\n")
+	}
+	body.WriteString("You can change how you are notified in your notification preferences.\n")
+
+	return c.mailer.Send(ctx, mailer.Message{
+		To:      u.Email,
+		Subject: subject,
+		Body:    body.String(),
+	})
+}
+
+// WebhookChannel POSTs notifications to the user's own webhook URL. Requests
+// are signed like room webhooks, with the X-Notification-Signature header.
+type WebhookChannel struct {
+	client *http.Client
+}
+
+func NewWebhookChannel(client *http.Client) *WebhookChannel {
+	return &WebhookChannel{client: client}
+}
+
+func (c *WebhookChannel) Name() string { return "webhook" }
+
+func (c *WebhookChannel) Enabled(prefs *Preferences) bool {
+	return prefs.WebhookURL != nil && prefs.WebhookSecret != nil
+}
+
+func (c *WebhookChannel) Send(ctx context.Context, u *user.User, prefs *Preferences, batch []*Notification) error {
+	payload, err := json.Marshal(map[string]any{
+		"user_id":       u.ID,
+		"notifications": batch,
+	})
+	if err != nil {
+		return err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, *prefs.WebhookURL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "golang-chat-api-notifications/1.0")
+	req.Header.Set("X-Notification-Signature", "t="+timestamp+",v1="+webhook.Sign(*prefs.WebhookSecret, timestamp, payload))
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("receiver responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
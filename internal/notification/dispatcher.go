@@ -0,0 +1,129 @@
+package notification
+
+import (
+	"context"
+	"github.com/maxwellzp/golang-chat-api/internal/config"
+	"github.com/maxwellzp/golang-chat-api/internal/logger"
+	"github.com/maxwellzp/golang-chat-api/internal/user"
+	"time"
+)
+
+// Dispatcher delivers stored notifications through the enabled channels,
+// holding them during quiet hours and batching them into digests. Several
+// dispatchers (one per replica) can run against the same database.
+type Dispatcher struct {
+	notificationRepository *NotificationRepository
+	userRepository         *user.UserRepository
+	channels               []Channel
+	cfg                    config.NotificationConfig
+	logger                 *logger.Logger
+}
+
+func NewDispatcher(
+	notificationRepository *NotificationRepository,
+	userRepository *user.UserRepository,
+	channels []Channel,
+	cfg config.NotificationConfig,
+	logger *logger.Logger,
+) *Dispatcher {
+	return &Dispatcher{
+		notificationRepository: notificationRepository,
+		userRepository:         userRepository,
+		channels:               channels,
+		cfg:                    cfg,
+		logger:                 logger,
+	}
+}
+
+// Run polls for due notifications until ctx is cancelled.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		d.DispatchDue(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DispatchDue delivers the pending notifications of one batch of users.
+func (d *Dispatcher) DispatchDue(ctx context.Context) {
+	now := time.Now().UTC()
+	userIDs, err := d.notificationRepository.DueUsers(ctx, now, d.cfg.BatchSize)
+	if err != nil {
+		d.logger.Errorw("Failed to load due notifications",
+			"error", err,
+		)
+		return
+	}
+	for _, userID := range userIDs {
+		if ctx.Err() != nil {
+			return
+		}
+		if err := d.dispatchUser(ctx, userID, now); err != nil {
+			d.logger.Errorw("Failed to dispatch notifications",
+				"user_id", userID,
+				"error", err,
+			)
+		}
+	}
+}
+
+func (d *Dispatcher) dispatchUser(ctx context.Context, userID int64, now time.Time) error {
+	prefs, err := d.notificationRepository.GetPreferences(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if prefs == nil {
+		prefs = defaultPreferences(userID)
+	}
+
+	if until := prefs.QuietUntil(now); !until.IsZero() {
+		return d.notificationRepository.Postpone(ctx, userID, until)
+	}
+	if prefs.DigestMinutes > 0 {
+		oldest, err := d.notificationRepository.OldestPending(ctx, userID)
+		if err != nil {
+			return err
+		}
+		sendAt := oldest.Add(time.Duration(prefs.DigestMinutes) * time.Minute)
+		if sendAt.After(now) {
+			return d.notificationRepository.Postpone(ctx, userID, sendAt)
+		}
+	}
+
+	batch, err := d.notificationRepository.ClaimPending(ctx, userID, now)
+	if err != nil || len(batch) == 0 {
+		return err
+	}
+
+	u, err := d.userRepository.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	// Bots read their notifications through the API only.
+	if u == nil || u.IsBot {
+		return nil
+	}
+
+	// Claimed notifications are not retried: a failing channel only loses
+	// the push, the record stays readable in GET /me/notifications.
+	for _, ch := range d.channels {
+		if !ch.Enabled(prefs) {
+			continue
+		}
+		if err := ch.Send(ctx, u, prefs, batch); err != nil {
+			d.logger.Warnw("Notification channel failed",
+				"channel", ch.Name(),
+				"user_id", userID,
+				"count", len(batch),
+				"error", err,
+			)
+		}
+	}
+	return nil
+}
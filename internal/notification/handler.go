@@ -0,0 +1,332 @@
+package notification
+
+import (
+	"encoding/json"
+	"github.com/maxwellzp/golang-chat-api/internal/httpx"
+	"github.com/maxwellzp/golang-chat-api/internal/logger"
+	"github.com/maxwellzp/golang-chat-api/internal/validatorx"
+	"net/http"
+)
+
+type NotificationHandler struct {
+	notificationService *NotificationService
+	vapidPublicKey      string
+	validator           *validatorx.Validator
+	logger              *logger.Logger
+}
+
+// NewNotificationHandler creates the handler. vapidPublicKey is empty when
+// Web Push is not configured.
+func NewNotificationHandler(notificationService *NotificationService, vapidPublicKey string, validator *validatorx.Validator, logger *logger.Logger) *NotificationHandler {
+	return &NotificationHandler{
+		notificationService: notificationService,
+		vapidPublicKey:      vapidPublicKey,
+		validator:           validator,
+		logger:              logger,
+	}
+}
+
+// List returns the current user's notifications, newest first. Pass
+// ?unread=true to only get unread ones.
+func (h *NotificationHandler) List() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := httpx.GetUserID(r.Context())
+		if err != nil {
+			h.logger.Warnw("Unauthorized request to list notifications")
+			httpx.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		limit, offset, err := httpx.ParsePagination(r, 50, 100)
+		if err != nil {
+			httpx.WriteValidationError(w, err)
+			return
+		}
+		unreadOnly := r.URL.Query().Get("unread") == "true"
+
+		notifications, err := h.notificationService.List(r.Context(), userID, unreadOnly, limit, offset)
+		if err != nil {
+			h.logger.Errorw("Failed to list notifications",
+				"error", err,
+				"user_id", userID,
+			)
+			httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
+			return
+		}
+		httpx.WriteJSON(w, http.StatusOK, notifications)
+	}
+}
+
+func (h *NotificationHandler) MarkRead() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := httpx.GetUserID(r.Context())
+		if err != nil {
+			h.logger.Warnw("Unauthorized request to mark notification read")
+			httpx.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		id, err := httpx.ParseInt64Param(r, "id")
+		if err != nil {
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid NotificationID")
+			return
+		}
+
+		if _, err := h.notificationService.MarkRead(r.Context(), userID, &id); err != nil {
+			h.logger.Errorw("Failed to mark notification read",
+				"error", err,
+				"user_id", userID,
+				"notification_id", id,
+			)
+			httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
+			return
+		}
+		httpx.WriteJSON(w, http.StatusNoContent, nil)
+	}
+}
+
+func (h *NotificationHandler) MarkAllRead() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := httpx.GetUserID(r.Context())
+		if err != nil {
+			h.logger.Warnw("Unauthorized request to mark notifications read")
+			httpx.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+
+		count, err := h.notificationService.MarkRead(r.Context(), userID, nil)
+		if err != nil {
+			h.logger.Errorw("Failed to mark notifications read",
+				"error", err,
+				"user_id", userID,
+			)
+			httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
+			return
+		}
+		httpx.WriteJSON(w, http.StatusOK, map[string]int64{"marked_read": count})
+	}
+}
+
+func (h *NotificationHandler) Preferences() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := httpx.GetUserID(r.Context())
+		if err != nil {
+			h.logger.Warnw("Unauthorized request to get notification preferences")
+			httpx.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+
+		prefs, err := h.notificationService.Preferences(r.Context(), userID)
+		if err != nil {
+			h.logger.Errorw("Failed to get notification preferences",
+				"error", err,
+				"user_id", userID,
+			)
+			httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
+			return
+		}
+		rooms, err := h.notificationService.RoomLevels(r.Context(), userID)
+		if err != nil {
+			h.logger.Errorw("Failed to get room notification levels",
+				"error", err,
+				"user_id", userID,
+			)
+			httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
+			return
+		}
+		httpx.WriteJSON(w, http.StatusOK, map[string]any{
+			"preferences": prefs,
+			"rooms":       rooms,
+		})
+	}
+}
+
+func (h *NotificationHandler) UpdatePreferences() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := httpx.GetUserID(r.Context())
+		if err != nil {
+			h.logger.Warnw("Unauthorized request to update notification preferences")
+			httpx.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+
+		var req UpdatePreferencesRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.logger.Warnw("Failed to decode UpdatePreferencesRequest",
+				"error", err,
+			)
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		if err := h.validator.Validate(&req); err != nil {
+			httpx.WriteValidationError(w, err)
+			return
+		}
+		if err := req.Validate(); err != nil {
+			httpx.WriteValidationError(w, err)
+			return
+		}
+
+		prefs, err := h.notificationService.UpdatePreferences(r.Context(), userID, req)
+		if httpx.WriteServiceError(w, err) {
+			return
+		}
+		if err != nil {
+			h.logger.Errorw("Failed to update notification preferences",
+				"error", err,
+				"user_id", userID,
+			)
+			httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
+			return
+		}
+		httpx.WriteJSON(w, http.StatusOK, prefs)
+	}
+}
+
+func (h *NotificationHandler) SetRoomLevel() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := httpx.GetUserID(r.Context())
+		if err != nil {
+			h.logger.Warnw("Unauthorized request to set room notification level")
+			httpx.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		roomID, err := httpx.ParseInt64Param(r, "room_id")
+		if err != nil {
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid RoomID")
+			return
+		}
+
+		var req RoomPreferenceRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.logger.Warnw("Failed to decode RoomPreferenceRequest",
+				"error", err,
+			)
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		if err := h.validator.Validate(&req); err != nil {
+			httpx.WriteValidationError(w, err)
+			return
+		}
+
+		err = h.notificationService.SetRoomLevel(r.Context(), userID, roomID, req.Level)
+		if httpx.WriteServiceError(w, err) {
+			return
+		}
+		if err != nil {
+			h.logger.Errorw("Failed to set room notification level",
+				"error", err,
+				"user_id", userID,
+				"room_id", roomID,
+			)
+			httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
+			return
+		}
+		httpx.WriteJSON(w, http.StatusOK, RoomPreference{RoomID: roomID, Level: req.Level})
+	}
+}
+
+// VAPIDKey returns the applicationServerKey for PushManager.subscribe.
+func (h *NotificationHandler) VAPIDKey() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if h.vapidPublicKey == "" {
+			httpx.WriteError(w, http.StatusNotFound, "Web Push is not enabled on this server")
+			return
+		}
+		httpx.WriteJSON(w, http.StatusOK, map[string]string{"public_key": h.vapidPublicKey})
+	}
+}
+
+func (h *NotificationHandler) Subscribe() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := httpx.GetUserID(r.Context())
+		if err != nil {
+			h.logger.Warnw("Unauthorized request to add push subscription")
+			httpx.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+
+		var req PushSubscriptionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.logger.Warnw("Failed to decode PushSubscriptionRequest",
+				"error", err,
+			)
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		if err := h.validator.Validate(&req); err != nil {
+			httpx.WriteValidationError(w, err)
+			return
+		}
+		if err := req.Validate(); err != nil {
+			httpx.WriteValidationError(w, err)
+			return
+		}
+
+		sub, err := h.notificationService.Subscribe(r.Context(), userID, req)
+		if httpx.WriteServiceError(w, err) {
+			return
+		}
+		if err != nil {
+			h.logger.Errorw("Failed to add push subscription",
+				"error", err,
+				"user_id", userID,
+			)
+			httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
+			return
+		}
+		httpx.WriteJSON(w, http.StatusCreated, sub)
+	}
+}
+
+func (h *NotificationHandler) Subscriptions() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := httpx.GetUserID(r.Context())
+		if err != nil {
+			h.logger.Warnw("Unauthorized request to list push subscriptions")
+			httpx.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+
+		subs, err := h.notificationService.Subscriptions(r.Context(), userID)
+		if err != nil {
+			h.logger.Errorw("Failed to list push subscriptions",
+				"error", err,
+				"user_id", userID,
+			)
+			httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
+			return
+		}
+		httpx.WriteJSON(w, http.StatusOK, subs)
+	}
+}
+
+func (h *NotificationHandler) Unsubscribe() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := httpx.GetUserID(r.Context())
+		if err != nil {
+			h.logger.Warnw("Unauthorized request to remove push subscription")
+			httpx.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		id, err := httpx.ParseInt64Param(r, "id")
+		if err != nil {
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid SubscriptionID")
+			return
+		}
+
+		err = h.notificationService.Unsubscribe(r.Context(), userID, id)
+		if httpx.WriteServiceError(w, err) {
+			return
+		}
+		if err != nil {
+			h.logger.Errorw("Failed to remove push subscription",
+				"error", err,
+				"user_id", userID,
+				"subscription_id", id,
+			)
+			httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
+			return
+		}
+		httpx.WriteJSON(w, http.StatusNoContent, nil)
+	}
+}
@@ -0,0 +1,139 @@
+package notification
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	TypeDirectMessage = "dm"
+	TypeMention       = "mention"
+	TypeInvite        = "invite"
+	// TypeMessage is any room message, for users whose room level is "all".
+	TypeMessage = "message"
+	// TypeReminder is a due reminder on a saved message.
+	TypeReminder = "reminder"
+	// TypeWarning is a moderator's warning about something the user posted.
+	TypeWarning = "warning"
+)
+
+// Per-room notification levels. LevelMentions is the default.
+const (
+	LevelAll      = "all"
+	LevelMentions = "mentions"
+	LevelNone     = "none"
+)
+
+type Notification struct {
+	ID           int64      `json:"id"`
+	UserID       int64      `json:"user_id"`
+	Type         string     `json:"type"`
+	RoomID       *int64     `json:"room_id,omitempty"`
+	MessageID    *int64     `json:"message_id,omitempty"`
+	ActorID      *int64     `json:"actor_id,omitempty"`
+	Title        string     `json:"title"`
+	Body         string     `json:"body"`
+	Read         bool       `json:"read"`
+	ReadAt       *time.Time `json:"read_at"`
+	DeliverAfter time.Time  `json:"-"`
+	DispatchedAt *time.Time `json:"-"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// Preferences are a user's delivery settings. Quiet hours are minutes after
+// midnight in Timezone; deliveries falling inside them are held until they
+// end. With DigestMinutes set, notifications are batched and sent at most
+// once per that many minutes.
+type Preferences struct {
+	UserID          int64     `json:"-"`
+	Timezone        string    `json:"timezone"`
+	QuietHoursStart *int      `json:"-"`
+	QuietHoursEnd   *int      `json:"-"`
+	DigestMinutes   int       `json:"digest_minutes"`
+	EmailEnabled    bool      `json:"email_enabled"`
+	PushEnabled     bool      `json:"push_enabled"`
+	WebhookURL      *string   `json:"webhook_url"`
+	WebhookSecret   *string   `json:"webhook_secret,omitempty"`
+	UpdatedAt       time.Time `json:"updated_at"`
+
+	// "HH:MM" renderings of the quiet hours for the API.
+	QuietStart *string `json:"quiet_hours_start"`
+	QuietEnd   *string `json:"quiet_hours_end"`
+}
+
+func defaultPreferences(userID int64) *Preferences {
+	return &Preferences{
+		UserID:      userID,
+		Timezone:    "UTC",
+		PushEnabled: true,
+	}
+}
+
+// fillClock sets the "HH:MM" fields from the stored minutes.
+func (p *Preferences) fillClock() {
+	p.QuietStart, p.QuietEnd = nil, nil
+	if p.QuietHoursStart != nil && p.QuietHoursEnd != nil {
+		start := formatClock(*p.QuietHoursStart)
+		end := formatClock(*p.QuietHoursEnd)
+		p.QuietStart, p.QuietEnd = &start, &end
+	}
+}
+
+// QuietUntil returns when the quiet hours containing now end, or the zero
+// time if now is outside quiet hours.
+func (p *Preferences) QuietUntil(now time.Time) time.Time {
+	if p.QuietHoursStart == nil || p.QuietHoursEnd == nil || *p.QuietHoursStart == *p.QuietHoursEnd {
+		return time.Time{}
+	}
+	loc, err := time.LoadLocation(p.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	local := now.In(loc)
+	minute := local.Hour()*60 + local.Minute()
+	start, end := *p.QuietHoursStart, *p.QuietHoursEnd
+
+	var quiet bool
+	if start < end {
+		quiet = minute >= start && minute < end
+	} else {
+		// The window wraps past midnight, e.g. 22:00-07:00.
+		quiet = minute >= start || minute < end
+	}
+	if !quiet {
+		return time.Time{}
+	}
+
+	until := time.Date(local.Year(), local.Month(), local.Day(), end/60, end%60, 0, 0, loc)
+	if !until.After(local) {
+		until = until.AddDate(0, 0, 1)
+	}
+	return until.UTC()
+}
+
+type RoomPreference struct {
+	RoomID int64  `json:"room_id"`
+	Level  string `json:"level"`
+}
+
+type PushSubscription struct {
+	ID        int64     `json:"id"`
+	UserID    int64     `json:"-"`
+	Endpoint  string    `json:"endpoint"`
+	P256dh    string    `json:"-"`
+	Auth      string    `json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func formatClock(minutes int) string {
+	return fmt.Sprintf("%02d:%02d", minutes/60, minutes%60)
+}
+
+// parseClock parses "HH:MM" into minutes after midnight.
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
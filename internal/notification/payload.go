@@ -0,0 +1,74 @@
+package notification
+
+import (
+	"github.com/maxwellzp/golang-chat-api/internal/httpx"
+	"net/url"
+	"time"
+)
+
+// UpdatePreferencesRequest changes only the fields that are set. An empty
+// webhook_url removes the webhook; empty quiet hours turn them off.
+type UpdatePreferencesRequest struct {
+	Timezone        *string `json:"timezone,omitempty"`
+	QuietHoursStart *string `json:"quiet_hours_start,omitempty"`
+	QuietHoursEnd   *string `json:"quiet_hours_end,omitempty"`
+	DigestMinutes   *int    `json:"digest_minutes,omitempty" validate:"omitempty,min=0,max=1440"`
+	EmailEnabled    *bool   `json:"email_enabled,omitempty"`
+	PushEnabled     *bool   `json:"push_enabled,omitempty"`
+	WebhookURL      *string `json:"webhook_url,omitempty"`
+}
+
+func (r *UpdatePreferencesRequest) Validate() error {
+	errs := httpx.ValidationErrorMap{}
+	if r.Timezone != nil {
+		if _, err := time.LoadLocation(*r.Timezone); err != nil || *r.Timezone == "" {
+			errs["timezone"] = "timezone must be an IANA time zone such as Europe/Berlin"
+		}
+	}
+	if (r.QuietHoursStart == nil) != (r.QuietHoursEnd == nil) {
+		errs["quiet_hours_start"] = "quiet_hours_start and quiet_hours_end must be set together"
+	} else if r.QuietHoursStart != nil && (*r.QuietHoursStart != "" || *r.QuietHoursEnd != "") {
+		if _, err := parseClock(*r.QuietHoursStart); err != nil {
+			errs["quiet_hours_start"] = "quiet_hours_start must be HH:MM"
+		}
+		if _, err := parseClock(*r.QuietHoursEnd); err != nil {
+			errs["quiet_hours_end"] = "quiet_hours_end must be HH:MM"
+		}
+	}
+	if r.WebhookURL != nil && *r.WebhookURL != "" {
+		u, err := url.Parse(*r.WebhookURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs["webhook_url"] = "webhook_url must be an absolute http or https URL"
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+type RoomPreferenceRequest struct {
+	Level string `json:"level" validate:"required,oneof=all mentions none"`
+}
+
+// PushSubscriptionRequest mirrors the browser's PushSubscription.toJSON().
+type PushSubscriptionRequest struct {
+	Endpoint string `json:"endpoint" validate:"required,url,max=2000"`
+	Keys     struct {
+		P256dh string `json:"p256dh" validate:"required"`
+		Auth   string `json:"auth" validate:"required"`
+	} `json:"keys"`
+}
+
+func (r *PushSubscriptionRequest) Validate() error {
+	if u, err := url.Parse(r.Endpoint); err != nil || u.Scheme != "https" {
+		return httpx.ValidationErrorMap{"endpoint": "endpoint must be an https URL"}
+	}
+	if key, err := decodeBase64URL(r.Keys.P256dh); err != nil || len(key) != 65 || key[0] != 0x04 {
+		return httpx.ValidationErrorMap{"keys.p256dh": "p256dh must be a base64url uncompressed P-256 public key"}
+	}
+	if secret, err := decodeBase64URL(r.Keys.Auth); err != nil || len(secret) != 16 {
+		return httpx.ValidationErrorMap{"keys.auth": "auth must be a base64url 16-byte secret"}
+	}
+	return nil
+}
@@ -0,0 +1,287 @@
+package notification
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/maxwellzp/golang-chat-api/internal/db"
+	"time"
+)
+
+type NotificationRepository struct {
+	database *db.Db
+}
+
+func NewNotificationRepository(database *db.Db) *NotificationRepository {
+	return &NotificationRepository{database: database}
+}
+
+const notificationColumns = `id, user_id, type, room_id, message_id, actor_id, title, body,
+			read_at, deliver_after, dispatched_at, created_at`
+
+func scanNotifications(rows *sql.Rows) ([]*Notification, error) {
+	defer rows.Close()
+
+	var notifications []*Notification
+	for rows.Next() {
+		var n Notification
+		err := rows.Scan(&n.ID, &n.UserID, &n.Type, &n.RoomID, &n.MessageID, &n.ActorID, &n.Title, &n.Body,
+			&n.ReadAt, &n.DeliverAfter, &n.DispatchedAt, &n.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		n.Read = n.ReadAt != nil
+		notifications = append(notifications, &n)
+	}
+	return notifications, rows.Err()
+}
+
+// Create stores the notification. It reports false, without error, if the
+// user was already notified about the same message. Reminders are exempt.
+func (r *NotificationRepository) Create(ctx context.Context, n *Notification) (bool, error) {
+	now := time.Now().UTC()
+	query := `INSERT INTO notifications (user_id, type, room_id, message_id, actor_id, title, body, deliver_after, created_at)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $8)
+				ON CONFLICT (user_id, message_id) WHERE message_id IS NOT NULL AND type <> 'reminder' DO NOTHING
+				RETURNING id, deliver_after, created_at`
+
+	row := r.database.QueryRowContext(ctx, query, n.UserID, n.Type, n.RoomID, n.MessageID, n.ActorID,
+		n.Title, n.Body, now)
+	if err := row.Scan(&n.ID, &n.DeliverAfter, &n.CreatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (r *NotificationRepository) ListByUser(ctx context.Context, userID int64, unreadOnly bool, limit int, offset int) ([]*Notification, error) {
+	query := `SELECT ` + notificationColumns + `
+			  FROM notifications
+			  WHERE user_id = $1 AND (NOT $2::boolean OR read_at IS NULL)
+			  ORDER BY created_at DESC, id DESC
+			  LIMIT $3 OFFSET $4`
+	rows, err := r.database.QueryContext(ctx, query, userID, unreadOnly, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	return scanNotifications(rows)
+}
+
+// MarkRead marks one notification (id set) or all of the user's unread
+// notifications as read, returning how many changed.
+func (r *NotificationRepository) MarkRead(ctx context.Context, userID int64, id *int64) (int64, error) {
+	res, err := r.database.ExecContext(ctx,
+		`UPDATE notifications SET read_at = $1
+		 WHERE user_id = $2 AND read_at IS NULL AND ($3::int IS NULL OR id = $3)`,
+		time.Now().UTC(), userID, id)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// DueUsers returns users with undispatched notifications that are due,
+// longest waiting first.
+func (r *NotificationRepository) DueUsers(ctx context.Context, now time.Time, limit int) ([]int64, error) {
+	rows, err := r.database.QueryContext(ctx,
+		`SELECT user_id FROM notifications
+		 WHERE dispatched_at IS NULL AND deliver_after <= $1
+		 GROUP BY user_id ORDER BY MIN(deliver_after) LIMIT $2`, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var userIDs []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, id)
+	}
+	return userIDs, rows.Err()
+}
+
+// OldestPending returns the creation time of the user's oldest undispatched
+// notification.
+func (r *NotificationRepository) OldestPending(ctx context.Context, userID int64) (time.Time, error) {
+	var oldest time.Time
+	err := r.database.QueryRowContext(ctx,
+		"SELECT MIN(created_at) FROM notifications WHERE user_id = $1 AND dispatched_at IS NULL",
+		userID).Scan(&oldest)
+	return oldest, err
+}
+
+// Postpone holds the user's undispatched notifications until the given time.
+func (r *NotificationRepository) Postpone(ctx context.Context, userID int64, until time.Time) error {
+	_, err := r.database.ExecContext(ctx,
+		"UPDATE notifications SET deliver_after = $1 WHERE user_id = $2 AND dispatched_at IS NULL",
+		until, userID)
+	return err
+}
+
+// ClaimPending marks the user's undispatched notifications as dispatched and
+// returns them. Concurrent dispatchers never claim the same rows.
+func (r *NotificationRepository) ClaimPending(ctx context.Context, userID int64, now time.Time) ([]*Notification, error) {
+	rows, err := r.database.QueryContext(ctx,
+		`UPDATE notifications SET dispatched_at = $1
+		 WHERE user_id = $2 AND dispatched_at IS NULL
+		 RETURNING `+notificationColumns, now, userID)
+	if err != nil {
+		return nil, err
+	}
+	return scanNotifications(rows)
+}
+
+// GetPreferences returns the user's stored preferences, or nil if they never
+// changed the defaults.
+func (r *NotificationRepository) GetPreferences(ctx context.Context, userID int64) (*Preferences, error) {
+	query := `SELECT user_id, timezone, quiet_hours_start, quiet_hours_end, digest_minutes,
+				email_enabled, push_enabled, webhook_url, webhook_secret, updated_at
+			  FROM notification_preferences WHERE user_id = $1`
+	var p Preferences
+	err := r.database.QueryRowContext(ctx, query, userID).Scan(&p.UserID, &p.Timezone,
+		&p.QuietHoursStart, &p.QuietHoursEnd, &p.DigestMinutes, &p.EmailEnabled, &p.PushEnabled,
+		&p.WebhookURL, &p.WebhookSecret, &p.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (r *NotificationRepository) SavePreferences(ctx context.Context, p *Preferences) error {
+	query := `INSERT INTO notification_preferences (user_id, timezone, quiet_hours_start, quiet_hours_end,
+				digest_minutes, email_enabled, push_enabled, webhook_url, webhook_secret, updated_at)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+			  ON CONFLICT (user_id) DO UPDATE SET
+				timezone = EXCLUDED.timezone,
+				quiet_hours_start = EXCLUDED.quiet_hours_start,
+				quiet_hours_end = EXCLUDED.quiet_hours_end,
+				digest_minutes = EXCLUDED.digest_minutes,
+				email_enabled = EXCLUDED.email_enabled,
+				push_enabled = EXCLUDED.push_enabled,
+				webhook_url = EXCLUDED.webhook_url,
+				webhook_secret = EXCLUDED.webhook_secret,
+				updated_at = EXCLUDED.updated_at`
+	p.UpdatedAt = time.Now().UTC()
+	_, err := r.database.ExecContext(ctx, query, p.UserID, p.Timezone, p.QuietHoursStart, p.QuietHoursEnd,
+		p.DigestMinutes, p.EmailEnabled, p.PushEnabled, p.WebhookURL, p.WebhookSecret, p.UpdatedAt)
+	return err
+}
+
+// RoomLevel returns the user's level for the room, LevelMentions by default.
+func (r *NotificationRepository) RoomLevel(ctx context.Context, userID int64, roomID int64) (string, error) {
+	var level string
+	err := r.database.QueryRowContext(ctx,
+		"SELECT level FROM notification_room_preferences WHERE user_id = $1 AND room_id = $2",
+		userID, roomID).Scan(&level)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return LevelMentions, nil
+		}
+		return "", err
+	}
+	return level, nil
+}
+
+func (r *NotificationRepository) SetRoomLevel(ctx context.Context, userID int64, roomID int64, level string) error {
+	_, err := r.database.ExecContext(ctx,
+		`INSERT INTO notification_room_preferences (user_id, room_id, level) VALUES ($1, $2, $3)
+		 ON CONFLICT (user_id, room_id) DO UPDATE SET level = EXCLUDED.level`,
+		userID, roomID, level)
+	return err
+}
+
+func (r *NotificationRepository) ListRoomLevels(ctx context.Context, userID int64) ([]*RoomPreference, error) {
+	rows, err := r.database.QueryContext(ctx,
+		"SELECT room_id, level FROM notification_room_preferences WHERE user_id = $1 ORDER BY room_id", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var prefs []*RoomPreference
+	for rows.Next() {
+		var p RoomPreference
+		if err := rows.Scan(&p.RoomID, &p.Level); err != nil {
+			return nil, err
+		}
+		prefs = append(prefs, &p)
+	}
+	return prefs, rows.Err()
+}
+
+// AllMessageSubscribers returns the room members whose level is LevelAll.
+func (r *NotificationRepository) AllMessageSubscribers(ctx context.Context, roomID int64) ([]int64, error) {
+	rows, err := r.database.QueryContext(ctx,
+		`SELECT p.user_id FROM notification_room_preferences p
+		 JOIN room_members m ON m.room_id = p.room_id AND m.user_id = p.user_id
+		 WHERE p.room_id = $1 AND p.level = 'all'`, roomID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var userIDs []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, id)
+	}
+	return userIDs, rows.Err()
+}
+
+// SavePushSubscription stores a browser subscription. Re-subscribing the
+// same endpoint updates its keys and owner.
+func (r *NotificationRepository) SavePushSubscription(ctx context.Context, sub *PushSubscription) error {
+	query := `INSERT INTO push_subscriptions (user_id, endpoint, p256dh, auth, created_at)
+			  VALUES ($1, $2, $3, $4, $5)
+			  ON CONFLICT (endpoint) DO UPDATE SET user_id = EXCLUDED.user_id, p256dh = EXCLUDED.p256dh, auth = EXCLUDED.auth
+			  RETURNING id, created_at`
+	return r.database.QueryRowContext(ctx, query, sub.UserID, sub.Endpoint, sub.P256dh, sub.Auth,
+		time.Now().UTC()).Scan(&sub.ID, &sub.CreatedAt)
+}
+
+func (r *NotificationRepository) ListPushSubscriptions(ctx context.Context, userID int64) ([]*PushSubscription, error) {
+	rows, err := r.database.QueryContext(ctx,
+		`SELECT id, user_id, endpoint, p256dh, auth, created_at
+		 FROM push_subscriptions WHERE user_id = $1 ORDER BY id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subs []*PushSubscription
+	for rows.Next() {
+		var s PushSubscription
+		if err := rows.Scan(&s.ID, &s.UserID, &s.Endpoint, &s.P256dh, &s.Auth, &s.CreatedAt); err != nil {
+			return nil, err
+		}
+		subs = append(subs, &s)
+	}
+	return subs, rows.Err()
+}
+
+func (r *NotificationRepository) DeletePushSubscription(ctx context.Context, userID int64, id int64) error {
+	res, err := r.database.ExecContext(ctx,
+		"DELETE FROM push_subscriptions WHERE id = $1 AND user_id = $2", id, userID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrSubscriptionNotFound
+	}
+	return nil
+}
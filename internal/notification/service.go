@@ -0,0 +1,413 @@
+package notification
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"github.com/maxwellzp/golang-chat-api/internal/apperr"
+	"github.com/maxwellzp/golang-chat-api/internal/event"
+	"github.com/maxwellzp/golang-chat-api/internal/httpx"
+	"github.com/maxwellzp/golang-chat-api/internal/logger"
+	"github.com/maxwellzp/golang-chat-api/internal/message"
+	"github.com/maxwellzp/golang-chat-api/internal/netguard"
+	"github.com/maxwellzp/golang-chat-api/internal/room"
+	"github.com/maxwellzp/golang-chat-api/internal/user"
+	"unicode/utf8"
+)
+
+var ErrSubscriptionNotFound = apperr.NotFound("subscription_not_found", "push subscription not found")
+
+// maxBodyLength caps the message excerpt copied into a notification.
+const maxBodyLength = 200
+
+type NotificationService struct {
+	notificationRepository *NotificationRepository
+	userRepository         *user.UserRepository
+	roomRepository         *room.RoomRepository
+	guard                  *netguard.Guard
+	logger                 *logger.Logger
+}
+
+func NewNotificationService(
+	notificationRepository *NotificationRepository,
+	userRepository *user.UserRepository,
+	roomRepository *room.RoomRepository,
+	guard *netguard.Guard,
+	logger *logger.Logger,
+) *NotificationService {
+	return &NotificationService{
+		notificationRepository: notificationRepository,
+		userRepository:         userRepository,
+		roomRepository:         roomRepository,
+		guard:                  guard,
+		logger:                 logger,
+	}
+}
+
+func (s *NotificationService) List(ctx context.Context, userID int64, unreadOnly bool, limit int, offset int) ([]*Notification, error) {
+	return s.notificationRepository.ListByUser(ctx, userID, unreadOnly, limit, offset)
+}
+
+// MarkRead marks one notification, or all when id is nil, as read.
+func (s *NotificationService) MarkRead(ctx context.Context, userID int64, id *int64) (int64, error) {
+	return s.notificationRepository.MarkRead(ctx, userID, id)
+}
+
+func (s *NotificationService) Preferences(ctx context.Context, userID int64) (*Preferences, error) {
+	prefs, err := s.preferences(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	prefs.fillClock()
+	return prefs, nil
+}
+
+func (s *NotificationService) preferences(ctx context.Context, userID int64) (*Preferences, error) {
+	prefs, err := s.notificationRepository.GetPreferences(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if prefs == nil {
+		prefs = defaultPreferences(userID)
+	}
+	return prefs, nil
+}
+
+// UpdatePreferences applies the set fields. Setting a new webhook URL
+// generates the secret its requests are signed with.
+func (s *NotificationService) UpdatePreferences(ctx context.Context, userID int64, req UpdatePreferencesRequest) (*Preferences, error) {
+	prefs, err := s.preferences(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Timezone != nil {
+		prefs.Timezone = *req.Timezone
+	}
+	if req.QuietHoursStart != nil && req.QuietHoursEnd != nil {
+		if *req.QuietHoursStart == "" && *req.QuietHoursEnd == "" {
+			prefs.QuietHoursStart, prefs.QuietHoursEnd = nil, nil
+		} else {
+			start, _ := parseClock(*req.QuietHoursStart)
+			end, _ := parseClock(*req.QuietHoursEnd)
+			prefs.QuietHoursStart, prefs.QuietHoursEnd = &start, &end
+		}
+	}
+	if req.DigestMinutes != nil {
+		prefs.DigestMinutes = *req.DigestMinutes
+	}
+	if req.EmailEnabled != nil {
+		prefs.EmailEnabled = *req.EmailEnabled
+	}
+	if req.PushEnabled != nil {
+		prefs.PushEnabled = *req.PushEnabled
+	}
+	if req.WebhookURL != nil {
+		if *req.WebhookURL == "" {
+			prefs.WebhookURL, prefs.WebhookSecret = nil, nil
+		} else if prefs.WebhookURL == nil || *prefs.WebhookURL != *req.WebhookURL {
+			if err := s.guard.CheckURL(ctx, *req.WebhookURL); err != nil {
+				return nil, httpx.ValidationErrorMap{"webhook_url": err.Error()}
+			}
+			secret := make([]byte, 32)
+			if _, err := rand.Read(secret); err != nil {
+				return nil, err
+			}
+			encoded := hex.EncodeToString(secret)
+			prefs.WebhookURL, prefs.WebhookSecret = req.WebhookURL, &encoded
+		}
+	}
+
+	if err := s.notificationRepository.SavePreferences(ctx, prefs); err != nil {
+		return nil, err
+	}
+	prefs.fillClock()
+	return prefs, nil
+}
+
+func (s *NotificationService) RoomLevels(ctx context.Context, userID int64) ([]*RoomPreference, error) {
+	return s.notificationRepository.ListRoomLevels(ctx, userID)
+}
+
+func (s *NotificationService) SetRoomLevel(ctx context.Context, userID int64, roomID int64, level string) error {
+	rm, err := s.roomRepository.GetByID(ctx, roomID)
+	if err != nil {
+		return err
+	}
+	if rm == nil {
+		return room.ErrRoomNotFound
+	}
+	return s.notificationRepository.SetRoomLevel(ctx, userID, roomID, level)
+}
+
+func (s *NotificationService) Subscribe(ctx context.Context, userID int64, req PushSubscriptionRequest) (*PushSubscription, error) {
+	if err := s.guard.CheckURL(ctx, req.Endpoint); err != nil {
+		return nil, httpx.ValidationErrorMap{"endpoint": err.Error()}
+	}
+	sub := &PushSubscription{
+		UserID:   userID,
+		Endpoint: req.Endpoint,
+		P256dh:   req.Keys.P256dh,
+		Auth:     req.Keys.Auth,
+	}
+	if err := s.notificationRepository.SavePushSubscription(ctx, sub); err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+func (s *NotificationService) Subscriptions(ctx context.Context, userID int64) ([]*PushSubscription, error) {
+	return s.notificationRepository.ListPushSubscriptions(ctx, userID)
+}
+
+func (s *NotificationService) Unsubscribe(ctx context.Context, userID int64, id int64) error {
+	return s.notificationRepository.DeletePushSubscription(ctx, userID, id)
+}
+
+// HandleEvent turns DMs, mentions, invites, reminders and (for users who
+// asked for them) room messages into notification records. It is registered on the
+// event bus; the Dispatcher delivers the records.
+func (s *NotificationService) HandleEvent(ctx context.Context, e event.Event) {
+	var err error
+	switch e.Type {
+	case event.MessageCreated:
+		msg, ok := e.Data.(*message.Message)
+		if !ok {
+			return
+		}
+		if msg.ReceiverID != nil {
+			err = s.notifyDirectMessage(ctx, msg)
+		} else if msg.RoomID != nil {
+			err = s.notifyRoomMessage(ctx, msg)
+		}
+	case event.MentionCreated:
+		data, ok := e.Data.(message.MentionData)
+		if !ok {
+			return
+		}
+		err = s.notifyMention(ctx, data)
+	case event.MemberJoined:
+		err = s.notifyInvite(ctx, e)
+	case event.SavedMessageReminder:
+		saved, ok := e.Data.(*message.SavedMessage)
+		if !ok {
+			return
+		}
+		err = s.notifyReminder(ctx, saved)
+	case event.ReminderDue:
+		msg, ok := e.Data.(*message.Message)
+		if !ok {
+			return
+		}
+		err = s.create(ctx, &Notification{
+			UserID:    e.ActorID,
+			Type:      TypeReminder,
+			MessageID: &msg.ID,
+			Title:     "Reminder",
+			Body:      excerpt(msg.Content),
+		})
+	default:
+		return
+	}
+	if err != nil {
+		s.logger.Errorw("Failed to create notification",
+			"event", e.Type,
+			"error", err,
+		)
+	}
+}
+
+func (s *NotificationService) notifyDirectMessage(ctx context.Context, msg *message.Message) error {
+	if *msg.ReceiverID == msg.SenderID {
+		return nil
+	}
+	sender, err := s.senderName(ctx, msg)
+	if err != nil {
+		return err
+	}
+	return s.create(ctx, &Notification{
+		UserID:    *msg.ReceiverID,
+		Type:      TypeDirectMessage,
+		MessageID: &msg.ID,
+		ActorID:   &msg.SenderID,
+		Title:     "New message from " + sender,
+		Body:      excerpt(msg.Content),
+	})
+}
+
+func (s *NotificationService) notifyReminder(ctx context.Context, saved *message.SavedMessage) error {
+	title := "Reminder about a saved message"
+	if saved.Note != nil && *saved.Note != "" {
+		title = "Reminder: " + excerpt(*saved.Note)
+	}
+	return s.create(ctx, &Notification{
+		UserID:    saved.UserID,
+		Type:      TypeReminder,
+		RoomID:    saved.RoomID,
+		MessageID: &saved.ID,
+		ActorID:   &saved.SenderID,
+		Title:     title,
+		Body:      excerpt(saved.Content),
+	})
+}
+
+func (s *NotificationService) notifyRoomMessage(ctx context.Context, msg *message.Message) error {
+	subscribers, err := s.notificationRepository.AllMessageSubscribers(ctx, *msg.RoomID)
+	if err != nil || len(subscribers) == 0 {
+		return err
+	}
+	sender, err := s.senderName(ctx, msg)
+	if err != nil {
+		return err
+	}
+	roomName, err := s.roomName(ctx, *msg.RoomID)
+	if err != nil {
+		return err
+	}
+	for _, userID := range subscribers {
+		if userID == msg.SenderID {
+			continue
+		}
+		err := s.create(ctx, &Notification{
+			UserID:    userID,
+			Type:      TypeMessage,
+			RoomID:    msg.RoomID,
+			MessageID: &msg.ID,
+			ActorID:   &msg.SenderID,
+			Title:     fmt.Sprintf("%s in #%s", sender, roomName),
+			Body:      excerpt(msg.Content),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *NotificationService) notifyMention(ctx context.Context, data message.MentionData) error {
+	msg := data.Message
+	title := ""
+	sender, err := s.senderName(ctx, msg)
+	if err != nil {
+		return err
+	}
+	if msg.RoomID != nil {
+		level, err := s.notificationRepository.RoomLevel(ctx, data.UserID, *msg.RoomID)
+		if err != nil {
+			return err
+		}
+		if level == LevelNone {
+			return nil
+		}
+		roomName, err := s.roomName(ctx, *msg.RoomID)
+		if err != nil {
+			return err
+		}
+		title = fmt.Sprintf("%s mentioned you in #%s", sender, roomName)
+		if data.Kind != message.MentionUser {
+			title = fmt.Sprintf("%s notified @%s in #%s", sender, data.Kind, roomName)
+		}
+	} else {
+		title = sender + " mentioned you"
+	}
+	return s.create(ctx, &Notification{
+		UserID:    data.UserID,
+		Type:      TypeMention,
+		RoomID:    msg.RoomID,
+		MessageID: &msg.ID,
+		ActorID:   &msg.SenderID,
+		Title:     title,
+		Body:      excerpt(msg.Content),
+	})
+}
+
+// notifyInvite notifies users added to a room by someone else.
+func (s *NotificationService) notifyInvite(ctx context.Context, e event.Event) error {
+	data, ok := e.Data.(map[string]any)
+	if !ok || e.RoomID == nil {
+		return nil
+	}
+	userID, ok := data["user_id"].(int64)
+	if !ok || userID == e.ActorID {
+		return nil
+	}
+	actor, err := s.userRepository.FindByID(ctx, e.ActorID)
+	if err != nil {
+		return err
+	}
+	actorName := "Someone"
+	if actor != nil {
+		actorName = actor.Username
+	}
+	roomName, err := s.roomName(ctx, *e.RoomID)
+	if err != nil {
+		return err
+	}
+	return s.create(ctx, &Notification{
+		UserID:  userID,
+		Type:    TypeInvite,
+		RoomID:  e.RoomID,
+		ActorID: &e.ActorID,
+		Title:   fmt.Sprintf("%s added you to #%s", actorName, roomName),
+	})
+}
+
+// Warn notifies a user that a moderator warned them, in a room if roomID is
+// set. The note, if any, becomes the body.
+func (s *NotificationService) Warn(ctx context.Context, userID int64, actorID int64, roomID *int64, note string) error {
+	title := "A moderator warned you"
+	if roomID != nil {
+		roomName, err := s.roomName(ctx, *roomID)
+		if err != nil {
+			return err
+		}
+		title += " in #" + roomName
+	}
+	return s.create(ctx, &Notification{
+		UserID:  userID,
+		Type:    TypeWarning,
+		RoomID:  roomID,
+		ActorID: &actorID,
+		Title:   title,
+		Body:    excerpt(note),
+	})
+}
+
+func (s *NotificationService) create(ctx context.Context, n *Notification) error {
+	_, err := s.notificationRepository.Create(ctx, n)
+	return err
+}
+
+// senderName prefers the display name of webhook messages.
+func (s *NotificationService) senderName(ctx context.Context, msg *message.Message) (string, error) {
+	if msg.DisplayName != nil && *msg.DisplayName != "" {
+		return *msg.DisplayName, nil
+	}
+	sender, err := s.userRepository.FindByID(ctx, msg.SenderID)
+	if err != nil {
+		return "", err
+	}
+	if sender == nil {
+		return "Someone", nil
+	}
+	return sender.Username, nil
+}
+
+func (s *NotificationService) roomName(ctx context.Context, roomID int64) (string, error) {
+	rm, err := s.roomRepository.GetByID(ctx, roomID)
+	if err != nil {
+		return "", err
+	}
+	if rm == nil {
+		return "unknown", nil
+	}
+	return rm.Name, nil
+}
+
+func excerpt(content string) string {
+	if utf8.RuneCountInString(content) <= maxBodyLength {
+		return content
+	}
+	return string([]rune(content)[:maxBodyLength-1]) + "…"
+}
@@ -0,0 +1,273 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/maxwellzp/golang-chat-api/internal/logger"
+	"github.com/maxwellzp/golang-chat-api/internal/user"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// pushRecordSize is the aes128gcm record size advertised to push services.
+const pushRecordSize = 4096
+
+// WebPushChannel sends notifications to the user's browser push
+// subscriptions: payloads are encrypted per RFC 8291 and requests
+// authenticated with VAPID (RFC 8292).
+type WebPushChannel struct {
+	notificationRepository *NotificationRepository
+	client                 *http.Client
+	publicKey              string
+	privateKey             *ecdsa.PrivateKey
+	subject                string
+	logger                 *logger.Logger
+}
+
+// NewWebPushChannel parses the base64url VAPID key pair.
+func NewWebPushChannel(notificationRepository *NotificationRepository, client *http.Client, publicKey string, privateKey string, subject string, logger *logger.Logger) (*WebPushChannel, error) {
+	key, point, err := parseVAPIDPrivateKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	pub, err := decodeBase64URL(publicKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid VAPID public key: %w", err)
+	}
+	if !bytes.Equal(pub, point) {
+		return nil, errors.New("VAPID public key does not match the private key")
+	}
+	return &WebPushChannel{
+		notificationRepository: notificationRepository,
+		client:                 client,
+		publicKey:              publicKey,
+		privateKey:             key,
+		subject:                subject,
+		logger:                 logger,
+	}, nil
+}
+
+// PublicKey is the applicationServerKey browsers subscribe with.
+func (c *WebPushChannel) PublicKey() string { return c.publicKey }
+
+func (c *WebPushChannel) Name() string { return "webpush" }
+
+func (c *WebPushChannel) Enabled(prefs *Preferences) bool { return prefs.PushEnabled }
+
+func (c *WebPushChannel) Send(ctx context.Context, u *user.User, prefs *Preferences, batch []*Notification) error {
+	subs, err := c.notificationRepository.ListPushSubscriptions(ctx, u.ID)
+	if err != nil || len(subs) == 0 {
+		return err
+	}
+
+	content := map[string]any{
+		"title": batch[0].Title,
+		"body":  batch[0].Body,
+		"id":    batch[0].ID,
+	}
+	if len(batch) > 1 {
+		content = map[string]any{
+			"title": fmt.Sprintf("You have %d new notifications", len(batch)),
+			"body":  batch[0].Title,
+			"count": len(batch),
+		}
+	}
+	payload, err := json.Marshal(content)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, sub := range subs {
+		gone, err := c.push(ctx, sub, payload)
+		if gone {
+			// The browser unsubscribed; forget the endpoint.
+			if err := c.notificationRepository.DeletePushSubscription(ctx, u.ID, sub.ID); err != nil {
+				c.logger.Warnw("Failed to remove expired push subscription",
+					"subscription_id", sub.ID,
+					"error", err,
+				)
+			}
+			continue
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// push sends one encrypted message. It reports gone when the push service
+// says the subscription no longer exists.
+func (c *WebPushChannel) push(ctx context.Context, sub *PushSubscription, payload []byte) (bool, error) {
+	body, err := encryptPushPayload(sub, payload)
+	if err != nil {
+		return false, err
+	}
+	authorization, err := c.vapidAuthorization(sub.Endpoint)
+	if err != nil {
+		return false, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("TTL", "86400")
+	req.Header.Set("Authorization", authorization)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return true, nil
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		return false, fmt.Errorf("push service responded with status %d", resp.StatusCode)
+	}
+	return false, nil
+}
+
+// vapidAuthorization builds the "vapid t=<jwt>, k=<public key>" header for
+// the endpoint's origin.
+func (c *WebPushChannel) vapidAuthorization(endpoint string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		"aud": u.Scheme + "://" + u.Host,
+		"exp": time.Now().Add(12 * time.Hour).Unix(),
+		"sub": c.subject,
+	})
+	signed, err := token.SignedString(c.privateKey)
+	if err != nil {
+		return "", err
+	}
+	return "vapid t=" + signed + ", k=" + c.publicKey, nil
+}
+
+// encryptPushPayload encrypts payload for the subscription as a single
+// aes128gcm record (RFC 8188, keys derived per RFC 8291).
+func encryptPushPayload(sub *PushSubscription, payload []byte) ([]byte, error) {
+	uaPublic, err := decodeBase64URL(sub.P256dh)
+	if err != nil {
+		return nil, fmt.Errorf("invalid p256dh key: %w", err)
+	}
+	authSecret, err := decodeBase64URL(sub.Auth)
+	if err != nil {
+		return nil, fmt.Errorf("invalid auth secret: %w", err)
+	}
+	uaKey, err := ecdh.P256().NewPublicKey(uaPublic)
+	if err != nil {
+		return nil, fmt.Errorf("invalid p256dh key: %w", err)
+	}
+
+	asKey, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	asPublic := asKey.PublicKey().Bytes()
+	sharedSecret, err := asKey.ECDH(uaKey)
+	if err != nil {
+		return nil, err
+	}
+
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	keyInfo := append([]byte("WebPush: info\x00"), uaPublic...)
+	keyInfo = append(keyInfo, asPublic...)
+	ikm := hkdf(authSecret, sharedSecret, keyInfo, 32)
+	cek := hkdf(salt, ikm, []byte("Content-Encoding: aes128gcm\x00"), 16)
+	nonce := hkdf(salt, ikm, []byte("Content-Encoding: nonce\x00"), 12)
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	// 0x02 marks the last (and only) record.
+	plaintext := append(append([]byte{}, payload...), 0x02)
+	if len(plaintext)+gcm.Overhead() > pushRecordSize {
+		return nil, errors.New("push payload too large")
+	}
+	ciphertext := gcm.Seal(nil, nonce, plaintext, nil)
+
+	header := make([]byte, 0, 16+4+1+len(asPublic))
+	header = append(header, salt...)
+	header = binary.BigEndian.AppendUint32(header, pushRecordSize)
+	header = append(header, byte(len(asPublic)))
+	header = append(header, asPublic...)
+	return append(header, ciphertext...), nil
+}
+
+// hkdf is HKDF-SHA256 (RFC 5869) for output lengths up to one hash block.
+func hkdf(salt, secret, info []byte, length int) []byte {
+	extract := hmac.New(sha256.New, salt)
+	extract.Write(secret)
+	prk := extract.Sum(nil)
+
+	expand := hmac.New(sha256.New, prk)
+	expand.Write(info)
+	expand.Write([]byte{0x01})
+	return expand.Sum(nil)[:length]
+}
+
+// parseVAPIDPrivateKey returns the signing key and its uncompressed public
+// point.
+func parseVAPIDPrivateKey(raw string) (*ecdsa.PrivateKey, []byte, error) {
+	d, err := decodeBase64URL(raw)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid VAPID private key: %w", err)
+	}
+	key, err := ecdh.P256().NewPrivateKey(d)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid VAPID private key: %w", err)
+	}
+	// Uncompressed point: 0x04 || X || Y.
+	point := key.PublicKey().Bytes()
+	return &ecdsa.PrivateKey{
+		PublicKey: ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(point[1:33]),
+			Y:     new(big.Int).SetBytes(point[33:]),
+		},
+		D: new(big.Int).SetBytes(d),
+	}, point, nil
+}
+
+// decodeBase64URL accepts base64url with or without padding, as browsers
+// and key generators disagree.
+func decodeBase64URL(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
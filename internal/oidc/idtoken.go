@@ -0,0 +1,52 @@
+package oidc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// IDTokenClaims are the standard claims we read from an ID token.
+type IDTokenClaims struct {
+	jwt.RegisteredClaims
+	Nonce             string `json:"nonce"`
+	Email             string `json:"email"`
+	EmailVerified     bool   `json:"email_verified"`
+	Name              string `json:"name"`
+	PreferredUsername string `json:"preferred_username"`
+}
+
+var signingMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}
+
+// VerifyIDToken checks the ID token signature against the provider's JWKS
+// and validates issuer, audience, expiry and nonce.
+func (p *Provider) VerifyIDToken(ctx context.Context, rawToken, nonce string) (*IDTokenClaims, error) {
+	claims := &IDTokenClaims{}
+	parser := jwt.NewParser(jwt.WithValidMethods(signingMethods))
+
+	_, err := parser.ParseWithClaims(rawToken, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.publicKey(ctx, kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("oidc: invalid id_token: %w", err)
+	}
+
+	if !claims.VerifyIssuer(p.issuer, true) && !claims.VerifyIssuer(p.issuer+"/", true) {
+		return nil, errors.New("oidc: id_token issuer mismatch")
+	}
+	if !claims.VerifyAudience(p.clientID, true) {
+		return nil, errors.New("oidc: id_token audience mismatch")
+	}
+	if claims.ExpiresAt == nil {
+		return nil, errors.New("oidc: id_token has no expiry")
+	}
+	if claims.Subject == "" {
+		return nil, errors.New("oidc: id_token has no subject")
+	}
+	if claims.Nonce != nonce {
+		return nil, errors.New("oidc: id_token nonce mismatch")
+	}
+	return claims, nil
+}
@@ -0,0 +1,200 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/golang-jwt/jwt/v4"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// mockIssuer serves a discovery document and a JWKS holding the public keys
+// of its signing keys.
+type mockIssuer struct {
+	server *httptest.Server
+
+	mu   sync.Mutex
+	keys map[string]*rsa.PrivateKey
+}
+
+func newMockIssuer(t *testing.T) *mockIssuer {
+	t.Helper()
+	m := &mockIssuer{keys: make(map[string]*rsa.PrivateKey)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(Discovery{
+			Issuer:                m.server.URL,
+			AuthorizationEndpoint: m.server.URL + "/authorize",
+			TokenEndpoint:         m.server.URL + "/token",
+			JWKSURI:               m.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		var keys []jsonWebKey
+		for kid, key := range m.keys {
+			keys = append(keys, jsonWebKey{
+				Kty: "RSA",
+				Kid: kid,
+				Use: "sig",
+				Alg: "RS256",
+				N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			})
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"keys": keys})
+	})
+	m.server = httptest.NewServer(mux)
+	t.Cleanup(m.server.Close)
+	return m
+}
+
+func (m *mockIssuer) addKey(t *testing.T, kid string) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.mu.Lock()
+	m.keys[kid] = key
+	m.mu.Unlock()
+	return key
+}
+
+func (m *mockIssuer) provider() *Provider {
+	return NewProvider(Config{Name: "mock", Issuer: m.server.URL, ClientID: "client-1"}, m.server.Client())
+}
+
+func signIDToken(t *testing.T, key *rsa.PrivateKey, kid string, claims IDTokenClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func validClaims(issuer string) IDTokenClaims {
+	now := time.Now()
+	return IDTokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    issuer,
+			Subject:   "user-42",
+			Audience:  jwt.ClaimStrings{"client-1"},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(5 * time.Minute)),
+		},
+		Nonce:         "nonce-1",
+		Email:         "jane@example.com",
+		EmailVerified: true,
+	}
+}
+
+func TestVerifyIDToken(t *testing.T) {
+	issuer := newMockIssuer(t)
+	key := issuer.addKey(t, "key-1")
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		key     *rsa.PrivateKey
+		kid     string
+		modify  func(c *IDTokenClaims)
+		nonce   string
+		wantErr string
+	}{
+		{name: "valid", key: key, kid: "key-1", nonce: "nonce-1"},
+		{name: "issuer with trailing slash", key: key, kid: "key-1", nonce: "nonce-1",
+			modify: func(c *IDTokenClaims) { c.Issuer += "/" }},
+		{name: "wrong nonce", key: key, kid: "key-1", nonce: "nonce-2", wantErr: "nonce mismatch"},
+		{name: "wrong audience", key: key, kid: "key-1", nonce: "nonce-1", wantErr: "audience mismatch",
+			modify: func(c *IDTokenClaims) { c.Audience = jwt.ClaimStrings{"someone-else"} }},
+		{name: "wrong issuer", key: key, kid: "key-1", nonce: "nonce-1", wantErr: "issuer mismatch",
+			modify: func(c *IDTokenClaims) { c.Issuer = "https://evil.example.com" }},
+		{name: "expired", key: key, kid: "key-1", nonce: "nonce-1", wantErr: "invalid id_token",
+			modify: func(c *IDTokenClaims) { c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute)) }},
+		{name: "no expiry", key: key, kid: "key-1", nonce: "nonce-1", wantErr: "no expiry",
+			modify: func(c *IDTokenClaims) { c.ExpiresAt = nil }},
+		{name: "no subject", key: key, kid: "key-1", nonce: "nonce-1", wantErr: "no subject",
+			modify: func(c *IDTokenClaims) { c.Subject = "" }},
+		{name: "signed with another key", key: otherKey, kid: "key-1", nonce: "nonce-1", wantErr: "invalid id_token"},
+		{name: "unknown kid", key: key, kid: "key-9", nonce: "nonce-1", wantErr: "unknown signing key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := validClaims(issuer.server.URL)
+			if tt.modify != nil {
+				tt.modify(&claims)
+			}
+			raw := signIDToken(t, tt.key, tt.kid, claims)
+
+			got, err := issuer.provider().VerifyIDToken(context.Background(), raw, tt.nonce)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.Subject != "user-42" || got.Email != "jane@example.com" {
+				t.Errorf("claims = %+v", got)
+			}
+		})
+	}
+}
+
+func TestVerifyIDTokenRejectsUnsignedToken(t *testing.T) {
+	issuer := newMockIssuer(t)
+	issuer.addKey(t, "key-1")
+
+	token := jwt.NewWithClaims(jwt.SigningMethodNone, validClaims(issuer.server.URL))
+	raw, err := token.SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := issuer.provider().VerifyIDToken(context.Background(), raw, "nonce-1"); err == nil {
+		t.Fatal("expected an unsigned token to be rejected")
+	}
+}
+
+func TestVerifyIDTokenPicksUpRotatedKey(t *testing.T) {
+	issuer := newMockIssuer(t)
+	oldKey := issuer.addKey(t, "key-1")
+	p := issuer.provider()
+
+	raw := signIDToken(t, oldKey, "key-1", validClaims(issuer.server.URL))
+	if _, err := p.VerifyIDToken(context.Background(), raw, "nonce-1"); err != nil {
+		t.Fatalf("old key: %v", err)
+	}
+
+	newKey := issuer.addKey(t, "key-2")
+	raw = signIDToken(t, newKey, "key-2", validClaims(issuer.server.URL))
+
+	// Within minKeyRefresh an unknown kid doesn't refetch the JWKS.
+	if _, err := p.VerifyIDToken(context.Background(), raw, "nonce-1"); err == nil {
+		t.Fatal("expected the new key to be unknown before the refresh interval")
+	}
+
+	p.mu.Lock()
+	p.keys.fetchedAt = time.Now().Add(-2 * minKeyRefresh)
+	p.mu.Unlock()
+	if _, err := p.VerifyIDToken(context.Background(), raw, "nonce-1"); err != nil {
+		t.Fatalf("rotated key: %v", err)
+	}
+}
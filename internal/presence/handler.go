@@ -0,0 +1,243 @@
+package presence
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/maxwellzp/golang-chat-api/internal/httpx"
+	"github.com/maxwellzp/golang-chat-api/internal/logger"
+	"github.com/maxwellzp/golang-chat-api/internal/validatorx"
+	"io"
+	"net/http"
+	"time"
+)
+
+type PresenceHandler struct {
+	presenceService *PresenceService
+	validator       *validatorx.Validator
+	logger          *logger.Logger
+}
+
+func NewPresenceHandler(presenceService *PresenceService, validator *validatorx.Validator, logger *logger.Logger) *PresenceHandler {
+	return &PresenceHandler{
+		presenceService: presenceService,
+		validator:       validator,
+		logger:          logger,
+	}
+}
+
+// Heartbeat keeps the current user online for the configured TTL. The body
+// is optional.
+func (h *PresenceHandler) Heartbeat() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := httpx.GetUserID(r.Context())
+		if err != nil {
+			h.logger.Warnw("Unauthorized presence heartbeat")
+			httpx.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+
+		var req HeartbeatRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+			h.logger.Warnw("Failed to decode HeartbeatRequest",
+				"error", err,
+			)
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+
+		if err := h.presenceService.Heartbeat(r.Context(), userID, req.Idle); err != nil {
+			h.logger.Errorw("Failed to record presence heartbeat",
+				"error", err,
+				"user_id", userID,
+			)
+			httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
+			return
+		}
+		httpx.WriteJSON(w, http.StatusNoContent, nil)
+	}
+}
+
+// Stream holds the current user online for as long as the connection is
+// open. It is a server-sent event stream that starts with the user's own
+// presence and then only sends keep-alive comments.
+func (h *PresenceHandler) Stream() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := httpx.GetUserID(r.Context())
+		if err != nil {
+			h.logger.Warnw("Unauthorized request to open presence stream")
+			httpx.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+
+		release, err := h.presenceService.Connect(r.Context(), userID)
+		if err != nil {
+			h.logger.Errorw("Failed to connect presence stream",
+				"error", err,
+				"user_id", userID,
+			)
+			httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
+			return
+		}
+		defer release()
+
+		p, err := h.presenceService.Get(r.Context(), userID)
+		if err != nil {
+			h.logger.Errorw("Failed to get presence",
+				"error", err,
+				"user_id", userID,
+			)
+			httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
+			return
+		}
+		stream, err := httpx.NewSSEWriter(w)
+		if err != nil {
+			h.logger.Errorw("Response does not support streaming",
+				"error", err,
+			)
+			return
+		}
+		if err := stream.Event("presence", p); err != nil {
+			return
+		}
+
+		ticker := time.NewTicker(h.presenceService.StreamInterval())
+		defer ticker.Stop()
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-h.presenceService.Closing():
+				return
+			case <-ticker.C:
+				if err := stream.Ping(); err != nil {
+					return
+				}
+			}
+		}
+	}
+}
+
+func (h *PresenceHandler) Get() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, err := httpx.GetUserID(r.Context()); err != nil {
+			h.logger.Warnw("Unauthorized request to get presence")
+			httpx.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		id, err := httpx.ParseInt64Param(r, "id")
+		if err != nil {
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid UserID")
+			return
+		}
+
+		p, err := h.presenceService.Get(r.Context(), id)
+		if httpx.WriteServiceError(w, err) {
+			return
+		}
+		if err != nil {
+			h.logger.Errorw("Failed to get presence",
+				"error", err,
+				"user_id", id,
+			)
+			httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
+			return
+		}
+		httpx.WriteJSON(w, http.StatusOK, p)
+	}
+}
+
+// Query returns the presence of up to 200 users at once. Unknown user IDs
+// are left out of the result.
+func (h *PresenceHandler) Query() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, err := httpx.GetUserID(r.Context()); err != nil {
+			h.logger.Warnw("Unauthorized request to query presence")
+			httpx.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+
+		var req QueryRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.logger.Warnw("Failed to decode QueryRequest",
+				"error", err,
+			)
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		if err := h.validator.Validate(&req); err != nil {
+			httpx.WriteValidationError(w, err)
+			return
+		}
+
+		presences, err := h.presenceService.Query(r.Context(), req.UserIDs)
+		if err != nil {
+			h.logger.Errorw("Failed to query presence",
+				"error", err,
+				"users", len(req.UserIDs),
+			)
+			httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
+			return
+		}
+		httpx.WriteJSON(w, http.StatusOK, presences)
+	}
+}
+
+func (h *PresenceHandler) SetStatus() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := httpx.GetUserID(r.Context())
+		if err != nil {
+			h.logger.Warnw("Unauthorized request to set status")
+			httpx.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+
+		var req SetStatusRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.logger.Warnw("Failed to decode SetStatusRequest",
+				"error", err,
+			)
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		if err := h.validator.Validate(&req); err != nil {
+			httpx.WriteValidationError(w, err)
+			return
+		}
+		if err := req.Validate(); err != nil {
+			httpx.WriteValidationError(w, err)
+			return
+		}
+
+		p, err := h.presenceService.SetStatus(r.Context(), userID, req)
+		if err != nil {
+			h.logger.Errorw("Failed to set status",
+				"error", err,
+				"user_id", userID,
+			)
+			httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
+			return
+		}
+		httpx.WriteJSON(w, http.StatusOK, p)
+	}
+}
+
+func (h *PresenceHandler) ClearStatus() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := httpx.GetUserID(r.Context())
+		if err != nil {
+			h.logger.Warnw("Unauthorized request to clear status")
+			httpx.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+
+		if err := h.presenceService.ClearStatus(r.Context(), userID); err != nil {
+			h.logger.Errorw("Failed to clear status",
+				"error", err,
+				"user_id", userID,
+			)
+			httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
+			return
+		}
+		httpx.WriteJSON(w, http.StatusNoContent, nil)
+	}
+}
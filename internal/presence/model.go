@@ -0,0 +1,55 @@
+package presence
+
+import "time"
+
+const (
+	StatusOnline  = "online"
+	StatusAway    = "away"
+	StatusOffline = "offline"
+)
+
+// Presence is a user's presence as other users see it. LastSeenAt is nil
+// for users who have never connected. The custom status is omitted once it
+// has expired.
+type Presence struct {
+	UserID          int64      `json:"user_id"`
+	Status          string     `json:"status"`
+	LastSeenAt      *time.Time `json:"last_seen_at"`
+	StatusText      *string    `json:"status_text,omitempty"`
+	StatusEmoji     *string    `json:"status_emoji,omitempty"`
+	StatusExpiresAt *time.Time `json:"status_expires_at,omitempty"`
+}
+
+// record is the stored state presence is derived from. Idle is reported by
+// the client's heartbeats; ManualAway is set by the user.
+type record struct {
+	UserID          int64
+	LastSeenAt      *time.Time
+	Idle            bool
+	ManualAway      bool
+	StatusText      *string
+	StatusEmoji     *string
+	StatusExpiresAt *time.Time
+}
+
+// derive computes the presence of rec at now. connected reports whether the
+// user holds an open stream on this replica.
+func (rec *record) derive(now time.Time, onlineTTL time.Duration, connected bool) *Presence {
+	p := &Presence{
+		UserID:     rec.UserID,
+		Status:     StatusOffline,
+		LastSeenAt: rec.LastSeenAt,
+	}
+	if connected || (rec.LastSeenAt != nil && now.Sub(*rec.LastSeenAt) < onlineTTL) {
+		p.Status = StatusOnline
+		if rec.Idle || rec.ManualAway {
+			p.Status = StatusAway
+		}
+	}
+	if rec.StatusExpiresAt == nil || rec.StatusExpiresAt.After(now) {
+		p.StatusText = rec.StatusText
+		p.StatusEmoji = rec.StatusEmoji
+		p.StatusExpiresAt = rec.StatusExpiresAt
+	}
+	return p
+}
@@ -0,0 +1,33 @@
+package presence
+
+import (
+	"github.com/maxwellzp/golang-chat-api/internal/httpx"
+	"time"
+)
+
+// HeartbeatRequest keeps the user online. Clients set idle when the user
+// has stopped interacting, which shows them as away.
+type HeartbeatRequest struct {
+	Idle bool `json:"idle"`
+}
+
+type QueryRequest struct {
+	UserIDs []int64 `json:"user_ids" validate:"required,min=1,max=200,dive,gt=0"`
+}
+
+// SetStatusRequest replaces the custom status. Away shows the user as away
+// while they are online, whatever their client reports. A nil expires_at
+// keeps the status until it is cleared.
+type SetStatusRequest struct {
+	Text      string     `json:"text" validate:"max=100"`
+	Emoji     string     `json:"emoji" validate:"max=32"`
+	Away      bool       `json:"away"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+func (r *SetStatusRequest) Validate() error {
+	if r.ExpiresAt != nil && !r.ExpiresAt.After(time.Now()) {
+		return httpx.ValidationErrorMap{"expires_at": "expires_at must be in the future"}
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package presence
+
+import (
+	"context"
+	"github.com/lib/pq"
+	"github.com/maxwellzp/golang-chat-api/internal/db"
+	"time"
+)
+
+type PresenceRepository struct {
+	database *db.Db
+}
+
+func NewPresenceRepository(database *db.Db) *PresenceRepository {
+	return &PresenceRepository{database: database}
+}
+
+// Heartbeat records that the user was seen at now and whether their client
+// is idle.
+func (r *PresenceRepository) Heartbeat(ctx context.Context, userID int64, now time.Time, idle bool) error {
+	query := `INSERT INTO user_presence (user_id, last_seen_at, idle)
+		VALUES ($1, $2, $3::boolean)
+		ON CONFLICT (user_id) DO UPDATE SET last_seen_at = EXCLUDED.last_seen_at, idle = EXCLUDED.idle`
+	_, err := r.database.ExecContext(ctx, query, userID, now, idle)
+	return err
+}
+
+// Touch moves last-seen forward for users with open streams without changing
+// their idle flag.
+func (r *PresenceRepository) Touch(ctx context.Context, userIDs []int64, now time.Time) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	query := `INSERT INTO user_presence (user_id, last_seen_at)
+		SELECT id, $2 FROM users WHERE id = ANY($1)
+		ON CONFLICT (user_id) DO UPDATE SET last_seen_at = EXCLUDED.last_seen_at`
+	_, err := r.database.ExecContext(ctx, query, pq.Array(userIDs), now)
+	return err
+}
+
+func (r *PresenceRepository) SetStatus(ctx context.Context, userID int64, req SetStatusRequest) error {
+	query := `INSERT INTO user_presence (user_id, status_text, status_emoji, status_expires_at, manual_away)
+		VALUES ($1, NULLIF($2, ''), NULLIF($3, ''), $4, $5::boolean)
+		ON CONFLICT (user_id) DO UPDATE SET
+			status_text = EXCLUDED.status_text,
+			status_emoji = EXCLUDED.status_emoji,
+			status_expires_at = EXCLUDED.status_expires_at,
+			manual_away = EXCLUDED.manual_away`
+	_, err := r.database.ExecContext(ctx, query, userID,
+		req.Text, req.Emoji, req.ExpiresAt, req.Away)
+	return err
+}
+
+func (r *PresenceRepository) ClearStatus(ctx context.Context, userID int64) error {
+	query := `UPDATE user_presence
+		SET status_text = NULL, status_emoji = NULL, status_expires_at = NULL, manual_away = FALSE
+		WHERE user_id = $1`
+	_, err := r.database.ExecContext(ctx, query, userID)
+	return err
+}
+
+// GetMany returns the stored presence of the given users. Users that don't
+// exist are left out; users that never connected get an empty record.
+func (r *PresenceRepository) GetMany(ctx context.Context, userIDs []int64) (map[int64]*record, error) {
+	query := `SELECT u.id, p.last_seen_at, COALESCE(p.idle, FALSE), COALESCE(p.manual_away, FALSE),
+			p.status_text, p.status_emoji, p.status_expires_at
+		FROM users u
+		LEFT JOIN user_presence p ON p.user_id = u.id
+		WHERE u.id = ANY($1)`
+	rows, err := r.database.QueryContext(ctx, query, pq.Array(userIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := make(map[int64]*record, len(userIDs))
+	for rows.Next() {
+		var rec record
+		if err := rows.Scan(&rec.UserID, &rec.LastSeenAt, &rec.Idle, &rec.ManualAway,
+			&rec.StatusText, &rec.StatusEmoji, &rec.StatusExpiresAt); err != nil {
+			return nil, err
+		}
+		records[rec.UserID] = &rec
+	}
+	return records, rows.Err()
+}
@@ -0,0 +1,181 @@
+package presence
+
+import (
+	"context"
+	"github.com/maxwellzp/golang-chat-api/internal/apperr"
+	"github.com/maxwellzp/golang-chat-api/internal/config"
+	"github.com/maxwellzp/golang-chat-api/internal/logger"
+	"sync"
+	"time"
+)
+
+var ErrUserNotFound = apperr.NotFound("user_not_found", "user not found")
+
+// PresenceService derives presence from heartbeats and open presence
+// streams. Streams are counted per replica; the refresh loop in Run keeps
+// their last-seen fresh in the database so every replica sees them online.
+type PresenceService struct {
+	presenceRepository *PresenceRepository
+	cfg                config.PresenceConfig
+	logger             *logger.Logger
+
+	mu          sync.Mutex
+	connections map[int64]int
+	closing     chan struct{}
+	closeOnce   sync.Once
+}
+
+func NewPresenceService(presenceRepository *PresenceRepository, cfg config.PresenceConfig, logger *logger.Logger) *PresenceService {
+	return &PresenceService{
+		presenceRepository: presenceRepository,
+		cfg:                cfg,
+		logger:             logger,
+		connections:        make(map[int64]int),
+		closing:            make(chan struct{}),
+	}
+}
+
+func (ps *PresenceService) Heartbeat(ctx context.Context, userID int64, idle bool) error {
+	return ps.presenceRepository.Heartbeat(ctx, userID, time.Now().UTC(), idle)
+}
+
+// Connect registers an open presence stream for the user, who stays online
+// until release is called. The last release records the final last-seen.
+func (ps *PresenceService) Connect(ctx context.Context, userID int64) (release func(), err error) {
+	if err := ps.presenceRepository.Heartbeat(ctx, userID, time.Now().UTC(), false); err != nil {
+		return nil, err
+	}
+	ps.mu.Lock()
+	ps.connections[userID]++
+	ps.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			ps.mu.Lock()
+			ps.connections[userID]--
+			last := ps.connections[userID] == 0
+			if last {
+				delete(ps.connections, userID)
+			}
+			ps.mu.Unlock()
+
+			if !last {
+				return
+			}
+			// The request context is already done when the client hangs up
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := ps.presenceRepository.Touch(ctx, []int64{userID}, time.Now().UTC()); err != nil {
+				ps.logger.Warnw("Failed to record last seen",
+					"error", err,
+					"user_id", userID,
+				)
+			}
+		})
+	}, nil
+}
+
+// Closing is closed when the server shuts down so open streams can end.
+func (ps *PresenceService) Closing() <-chan struct{} {
+	return ps.closing
+}
+
+// Shutdown ends all open presence streams. It is registered with
+// http.Server.RegisterOnShutdown: Shutdown waits for active requests, and
+// streams never finish on their own.
+func (ps *PresenceService) Shutdown() {
+	ps.closeOnce.Do(func() {
+		close(ps.closing)
+	})
+}
+
+// StreamInterval is how often open streams should write a keep-alive.
+func (ps *PresenceService) StreamInterval() time.Duration {
+	return ps.cfg.RefreshInterval
+}
+
+// Run refreshes last-seen of users with open streams until ctx is cancelled.
+func (ps *PresenceService) Run(ctx context.Context) {
+	ticker := time.NewTicker(ps.cfg.RefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		ps.mu.Lock()
+		userIDs := make([]int64, 0, len(ps.connections))
+		for userID := range ps.connections {
+			userIDs = append(userIDs, userID)
+		}
+		ps.mu.Unlock()
+
+		if err := ps.presenceRepository.Touch(ctx, userIDs, time.Now().UTC()); err != nil && ctx.Err() == nil {
+			ps.logger.Errorw("Failed to refresh presence",
+				"error", err,
+				"users", len(userIDs),
+			)
+		}
+	}
+}
+
+func (ps *PresenceService) Get(ctx context.Context, userID int64) (*Presence, error) {
+	presences, err := ps.Lookup(ctx, []int64{userID})
+	if err != nil {
+		return nil, err
+	}
+	p, ok := presences[userID]
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	return p, nil
+}
+
+// Query returns the presence of each existing user in userIDs, in request
+// order. Unknown users and duplicates are skipped.
+func (ps *PresenceService) Query(ctx context.Context, userIDs []int64) ([]*Presence, error) {
+	presences, err := ps.Lookup(ctx, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*Presence, 0, len(presences))
+	for _, userID := range userIDs {
+		if p, ok := presences[userID]; ok {
+			result = append(result, p)
+			delete(presences, userID)
+		}
+	}
+	return result, nil
+}
+
+// Lookup returns the presence of the given users keyed by user ID.
+func (ps *PresenceService) Lookup(ctx context.Context, userIDs []int64) (map[int64]*Presence, error) {
+	records, err := ps.presenceRepository.GetMany(ctx, userIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	presences := make(map[int64]*Presence, len(records))
+	for userID, rec := range records {
+		presences[userID] = rec.derive(now, ps.cfg.OnlineTTL, ps.connections[userID] > 0)
+	}
+	return presences, nil
+}
+
+func (ps *PresenceService) SetStatus(ctx context.Context, userID int64, req SetStatusRequest) (*Presence, error) {
+	if err := ps.presenceRepository.SetStatus(ctx, userID, req); err != nil {
+		return nil, err
+	}
+	return ps.Get(ctx, userID)
+}
+
+func (ps *PresenceService) ClearStatus(ctx context.Context, userID int64) error {
+	return ps.presenceRepository.ClearStatus(ctx, userID)
+}
@@ -0,0 +1,207 @@
+package retention
+
+import (
+	"encoding/json"
+	"github.com/maxwellzp/golang-chat-api/internal/httpx"
+	"github.com/maxwellzp/golang-chat-api/internal/logger"
+	"github.com/maxwellzp/golang-chat-api/internal/validatorx"
+	"net/http"
+)
+
+type RetentionHandler struct {
+	retentionService *RetentionService
+	validator        *validatorx.Validator
+	logger           *logger.Logger
+}
+
+func NewRetentionHandler(retentionService *RetentionService, validator *validatorx.Validator, logger *logger.Logger) *RetentionHandler {
+	return &RetentionHandler{
+		retentionService: retentionService,
+		validator:        validator,
+		logger:           logger,
+	}
+}
+
+// RoomPolicy returns the room's retention policy and how many of its
+// messages a purge would delete now.
+func (h *RetentionHandler) RoomPolicy() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := httpx.GetUserID(r.Context())
+		if err != nil {
+			h.logger.Warnw("Unauthorized request to get retention policy")
+			httpx.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		roomID, err := httpx.ParseInt64Param(r, "id")
+		if err != nil {
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid RoomID")
+			return
+		}
+
+		policy, err := h.retentionService.RoomPolicy(r.Context(), roomID, userID)
+		if httpx.WriteServiceError(w, err) {
+			return
+		}
+		if err != nil {
+			h.logger.Errorw("Failed to get retention policy",
+				"error", err,
+				"room_id", roomID,
+			)
+			httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
+			return
+		}
+		httpx.WriteJSON(w, http.StatusOK, policy)
+	}
+}
+
+func (h *RetentionHandler) SetRoomPolicy() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := httpx.GetUserID(r.Context())
+		if err != nil {
+			h.logger.Warnw("Unauthorized request to set retention policy")
+			httpx.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		roomID, err := httpx.ParseInt64Param(r, "id")
+		if err != nil {
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid RoomID")
+			return
+		}
+
+		var req SetRoomPolicyRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.logger.Warnw("Failed to decode SetRoomPolicyRequest",
+				"error", err,
+			)
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		if err := h.validator.Validate(&req); err != nil {
+			httpx.WriteValidationError(w, err)
+			return
+		}
+
+		policy, err := h.retentionService.SetRoomPolicy(r.Context(), roomID, userID, req)
+		if httpx.WriteServiceError(w, err) {
+			return
+		}
+		if err != nil {
+			h.logger.Errorw("Failed to set retention policy",
+				"error", err,
+				"room_id", roomID,
+			)
+			httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
+			return
+		}
+		h.logger.Infow("Retention policy changed",
+			"room_id", roomID,
+			"retention_days", req.RetentionDays,
+			"user_id", userID,
+		)
+		httpx.WriteJSON(w, http.StatusOK, policy)
+	}
+}
+
+// Report is a dry run of the purge across all rooms and direct messages.
+// Like the legal hold endpoints it is mounted under /admin.
+func (h *RetentionHandler) Report() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		report, err := h.retentionService.Report(r.Context())
+		if err != nil {
+			h.logger.Errorw("Failed to build retention report",
+				"error", err,
+			)
+			httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
+			return
+		}
+		httpx.WriteJSON(w, http.StatusOK, report)
+	}
+}
+
+func (h *RetentionHandler) HoldRoom() http.HandlerFunc {
+	return h.changeRoomHold(true)
+}
+
+func (h *RetentionHandler) ReleaseRoom() http.HandlerFunc {
+	return h.changeRoomHold(false)
+}
+
+func (h *RetentionHandler) changeRoomHold(hold bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := httpx.GetUserID(r.Context())
+		if err != nil {
+			h.logger.Warnw("Unauthorized request to change room legal hold")
+			httpx.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		roomID, err := httpx.ParseInt64Param(r, "id")
+		if err != nil {
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid RoomID")
+			return
+		}
+
+		err = h.retentionService.SetRoomLegalHold(r.Context(), roomID, hold)
+		if httpx.WriteServiceError(w, err) {
+			return
+		}
+		if err != nil {
+			h.logger.Errorw("Failed to change room legal hold",
+				"error", err,
+				"hold", hold,
+				"room_id", roomID,
+			)
+			httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
+			return
+		}
+		h.logger.Infow("Room legal hold changed",
+			"hold", hold,
+			"room_id", roomID,
+			"user_id", userID,
+		)
+		httpx.WriteJSON(w, http.StatusNoContent, nil)
+	}
+}
+
+func (h *RetentionHandler) HoldUser() http.HandlerFunc {
+	return h.changeUserHold(true)
+}
+
+func (h *RetentionHandler) ReleaseUser() http.HandlerFunc {
+	return h.changeUserHold(false)
+}
+
+func (h *RetentionHandler) changeUserHold(hold bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := httpx.GetUserID(r.Context())
+		if err != nil {
+			h.logger.Warnw("Unauthorized request to change user legal hold")
+			httpx.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		targetID, err := httpx.ParseInt64Param(r, "id")
+		if err != nil {
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid UserID")
+			return
+		}
+
+		err = h.retentionService.SetUserLegalHold(r.Context(), targetID, hold)
+		if httpx.WriteServiceError(w, err) {
+			return
+		}
+		if err != nil {
+			h.logger.Errorw("Failed to change user legal hold",
+				"error", err,
+				"hold", hold,
+				"target_id", targetID,
+			)
+			httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
+			return
+		}
+		h.logger.Infow("User legal hold changed",
+			"hold", hold,
+			"target_id", targetID,
+			"user_id", userID,
+		)
+		httpx.WriteJSON(w, http.StatusNoContent, nil)
+	}
+}
@@ -0,0 +1,36 @@
+package retention
+
+import (
+	"time"
+)
+
+// RoomPolicy is the retention policy in effect for a room and what a purge
+// run now would delete from it. RetentionDays is the room's override, nil
+// when it follows the global default; EffectiveDays is zero when messages
+// are kept forever.
+type RoomPolicy struct {
+	RoomID            int64      `json:"room_id"`
+	RoomName          string     `json:"room_name"`
+	RetentionDays     *int       `json:"retention_days"`
+	EffectiveDays     int        `json:"effective_days"`
+	LegalHold         bool       `json:"legal_hold"`
+	PurgeableMessages int64      `json:"purgeable_messages"`
+	OldestPurgeableAt *time.Time `json:"oldest_purgeable_at"`
+}
+
+// Purgeable counts the messages a purge run would delete.
+type Purgeable struct {
+	Messages int64      `json:"messages"`
+	OldestAt *time.Time `json:"oldest_at"`
+}
+
+// Report is a dry run of the purge across all rooms and direct messages,
+// which always follow the global default.
+type Report struct {
+	GeneratedAt    time.Time     `json:"generated_at"`
+	DefaultDays    int           `json:"default_days"`
+	Rooms          []*RoomPolicy `json:"rooms"`
+	DirectMessages Purgeable     `json:"direct_messages"`
+	HeldUserIDs    []int64       `json:"held_user_ids"`
+	TotalPurgeable int64         `json:"total_purgeable"`
+}
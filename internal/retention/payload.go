@@ -0,0 +1,7 @@
+package retention
+
+// SetRoomPolicyRequest replaces a room's retention override. A null
+// retention_days makes the room follow the global default again.
+type SetRoomPolicyRequest struct {
+	RetentionDays *int `json:"retention_days" validate:"omitempty,min=1,max=36500"`
+}
@@ -0,0 +1,75 @@
+package retention
+
+import (
+	"context"
+	"github.com/maxwellzp/golang-chat-api/internal/config"
+	"github.com/maxwellzp/golang-chat-api/internal/logger"
+	"time"
+)
+
+// Purger deletes messages past retention in small batches, pausing between
+// them so the purge never holds locks on messages for long. Mentions, pins
+// and bookmarks cascade. Several purgers (one per replica) can run against
+// the same database.
+type Purger struct {
+	retentionRepository *RetentionRepository
+	cfg                 config.RetentionConfig
+	logger              *logger.Logger
+}
+
+func NewPurger(retentionRepository *RetentionRepository, cfg config.RetentionConfig, logger *logger.Logger) *Purger {
+	return &Purger{
+		retentionRepository: retentionRepository,
+		cfg:                 cfg,
+		logger:              logger,
+	}
+}
+
+// Run purges every PollInterval until ctx is cancelled.
+func (p *Purger) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		p.Purge(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Purge deletes batches until none are left. The cutoff is fixed when the
+// run starts.
+func (p *Purger) Purge(ctx context.Context) {
+	now := time.Now().UTC()
+	var total int64
+	for {
+		deleted, err := p.retentionRepository.PurgeBatch(ctx, now, p.cfg.DefaultDays, p.cfg.BatchSize)
+		if err != nil {
+			if ctx.Err() == nil {
+				p.logger.Errorw("Failed to purge messages past retention",
+					"error", err,
+					"purged", total,
+				)
+			}
+			return
+		}
+		total += deleted
+		if deleted < int64(p.cfg.BatchSize) {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(p.cfg.BatchPause):
+		}
+	}
+	if total > 0 {
+		p.logger.Infow("Purged messages past retention",
+			"count", total,
+		)
+	}
+}
@@ -0,0 +1,145 @@
+package retention
+
+import (
+	"context"
+	"github.com/maxwellzp/golang-chat-api/internal/db"
+	"time"
+)
+
+type RetentionRepository struct {
+	database *db.Db
+}
+
+func NewRetentionRepository(database *db.Db) *RetentionRepository {
+	return &RetentionRepository{database: database}
+}
+
+// purgeable is a condition matching messages m, with their room left joined
+// as r, that are past retention at nowExpr when rooms without an override
+// keep messages for defaultExpr days. Messages in rooms under legal hold, or
+// sent or received by a user under legal hold, never match.
+func purgeable(nowExpr, defaultExpr string) string {
+	days := `COALESCE(r.retention_days, ` + defaultExpr + `)`
+	return `(` + days + ` > 0
+		AND m.created_at < ` + nowExpr + `::timestamp - ` + days + ` * INTERVAL '1 day'
+		AND NOT COALESCE(r.legal_hold, FALSE)
+		AND NOT EXISTS (SELECT 1 FROM users h WHERE h.legal_hold AND h.id IN (m.sender_id, m.receiver_id)))`
+}
+
+// RoomPolicies returns the policy and purgeable messages of one room, or of
+// every room when roomID is nil.
+func (r *RetentionRepository) RoomPolicies(ctx context.Context, roomID *int64, now time.Time, defaultDays int) ([]*RoomPolicy, error) {
+	query := `SELECT r.id, r.name, r.retention_days, r.legal_hold, COUNT(m.id), MIN(m.created_at)
+			  FROM rooms r
+			  LEFT JOIN messages m ON m.room_id = r.id AND ` + purgeable("$1", "$2") + `
+			  WHERE ($3::int IS NULL OR r.id = $3)
+			  GROUP BY r.id
+			  ORDER BY r.id`
+	rows, err := r.database.QueryContext(ctx, query, now, defaultDays, roomID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	policies := []*RoomPolicy{}
+	for rows.Next() {
+		var p RoomPolicy
+		err := rows.Scan(&p.RoomID, &p.RoomName, &p.RetentionDays, &p.LegalHold, &p.PurgeableMessages, &p.OldestPurgeableAt)
+		if err != nil {
+			return nil, err
+		}
+		p.EffectiveDays = defaultDays
+		if p.RetentionDays != nil {
+			p.EffectiveDays = *p.RetentionDays
+		}
+		policies = append(policies, &p)
+	}
+	return policies, rows.Err()
+}
+
+// PurgeableDirectMessages counts the direct messages a purge would delete.
+func (r *RetentionRepository) PurgeableDirectMessages(ctx context.Context, now time.Time, defaultDays int) (Purgeable, error) {
+	query := `SELECT COUNT(*), MIN(m.created_at)
+			  FROM messages m
+			  LEFT JOIN rooms r ON r.id = m.room_id
+			  WHERE m.room_id IS NULL AND ` + purgeable("$1", "$2")
+	var p Purgeable
+	err := r.database.QueryRowContext(ctx, query, now, defaultDays).Scan(&p.Messages, &p.OldestAt)
+	return p, err
+}
+
+// HeldUserIDs returns the users under legal hold.
+func (r *RetentionRepository) HeldUserIDs(ctx context.Context) ([]int64, error) {
+	rows, err := r.database.QueryContext(ctx, "SELECT id FROM users WHERE legal_hold ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// SetRoomRetention reports false if the room doesn't exist.
+func (r *RetentionRepository) SetRoomRetention(ctx context.Context, roomID int64, days *int) (bool, error) {
+	res, err := r.database.ExecContext(ctx, "UPDATE rooms SET retention_days = $1 WHERE id = $2", days, roomID)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected > 0, nil
+}
+
+// SetRoomLegalHold reports false if the room doesn't exist.
+func (r *RetentionRepository) SetRoomLegalHold(ctx context.Context, roomID int64, hold bool) (bool, error) {
+	res, err := r.database.ExecContext(ctx, "UPDATE rooms SET legal_hold = $1 WHERE id = $2", hold, roomID)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected > 0, nil
+}
+
+// SetUserLegalHold reports false if the user doesn't exist.
+func (r *RetentionRepository) SetUserLegalHold(ctx context.Context, userID int64, hold bool) (bool, error) {
+	res, err := r.database.ExecContext(ctx, "UPDATE users SET legal_hold = $1 WHERE id = $2", hold, userID)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected > 0, nil
+}
+
+// PurgeBatch deletes up to limit messages past retention, oldest first, and
+// returns how many it deleted. Rows locked by another replica are skipped.
+func (r *RetentionRepository) PurgeBatch(ctx context.Context, now time.Time, defaultDays int, limit int) (int64, error) {
+	query := `DELETE FROM messages WHERE id IN (
+				SELECT m.id FROM messages m
+				LEFT JOIN rooms r ON r.id = m.room_id
+				WHERE ` + purgeable("$1", "$2") + `
+				ORDER BY m.created_at
+				LIMIT $3
+				FOR UPDATE OF m SKIP LOCKED
+			  )`
+	res, err := r.database.ExecContext(ctx, query, now, defaultDays, limit)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
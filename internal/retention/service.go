@@ -0,0 +1,117 @@
+package retention
+
+import (
+	"context"
+	"github.com/maxwellzp/golang-chat-api/internal/apperr"
+	"github.com/maxwellzp/golang-chat-api/internal/config"
+	"github.com/maxwellzp/golang-chat-api/internal/room"
+	"time"
+)
+
+var ErrUserNotFound = apperr.NotFound("user_not_found", "user not found")
+
+type RetentionService struct {
+	retentionRepository *RetentionRepository
+	roomService         *room.RoomService
+	cfg                 config.RetentionConfig
+}
+
+func NewRetentionService(retentionRepository *RetentionRepository, roomService *room.RoomService, cfg config.RetentionConfig) *RetentionService {
+	return &RetentionService{
+		retentionRepository: retentionRepository,
+		roomService:         roomService,
+		cfg:                 cfg,
+	}
+}
+
+// RoomPolicy returns the room's policy with a dry run of its purge. Only
+// room admins can see it.
+func (s *RetentionService) RoomPolicy(ctx context.Context, roomID int64, userID int64) (*RoomPolicy, error) {
+	if err := s.roomService.RequireRole(ctx, roomID, userID, room.RoleAdmin); err != nil {
+		return nil, err
+	}
+	return s.roomPolicy(ctx, roomID)
+}
+
+// SetRoomPolicy replaces the room's retention override. Only room admins
+// can change it; a legal hold still wins over a shorter retention.
+func (s *RetentionService) SetRoomPolicy(ctx context.Context, roomID int64, userID int64, req SetRoomPolicyRequest) (*RoomPolicy, error) {
+	if err := s.roomService.RequireRole(ctx, roomID, userID, room.RoleAdmin); err != nil {
+		return nil, err
+	}
+	found, err := s.retentionRepository.SetRoomRetention(ctx, roomID, req.RetentionDays)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, room.ErrRoomNotFound
+	}
+	return s.roomPolicy(ctx, roomID)
+}
+
+func (s *RetentionService) roomPolicy(ctx context.Context, roomID int64) (*RoomPolicy, error) {
+	policies, err := s.retentionRepository.RoomPolicies(ctx, &roomID, time.Now().UTC(), s.cfg.DefaultDays)
+	if err != nil {
+		return nil, err
+	}
+	if len(policies) == 0 {
+		return nil, room.ErrRoomNotFound
+	}
+	return policies[0], nil
+}
+
+// Report is a dry run of the purge across the whole service.
+func (s *RetentionService) Report(ctx context.Context) (*Report, error) {
+	now := time.Now().UTC()
+	rooms, err := s.retentionRepository.RoomPolicies(ctx, nil, now, s.cfg.DefaultDays)
+	if err != nil {
+		return nil, err
+	}
+	direct, err := s.retentionRepository.PurgeableDirectMessages(ctx, now, s.cfg.DefaultDays)
+	if err != nil {
+		return nil, err
+	}
+	held, err := s.retentionRepository.HeldUserIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{
+		GeneratedAt:    now,
+		DefaultDays:    s.cfg.DefaultDays,
+		Rooms:          rooms,
+		DirectMessages: direct,
+		HeldUserIDs:    held,
+		TotalPurgeable: direct.Messages,
+	}
+	for _, p := range rooms {
+		report.TotalPurgeable += p.PurgeableMessages
+	}
+	return report, nil
+}
+
+// SetRoomLegalHold places the room under legal hold, or releases it. Held
+// rooms are never purged.
+func (s *RetentionService) SetRoomLegalHold(ctx context.Context, roomID int64, hold bool) error {
+	found, err := s.retentionRepository.SetRoomLegalHold(ctx, roomID, hold)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return room.ErrRoomNotFound
+	}
+	return nil
+}
+
+// SetUserLegalHold places the user under legal hold, or releases them.
+// Messages they sent and direct messages they received are never purged.
+func (s *RetentionService) SetUserLegalHold(ctx context.Context, targetID int64, hold bool) error {
+	found, err := s.retentionRepository.SetUserLegalHold(ctx, targetID, hold)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrUserNotFound
+	}
+	return nil
+}
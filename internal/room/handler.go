@@ -102,6 +102,9 @@ func (h *RoomHandler) Update() http.HandlerFunc {
 		}
 
 		if err := h.roomService.Update(r.Context(), id, userID, req); err != nil {
+			if httpx.WriteServiceError(w, err) {
+				return
+			}
 			h.logger.Errorw("Failed to update room",
 				"error", err,
 				"user_id", userID,
@@ -136,6 +139,9 @@ func (h *RoomHandler) Delete() http.HandlerFunc {
 		}
 
 		if err := h.roomService.Delete(r.Context(), id, userID); err != nil {
+			if httpx.WriteServiceError(w, err) {
+				return
+			}
 			h.logger.Errorw("Failed to delete room",
 				"error", err,
 				"user_id", userID,
@@ -201,3 +207,227 @@ func (h *RoomHandler) List() http.HandlerFunc {
 		httpx.WriteJSON(w, http.StatusOK, rooms)
 	}
 }
+
+func (h *RoomHandler) Join() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := httpx.GetUserID(r.Context())
+		if err != nil {
+			h.logger.Warnw("Unauthorized request to join room")
+			httpx.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		id, err := httpx.ParseInt64Param(r, "id")
+		if err != nil {
+			h.logger.Warnw("Invalid room ID for join",
+				"error", err,
+			)
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid RoomID")
+			return
+		}
+
+		err = h.roomService.Join(r.Context(), id, userID)
+		if httpx.WriteServiceError(w, err) {
+			return
+		}
+		if err != nil {
+			h.logger.Errorw("Failed to join room",
+				"error", err,
+				"user_id", userID,
+				"room_id", id,
+			)
+			httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
+			return
+		}
+		h.logger.Infow("Room joined",
+			"room_id", id,
+			"user_id", userID,
+		)
+		httpx.WriteJSON(w, http.StatusNoContent, nil)
+	}
+}
+
+func (h *RoomHandler) Leave() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := httpx.GetUserID(r.Context())
+		if err != nil {
+			h.logger.Warnw("Unauthorized request to leave room")
+			httpx.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		id, err := httpx.ParseInt64Param(r, "id")
+		if err != nil {
+			h.logger.Warnw("Invalid room ID for leave",
+				"error", err,
+			)
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid RoomID")
+			return
+		}
+
+		err = h.roomService.Leave(r.Context(), id, userID)
+		if httpx.WriteServiceError(w, err) {
+			return
+		}
+		if err != nil {
+			h.logger.Errorw("Failed to leave room",
+				"error", err,
+				"user_id", userID,
+				"room_id", id,
+			)
+			httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
+			return
+		}
+		h.logger.Infow("Room left",
+			"room_id", id,
+			"user_id", userID,
+		)
+		httpx.WriteJSON(w, http.StatusNoContent, nil)
+	}
+}
+
+func (h *RoomHandler) Members() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := httpx.GetUserID(r.Context())
+		if err != nil {
+			h.logger.Warnw("Unauthorized request to list room members")
+			httpx.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		id, err := httpx.ParseInt64Param(r, "id")
+		if err != nil {
+			h.logger.Warnw("Invalid room ID for members",
+				"error", err,
+			)
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid RoomID")
+			return
+		}
+
+		members, err := h.roomService.Members(r.Context(), id, userID)
+		if httpx.WriteServiceError(w, err) {
+			return
+		}
+		if err != nil {
+			h.logger.Errorw("Failed to list room members",
+				"error", err,
+				"room_id", id,
+			)
+			httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
+			return
+		}
+		httpx.WriteJSON(w, http.StatusOK, members)
+	}
+}
+
+func (h *RoomHandler) AddMember() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := httpx.GetUserID(r.Context())
+		if err != nil {
+			h.logger.Warnw("Unauthorized request to add room member")
+			httpx.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		id, err := httpx.ParseInt64Param(r, "id")
+		if err != nil {
+			h.logger.Warnw("Invalid room ID for add member",
+				"error", err,
+			)
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid RoomID")
+			return
+		}
+
+		var req AddMemberRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.logger.Warnw("Invalid AddMemberRequest body",
+				"error", err,
+			)
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		if err := h.validator.Validate(&req); err != nil {
+			httpx.WriteValidationError(w, err)
+			return
+		}
+
+		err = h.roomService.AddMember(r.Context(), id, userID, req.UserID)
+		if httpx.WriteServiceError(w, err) {
+			return
+		}
+		if err != nil {
+			h.logger.Errorw("Failed to add room member",
+				"error", err,
+				"user_id", userID,
+				"room_id", id,
+				"member_id", req.UserID,
+			)
+			httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
+			return
+		}
+		h.logger.Infow("Room member added",
+			"room_id", id,
+			"user_id", userID,
+			"member_id", req.UserID,
+		)
+		httpx.WriteJSON(w, http.StatusNoContent, nil)
+	}
+}
+
+func (h *RoomHandler) UpdateMemberRole() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := httpx.GetUserID(r.Context())
+		if err != nil {
+			h.logger.Warnw("Unauthorized request to update member role")
+			httpx.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		id, err := httpx.ParseInt64Param(r, "id")
+		if err != nil {
+			h.logger.Warnw("Invalid room ID for member role",
+				"error", err,
+			)
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid RoomID")
+			return
+		}
+		memberID, err := httpx.ParseInt64Param(r, "user_id")
+		if err != nil {
+			h.logger.Warnw("Invalid user ID for member role",
+				"error", err,
+			)
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid UserID")
+			return
+		}
+
+		var req UpdateMemberRoleRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.logger.Warnw("Invalid UpdateMemberRoleRequest body",
+				"error", err,
+			)
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		if err := h.validator.Validate(&req); err != nil {
+			httpx.WriteValidationError(w, err)
+			return
+		}
+
+		err = h.roomService.SetMemberRole(r.Context(), id, userID, memberID, req.Role)
+		if httpx.WriteServiceError(w, err) {
+			return
+		}
+		if err != nil {
+			h.logger.Errorw("Failed to update member role",
+				"error", err,
+				"user_id", userID,
+				"room_id", id,
+				"member_id", memberID,
+			)
+			httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
+			return
+		}
+		h.logger.Infow("Room member role updated",
+			"room_id", id,
+			"user_id", userID,
+			"member_id", memberID,
+			"role", req.Role,
+		)
+		httpx.WriteJSON(w, http.StatusNoContent, nil)
+	}
+}
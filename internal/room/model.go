@@ -1,6 +1,9 @@
 package room
 
-import "time"
+import (
+	"github.com/maxwellzp/golang-chat-api/internal/presence"
+	"time"
+)
 
 type Room struct {
 	ID        int64     `json:"id"`
@@ -8,4 +11,44 @@ type Room struct {
 	IsPrivate bool      `json:"is_private"`
 	CreatedBy *int64    `json:"created_by"`
 	CreatedAt time.Time `json:"created_at"`
+	Topic     string    `json:"topic"`
+
+	// Message rules, configured by room admins. Zero means no limit.
+	SlowModeSeconds  int  `json:"slow_mode_seconds"`
+	MaxMessageLength int  `json:"max_message_length"`
+	AllowLinks       bool `json:"allow_links"`
+	// MessageTTLSeconds makes new messages in the room expire after that
+	// many seconds.
+	MessageTTLSeconds int `json:"message_ttl_seconds"`
+}
+
+const (
+	RoleOwner     = "owner"
+	RoleAdmin     = "admin"
+	RoleModerator = "moderator"
+	RoleMember    = "member"
+)
+
+var roleRank = map[string]int{
+	RoleMember:    1,
+	RoleModerator: 2,
+	RoleAdmin:     3,
+	RoleOwner:     4,
+}
+
+// RoleAtLeast reports whether role grants at least the permissions of min.
+// An empty role (not a member) never does.
+func RoleAtLeast(role, min string) bool {
+	return roleRank[role] > 0 && roleRank[role] >= roleRank[min]
+}
+
+type Member struct {
+	RoomID   int64     `json:"room_id"`
+	UserID   int64     `json:"user_id"`
+	Username string    `json:"username"`
+	Role     string    `json:"role"`
+	JoinedAt time.Time `json:"joined_at"`
+
+	MutedUntil *time.Time         `json:"muted_until,omitempty"`
+	Presence   *presence.Presence `json:"presence,omitempty"`
 }
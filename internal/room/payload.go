@@ -5,7 +5,23 @@ type CreateRoomRequest struct {
 	Private bool   `json:"private"`
 }
 
+// UpdateRoomRequest replaces name and privacy. Message rules are optional and
+// left unchanged when omitted.
 type UpdateRoomRequest struct {
-	Name    string `json:"name" validate:"required,min=3,max=50"`
-	Private bool   `json:"private"`
+	Name             string `json:"name" validate:"required,min=3,max=50"`
+	Private          bool   `json:"private"`
+	SlowModeSeconds  *int   `json:"slow_mode_seconds" validate:"omitempty,min=0,max=21600"`
+	MaxMessageLength *int   `json:"max_message_length" validate:"omitempty,min=0,max=10000"`
+	AllowLinks       *bool  `json:"allow_links"`
+	// MessageTTLSeconds is the default lifetime of new messages, up to 30
+	// days. Zero keeps messages until deleted.
+	MessageTTLSeconds *int `json:"message_ttl_seconds" validate:"omitempty,min=0,max=2592000"`
+}
+
+type AddMemberRequest struct {
+	UserID int64 `json:"user_id" validate:"required"`
+}
+
+type UpdateMemberRoleRequest struct {
+	Role string `json:"role" validate:"required,oneof=admin moderator member"`
 }
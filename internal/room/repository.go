@@ -16,24 +16,68 @@ func NewRoomRepository(database *db.Db) *RoomRepository {
 	return &RoomRepository{database: database}
 }
 
+// roomColumns is the select list matching scanRoom.
+const roomColumns = `id, name, is_private, created_by, created_at, topic,
+				slow_mode_seconds, max_message_length, allow_links, message_ttl_seconds`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanRoom(row rowScanner) (*Room, error) {
+	var rm Room
+	err := row.Scan(
+		&rm.ID,
+		&rm.Name,
+		&rm.IsPrivate,
+		&rm.CreatedBy,
+		&rm.CreatedAt,
+		&rm.Topic,
+		&rm.SlowModeSeconds,
+		&rm.MaxMessageLength,
+		&rm.AllowLinks,
+		&rm.MessageTTLSeconds,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &rm, nil
+}
+
 func (r *RoomRepository) Create(ctx context.Context, room *Room) error {
-	query := `INSERT INTO rooms (name, is_private, created_by, created_at) 
+	// The creator becomes the room owner in the same statement.
+	query := `WITH new_room AS (
+				INSERT INTO rooms (name, is_private, created_by, created_at) 
 				VALUES ($1, $2, $3, $4)
-				RETURNING id, created_at;`
+				RETURNING id, created_by, created_at, slow_mode_seconds, max_message_length, allow_links, message_ttl_seconds
+			), owner AS (
+				INSERT INTO room_members (room_id, user_id, role, joined_at)
+				SELECT id, created_by, 'owner', created_at FROM new_room WHERE created_by IS NOT NULL
+			)
+			SELECT id, created_at, slow_mode_seconds, max_message_length, allow_links, message_ttl_seconds FROM new_room;`
 
 	row := r.database.QueryRowContext(ctx, query, room.Name, room.IsPrivate, room.CreatedBy, time.Now())
-	err := row.Scan(&room.ID, &room.CreatedAt)
+	err := row.Scan(&room.ID, &room.CreatedAt, &room.SlowModeSeconds, &room.MaxMessageLength, &room.AllowLinks, &room.MessageTTLSeconds)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func (r *RoomRepository) Update(ctx context.Context, id int64, userID int64, name string, isPrivate bool) error {
+func (r *RoomRepository) Update(ctx context.Context, id int64, userID int64, req UpdateRoomRequest) error {
 	query := `
-		UPDATE rooms SET name = $1, is_private = $2 WHERE id = $3 AND created_by = $4;
+		UPDATE rooms SET name = $1, is_private = $2,
+			slow_mode_seconds = COALESCE($5, slow_mode_seconds),
+			max_message_length = COALESCE($6, max_message_length),
+			allow_links = COALESCE($7, allow_links),
+			message_ttl_seconds = COALESCE($8, message_ttl_seconds)
+		WHERE id = $3 AND EXISTS (
+			SELECT 1 FROM room_members
+			WHERE room_id = $3 AND user_id = $4 AND role IN ('owner', 'admin')
+		);
 `
-	res, err := r.database.ExecContext(ctx, query, name, isPrivate, id, userID)
+	res, err := r.database.ExecContext(ctx, query, req.Name, req.Private, id, userID,
+		req.SlowModeSeconds, req.MaxMessageLength, req.AllowLinks, req.MessageTTLSeconds)
 	if err != nil {
 		return err
 	}
@@ -43,7 +87,7 @@ func (r *RoomRepository) Update(ctx context.Context, id int64, userID int64, nam
 		return err
 	}
 	if rowsAffected == 0 {
-		return errors.New("no room found or permission denied")
+		return ErrForbidden
 	}
 
 	return nil
@@ -60,29 +104,29 @@ func (r *RoomRepository) Delete(ctx context.Context, roomID int64, userID int64)
 		return err
 	}
 	if rowsAffected == 0 {
-		return errors.New("no room found or permission denied")
+		return ErrForbidden
 	}
 	return nil
 }
 
 func (r *RoomRepository) GetByID(ctx context.Context, roomID int64) (*Room, error) {
-	query := `SELECT id, name, is_private, created_by, created_at FROM rooms WHERE id = $1`
+	query := `SELECT ` + roomColumns + `
+			  FROM rooms WHERE id = $1`
 	row := r.database.QueryRowContext(ctx, query, roomID)
 
-	var rm Room
-	err := row.Scan(&rm.ID, &rm.Name, &rm.IsPrivate, &rm.CreatedBy, &rm.CreatedAt)
-
+	rm, err := scanRoom(row)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil
 		}
 		return nil, err
 	}
-	return &rm, nil
+	return rm, nil
 }
 
 func (r *RoomRepository) List(ctx context.Context) ([]*Room, error) {
-	query := `SELECT id, name, is_private, created_by, created_at FROM rooms
+	query := `SELECT ` + roomColumns + `
+			  FROM rooms
 `
 	rows, err := r.database.QueryContext(ctx, query)
 	if err != nil {
@@ -92,18 +136,189 @@ func (r *RoomRepository) List(ctx context.Context) ([]*Room, error) {
 
 	var rooms []*Room
 	for rows.Next() {
-		var msg Room
-		err := rows.Scan(
-			&msg.ID,
-			&msg.Name,
-			&msg.IsPrivate,
-			&msg.CreatedBy,
-			&msg.CreatedAt,
-		)
+		rm, err := scanRoom(rows)
 		if err != nil {
 			return nil, err
 		}
-		rooms = append(rooms, &msg)
+		rooms = append(rooms, rm)
 	}
 	return rooms, nil
 }
+
+// AddMember adds the user with role; it reports false if they were already a
+// member, in which case their role is left unchanged.
+func (r *RoomRepository) AddMember(ctx context.Context, roomID int64, userID int64, role string) (bool, error) {
+	res, err := r.database.ExecContext(ctx,
+		`INSERT INTO room_members (room_id, user_id, role, joined_at) VALUES ($1, $2, $3, $4)
+		 ON CONFLICT (room_id, user_id) DO NOTHING`,
+		roomID, userID, role, time.Now())
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected > 0, nil
+}
+
+func (r *RoomRepository) RemoveMember(ctx context.Context, roomID int64, userID int64) error {
+	res, err := r.database.ExecContext(ctx,
+		"DELETE FROM room_members WHERE room_id = $1 AND user_id = $2", roomID, userID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrNotMember
+	}
+	return nil
+}
+
+// MemberRole returns the user's role in the room, or "" if they aren't a member.
+func (r *RoomRepository) MemberRole(ctx context.Context, roomID int64, userID int64) (string, error) {
+	var role string
+	err := r.database.QueryRowContext(ctx,
+		"SELECT role FROM room_members WHERE room_id = $1 AND user_id = $2", roomID, userID).Scan(&role)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", nil
+		}
+		return "", err
+	}
+	return role, nil
+}
+
+func (r *RoomRepository) UpdateMemberRole(ctx context.Context, roomID int64, userID int64, role string) error {
+	res, err := r.database.ExecContext(ctx,
+		"UPDATE room_members SET role = $1 WHERE room_id = $2 AND user_id = $3", role, roomID, userID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrNotMember
+	}
+	return nil
+}
+
+func (r *RoomRepository) ListMembers(ctx context.Context, roomID int64) ([]*Member, error) {
+	query := `SELECT m.room_id, m.user_id, u.username, m.role, m.joined_at, m.muted_until
+			  FROM room_members m
+			  JOIN users u ON u.id = m.user_id
+			  WHERE m.room_id = $1
+			  ORDER BY m.joined_at ASC`
+	rows, err := r.database.QueryContext(ctx, query, roomID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var members []*Member
+	for rows.Next() {
+		var m Member
+		if err := rows.Scan(&m.RoomID, &m.UserID, &m.Username, &m.Role, &m.JoinedAt, &m.MutedUntil); err != nil {
+			return nil, err
+		}
+		members = append(members, &m)
+	}
+	return members, rows.Err()
+}
+
+func (r *RoomRepository) SetTopic(ctx context.Context, roomID int64, topic string) error {
+	res, err := r.database.ExecContext(ctx, "UPDATE rooms SET topic = $1 WHERE id = $2", topic, roomID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrRoomNotFound
+	}
+	return nil
+}
+
+// SetMutedUntil mutes a member until the given time, or unmutes them when
+// until is nil.
+func (r *RoomRepository) SetMutedUntil(ctx context.Context, roomID int64, userID int64, until *time.Time) error {
+	res, err := r.database.ExecContext(ctx,
+		"UPDATE room_members SET muted_until = $1 WHERE room_id = $2 AND user_id = $3", until, roomID, userID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrNotMember
+	}
+	return nil
+}
+
+// MutedUntil returns when the member's mute ends, or nil if they aren't muted
+// (or aren't a member).
+func (r *RoomRepository) MutedUntil(ctx context.Context, roomID int64, userID int64) (*time.Time, error) {
+	var until *time.Time
+	err := r.database.QueryRowContext(ctx,
+		"SELECT muted_until FROM room_members WHERE room_id = $1 AND user_id = $2", roomID, userID).Scan(&until)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return until, nil
+}
+
+// Ban removes the user from the room and records the ban, which keeps them
+// from joining or posting again.
+func (r *RoomRepository) Ban(ctx context.Context, roomID int64, userID int64, bannedBy int64) error {
+	tx, err := r.database.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx,
+		"DELETE FROM room_members WHERE room_id = $1 AND user_id = $2", roomID, userID); err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO room_bans (room_id, user_id, banned_by, created_at) VALUES ($1, $2, $3, $4)
+		 ON CONFLICT (room_id, user_id) DO NOTHING`,
+		roomID, userID, bannedBy, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Unban lifts the ban; it reports false if the user wasn't banned.
+func (r *RoomRepository) Unban(ctx context.Context, roomID int64, userID int64) (bool, error) {
+	res, err := r.database.ExecContext(ctx,
+		"DELETE FROM room_bans WHERE room_id = $1 AND user_id = $2", roomID, userID)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected > 0, nil
+}
+
+func (r *RoomRepository) IsBanned(ctx context.Context, roomID int64, userID int64) (bool, error) {
+	var banned bool
+	err := r.database.QueryRowContext(ctx,
+		"SELECT EXISTS (SELECT 1 FROM room_bans WHERE room_id = $1 AND user_id = $2)", roomID, userID).Scan(&banned)
+	return banned, err
+}
@@ -2,14 +2,33 @@ package room
 
 import (
 	"context"
+	"github.com/maxwellzp/golang-chat-api/internal/apperr"
+	"github.com/maxwellzp/golang-chat-api/internal/audit"
+	"github.com/maxwellzp/golang-chat-api/internal/event"
+	"github.com/maxwellzp/golang-chat-api/internal/presence"
+	"time"
+)
+
+var (
+	ErrRoomNotFound      = apperr.NotFound("room_not_found", "room not found")
+	ErrPrivateRoom       = apperr.Forbidden("room_private", "room is private, ask a room admin to add you")
+	ErrForbidden         = apperr.Forbidden("room_forbidden", "you don't have permission to do that in this room")
+	ErrOwnerCannotLeave  = apperr.Conflict("owner_cannot_leave", "the room owner can't leave the room, delete it instead")
+	ErrCannotChangeOwner = apperr.Conflict("owner_role_locked", "the room owner's role can't be changed")
+	ErrNotMember         = apperr.NotFound("not_member", "user is not a member of this room")
+	ErrBanned            = apperr.Forbidden("banned", "user is banned from this room")
+	ErrNotBanned         = apperr.NotFound("not_banned", "user is not banned from this room")
 )
 
 type RoomService struct {
-	roomRepository *RoomRepository
+	roomRepository  *RoomRepository
+	presenceService *presence.PresenceService
+	audit           *audit.AuditService
+	bus             *event.Bus
 }
 
-func NewRoomService(roomRepository *RoomRepository) *RoomService {
-	return &RoomService{roomRepository: roomRepository}
+func NewRoomService(roomRepository *RoomRepository, presenceService *presence.PresenceService, audit *audit.AuditService, bus *event.Bus) *RoomService {
+	return &RoomService{roomRepository: roomRepository, presenceService: presenceService, audit: audit, bus: bus}
 }
 
 func (rs *RoomService) Create(ctx context.Context, userId int64, req CreateRoomRequest) (*Room, error) {
@@ -22,16 +41,48 @@ func (rs *RoomService) Create(ctx context.Context, userId int64, req CreateRoomR
 	if err := rs.roomRepository.Create(ctx, rm); err != nil {
 		return nil, err
 	}
+	rs.record(ctx, audit.ActionRoomCreate, userId, rm.ID, nil, map[string]any{
+		"name":       rm.Name,
+		"is_private": rm.IsPrivate,
+	})
 	return rm, nil
 }
 
 func (rs *RoomService) Update(ctx context.Context, roomID int64, userID int64, req UpdateRoomRequest) error {
-
-	return rs.roomRepository.Update(ctx, roomID, userID, req.Name, req.Private)
+	before, err := rs.roomRepository.GetByID(ctx, roomID)
+	if err != nil {
+		return err
+	}
+	if before == nil {
+		return ErrRoomNotFound
+	}
+	if err := rs.roomRepository.Update(ctx, roomID, userID, req); err != nil {
+		return err
+	}
+	after, err := rs.roomRepository.GetByID(ctx, roomID)
+	if err != nil {
+		return err
+	}
+	if after != nil {
+		changedBefore, changedAfter := audit.Changes(auditFields(before), auditFields(after))
+		rs.record(ctx, audit.ActionRoomUpdate, userID, roomID, changedBefore, changedAfter)
+	}
+	return nil
 }
 
 func (rs *RoomService) Delete(ctx context.Context, roomID int64, userID int64) error {
-	return rs.roomRepository.Delete(ctx, roomID, userID)
+	rm, err := rs.roomRepository.GetByID(ctx, roomID)
+	if err != nil {
+		return err
+	}
+	if rm == nil {
+		return ErrRoomNotFound
+	}
+	if err := rs.roomRepository.Delete(ctx, roomID, userID); err != nil {
+		return err
+	}
+	rs.record(ctx, audit.ActionRoomDelete, userID, roomID, auditFields(rm), nil)
+	return nil
 }
 
 func (rs *RoomService) GetByID(ctx context.Context, roomID int64) (*Room, error) {
@@ -41,3 +92,309 @@ func (rs *RoomService) GetByID(ctx context.Context, roomID int64) (*Room, error)
 func (rs *RoomService) List(ctx context.Context) ([]*Room, error) {
 	return rs.roomRepository.List(ctx)
 }
+
+// MemberRole returns the user's role in the room, or "" for non-members.
+func (rs *RoomService) MemberRole(ctx context.Context, roomID int64, userID int64) (string, error) {
+	return rs.roomRepository.MemberRole(ctx, roomID, userID)
+}
+
+// RequireRole returns ErrForbidden unless the user holds at least min in the
+// room, and ErrRoomNotFound if the room doesn't exist.
+func (rs *RoomService) RequireRole(ctx context.Context, roomID int64, userID int64, min string) error {
+	rm, err := rs.roomRepository.GetByID(ctx, roomID)
+	if err != nil {
+		return err
+	}
+	if rm == nil {
+		return ErrRoomNotFound
+	}
+	role, err := rs.roomRepository.MemberRole(ctx, roomID, userID)
+	if err != nil {
+		return err
+	}
+	if !RoleAtLeast(role, min) {
+		return rs.forbid(ctx, roomID, userID, map[string]any{"role": role, "required_role": min})
+	}
+	return nil
+}
+
+// Join adds the user to a public room. Private rooms need an admin to add
+// members with AddMember.
+func (rs *RoomService) Join(ctx context.Context, roomID int64, userID int64) error {
+	rm, err := rs.roomRepository.GetByID(ctx, roomID)
+	if err != nil {
+		return err
+	}
+	if rm == nil {
+		return ErrRoomNotFound
+	}
+	if rm.IsPrivate {
+		return ErrPrivateRoom
+	}
+	return rs.addMember(ctx, roomID, userID, userID, audit.ActionMemberJoin)
+}
+
+// AddMember lets a room admin add another user, e.g. to a private room.
+func (rs *RoomService) AddMember(ctx context.Context, roomID int64, actorID int64, userID int64) error {
+	if err := rs.RequireRole(ctx, roomID, actorID, RoleAdmin); err != nil {
+		return err
+	}
+	return rs.addMember(ctx, roomID, actorID, userID, audit.ActionMemberAdd)
+}
+
+func (rs *RoomService) addMember(ctx context.Context, roomID int64, actorID int64, userID int64, action string) error {
+	banned, err := rs.roomRepository.IsBanned(ctx, roomID, userID)
+	if err != nil {
+		return err
+	}
+	if banned {
+		return ErrBanned
+	}
+	added, err := rs.roomRepository.AddMember(ctx, roomID, userID, RoleMember)
+	if err != nil {
+		return err
+	}
+	if added {
+		rs.announceMember(ctx, action, roomID, actorID, userID)
+	}
+	return nil
+}
+
+// MemberAdded records and announces a member that another package added in
+// its own transaction, such as an incoming webhook's bot.
+func (rs *RoomService) MemberAdded(ctx context.Context, roomID int64, actorID int64, userID int64) {
+	rs.announceMember(ctx, audit.ActionMemberAdd, roomID, actorID, userID)
+}
+
+func (rs *RoomService) announceMember(ctx context.Context, action string, roomID int64, actorID int64, userID int64) {
+	rs.record(ctx, action, actorID, roomID, nil, map[string]any{"user_id": userID, "role": RoleMember})
+	rs.bus.Publish(ctx, event.Event{
+		Type:    event.MemberJoined,
+		RoomID:  &roomID,
+		ActorID: actorID,
+		Data: map[string]any{
+			"room_id": roomID,
+			"user_id": userID,
+			"role":    RoleMember,
+		},
+	})
+}
+
+func (rs *RoomService) Leave(ctx context.Context, roomID int64, userID int64) error {
+	role, err := rs.roomRepository.MemberRole(ctx, roomID, userID)
+	if err != nil {
+		return err
+	}
+	if role == RoleOwner {
+		return ErrOwnerCannotLeave
+	}
+	if err := rs.roomRepository.RemoveMember(ctx, roomID, userID); err != nil {
+		return err
+	}
+	rs.record(ctx, audit.ActionMemberLeave, userID, roomID, map[string]any{"user_id": userID, "role": role}, nil)
+	return nil
+}
+
+// SetMemberRole changes a member's role. Admins manage moderators and
+// members; only the owner can grant or revoke admin.
+func (rs *RoomService) SetMemberRole(ctx context.Context, roomID int64, actorID int64, userID int64, role string) error {
+	if err := rs.RequireRole(ctx, roomID, actorID, RoleAdmin); err != nil {
+		return err
+	}
+	actorRole, err := rs.roomRepository.MemberRole(ctx, roomID, actorID)
+	if err != nil {
+		return err
+	}
+	currentRole, err := rs.roomRepository.MemberRole(ctx, roomID, userID)
+	if err != nil {
+		return err
+	}
+	if currentRole == RoleOwner {
+		return ErrCannotChangeOwner
+	}
+	if (role == RoleAdmin || currentRole == RoleAdmin) && actorRole != RoleOwner {
+		return rs.forbid(ctx, roomID, actorID, map[string]any{
+			"role":          actorRole,
+			"required_role": RoleOwner,
+			"user_id":       userID,
+		})
+	}
+	if err := rs.roomRepository.UpdateMemberRole(ctx, roomID, userID, role); err != nil {
+		return err
+	}
+	rs.record(ctx, audit.ActionMemberRole, actorID, roomID,
+		map[string]any{"user_id": userID, "role": currentRole},
+		map[string]any{"user_id": userID, "role": role})
+	return nil
+}
+
+// RequireAccess checks that userID can see the room: anyone can see a public
+// room, only members a private one.
+func (rs *RoomService) RequireAccess(ctx context.Context, roomID int64, userID int64) error {
+	rm, err := rs.roomRepository.GetByID(ctx, roomID)
+	if err != nil {
+		return err
+	}
+	if rm == nil {
+		return ErrRoomNotFound
+	}
+	if rm.IsPrivate {
+		role, err := rs.roomRepository.MemberRole(ctx, roomID, userID)
+		if err != nil {
+			return err
+		}
+		if role == "" {
+			return rs.forbid(ctx, roomID, userID, map[string]any{"role": role, "required_role": RoleMember})
+		}
+	}
+	return nil
+}
+
+// Members lists the room's members with their presence. Private room member
+// lists are only visible to members.
+func (rs *RoomService) Members(ctx context.Context, roomID int64, userID int64) ([]*Member, error) {
+	if err := rs.RequireAccess(ctx, roomID, userID); err != nil {
+		return nil, err
+	}
+
+	members, err := rs.roomRepository.ListMembers(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+	userIDs := make([]int64, len(members))
+	for i, m := range members {
+		userIDs[i] = m.UserID
+	}
+	presences, err := rs.presenceService.Lookup(ctx, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range members {
+		m.Presence = presences[m.UserID]
+	}
+	return members, nil
+}
+
+// SetTopic changes the room topic. Moderators and above may set it.
+func (rs *RoomService) SetTopic(ctx context.Context, roomID int64, actorID int64, topic string) error {
+	if err := rs.RequireRole(ctx, roomID, actorID, RoleModerator); err != nil {
+		return err
+	}
+	rm, err := rs.roomRepository.GetByID(ctx, roomID)
+	if err != nil {
+		return err
+	}
+	if err := rs.roomRepository.SetTopic(ctx, roomID, topic); err != nil {
+		return err
+	}
+	if rm != nil {
+		rs.record(ctx, audit.ActionRoomTopic, actorID, roomID, map[string]any{"topic": rm.Topic}, map[string]any{"topic": topic})
+	}
+	return nil
+}
+
+// Mute stops a member from posting in the room until the given time; a nil
+// until lifts the mute. Moderators can only mute members ranked below them.
+func (rs *RoomService) Mute(ctx context.Context, roomID int64, actorID int64, userID int64, until *time.Time) error {
+	if err := rs.requireOutranks(ctx, roomID, actorID, userID); err != nil {
+		return err
+	}
+	if err := rs.roomRepository.SetMutedUntil(ctx, roomID, userID, until); err != nil {
+		return err
+	}
+	rs.record(ctx, audit.ActionMemberMute, actorID, roomID, nil, map[string]any{"user_id": userID, "muted_until": until})
+	return nil
+}
+
+// requireOutranks checks that actorID moderates the room and that userID is
+// a member ranked below them.
+func (rs *RoomService) requireOutranks(ctx context.Context, roomID int64, actorID int64, userID int64) error {
+	if err := rs.RequireRole(ctx, roomID, actorID, RoleModerator); err != nil {
+		return err
+	}
+	actorRole, err := rs.roomRepository.MemberRole(ctx, roomID, actorID)
+	if err != nil {
+		return err
+	}
+	targetRole, err := rs.roomRepository.MemberRole(ctx, roomID, userID)
+	if err != nil {
+		return err
+	}
+	if targetRole == "" {
+		return ErrNotMember
+	}
+	if roleRank[targetRole] >= roleRank[actorRole] {
+		return rs.forbid(ctx, roomID, actorID, map[string]any{
+			"role":      actorRole,
+			"user_id":   userID,
+			"user_role": targetRole,
+		})
+	}
+	return nil
+}
+
+// Ban removes a member from the room and keeps them from joining, being
+// added or posting again until Unban. Like Mute, moderators can only ban
+// members ranked below them.
+func (rs *RoomService) Ban(ctx context.Context, roomID int64, actorID int64, userID int64) error {
+	if err := rs.requireOutranks(ctx, roomID, actorID, userID); err != nil {
+		return err
+	}
+	if err := rs.roomRepository.Ban(ctx, roomID, userID, actorID); err != nil {
+		return err
+	}
+	rs.record(ctx, audit.ActionMemberBan, actorID, roomID, nil, map[string]any{"user_id": userID})
+	return nil
+}
+
+// Unban lets a banned user join the room again. Moderators and above may
+// lift bans.
+func (rs *RoomService) Unban(ctx context.Context, roomID int64, actorID int64, userID int64) error {
+	if err := rs.RequireRole(ctx, roomID, actorID, RoleModerator); err != nil {
+		return err
+	}
+	found, err := rs.roomRepository.Unban(ctx, roomID, userID)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrNotBanned
+	}
+	rs.record(ctx, audit.ActionMemberUnban, actorID, roomID, nil, map[string]any{"user_id": userID})
+	return nil
+}
+
+// IsBanned reports whether the user is banned from the room.
+func (rs *RoomService) IsBanned(ctx context.Context, roomID int64, userID int64) (bool, error) {
+	return rs.roomRepository.IsBanned(ctx, roomID, userID)
+}
+
+// forbid records a refused room action in the audit log and returns
+// ErrForbidden.
+func (rs *RoomService) forbid(ctx context.Context, roomID int64, userID int64, details map[string]any) error {
+	rs.record(ctx, audit.ActionPermDenied, userID, roomID, nil, details)
+	return ErrForbidden
+}
+
+func (rs *RoomService) record(ctx context.Context, action string, actorID int64, roomID int64, before map[string]any, after map[string]any) {
+	rs.audit.Record(ctx, audit.Entry{
+		Action:     action,
+		ActorID:    &actorID,
+		TargetType: audit.TargetRoom,
+		TargetID:   &roomID,
+		Before:     before,
+		After:      after,
+	})
+}
+
+// auditFields are the room settings the audit log tracks.
+func auditFields(rm *Room) map[string]any {
+	return map[string]any{
+		"name":                rm.Name,
+		"is_private":          rm.IsPrivate,
+		"slow_mode_seconds":   rm.SlowModeSeconds,
+		"max_message_length":  rm.MaxMessageLength,
+		"allow_links":         rm.AllowLinks,
+		"message_ttl_seconds": rm.MessageTTLSeconds,
+	}
+}
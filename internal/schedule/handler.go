@@ -0,0 +1,183 @@
+package schedule
+
+import (
+	"encoding/json"
+	"github.com/maxwellzp/golang-chat-api/internal/httpx"
+	"github.com/maxwellzp/golang-chat-api/internal/logger"
+	"github.com/maxwellzp/golang-chat-api/internal/validatorx"
+	"net/http"
+)
+
+type ScheduleHandler struct {
+	scheduleService *ScheduleService
+	validator       *validatorx.Validator
+	logger          *logger.Logger
+}
+
+func NewScheduleHandler(scheduleService *ScheduleService, validator *validatorx.Validator, logger *logger.Logger) *ScheduleHandler {
+	return &ScheduleHandler{
+		scheduleService: scheduleService,
+		validator:       validator,
+		logger:          logger,
+	}
+}
+
+func (h *ScheduleHandler) Schedule() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := httpx.GetUserID(r.Context())
+		if err != nil {
+			h.logger.Warnw("Unauthorized request to schedule message")
+			httpx.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+
+		var req ScheduleMessageRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.logger.Warnw("Failed to decode ScheduleMessageRequest",
+				"error", err,
+			)
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		if err := h.validator.Validate(&req); err != nil {
+			httpx.WriteValidationError(w, err)
+			return
+		}
+		if err := req.Validate(); err != nil {
+			httpx.WriteValidationError(w, err)
+			return
+		}
+
+		sm, err := h.scheduleService.Schedule(r.Context(), userID, req)
+		if httpx.WriteServiceError(w, err) {
+			return
+		}
+		if err != nil {
+			h.logger.Errorw("Failed to schedule message",
+				"error", err,
+				"user_id", userID,
+			)
+			httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
+			return
+		}
+		h.logger.Infow("Message scheduled",
+			"scheduled_id", sm.ID,
+			"user_id", userID,
+			"send_at", sm.SendAt,
+		)
+		httpx.WriteJSON(w, http.StatusCreated, sm)
+	}
+}
+
+// List returns the current user's scheduled messages and reminders, soonest
+// first. Pass ?status=pending, sent or failed to filter.
+func (h *ScheduleHandler) List() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := httpx.GetUserID(r.Context())
+		if err != nil {
+			h.logger.Warnw("Unauthorized request to list scheduled messages")
+			httpx.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		limit, offset, err := httpx.ParsePagination(r, 50, 100)
+		if err != nil {
+			httpx.WriteValidationError(w, err)
+			return
+		}
+		status := r.URL.Query().Get("status")
+		switch status {
+		case "", StatusPending, StatusSent, StatusFailed:
+		default:
+			httpx.WriteValidationError(w, httpx.ValidationErrorMap{"status": "status must be pending, sent or failed"})
+			return
+		}
+
+		scheduled, err := h.scheduleService.List(r.Context(), userID, status, limit, offset)
+		if err != nil {
+			h.logger.Errorw("Failed to list scheduled messages",
+				"error", err,
+				"user_id", userID,
+			)
+			httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
+			return
+		}
+		httpx.WriteJSON(w, http.StatusOK, scheduled)
+	}
+}
+
+func (h *ScheduleHandler) Update() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := httpx.GetUserID(r.Context())
+		if err != nil {
+			h.logger.Warnw("Unauthorized request to update scheduled message")
+			httpx.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		id, err := httpx.ParseInt64Param(r, "id")
+		if err != nil {
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid ScheduledMessageID")
+			return
+		}
+
+		var req UpdateScheduledMessageRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.logger.Warnw("Failed to decode UpdateScheduledMessageRequest",
+				"error", err,
+			)
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		if err := h.validator.Validate(&req); err != nil {
+			httpx.WriteValidationError(w, err)
+			return
+		}
+		if err := req.Validate(); err != nil {
+			httpx.WriteValidationError(w, err)
+			return
+		}
+
+		sm, err := h.scheduleService.Update(r.Context(), id, userID, req)
+		if httpx.WriteServiceError(w, err) {
+			return
+		}
+		if err != nil {
+			h.logger.Errorw("Failed to update scheduled message",
+				"error", err,
+				"scheduled_id", id,
+			)
+			httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
+			return
+		}
+		httpx.WriteJSON(w, http.StatusOK, sm)
+	}
+}
+
+func (h *ScheduleHandler) Cancel() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := httpx.GetUserID(r.Context())
+		if err != nil {
+			h.logger.Warnw("Unauthorized request to cancel scheduled message")
+			httpx.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		id, err := httpx.ParseInt64Param(r, "id")
+		if err != nil {
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid ScheduledMessageID")
+			return
+		}
+
+		err = h.scheduleService.Cancel(r.Context(), id, userID)
+		if httpx.WriteServiceError(w, err) {
+			return
+		}
+		if err != nil {
+			h.logger.Errorw("Failed to cancel scheduled message",
+				"error", err,
+				"scheduled_id", id,
+			)
+			httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
+			return
+		}
+		httpx.WriteJSON(w, http.StatusNoContent, nil)
+	}
+}
@@ -0,0 +1,40 @@
+package schedule
+
+import "time"
+
+const (
+	KindMessage = "message"
+	// KindReminder is a /remind reminder, posted to the user's own DMs.
+	KindReminder = "reminder"
+)
+
+const (
+	StatusPending = "pending"
+	StatusSent    = "sent"
+	StatusFailed  = "failed"
+)
+
+type ScheduledMessage struct {
+	ID         int64     `json:"id"`
+	UserID     int64     `json:"user_id"`
+	Kind       string    `json:"kind"`
+	RoomID     *int64    `json:"room_id,omitempty"`
+	ReceiverID *int64    `json:"receiver_id,omitempty"`
+	Content    string    `json:"content"`
+	SendAt     time.Time `json:"send_at"`
+	Status     string    `json:"status"`
+	Attempts   int       `json:"attempts"`
+	LastError  *string   `json:"last_error,omitempty"`
+	MessageID  *int64    `json:"message_id,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// outcome is the result of one attempt to post a scheduled message. A
+// pending outcome is retried at SendAt.
+type outcome struct {
+	Status    string
+	SendAt    time.Time
+	MessageID *int64
+	Error     *string
+}
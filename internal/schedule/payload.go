@@ -0,0 +1,67 @@
+package schedule
+
+import (
+	"github.com/maxwellzp/golang-chat-api/internal/command"
+	"github.com/maxwellzp/golang-chat-api/internal/httpx"
+	"github.com/maxwellzp/golang-chat-api/internal/message"
+	"time"
+)
+
+// maxScheduleAhead is how far in the future a message can be scheduled.
+const maxScheduleAhead = 365 * 24 * time.Hour
+
+type ScheduleMessageRequest struct {
+	RoomID     *int64    `json:"room_id,omitempty"`
+	ReceiverID *int64    `json:"receiver_id,omitempty"`
+	Content    string    `json:"content" validate:"required,min=3"`
+	SendAt     time.Time `json:"send_at" validate:"required"`
+}
+
+func (r *ScheduleMessageRequest) Validate() error {
+	target := message.CreateMessageRequest{RoomID: r.RoomID, ReceiverID: r.ReceiverID}
+	if err := target.Validate(); err != nil {
+		return err
+	}
+	if err := validateContent(r.Content); err != nil {
+		return err
+	}
+	return validateSendAt(r.SendAt)
+}
+
+// UpdateScheduledMessageRequest changes only the fields that are set.
+type UpdateScheduledMessageRequest struct {
+	Content *string    `json:"content,omitempty" validate:"omitempty,min=3"`
+	SendAt  *time.Time `json:"send_at,omitempty"`
+}
+
+func (r *UpdateScheduledMessageRequest) Validate() error {
+	if r.Content != nil {
+		if err := validateContent(*r.Content); err != nil {
+			return err
+		}
+	}
+	if r.SendAt != nil {
+		return validateSendAt(*r.SendAt)
+	}
+	return nil
+}
+
+// validateContent rejects slash commands: their output is only shown to the
+// sender, who won't be looking when the message goes out.
+func validateContent(content string) error {
+	if _, _, ok := command.Parse(content); ok {
+		return httpx.ValidationErrorMap{"content": "slash commands can't be scheduled, start with // to post a literal slash"}
+	}
+	return nil
+}
+
+func validateSendAt(sendAt time.Time) error {
+	now := time.Now()
+	if !sendAt.After(now) {
+		return httpx.ValidationErrorMap{"send_at": "send_at must be in the future"}
+	}
+	if sendAt.After(now.Add(maxScheduleAhead)) {
+		return httpx.ValidationErrorMap{"send_at": "send_at can be at most a year ahead"}
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/maxwellzp/golang-chat-api/internal/command"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const remindUsage = "Usage: /remind [me] in <duration> <text>, or /remind [me] at <HH:MM UTC | RFC 3339 time> <text>"
+
+var errRemindUsage = errors.New(remindUsage)
+
+var amountPattern = regexp.MustCompile(`^(\d+)([a-z]*)$`)
+
+var units = map[string]time.Duration{
+	"m": time.Minute, "min": time.Minute, "mins": time.Minute, "minute": time.Minute, "minutes": time.Minute,
+	"h": time.Hour, "hr": time.Hour, "hrs": time.Hour, "hour": time.Hour, "hours": time.Hour,
+	"d": 24 * time.Hour, "day": 24 * time.Hour, "days": 24 * time.Hour,
+	"w": 7 * 24 * time.Hour, "week": 7 * 24 * time.Hour, "weeks": 7 * 24 * time.Hour,
+}
+
+// RegisterCommands adds /remind to the command registry.
+func RegisterCommands(r *command.Registry, scheduleService *ScheduleService) {
+	r.Register(command.Command{
+		Name:        "remind",
+		Description: "Set a personal reminder, delivered to your own DMs",
+		Usage:       "/remind me in 30m <text>",
+	}, func(ctx context.Context, inv command.Invocation) (*command.Response, error) {
+		at, text, err := parseReminder(inv.Args, time.Now().UTC())
+		if err != nil {
+			return &command.Response{Text: err.Error()}, nil
+		}
+		sm, err := scheduleService.Remind(ctx, inv.UserID, at, text)
+		if err != nil {
+			return nil, err
+		}
+		return &command.Response{
+			Text: fmt.Sprintf("OK, I'll remind you at %s (reminder #%d)", sm.SendAt.Format(time.RFC3339), sm.ID),
+		}, nil
+	})
+}
+
+// parseReminder reads "[me] in <duration> [to] <text>" or
+// "[me] at <time> [to] <text>". Durations are Go durations ("1h30m") or an
+// amount with a unit ("10 minutes", "2d"); times are RFC 3339 or "HH:MM" in
+// UTC, meaning the next time the clock shows it.
+func parseReminder(args string, now time.Time) (time.Time, string, error) {
+	fields := strings.Fields(args)
+	if len(fields) > 0 && strings.EqualFold(fields[0], "me") {
+		fields = fields[1:]
+	}
+	if len(fields) < 3 {
+		return time.Time{}, "", errRemindUsage
+	}
+
+	var at time.Time
+	var rest []string
+	switch strings.ToLower(fields[0]) {
+	case "in":
+		d, used, ok := parseAmount(fields[1:])
+		if !ok {
+			return time.Time{}, "", errRemindUsage
+		}
+		at, rest = now.Add(d), fields[1+used:]
+	case "at":
+		t, ok := parseClockTime(fields[1], now)
+		if !ok {
+			return time.Time{}, "", errRemindUsage
+		}
+		at, rest = t, fields[2:]
+	default:
+		return time.Time{}, "", errRemindUsage
+	}
+
+	if len(rest) > 0 && strings.EqualFold(rest[0], "to") {
+		rest = rest[1:]
+	}
+	text := strings.Join(rest, " ")
+	if text == "" {
+		return time.Time{}, "", errRemindUsage
+	}
+	if err := validateSendAt(at); err != nil {
+		return time.Time{}, "", errors.New("reminders must be set for the future and at most a year ahead")
+	}
+	return at, text, nil
+}
+
+// parseAmount parses a duration from the start of fields and returns how
+// many fields it used.
+func parseAmount(fields []string) (time.Duration, int, bool) {
+	first := strings.ToLower(fields[0])
+	if d, err := time.ParseDuration(first); err == nil && d > 0 {
+		return d, 1, true
+	}
+	m := amountPattern.FindStringSubmatch(first)
+	if m == nil {
+		return 0, 0, false
+	}
+	n, err := strconv.Atoi(m[1])
+	if err != nil || n <= 0 {
+		return 0, 0, false
+	}
+	used := 1
+	unit := m[2]
+	if unit == "" {
+		if len(fields) < 2 {
+			return 0, 0, false
+		}
+		unit, used = strings.ToLower(fields[1]), 2
+	}
+	d, ok := units[unit]
+	if !ok {
+		return 0, 0, false
+	}
+	return time.Duration(n) * d, used, true
+}
+
+func parseClockTime(s string, now time.Time) (time.Time, bool) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t.UTC(), true
+	}
+	clock, err := time.Parse("15:04", s)
+	if err != nil {
+		return time.Time{}, false
+	}
+	t := time.Date(now.Year(), now.Month(), now.Day(), clock.Hour(), clock.Minute(), 0, 0, time.UTC)
+	if !t.After(now) {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, true
+}
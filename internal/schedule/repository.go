@@ -0,0 +1,159 @@
+package schedule
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/maxwellzp/golang-chat-api/internal/db"
+	"time"
+)
+
+type ScheduleRepository struct {
+	database *db.Db
+}
+
+func NewScheduleRepository(database *db.Db) *ScheduleRepository {
+	return &ScheduleRepository{database: database}
+}
+
+const scheduledColumns = `id, user_id, kind, room_id, receiver_id, content, send_at, status, attempts,
+			last_error, message_id, created_at, updated_at`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanScheduled(row rowScanner) (*ScheduledMessage, error) {
+	var sm ScheduledMessage
+	err := row.Scan(&sm.ID, &sm.UserID, &sm.Kind, &sm.RoomID, &sm.ReceiverID, &sm.Content, &sm.SendAt,
+		&sm.Status, &sm.Attempts, &sm.LastError, &sm.MessageID, &sm.CreatedAt, &sm.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &sm, nil
+}
+
+func (r *ScheduleRepository) Create(ctx context.Context, sm *ScheduledMessage) error {
+	now := time.Now().UTC()
+	query := `INSERT INTO scheduled_messages (user_id, kind, room_id, receiver_id, content, send_at, created_at, updated_at)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $7)
+			  RETURNING ` + scheduledColumns
+	row := r.database.QueryRowContext(ctx, query, sm.UserID, sm.Kind, sm.RoomID, sm.ReceiverID, sm.Content,
+		sm.SendAt.UTC(), now)
+	created, err := scanScheduled(row)
+	if err != nil {
+		return err
+	}
+	*sm = *created
+	return nil
+}
+
+func (r *ScheduleRepository) GetByID(ctx context.Context, id int64, userID int64) (*ScheduledMessage, error) {
+	query := `SELECT ` + scheduledColumns + ` FROM scheduled_messages WHERE id = $1 AND user_id = $2`
+	sm, err := scanScheduled(r.database.QueryRowContext(ctx, query, id, userID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return sm, nil
+}
+
+// ListByUser returns the user's scheduled messages, soonest first. An empty
+// status lists all of them.
+func (r *ScheduleRepository) ListByUser(ctx context.Context, userID int64, status string, limit int, offset int) ([]*ScheduledMessage, error) {
+	query := `SELECT ` + scheduledColumns + `
+			  FROM scheduled_messages
+			  WHERE user_id = $1 AND ($2 = '' OR status = $2)
+			  ORDER BY send_at ASC, id ASC
+			  LIMIT $3 OFFSET $4`
+	rows, err := r.database.QueryContext(ctx, query, userID, status, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	scheduled := []*ScheduledMessage{}
+	for rows.Next() {
+		sm, err := scanScheduled(rows)
+		if err != nil {
+			return nil, err
+		}
+		scheduled = append(scheduled, sm)
+	}
+	return scheduled, rows.Err()
+}
+
+// Update changes a pending message. Nil fields are kept. It returns nil if
+// there is no pending message with that ID, e.g. because it was just sent.
+func (r *ScheduleRepository) Update(ctx context.Context, id int64, userID int64, content *string, sendAt *time.Time) (*ScheduledMessage, error) {
+	if sendAt != nil {
+		utc := sendAt.UTC()
+		sendAt = &utc
+	}
+	query := `UPDATE scheduled_messages
+			  SET content = COALESCE($3, content), send_at = COALESCE($4, send_at), updated_at = $5
+			  WHERE id = $1 AND user_id = $2 AND status = 'pending'
+			  RETURNING ` + scheduledColumns
+	sm, err := scanScheduled(r.database.QueryRowContext(ctx, query, id, userID, content, sendAt, time.Now().UTC()))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return sm, nil
+}
+
+// Delete removes a pending message. It reports false if there is no pending
+// message with that ID.
+func (r *ScheduleRepository) Delete(ctx context.Context, id int64, userID int64) (bool, error) {
+	res, err := r.database.ExecContext(ctx,
+		"DELETE FROM scheduled_messages WHERE id = $1 AND user_id = $2 AND status = 'pending'", id, userID)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected > 0, nil
+}
+
+// ProcessNext locks the oldest due pending message, skipping rows another
+// replica is working on, and stores the outcome of send. The row stays
+// locked while send runs, so edits and cancellations wait for it. It
+// reports false when nothing is due.
+func (r *ScheduleRepository) ProcessNext(ctx context.Context, now time.Time, send func(*ScheduledMessage) outcome) (bool, error) {
+	tx, err := r.database.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	query := `SELECT ` + scheduledColumns + `
+			  FROM scheduled_messages
+			  WHERE status = 'pending' AND send_at <= $1
+			  ORDER BY send_at ASC, id ASC
+			  LIMIT 1
+			  FOR UPDATE SKIP LOCKED`
+	sm, err := scanScheduled(tx.QueryRowContext(ctx, query, now))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	result := send(sm)
+	_, err = tx.ExecContext(ctx,
+		`UPDATE scheduled_messages
+		 SET status = $2, send_at = $3, message_id = $4, last_error = $5, attempts = attempts + 1, updated_at = $6
+		 WHERE id = $1`,
+		sm.ID, result.Status, result.SendAt.UTC(), result.MessageID, result.Error, time.Now().UTC())
+	if err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
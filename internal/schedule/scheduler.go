@@ -0,0 +1,179 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"github.com/maxwellzp/golang-chat-api/internal/apperr"
+	"github.com/maxwellzp/golang-chat-api/internal/config"
+	"github.com/maxwellzp/golang-chat-api/internal/event"
+	"github.com/maxwellzp/golang-chat-api/internal/httpx"
+	"github.com/maxwellzp/golang-chat-api/internal/logger"
+	"github.com/maxwellzp/golang-chat-api/internal/message"
+	"github.com/maxwellzp/golang-chat-api/internal/room"
+	"sort"
+	"strings"
+	"time"
+)
+
+// AccountChecker rejects users who were suspended or deleted after they
+// scheduled a message.
+type AccountChecker interface {
+	CheckAccount(ctx context.Context, userID int64) error
+}
+
+// Scheduler posts due scheduled messages through the message service, so
+// they go through the same room rules, mentions and events as live ones.
+// Several schedulers (one per replica) can run against the same database.
+type Scheduler struct {
+	scheduleRepository *ScheduleRepository
+	messageService     *message.MessageService
+	accounts           AccountChecker
+	bus                *event.Bus
+	cfg                config.ScheduleConfig
+	logger             *logger.Logger
+}
+
+func NewScheduler(scheduleRepository *ScheduleRepository, messageService *message.MessageService, accounts AccountChecker, bus *event.Bus, cfg config.ScheduleConfig, logger *logger.Logger) *Scheduler {
+	return &Scheduler{
+		scheduleRepository: scheduleRepository,
+		messageService:     messageService,
+		accounts:           accounts,
+		bus:                bus,
+		cfg:                cfg,
+		logger:             logger,
+	}
+}
+
+// Run polls for due messages until ctx is cancelled.
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		s.SendDue(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// SendDue posts up to one batch of due messages.
+func (s *Scheduler) SendDue(ctx context.Context) {
+	for i := 0; i < s.cfg.BatchSize; i++ {
+		found, err := s.scheduleRepository.ProcessNext(ctx, time.Now().UTC(), func(sm *ScheduledMessage) outcome {
+			return s.send(ctx, sm)
+		})
+		if err != nil {
+			if ctx.Err() == nil {
+				s.logger.Errorw("Failed to process scheduled message",
+					"error", err,
+				)
+			}
+			return
+		}
+		if !found {
+			return
+		}
+	}
+}
+
+func (s *Scheduler) send(ctx context.Context, sm *ScheduledMessage) outcome {
+	now := time.Now().UTC()
+
+	// A suspended or deleted user can't post live, so their scheduled
+	// messages fail rather than go out on their behalf.
+	if err := s.accounts.CheckAccount(ctx, sm.UserID); err != nil {
+		var appErr *apperr.Error
+		if errors.As(err, &appErr) {
+			s.logger.Infow("Scheduled message dropped, sender can no longer post",
+				"scheduled_id", sm.ID,
+				"user_id", sm.UserID,
+				"error", err,
+			)
+			return failed(sm, err.Error())
+		}
+		return s.retryOrFail(sm, now, err)
+	}
+
+	req := message.CreateMessageRequest{
+		RoomID:     sm.RoomID,
+		ReceiverID: sm.ReceiverID,
+		Content:    sm.Content,
+	}
+	if sm.Kind == KindReminder {
+		req.Content = "Reminder: " + sm.Content
+		req.Verbatim = true
+	}
+
+	msg, err := s.messageService.Create(ctx, sm.UserID, req)
+
+	var slowMode *message.SlowModeError
+	var muted *message.MutedError
+	var ruleErr httpx.ValidationErrorMap
+	switch {
+	case err == nil:
+		s.logger.Infow("Scheduled message sent",
+			"scheduled_id", sm.ID,
+			"user_id", sm.UserID,
+		)
+		result := outcome{Status: StatusSent, SendAt: sm.SendAt}
+		if !msg.Ephemeral {
+			result.MessageID = &msg.ID
+		}
+		if sm.Kind == KindReminder {
+			s.bus.Publish(ctx, event.Event{
+				Type:       event.ReminderDue,
+				ReceiverID: sm.ReceiverID,
+				ActorID:    sm.UserID,
+				Data:       msg,
+			})
+		}
+		return result
+	case errors.As(err, &slowMode):
+		return retry(now.Add(slowMode.RetryAfter), err)
+	case errors.As(err, &muted):
+		return retry(muted.Until, err)
+	case errors.As(err, &ruleErr):
+		return failed(sm, ruleErrorText(ruleErr))
+	case errors.Is(err, message.ErrRoomNotFound), errors.Is(err, room.ErrRoomNotFound),
+		errors.Is(err, room.ErrForbidden):
+		return failed(sm, err.Error())
+	}
+
+	return s.retryOrFail(sm, now, err)
+}
+
+// retryOrFail backs off after an unexpected error, giving up after the
+// configured number of attempts.
+func (s *Scheduler) retryOrFail(sm *ScheduledMessage, now time.Time, err error) outcome {
+	s.logger.Warnw("Scheduled message attempt failed",
+		"scheduled_id", sm.ID,
+		"attempt", sm.Attempts+1,
+		"error", err,
+	)
+	if sm.Attempts+1 >= s.cfg.MaxAttempts {
+		return failed(sm, err.Error())
+	}
+	return retry(now.Add(s.cfg.RetryBackoff<<sm.Attempts), err)
+}
+
+func retry(at time.Time, err error) outcome {
+	msg := err.Error()
+	return outcome{Status: StatusPending, SendAt: at, Error: &msg}
+}
+
+func failed(sm *ScheduledMessage, reason string) outcome {
+	return outcome{Status: StatusFailed, SendAt: sm.SendAt, Error: &reason}
+}
+
+// ruleErrorText joins the messages of a room rule violation.
+func ruleErrorText(errs httpx.ValidationErrorMap) string {
+	msgs := make([]string, 0, len(errs))
+	for _, msg := range errs {
+		msgs = append(msgs, msg)
+	}
+	sort.Strings(msgs)
+	return strings.Join(msgs, "; ")
+}
@@ -0,0 +1,115 @@
+package schedule
+
+import (
+	"context"
+	"github.com/maxwellzp/golang-chat-api/internal/apperr"
+	"github.com/maxwellzp/golang-chat-api/internal/room"
+	"github.com/maxwellzp/golang-chat-api/internal/user"
+	"time"
+)
+
+var (
+	ErrScheduledNotFound = apperr.NotFound("scheduled_message_not_found", "scheduled message not found")
+	ErrNotPending        = apperr.Conflict("not_pending", "scheduled message has already been sent")
+	ErrReceiverNotFound  = apperr.NotFound("receiver_not_found", "receiver not found")
+)
+
+type ScheduleService struct {
+	scheduleRepository *ScheduleRepository
+	roomService        *room.RoomService
+	userRepository     *user.UserRepository
+}
+
+func NewScheduleService(scheduleRepository *ScheduleRepository, roomService *room.RoomService, userRepository *user.UserRepository) *ScheduleService {
+	return &ScheduleService{
+		scheduleRepository: scheduleRepository,
+		roomService:        roomService,
+		userRepository:     userRepository,
+	}
+}
+
+// Schedule stores a message to be posted at req.SendAt. Room access is
+// checked now and again when the message is posted.
+func (s *ScheduleService) Schedule(ctx context.Context, userID int64, req ScheduleMessageRequest) (*ScheduledMessage, error) {
+	if req.RoomID != nil {
+		if err := s.roomService.RequireAccess(ctx, *req.RoomID, userID); err != nil {
+			return nil, err
+		}
+	} else {
+		receiver, err := s.userRepository.FindByID(ctx, *req.ReceiverID)
+		if err != nil {
+			return nil, err
+		}
+		if receiver == nil {
+			return nil, ErrReceiverNotFound
+		}
+	}
+
+	sm := &ScheduledMessage{
+		UserID:     userID,
+		Kind:       KindMessage,
+		RoomID:     req.RoomID,
+		ReceiverID: req.ReceiverID,
+		Content:    req.Content,
+		SendAt:     req.SendAt,
+	}
+	if err := s.scheduleRepository.Create(ctx, sm); err != nil {
+		return nil, err
+	}
+	return sm, nil
+}
+
+// Remind schedules a personal reminder, posted to the user's own DMs.
+func (s *ScheduleService) Remind(ctx context.Context, userID int64, at time.Time, text string) (*ScheduledMessage, error) {
+	sm := &ScheduledMessage{
+		UserID:     userID,
+		Kind:       KindReminder,
+		ReceiverID: &userID,
+		Content:    text,
+		SendAt:     at,
+	}
+	if err := s.scheduleRepository.Create(ctx, sm); err != nil {
+		return nil, err
+	}
+	return sm, nil
+}
+
+func (s *ScheduleService) List(ctx context.Context, userID int64, status string, limit int, offset int) ([]*ScheduledMessage, error) {
+	return s.scheduleRepository.ListByUser(ctx, userID, status, limit, offset)
+}
+
+// Update edits a message that hasn't been sent yet.
+func (s *ScheduleService) Update(ctx context.Context, id int64, userID int64, req UpdateScheduledMessageRequest) (*ScheduledMessage, error) {
+	sm, err := s.scheduleRepository.Update(ctx, id, userID, req.Content, req.SendAt)
+	if err != nil {
+		return nil, err
+	}
+	if sm == nil {
+		return nil, s.notPending(ctx, id, userID)
+	}
+	return sm, nil
+}
+
+// Cancel deletes a message that hasn't been sent yet.
+func (s *ScheduleService) Cancel(ctx context.Context, id int64, userID int64) error {
+	deleted, err := s.scheduleRepository.Delete(ctx, id, userID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return s.notPending(ctx, id, userID)
+	}
+	return nil
+}
+
+// notPending explains why no pending message with that ID was found.
+func (s *ScheduleService) notPending(ctx context.Context, id int64, userID int64) error {
+	sm, err := s.scheduleRepository.GetByID(ctx, id, userID)
+	if err != nil {
+		return err
+	}
+	if sm == nil {
+		return ErrScheduledNotFound
+	}
+	return ErrNotPending
+}
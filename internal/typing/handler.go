@@ -0,0 +1,163 @@
+package typing
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/maxwellzp/golang-chat-api/internal/httpx"
+	"github.com/maxwellzp/golang-chat-api/internal/logger"
+	"io"
+	"net/http"
+	"time"
+)
+
+// streamPingInterval keeps idle typing streams open through proxies.
+const streamPingInterval = 30 * time.Second
+
+type TypingHandler struct {
+	typingService *TypingService
+	logger        *logger.Logger
+}
+
+func NewTypingHandler(typingService *TypingService, logger *logger.Logger) *TypingHandler {
+	return &TypingHandler{
+		typingService: typingService,
+		logger:        logger,
+	}
+}
+
+// key resolves the typing key of the room or conversation in the path. It
+// writes the error response and returns false if that fails.
+func (h *TypingHandler) key(w http.ResponseWriter, r *http.Request, userID int64, conversation bool) (string, bool) {
+	var key string
+	var err error
+	if conversation {
+		otherID, perr := httpx.ParseInt64Param(r, "user_id")
+		if perr != nil {
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid UserID")
+			return "", false
+		}
+		key, err = h.typingService.ConversationKey(r.Context(), userID, otherID)
+	} else {
+		roomID, perr := httpx.ParseInt64Param(r, "id")
+		if perr != nil {
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid RoomID")
+			return "", false
+		}
+		key, err = h.typingService.RoomKey(r.Context(), roomID, userID)
+	}
+	if httpx.WriteServiceError(w, err) {
+		return "", false
+	}
+	if err != nil {
+		h.logger.Errorw("Failed to resolve typing target",
+			"error", err,
+			"user_id", userID,
+		)
+		httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
+		return "", false
+	}
+	return key, true
+}
+
+// Signal marks the current user as typing in the room (or DM, with
+// conversation set) for the configured TTL.
+func (h *TypingHandler) Signal(conversation bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := httpx.GetUserID(r.Context())
+		if err != nil {
+			h.logger.Warnw("Unauthorized typing signal")
+			httpx.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		key, ok := h.key(w, r, userID, conversation)
+		if !ok {
+			return
+		}
+
+		var req SignalRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+			h.logger.Warnw("Failed to decode SignalRequest",
+				"error", err,
+			)
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+
+		err = h.typingService.Signal(r.Context(), key, userID, req.Stopped)
+		if httpx.WriteServiceError(w, err) {
+			return
+		}
+		if err != nil {
+			h.logger.Errorw("Failed to record typing signal",
+				"error", err,
+				"user_id", userID,
+			)
+			httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
+			return
+		}
+		httpx.WriteJSON(w, http.StatusNoContent, nil)
+	}
+}
+
+// Current returns who is typing right now.
+func (h *TypingHandler) Current(conversation bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := httpx.GetUserID(r.Context())
+		if err != nil {
+			h.logger.Warnw("Unauthorized request to get typing users")
+			httpx.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		key, ok := h.key(w, r, userID, conversation)
+		if !ok {
+			return
+		}
+		httpx.WriteJSON(w, http.StatusOK, h.typingService.Current(key))
+	}
+}
+
+// Stream is a server-sent event stream of the typing set: a "typing" event
+// with the current set on connect and again on every change.
+func (h *TypingHandler) Stream(conversation bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := httpx.GetUserID(r.Context())
+		if err != nil {
+			h.logger.Warnw("Unauthorized request to open typing stream")
+			httpx.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		key, ok := h.key(w, r, userID, conversation)
+		if !ok {
+			return
+		}
+
+		stream, err := httpx.NewSSEWriter(w)
+		if err != nil {
+			h.logger.Errorw("Response does not support streaming",
+				"error", err,
+			)
+			return
+		}
+		updates, cancel := h.typingService.Subscribe(key)
+		defer cancel()
+
+		ticker := time.NewTicker(streamPingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-h.typingService.Closing():
+				return
+			case typists := <-updates:
+				if err := stream.Event("typing", State{Typing: typists}); err != nil {
+					return
+				}
+			case <-ticker.C:
+				if err := stream.Ping(); err != nil {
+					return
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package typing
+
+import "strconv"
+
+type Typist struct {
+	UserID   int64  `json:"user_id"`
+	Username string `json:"username"`
+}
+
+// State is the set of users currently typing in a room or conversation.
+type State struct {
+	Typing []Typist `json:"typing"`
+}
+
+// Signal is a typing change as it is relayed between replicas. Origin
+// identifies the sending replica so it can skip its own signals.
+type Signal struct {
+	Key      string `json:"key"`
+	UserID   int64  `json:"user_id"`
+	Username string `json:"username,omitempty"`
+	Stopped  bool   `json:"stopped,omitempty"`
+	Origin   string `json:"origin"`
+}
+
+func RoomKey(roomID int64) string {
+	return "room:" + strconv.FormatInt(roomID, 10)
+}
+
+// ConversationKey is the same for both participants of a DM.
+func ConversationKey(userID int64, otherID int64) string {
+	if otherID < userID {
+		userID, otherID = otherID, userID
+	}
+	return "dm:" + strconv.FormatInt(userID, 10) + ":" + strconv.FormatInt(otherID, 10)
+}
@@ -0,0 +1,8 @@
+package typing
+
+// SignalRequest is optional: an empty body means the user is typing. Clients
+// repeat the signal while the user keeps typing and may send stopped when
+// they clear the input.
+type SignalRequest struct {
+	Stopped bool `json:"stopped"`
+}
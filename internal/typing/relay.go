@@ -0,0 +1,86 @@
+package typing
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/lib/pq"
+	"github.com/maxwellzp/golang-chat-api/internal/db"
+	"github.com/maxwellzp/golang-chat-api/internal/logger"
+	"time"
+)
+
+const relayChannel = "typing_signals"
+
+// Relay shares typing signals between replicas.
+type Relay interface {
+	Publish(ctx context.Context, sig Signal) error
+	Listen(ctx context.Context, receive func(Signal))
+}
+
+// PostgresRelay uses LISTEN/NOTIFY, so nothing is written to any table.
+type PostgresRelay struct {
+	database *db.Db
+	dsn      string
+	logger   *logger.Logger
+}
+
+// NewPostgresRelay creates a relay. Listening needs its own connection,
+// opened from dsn.
+func NewPostgresRelay(database *db.Db, dsn string, logger *logger.Logger) *PostgresRelay {
+	return &PostgresRelay{database: database, dsn: dsn, logger: logger}
+}
+
+func (r *PostgresRelay) Publish(ctx context.Context, sig Signal) error {
+	payload, err := json.Marshal(sig)
+	if err != nil {
+		return err
+	}
+	_, err = r.database.ExecContext(ctx, "SELECT pg_notify($1, $2)", relayChannel, string(payload))
+	return err
+}
+
+// Listen passes every relayed signal to receive until ctx is cancelled.
+// Signals sent while the connection is down are lost; typists they would
+// have added expire on their own.
+func (r *PostgresRelay) Listen(ctx context.Context, receive func(Signal)) {
+	listener := pq.NewListener(r.dsn, time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			r.logger.Warnw("Typing relay connection problem",
+				"event", ev,
+				"error", err,
+			)
+		}
+	})
+	defer listener.Close()
+
+	if err := listener.Listen(relayChannel); err != nil {
+		r.logger.Errorw("Failed to listen for typing signals",
+			"error", err,
+		)
+		return
+	}
+
+	ping := time.NewTicker(time.Minute)
+	defer ping.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case n := <-listener.Notify:
+			// nil after a reconnect
+			if n == nil {
+				continue
+			}
+			var sig Signal
+			if err := json.Unmarshal([]byte(n.Extra), &sig); err != nil {
+				r.logger.Warnw("Invalid typing signal",
+					"error", err,
+				)
+				continue
+			}
+			receive(sig)
+		case <-ping.C:
+			go listener.Ping()
+		}
+	}
+}
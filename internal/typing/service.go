@@ -0,0 +1,89 @@
+package typing
+
+import (
+	"context"
+	"github.com/maxwellzp/golang-chat-api/internal/apperr"
+	"github.com/maxwellzp/golang-chat-api/internal/event"
+	"github.com/maxwellzp/golang-chat-api/internal/room"
+	"github.com/maxwellzp/golang-chat-api/internal/user"
+)
+
+var ErrUserNotFound = apperr.NotFound("user_not_found", "user not found")
+
+type TypingService struct {
+	tracker        *Tracker
+	roomService    *room.RoomService
+	userRepository *user.UserRepository
+}
+
+func NewTypingService(tracker *Tracker, roomService *room.RoomService, userRepository *user.UserRepository) *TypingService {
+	return &TypingService{
+		tracker:        tracker,
+		roomService:    roomService,
+		userRepository: userRepository,
+	}
+}
+
+// RoomKey checks that userID can see the room and returns its typing key.
+func (ts *TypingService) RoomKey(ctx context.Context, roomID int64, userID int64) (string, error) {
+	if err := ts.roomService.RequireAccess(ctx, roomID, userID); err != nil {
+		return "", err
+	}
+	return RoomKey(roomID), nil
+}
+
+// ConversationKey checks that the other user exists and returns the typing
+// key of the DM between the two.
+func (ts *TypingService) ConversationKey(ctx context.Context, userID int64, otherID int64) (string, error) {
+	other, err := ts.userRepository.FindByID(ctx, otherID)
+	if err != nil {
+		return "", err
+	}
+	if other == nil {
+		return "", ErrUserNotFound
+	}
+	return ConversationKey(userID, otherID), nil
+}
+
+func (ts *TypingService) Signal(ctx context.Context, key string, userID int64, stopped bool) error {
+	username := ""
+	if !stopped {
+		u, err := ts.userRepository.FindByID(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if u == nil {
+			return ErrUserNotFound
+		}
+		username = u.Username
+	}
+	ts.tracker.Signal(ctx, key, userID, username, stopped)
+	return nil
+}
+
+func (ts *TypingService) Current(key string) *State {
+	return &State{Typing: ts.tracker.Current(key)}
+}
+
+func (ts *TypingService) Subscribe(key string) (<-chan []Typist, func()) {
+	return ts.tracker.Subscribe(key)
+}
+
+// Closing is closed when open typing streams should end.
+func (ts *TypingService) Closing() <-chan struct{} {
+	return ts.tracker.Closing()
+}
+
+// HandleEvent clears the sender's typing signal once their message is
+// posted. It is registered on the event bus.
+func (ts *TypingService) HandleEvent(ctx context.Context, e event.Event) {
+	if e.Type != event.MessageCreated {
+		return
+	}
+	switch {
+	case e.RoomID != nil:
+		ts.tracker.Signal(ctx, RoomKey(*e.RoomID), e.ActorID, "", true)
+	case e.ReceiverID != nil:
+		ts.tracker.Signal(ctx, ConversationKey(e.ActorID, *e.ReceiverID), e.ActorID, "", true)
+	}
+}
@@ -0,0 +1,255 @@
+package typing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"github.com/maxwellzp/golang-chat-api/internal/config"
+	"github.com/maxwellzp/golang-chat-api/internal/logger"
+	"sort"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often expired typists are removed.
+const sweepInterval = time.Second
+
+// Tracker keeps the typing sets in memory. Subscribers get the full set
+// whenever it changes; signals that only extend a typist's TTL are coalesced
+// and neither wake subscribers nor go to the relay more than once per
+// CoalesceInterval.
+type Tracker struct {
+	relay  Relay
+	origin string
+	cfg    config.TypingConfig
+	logger *logger.Logger
+
+	mu        sync.Mutex
+	channels  map[string]*channel
+	closing   chan struct{}
+	closeOnce sync.Once
+}
+
+type channel struct {
+	typists     map[int64]*entry
+	subscribers map[chan []Typist]struct{}
+}
+
+type entry struct {
+	username  string
+	expiresAt time.Time
+	relayedAt time.Time
+}
+
+// NewTracker creates a tracker. relay may be nil when only one replica runs.
+func NewTracker(relay Relay, cfg config.TypingConfig, logger *logger.Logger) *Tracker {
+	origin := make([]byte, 8)
+	_, _ = rand.Read(origin)
+	return &Tracker{
+		relay:    relay,
+		origin:   hex.EncodeToString(origin),
+		cfg:      cfg,
+		logger:   logger,
+		channels: make(map[string]*channel),
+		closing:  make(chan struct{}),
+	}
+}
+
+// Signal marks the user as typing under key, or as no longer typing.
+func (t *Tracker) Signal(ctx context.Context, key string, userID int64, username string, stopped bool) {
+	t.mu.Lock()
+	relay := t.apply(key, userID, username, stopped, time.Now(), false)
+	t.mu.Unlock()
+
+	if !relay || t.relay == nil {
+		return
+	}
+	err := t.relay.Publish(ctx, Signal{
+		Key:      key,
+		UserID:   userID,
+		Username: username,
+		Stopped:  stopped,
+		Origin:   t.origin,
+	})
+	if err != nil {
+		t.logger.Warnw("Failed to relay typing signal",
+			"error", err,
+			"key", key,
+		)
+	}
+}
+
+// apply updates the typing set and reports whether the signal should be
+// relayed to other replicas. The caller holds t.mu.
+func (t *Tracker) apply(key string, userID int64, username string, stopped bool, now time.Time, remote bool) bool {
+	ch := t.channels[key]
+	if stopped {
+		if ch == nil || ch.typists[userID] == nil {
+			return false
+		}
+		delete(ch.typists, userID)
+		ch.broadcast()
+		t.cleanup(key, ch)
+		return true
+	}
+
+	if ch == nil {
+		ch = &channel{
+			typists:     make(map[int64]*entry),
+			subscribers: make(map[chan []Typist]struct{}),
+		}
+		t.channels[key] = ch
+	}
+	e := ch.typists[userID]
+	if e == nil {
+		ch.typists[userID] = &entry{
+			username:  username,
+			expiresAt: now.Add(t.cfg.TTL),
+			relayedAt: now,
+		}
+		ch.broadcast()
+		return true
+	}
+
+	e.expiresAt = now.Add(t.cfg.TTL)
+	if remote || now.Sub(e.relayedAt) < t.cfg.CoalesceInterval {
+		return false
+	}
+	e.relayedAt = now
+	return true
+}
+
+// receive applies a signal relayed from another replica.
+func (t *Tracker) receive(sig Signal) {
+	if sig.Origin == t.origin {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.apply(sig.Key, sig.UserID, sig.Username, sig.Stopped, time.Now(), true)
+}
+
+// Current returns who is typing under key right now.
+func (t *Tracker) Current(key string) []Typist {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if ch := t.channels[key]; ch != nil {
+		return ch.snapshot()
+	}
+	return []Typist{}
+}
+
+// Subscribe returns a channel that receives the typing set under key, first
+// immediately and then on every change. Only the latest set is kept for slow
+// readers. cancel must be called when done.
+func (t *Tracker) Subscribe(key string) (updates <-chan []Typist, cancel func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	ch := t.channels[key]
+	if ch == nil {
+		ch = &channel{
+			typists:     make(map[int64]*entry),
+			subscribers: make(map[chan []Typist]struct{}),
+		}
+		t.channels[key] = ch
+	}
+	sub := make(chan []Typist, 1)
+	sub <- ch.snapshot()
+	ch.subscribers[sub] = struct{}{}
+
+	var once sync.Once
+	return sub, func() {
+		once.Do(func() {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			delete(ch.subscribers, sub)
+			t.cleanup(key, ch)
+		})
+	}
+}
+
+// Closing is closed when the server shuts down so open streams can end.
+func (t *Tracker) Closing() <-chan struct{} {
+	return t.closing
+}
+
+// Shutdown ends all open typing streams. It is registered with
+// http.Server.RegisterOnShutdown.
+func (t *Tracker) Shutdown() {
+	t.closeOnce.Do(func() {
+		close(t.closing)
+	})
+}
+
+// Run expires typists and, with a relay, receives signals from other
+// replicas until ctx is cancelled.
+func (t *Tracker) Run(ctx context.Context) {
+	if t.relay != nil {
+		go t.relay.Listen(ctx, t.receive)
+	}
+
+	ticker := time.NewTicker(sweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		t.sweep(time.Now())
+	}
+}
+
+func (t *Tracker) sweep(now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for key, ch := range t.channels {
+		changed := false
+		for userID, e := range ch.typists {
+			if !now.Before(e.expiresAt) {
+				delete(ch.typists, userID)
+				changed = true
+			}
+		}
+		if changed {
+			ch.broadcast()
+		}
+		t.cleanup(key, ch)
+	}
+}
+
+// cleanup drops a channel nobody is typing in or watching. The caller holds
+// t.mu.
+func (t *Tracker) cleanup(key string, ch *channel) {
+	if len(ch.typists) == 0 && len(ch.subscribers) == 0 {
+		delete(t.channels, key)
+	}
+}
+
+func (ch *channel) snapshot() []Typist {
+	typists := make([]Typist, 0, len(ch.typists))
+	for userID, e := range ch.typists {
+		typists = append(typists, Typist{UserID: userID, Username: e.username})
+	}
+	sort.Slice(typists, func(i, j int) bool {
+		return typists[i].UserID < typists[j].UserID
+	})
+	return typists
+}
+
+// broadcast replaces any set a subscriber hasn't read yet with the current
+// one, so it never blocks.
+func (ch *channel) broadcast() {
+	snapshot := ch.snapshot()
+	for sub := range ch.subscribers {
+		select {
+		case <-sub:
+		default:
+		}
+		select {
+		case sub <- snapshot:
+		default:
+		}
+	}
+}
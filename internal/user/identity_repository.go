@@ -0,0 +1,44 @@
+package user
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/maxwellzp/golang-chat-api/internal/db"
+	"time"
+)
+
+type IdentityRepository struct {
+	database *db.Db
+}
+
+func NewIdentityRepository(database *db.Db) *IdentityRepository {
+	return &IdentityRepository{database: database}
+}
+
+func (r *IdentityRepository) Create(ctx context.Context, identity *Identity) error {
+	query := `INSERT INTO user_identities (user_id, provider, subject, email, created_at)
+				VALUES ($1, $2, $3, $4, $5)
+				RETURNING id, created_at`
+
+	row := r.database.QueryRowContext(ctx, query,
+		identity.UserID, identity.Provider, identity.Subject, identity.Email, time.Now())
+	return row.Scan(&identity.ID, &identity.CreatedAt)
+}
+
+func (r *IdentityRepository) FindByProviderSubject(ctx context.Context, provider, subject string) (*Identity, error) {
+	query := `SELECT id, user_id, provider, subject, email, created_at
+			  FROM user_identities WHERE provider = $1 AND subject = $2`
+	row := r.database.QueryRowContext(ctx, query, provider, subject)
+
+	identity := &Identity{}
+	err := row.Scan(&identity.ID, &identity.UserID, &identity.Provider, &identity.Subject,
+		&identity.Email, &identity.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return identity, nil
+}
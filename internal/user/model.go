@@ -7,5 +7,47 @@ type User struct {
 	Username  string    `json:"username"`
 	Email     string    `json:"email"`
 	Password  string    `json:"-"`
+	IsBot     bool      `json:"is_bot"`
+	OwnerID   *int64    `json:"owner_id,omitempty"`
+	Role      string    `json:"role"`
 	CreatedAt time.Time `json:"created_at"`
+
+	// Suspended users can't sign in and their tokens and API keys stop
+	// working until an admin lifts the suspension.
+	SuspendedAt      *time.Time `json:"suspended_at,omitempty"`
+	SuspensionReason *string    `json:"suspension_reason,omitempty"`
+	// PasswordResetRequired blocks password sign-in until the user resets
+	// their password.
+	PasswordResetRequired bool `json:"password_reset_required,omitempty"`
+	// EmailVerifiedAt is set once the user has proven they own Email.
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"`
+}
+
+// Global roles. Room roles are separate; see room.RoleOwner.
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+// Identity links a user to an account at an external OpenID Connect provider.
+type Identity struct {
+	ID        int64     `json:"id"`
+	UserID    int64     `json:"user_id"`
+	Provider  string    `json:"provider"`
+	Subject   string    `json:"subject"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// DeletedUsername is the username of the placeholder account that messages
+// of deleted users are reassigned to.
+const DeletedUsername = "deleted user"
+
+// SessionState is what authenticating a session token needs to know about
+// its user.
+type SessionState struct {
+	Deleted   bool
+	Suspended bool
+	// Tokens issued at or before RevokedAt are no longer accepted.
+	RevokedAt *time.Time
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"github.com/lib/pq"
 	"github.com/maxwellzp/golang-chat-api/internal/db"
 	"time"
 )
@@ -16,13 +17,44 @@ func NewUserRepository(database *db.Db) *UserRepository {
 	return &UserRepository{database: database}
 }
 
+// userColumns is the select list matching scanUser.
+const userColumns = `id, username, email, password, is_bot, owner_id, role, created_at,
+	suspended_at, suspension_reason, password_reset_required, email_verified_at`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanUser(row rowScanner) (*User, error) {
+	user := &User{}
+	err := row.Scan(&user.ID, &user.Username, &user.Email, &user.Password, &user.IsBot, &user.OwnerID, &user.Role, &user.CreatedAt,
+		&user.SuspendedAt, &user.SuspensionReason, &user.PasswordResetRequired, &user.EmailVerifiedAt)
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// findOne returns nil if no user matches.
+func (r *UserRepository) findOne(ctx context.Context, where string, arg any) (*User, error) {
+	user, err := scanUser(r.database.QueryRowContext(ctx, `SELECT `+userColumns+` FROM users WHERE `+where, arg))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
 func (r *UserRepository) Create(ctx context.Context, user *User) error {
-	query := `INSERT INTO users (username, email, password, created_at) 
-				VALUES ($1, $2, $3, $4)
-				RETURNING id, created_at`
+	query := `INSERT INTO users (username, email, password, is_bot, owner_id, email_verified_at, created_at) 
+				VALUES ($1, $2, $3, $4, $5, $6, $7)
+				RETURNING id, role, created_at`
 
-	row := r.database.QueryRowContext(ctx, query, user.Username, user.Email, user.Password, time.Now())
-	err := row.Scan(&user.ID, &user.CreatedAt)
+	row := r.database.QueryRowContext(ctx, query, user.Username, user.Email, user.Password,
+		user.IsBot, user.OwnerID, user.EmailVerifiedAt, time.Now())
+	err := row.Scan(&user.ID, &user.Role, &user.CreatedAt)
 	if err != nil {
 		return err
 	}
@@ -30,16 +62,181 @@ func (r *UserRepository) Create(ctx context.Context, user *User) error {
 }
 
 func (r *UserRepository) FindByEmail(ctx context.Context, email string) (*User, error) {
-	query := `SELECT id, username, email, password FROM users WHERE email = $1`
-	row := r.database.QueryRowContext(ctx, query, email)
+	return r.findOne(ctx, "email = $1", email)
+}
 
-	user := &User{}
-	if err := row.Scan(&user.ID, &user.Username, &user.Email, &user.Password); err != nil {
+func (r *UserRepository) FindByID(ctx context.Context, id int64) (*User, error) {
+	return r.findOne(ctx, "id = $1", id)
+}
+
+func (r *UserRepository) FindByUsername(ctx context.Context, username string) (*User, error) {
+	return r.findOne(ctx, "username = $1", username)
+}
+
+// UpdatePassword sets a new password after a reset and revokes every
+// session issued before it, so a stolen token dies with the old password.
+// The reset link was delivered by email, so completing it also verifies the
+// address.
+func (r *UserRepository) UpdatePassword(ctx context.Context, id int64, password string, now time.Time) error {
+	res, err := r.database.ExecContext(ctx,
+		`UPDATE users SET password = $1, password_reset_required = FALSE, sessions_revoked_at = $2,
+			email_verified_at = COALESCE(email_verified_at, $2)
+		 WHERE id = $3`, password, now, id)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return errors.New("no user found")
+	}
+	return nil
+}
+
+func (r *UserRepository) ExistsByUsername(ctx context.Context, username string) (bool, error) {
+	var exists bool
+	err := r.database.QueryRowContext(ctx,
+		"SELECT EXISTS (SELECT 1 FROM users WHERE username = $1)", username).Scan(&exists)
+	return exists, err
+}
+
+func (r *UserRepository) ListBotsByOwner(ctx context.Context, ownerID int64) ([]*User, error) {
+	query := `SELECT id, username, email, is_bot, owner_id, role, created_at
+			  FROM users WHERE is_bot AND owner_id = $1 ORDER BY id`
+	rows, err := r.database.QueryContext(ctx, query, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var bots []*User
+	for rows.Next() {
+		var u User
+		if err := rows.Scan(&u.ID, &u.Username, &u.Email, &u.IsBot, &u.OwnerID, &u.Role, &u.CreatedAt); err != nil {
+			return nil, err
+		}
+		bots = append(bots, &u)
+	}
+	return bots, rows.Err()
+}
+
+// SessionState returns nil for unknown users.
+func (r *UserRepository) SessionState(ctx context.Context, id int64) (*SessionState, error) {
+	var state SessionState
+	err := r.database.QueryRowContext(ctx,
+		"SELECT deleted_at IS NOT NULL, suspended_at IS NOT NULL, sessions_revoked_at FROM users WHERE id = $1", id).
+		Scan(&state.Deleted, &state.Suspended, &state.RevokedAt)
+	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil
 		}
 		return nil, err
 	}
+	return &state, nil
+}
 
-	return user, nil
+// RevokeSessions invalidates every token issued to the user until now.
+func (r *UserRepository) RevokeSessions(ctx context.Context, id int64, now time.Time) error {
+	_, err := r.database.ExecContext(ctx, "UPDATE users SET sessions_revoked_at = $1 WHERE id = $2", now, id)
+	return err
+}
+
+// Role returns the user's global role, or "" for unknown and deleted users.
+func (r *UserRepository) Role(ctx context.Context, id int64) (string, error) {
+	var role string
+	err := r.database.QueryRowContext(ctx,
+		"SELECT role FROM users WHERE id = $1 AND deleted_at IS NULL", id).Scan(&role)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return "", err
+	}
+	return role, nil
+}
+
+// Search lists active users whose username or email contains query, which
+// must already have its LIKE wildcards escaped. Empty filters match
+// everyone.
+func (r *UserRepository) Search(ctx context.Context, query string, role string, suspended *bool, limit int, offset int) ([]*User, error) {
+	rows, err := r.database.QueryContext(ctx,
+		`SELECT `+userColumns+`
+		 FROM users
+		 WHERE deleted_at IS NULL
+		 AND ($1 = '' OR username ILIKE '%' || $1 || '%' OR email ILIKE '%' || $1 || '%')
+		 AND ($2 = '' OR role = $2)
+		 AND ($3::boolean IS NULL OR (suspended_at IS NOT NULL) = $3)
+		 ORDER BY id
+		 LIMIT $4 OFFSET $5`,
+		query, role, suspended, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []*User{}
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		u.Password = ""
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+// SetRole reports false if there is no such active user. Demoting an admin
+// revokes their sessions, which also ends the impersonation tokens they
+// issued.
+func (r *UserRepository) SetRole(ctx context.Context, id int64, role string, now time.Time) (bool, error) {
+	res, err := r.database.ExecContext(ctx,
+		`UPDATE users SET role = $1,
+			sessions_revoked_at = CASE WHEN role = 'admin' AND $1 <> 'admin' THEN $3 ELSE sessions_revoked_at END
+		 WHERE id = $2 AND deleted_at IS NULL`, role, id, now)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := res.RowsAffected()
+	return rowsAffected > 0, err
+}
+
+// Suspend suspends the user and revokes their sessions, so lifting the
+// suspension later doesn't bring old tokens back. It reports false if there
+// is no such active user.
+func (r *UserRepository) Suspend(ctx context.Context, id int64, reason *string, now time.Time) (bool, error) {
+	res, err := r.database.ExecContext(ctx,
+		`UPDATE users SET suspended_at = $1, suspension_reason = $2, sessions_revoked_at = $1
+		 WHERE id = $3 AND deleted_at IS NULL`,
+		now, reason, id)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := res.RowsAffected()
+	return rowsAffected > 0, err
+}
+
+// Unsuspend reports false if there is no such active user.
+func (r *UserRepository) Unsuspend(ctx context.Context, id int64) (bool, error) {
+	res, err := r.database.ExecContext(ctx,
+		"UPDATE users SET suspended_at = NULL, suspension_reason = NULL WHERE id = $1 AND deleted_at IS NULL", id)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := res.RowsAffected()
+	return rowsAffected > 0, err
+}
+
+// RequirePasswordReset blocks password sign-in until the user resets their
+// password, and revokes their sessions.
+func (r *UserRepository) RequirePasswordReset(ctx context.Context, id int64, now time.Time) error {
+	_, err := r.database.ExecContext(ctx,
+		"UPDATE users SET password_reset_required = TRUE, sessions_revoked_at = $1 WHERE id = $2", now, id)
+	return err
+}
+
+// PromoteAdmins gives the users the admin role.
+func (r *UserRepository) PromoteAdmins(ctx context.Context, ids []int64) error {
+	_, err := r.database.ExecContext(ctx,
+		"UPDATE users SET role = 'admin' WHERE id = ANY($1) AND deleted_at IS NULL", pq.Array(ids))
+	return err
 }
@@ -0,0 +1,180 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/maxwellzp/golang-chat-api/internal/config"
+	"github.com/maxwellzp/golang-chat-api/internal/logger"
+	"github.com/maxwellzp/golang-chat-api/internal/netguard"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Dispatcher sends queued deliveries. Several dispatchers (one per replica)
+// can run against the same database.
+type Dispatcher struct {
+	webhookRepository *WebhookRepository
+	client            *http.Client
+	cfg               config.WebhookConfig
+	logger            *logger.Logger
+}
+
+// NewDispatcher creates a dispatcher whose connections go through guard, so
+// a webhook can't reach the internal network even if its host starts
+// resolving to it after creation. Tests with an httptest receiver allow its
+// loopback address in the guard.
+func NewDispatcher(webhookRepository *WebhookRepository, guard *netguard.Guard, cfg config.WebhookConfig, logger *logger.Logger) *Dispatcher {
+	return &Dispatcher{
+		webhookRepository: webhookRepository,
+		client:            guard.Client(cfg.Timeout),
+		cfg:               cfg,
+		logger:            logger,
+	}
+}
+
+// Run polls for due deliveries until ctx is cancelled.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		d.DispatchDue(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DispatchDue sends one batch of due deliveries.
+func (d *Dispatcher) DispatchDue(ctx context.Context) {
+	// The lease must outlast a full batch of sends so a slow batch isn't
+	// picked up twice.
+	lease := d.cfg.Timeout*time.Duration(d.cfg.BatchSize) + time.Minute
+	deliveries, err := d.webhookRepository.ClaimDue(ctx, time.Now().UTC(), d.cfg.BatchSize, lease)
+	if err != nil {
+		if ctx.Err() == nil {
+			d.logger.Errorw("Failed to claim webhook deliveries",
+				"error", err,
+			)
+		}
+		return
+	}
+
+	for _, delivery := range deliveries {
+		d.deliver(ctx, delivery)
+	}
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, delivery *Delivery) {
+	attempt := &Attempt{
+		Attempt:     delivery.Attempts + 1,
+		AttemptedAt: time.Now().UTC(),
+	}
+
+	statusCode, sendErr := d.send(ctx, delivery)
+	attempt.DurationMs = int(time.Since(attempt.AttemptedAt).Milliseconds())
+	if statusCode != 0 {
+		attempt.StatusCode = &statusCode
+	}
+	if sendErr != nil {
+		msg := sendErr.Error()
+		attempt.Error = &msg
+	}
+
+	status, next := d.nextStatus(attempt.Attempt, sendErr, attempt.AttemptedAt)
+	if err := d.webhookRepository.RecordAttempt(ctx, delivery, attempt, status, next); err != nil {
+		d.logger.Errorw("Failed to record webhook attempt",
+			"delivery_id", delivery.ID,
+			"error", err,
+		)
+		return
+	}
+
+	switch status {
+	case StatusDead:
+		d.logger.Warnw("Webhook delivery moved to dead letter",
+			"delivery_id", delivery.ID,
+			"webhook_id", delivery.WebhookID,
+			"attempts", attempt.Attempt,
+			"error", sendErr,
+		)
+	case StatusPending:
+		d.logger.Infow("Webhook delivery failed, will retry",
+			"delivery_id", delivery.ID,
+			"webhook_id", delivery.WebhookID,
+			"attempt", attempt.Attempt,
+			"next_attempt_at", next,
+			"error", sendErr,
+		)
+	}
+}
+
+// send POSTs the payload. Receivers verify it by computing
+// HMAC-SHA256(secret, "<timestamp>.<body>") and comparing it to the v1 value
+// of the X-Webhook-Signature header ("t=<timestamp>,v1=<hex>").
+func (d *Dispatcher) send(ctx context.Context, delivery *Delivery) (int, error) {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.url, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "golang-chat-api-webhooks/1.0")
+	req.Header.Set("X-Webhook-Event", delivery.EventType)
+	req.Header.Set("X-Webhook-Delivery", strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set("X-Webhook-Signature", "t="+timestamp+",v1="+Sign(delivery.secret, timestamp, delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("receiver responded with status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// Sign computes the hex HMAC-SHA256 signature of a payload.
+func Sign(secret, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// nextStatus decides what follows an attempt made at: delivered, another
+// try after the backoff, or dead once MaxAttempts have failed.
+func (d *Dispatcher) nextStatus(attempt int, sendErr error, at time.Time) (string, time.Time) {
+	switch {
+	case sendErr == nil:
+		return StatusDelivered, at
+	case attempt >= d.cfg.MaxAttempts:
+		return StatusDead, at
+	default:
+		return StatusPending, at.Add(d.backoff(attempt))
+	}
+}
+
+func (d *Dispatcher) backoff(attempt int) time.Duration {
+	wait := d.cfg.BaseBackoff
+	for i := 1; i < attempt && wait < d.cfg.MaxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > d.cfg.MaxBackoff {
+		wait = d.cfg.MaxBackoff
+	}
+	return wait
+}
@@ -0,0 +1,140 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"github.com/maxwellzp/golang-chat-api/internal/config"
+	"github.com/maxwellzp/golang-chat-api/internal/netguard"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testWebhookConfig = config.WebhookConfig{
+	MaxAttempts: 4,
+	BaseBackoff: 30 * time.Second,
+	MaxBackoff:  time.Minute,
+	Timeout:     5 * time.Second,
+	BatchSize:   10,
+}
+
+// newTestDispatcher returns a dispatcher whose guard lets requests through
+// to an httptest receiver on the loopback interface.
+func newTestDispatcher(t *testing.T) *Dispatcher {
+	t.Helper()
+	guard, err := netguard.New([]string{"127.0.0.0/8", "::1/128"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewDispatcher(nil, guard, testWebhookConfig, nil)
+}
+
+func TestSendSignsDelivery(t *testing.T) {
+	const secret = "s3cret"
+	payload := []byte(`{"type":"message.created","data":{"id":7}}`)
+
+	var verified bool
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var timestamp, signature string
+		for _, part := range strings.Split(r.Header.Get("X-Webhook-Signature"), ",") {
+			key, value, _ := strings.Cut(part, "=")
+			switch key {
+			case "t":
+				timestamp = value
+			case "v1":
+				signature = value
+			}
+		}
+		verified = timestamp != "" && signature == Sign(secret, timestamp, body) &&
+			r.Header.Get("X-Webhook-Event") == "message.created" &&
+			r.Header.Get("X-Webhook-Delivery") == "42" &&
+			string(body) == string(payload)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	d := newTestDispatcher(t)
+	status, err := d.send(context.Background(), &Delivery{
+		ID:        42,
+		EventType: "message.created",
+		Payload:   payload,
+		url:       receiver.URL,
+		secret:    secret,
+	})
+	if err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	if status != http.StatusNoContent {
+		t.Errorf("status = %d, want %d", status, http.StatusNoContent)
+	}
+	if !verified {
+		t.Error("receiver could not verify the signed delivery")
+	}
+}
+
+func TestSendReportsReceiverFailure(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer receiver.Close()
+
+	d := newTestDispatcher(t)
+	status, err := d.send(context.Background(), &Delivery{ID: 1, Payload: []byte(`{}`), url: receiver.URL, secret: "x"})
+	if err == nil {
+		t.Fatal("expected an error for a 503 response")
+	}
+	if status != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want %d", status, http.StatusServiceUnavailable)
+	}
+}
+
+func TestSendRefusesPrivateAddresses(t *testing.T) {
+	var called bool
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer receiver.Close()
+
+	guard, err := netguard.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := NewDispatcher(nil, guard, testWebhookConfig, nil)
+	_, err = d.send(context.Background(), &Delivery{ID: 1, Payload: []byte(`{}`), url: receiver.URL, secret: "x"})
+	if !errors.Is(err, netguard.ErrPrivateAddress) {
+		t.Fatalf("err = %v, want %v", err, netguard.ErrPrivateAddress)
+	}
+	if called {
+		t.Error("receiver on a loopback address was reached")
+	}
+}
+
+func TestNextStatusRetriesWithBackoff(t *testing.T) {
+	d := newTestDispatcher(t)
+	at := time.Date(2025, 10, 19, 12, 0, 0, 0, time.UTC)
+	failure := errors.New("receiver responded with status 500")
+
+	tests := []struct {
+		attempt    int
+		err        error
+		wantStatus string
+		wantNext   time.Time
+	}{
+		{attempt: 1, err: nil, wantStatus: StatusDelivered, wantNext: at},
+		{attempt: 1, err: failure, wantStatus: StatusPending, wantNext: at.Add(30 * time.Second)},
+		{attempt: 2, err: failure, wantStatus: StatusPending, wantNext: at.Add(time.Minute)},
+		{attempt: 3, err: failure, wantStatus: StatusPending, wantNext: at.Add(time.Minute)},
+		{attempt: 4, err: failure, wantStatus: StatusDead, wantNext: at},
+	}
+	for _, tt := range tests {
+		status, next := d.nextStatus(tt.attempt, tt.err, at)
+		if status != tt.wantStatus || !next.Equal(tt.wantNext) {
+			t.Errorf("nextStatus(%d, %v) = %s, %s; want %s, %s",
+				tt.attempt, tt.err, status, next, tt.wantStatus, tt.wantNext)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package webhook
+
+import (
+	"encoding/json"
+	"github.com/maxwellzp/golang-chat-api/internal/httpx"
+	"github.com/maxwellzp/golang-chat-api/internal/logger"
+	"github.com/maxwellzp/golang-chat-api/internal/validatorx"
+	"net/http"
+)
+
+type WebhookHandler struct {
+	webhookService *WebhookService
+	validator      *validatorx.Validator
+	logger         *logger.Logger
+}
+
+func NewWebhookHandler(webhookService *WebhookService, validator *validatorx.Validator, logger *logger.Logger) *WebhookHandler {
+	return &WebhookHandler{
+		webhookService: webhookService,
+		validator:      validator,
+		logger:         logger,
+	}
+}
+
+func (h *WebhookHandler) Create() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := httpx.GetUserID(r.Context())
+		if err != nil {
+			h.logger.Warnw("Unauthorized request to create webhook")
+			httpx.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		roomID, err := httpx.ParseInt64Param(r, "id")
+		if err != nil {
+			h.logger.Warnw("Invalid room ID for webhook create",
+				"error", err,
+			)
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid RoomID")
+			return
+		}
+
+		var req CreateWebhookRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.logger.Warnw("Failed to decode CreateWebhookRequest",
+				"error", err,
+			)
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		if err := h.validator.Validate(&req); err != nil {
+			h.logger.Warnw("Validation failed for CreateWebhookRequest",
+				"error", err,
+				"user_id", userID,
+			)
+			httpx.WriteValidationError(w, err)
+			return
+		}
+		if err := req.Validate(); err != nil {
+			h.logger.Warnw("Custom validation failed for CreateWebhookRequest",
+				"error", err,
+				"user_id", userID,
+			)
+			httpx.WriteValidationError(w, err)
+			return
+		}
+
+		hook, err := h.webhookService.Create(r.Context(), roomID, userID, req)
+		if httpx.WriteServiceError(w, err) {
+			return
+		}
+		if err != nil {
+			h.logger.Errorw("Failed to create webhook",
+				"error", err,
+				"user_id", userID,
+				"room_id", roomID,
+			)
+			httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
+			return
+		}
+		h.logger.Infow("Webhook created",
+			"webhook_id", hook.ID,
+			"room_id", roomID,
+			"user_id", userID,
+		)
+		httpx.WriteJSON(w, http.StatusCreated, CreateWebhookResponse{Webhook: hook, Secret: hook.Secret})
+	}
+}
+
+func (h *WebhookHandler) List() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := httpx.GetUserID(r.Context())
+		if err != nil {
+			h.logger.Warnw("Unauthorized request to list webhooks")
+			httpx.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		roomID, err := httpx.ParseInt64Param(r, "id")
+		if err != nil {
+			h.logger.Warnw("Invalid room ID for webhook list",
+				"error", err,
+			)
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid RoomID")
+			return
+		}
+
+		hooks, err := h.webhookService.List(r.Context(), roomID, userID)
+		if httpx.WriteServiceError(w, err) {
+			return
+		}
+		if err != nil {
+			h.logger.Errorw("Failed to list webhooks",
+				"error", err,
+				"room_id", roomID,
+			)
+			httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
+			return
+		}
+		httpx.WriteJSON(w, http.StatusOK, hooks)
+	}
+}
+
+func (h *WebhookHandler) Delete() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := httpx.GetUserID(r.Context())
+		if err != nil {
+			h.logger.Warnw("Unauthorized request to delete webhook")
+			httpx.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		roomID, err := httpx.ParseInt64Param(r, "id")
+		if err != nil {
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid RoomID")
+			return
+		}
+		id, err := httpx.ParseInt64Param(r, "wid")
+		if err != nil {
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid WebhookID")
+			return
+		}
+
+		err = h.webhookService.Delete(r.Context(), roomID, id, userID)
+		if httpx.WriteServiceError(w, err) {
+			return
+		}
+		if err != nil {
+			h.logger.Errorw("Failed to delete webhook",
+				"error", err,
+				"user_id", userID,
+				"webhook_id", id,
+			)
+			httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
+			return
+		}
+		h.logger.Infow("Webhook deleted",
+			"webhook_id", id,
+			"room_id", roomID,
+			"user_id", userID,
+		)
+		httpx.WriteJSON(w, http.StatusNoContent, nil)
+	}
+}
+
+func (h *WebhookHandler) Deliveries() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := httpx.GetUserID(r.Context())
+		if err != nil {
+			h.logger.Warnw("Unauthorized request to list webhook deliveries")
+			httpx.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		roomID, err := httpx.ParseInt64Param(r, "id")
+		if err != nil {
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid RoomID")
+			return
+		}
+		id, err := httpx.ParseInt64Param(r, "wid")
+		if err != nil {
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid WebhookID")
+			return
+		}
+
+		status := r.URL.Query().Get("status")
+		if status != "" && status != StatusPending && status != StatusDelivered && status != StatusDead {
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid status")
+			return
+		}
+
+		deliveries, err := h.webhookService.Deliveries(r.Context(), roomID, id, userID, status)
+		if httpx.WriteServiceError(w, err) {
+			return
+		}
+		if err != nil {
+			h.logger.Errorw("Failed to list webhook deliveries",
+				"error", err,
+				"webhook_id", id,
+			)
+			httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
+			return
+		}
+		httpx.WriteJSON(w, http.StatusOK, deliveries)
+	}
+}
@@ -0,0 +1,248 @@
+package webhook
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/maxwellzp/golang-chat-api/internal/httpx"
+	"github.com/maxwellzp/golang-chat-api/internal/logger"
+	"github.com/maxwellzp/golang-chat-api/internal/validatorx"
+	"net/http"
+)
+
+type IncomingWebhookHandler struct {
+	incomingService *IncomingWebhookService
+	validator       *validatorx.Validator
+	logger          *logger.Logger
+}
+
+func NewIncomingWebhookHandler(incomingService *IncomingWebhookService, validator *validatorx.Validator, logger *logger.Logger) *IncomingWebhookHandler {
+	return &IncomingWebhookHandler{
+		incomingService: incomingService,
+		validator:       validator,
+		logger:          logger,
+	}
+}
+
+func (h *IncomingWebhookHandler) Create() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := httpx.GetUserID(r.Context())
+		if err != nil {
+			h.logger.Warnw("Unauthorized request to create incoming webhook")
+			httpx.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		roomID, err := httpx.ParseInt64Param(r, "id")
+		if err != nil {
+			h.logger.Warnw("Invalid room ID for incoming webhook create",
+				"error", err,
+			)
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid RoomID")
+			return
+		}
+
+		var req CreateIncomingWebhookRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.logger.Warnw("Failed to decode CreateIncomingWebhookRequest",
+				"error", err,
+			)
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		if err := h.validator.Validate(&req); err != nil {
+			h.logger.Warnw("Validation failed for CreateIncomingWebhookRequest",
+				"error", err,
+				"user_id", userID,
+			)
+			httpx.WriteValidationError(w, err)
+			return
+		}
+
+		hook, token, err := h.incomingService.Create(r.Context(), roomID, userID, req)
+		if httpx.WriteServiceError(w, err) {
+			return
+		}
+		if err != nil {
+			h.logger.Errorw("Failed to create incoming webhook",
+				"error", err,
+				"user_id", userID,
+				"room_id", roomID,
+			)
+			httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
+			return
+		}
+		h.logger.Infow("Incoming webhook created",
+			"incoming_webhook_id", hook.ID,
+			"bot_user_id", hook.BotUserID,
+			"room_id", roomID,
+			"user_id", userID,
+		)
+		httpx.WriteJSON(w, http.StatusCreated, IncomingWebhookTokenResponse{
+			IncomingWebhook: hook,
+			Token:           token,
+			URL:             h.incomingService.URL(token),
+		})
+	}
+}
+
+func (h *IncomingWebhookHandler) List() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := httpx.GetUserID(r.Context())
+		if err != nil {
+			h.logger.Warnw("Unauthorized request to list incoming webhooks")
+			httpx.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		roomID, err := httpx.ParseInt64Param(r, "id")
+		if err != nil {
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid RoomID")
+			return
+		}
+
+		hooks, err := h.incomingService.List(r.Context(), roomID, userID)
+		if httpx.WriteServiceError(w, err) {
+			return
+		}
+		if err != nil {
+			h.logger.Errorw("Failed to list incoming webhooks",
+				"error", err,
+				"room_id", roomID,
+			)
+			httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
+			return
+		}
+		httpx.WriteJSON(w, http.StatusOK, hooks)
+	}
+}
+
+func (h *IncomingWebhookHandler) Rotate() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := httpx.GetUserID(r.Context())
+		if err != nil {
+			h.logger.Warnw("Unauthorized request to rotate incoming webhook")
+			httpx.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		roomID, err := httpx.ParseInt64Param(r, "id")
+		if err != nil {
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid RoomID")
+			return
+		}
+		id, err := httpx.ParseInt64Param(r, "hid")
+		if err != nil {
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid WebhookID")
+			return
+		}
+
+		hook, token, err := h.incomingService.Rotate(r.Context(), roomID, id, userID)
+		if httpx.WriteServiceError(w, err) {
+			return
+		}
+		if err != nil {
+			h.logger.Errorw("Failed to rotate incoming webhook",
+				"error", err,
+				"user_id", userID,
+				"incoming_webhook_id", id,
+			)
+			httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
+			return
+		}
+		h.logger.Infow("Incoming webhook token rotated",
+			"incoming_webhook_id", id,
+			"room_id", roomID,
+			"user_id", userID,
+		)
+		httpx.WriteJSON(w, http.StatusOK, IncomingWebhookTokenResponse{
+			IncomingWebhook: hook,
+			Token:           token,
+			URL:             h.incomingService.URL(token),
+		})
+	}
+}
+
+func (h *IncomingWebhookHandler) Revoke() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := httpx.GetUserID(r.Context())
+		if err != nil {
+			h.logger.Warnw("Unauthorized request to revoke incoming webhook")
+			httpx.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		roomID, err := httpx.ParseInt64Param(r, "id")
+		if err != nil {
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid RoomID")
+			return
+		}
+		id, err := httpx.ParseInt64Param(r, "hid")
+		if err != nil {
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid WebhookID")
+			return
+		}
+
+		err = h.incomingService.Revoke(r.Context(), roomID, id, userID)
+		if httpx.WriteServiceError(w, err) {
+			return
+		}
+		if err != nil {
+			h.logger.Errorw("Failed to revoke incoming webhook",
+				"error", err,
+				"user_id", userID,
+				"incoming_webhook_id", id,
+			)
+			httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
+			return
+		}
+		h.logger.Infow("Incoming webhook revoked",
+			"incoming_webhook_id", id,
+			"room_id", roomID,
+			"user_id", userID,
+		)
+		httpx.WriteJSON(w, http.StatusNoContent, nil)
+	}
+}
+
+// Post is the public endpoint external tools call. The token in the path is
+// the only credential.
+func (h *IncomingWebhookHandler) Post() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := chi.URLParam(r, "token")
+
+		var req PostIncomingWebhookRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.logger.Warnw("Failed to decode PostIncomingWebhookRequest",
+				"error", err,
+			)
+			httpx.WriteError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		if err := h.validator.Validate(&req); err != nil {
+			h.logger.Warnw("Validation failed for PostIncomingWebhookRequest",
+				"error", err,
+			)
+			httpx.WriteValidationError(w, err)
+			return
+		}
+
+		msg, err := h.incomingService.Post(r.Context(), token, req)
+		if errors.Is(err, ErrInvalidHookToken) {
+			h.logger.Warnw("Incoming webhook called with invalid token",
+				"ip", httpx.ClientIP(r),
+			)
+		}
+		if httpx.WriteServiceError(w, err) {
+			return
+		}
+		if err != nil {
+			h.logger.Errorw("Failed to post incoming webhook message",
+				"error", err,
+			)
+			httpx.WriteError(w, http.StatusInternalServerError, "Something went wrong. Please try again later")
+			return
+		}
+		h.logger.Infow("Message posted via incoming webhook",
+			"message_id", msg.ID,
+			"sender_id", msg.SenderID,
+		)
+		httpx.WriteJSON(w, http.StatusCreated, msg)
+	}
+}
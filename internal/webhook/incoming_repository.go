@@ -0,0 +1,153 @@
+package webhook
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/maxwellzp/golang-chat-api/internal/db"
+	"github.com/maxwellzp/golang-chat-api/internal/room"
+	"github.com/maxwellzp/golang-chat-api/internal/user"
+	"time"
+)
+
+type IncomingWebhookRepository struct {
+	database *db.Db
+}
+
+func NewIncomingWebhookRepository(database *db.Db) *IncomingWebhookRepository {
+	return &IncomingWebhookRepository{database: database}
+}
+
+const incomingWebhookColumns = `id, room_id, bot_user_id, name, token_hash, created_by, created_at, rotated_at, revoked_at`
+
+// Create stores the hook together with its bot user and the bot's room
+// membership in one transaction, so a failure never leaves an orphan bot in
+// the room. It fills in the IDs of both.
+func (r *IncomingWebhookRepository) Create(ctx context.Context, hook *IncomingWebhook, bot *user.User) error {
+	tx, err := r.database.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+	err = tx.QueryRowContext(ctx,
+		`INSERT INTO users (username, email, password, is_bot, owner_id, created_at)
+		 VALUES ($1, $2, $3, $4, $5, $6)
+		 RETURNING id, role, created_at`,
+		bot.Username, bot.Email, bot.Password, bot.IsBot, bot.OwnerID, now).Scan(&bot.ID, &bot.Role, &bot.CreatedAt)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO room_members (room_id, user_id, role, joined_at) VALUES ($1, $2, $3, $4)",
+		hook.RoomID, bot.ID, room.RoleMember, now)
+	if err != nil {
+		return err
+	}
+
+	hook.BotUserID = bot.ID
+	err = tx.QueryRowContext(ctx,
+		`INSERT INTO incoming_webhooks (room_id, bot_user_id, name, token_hash, created_by, created_at)
+		 VALUES ($1, $2, $3, $4, $5, $6)
+		 RETURNING id, created_at`,
+		hook.RoomID, hook.BotUserID, hook.Name, hook.TokenHash, hook.CreatedBy, now).Scan(&hook.ID, &hook.CreatedAt)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *IncomingWebhookRepository) GetByID(ctx context.Context, roomID int64, id int64) (*IncomingWebhook, error) {
+	query := `SELECT ` + incomingWebhookColumns + ` FROM incoming_webhooks WHERE id = $1 AND room_id = $2`
+	return r.get(ctx, query, id, roomID)
+}
+
+// FindActiveByTokenHash returns the unrevoked hook with the given token hash.
+func (r *IncomingWebhookRepository) FindActiveByTokenHash(ctx context.Context, tokenHash string) (*IncomingWebhook, error) {
+	query := `SELECT ` + incomingWebhookColumns + ` FROM incoming_webhooks
+			  WHERE token_hash = $1 AND revoked_at IS NULL`
+	return r.get(ctx, query, tokenHash)
+}
+
+func (r *IncomingWebhookRepository) get(ctx context.Context, query string, args ...any) (*IncomingWebhook, error) {
+	row := r.database.QueryRowContext(ctx, query, args...)
+
+	var hook IncomingWebhook
+	err := row.Scan(&hook.ID, &hook.RoomID, &hook.BotUserID, &hook.Name, &hook.TokenHash,
+		&hook.CreatedBy, &hook.CreatedAt, &hook.RotatedAt, &hook.RevokedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &hook, nil
+}
+
+func (r *IncomingWebhookRepository) ListByRoom(ctx context.Context, roomID int64) ([]*IncomingWebhook, error) {
+	query := `SELECT ` + incomingWebhookColumns + ` FROM incoming_webhooks WHERE room_id = $1 ORDER BY id`
+	rows, err := r.database.QueryContext(ctx, query, roomID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hooks []*IncomingWebhook
+	for rows.Next() {
+		var hook IncomingWebhook
+		err := rows.Scan(&hook.ID, &hook.RoomID, &hook.BotUserID, &hook.Name, &hook.TokenHash,
+			&hook.CreatedBy, &hook.CreatedAt, &hook.RotatedAt, &hook.RevokedAt)
+		if err != nil {
+			return nil, err
+		}
+		hooks = append(hooks, &hook)
+	}
+	return hooks, rows.Err()
+}
+
+// RotateToken replaces the token hash of an unrevoked hook.
+func (r *IncomingWebhookRepository) RotateToken(ctx context.Context, roomID int64, id int64, tokenHash string, now time.Time) error {
+	query := `UPDATE incoming_webhooks SET token_hash = $1, rotated_at = $2
+			  WHERE id = $3 AND room_id = $4 AND revoked_at IS NULL`
+	res, err := r.database.ExecContext(ctx, query, tokenHash, now, id, roomID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrWebhookNotFound
+	}
+	return nil
+}
+
+// Revoke stops the hook's token from working and removes its bot from the
+// room.
+func (r *IncomingWebhookRepository) Revoke(ctx context.Context, roomID int64, id int64, now time.Time) error {
+	tx, err := r.database.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var botUserID int64
+	err = tx.QueryRowContext(ctx,
+		`UPDATE incoming_webhooks SET revoked_at = $1
+		 WHERE id = $2 AND room_id = $3 AND revoked_at IS NULL
+		 RETURNING bot_user_id`,
+		now, id, roomID).Scan(&botUserID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrWebhookNotFound
+	}
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "DELETE FROM room_members WHERE room_id = $1 AND user_id = $2", roomID, botUserID)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,152 @@
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/maxwellzp/golang-chat-api/internal/apperr"
+	"github.com/maxwellzp/golang-chat-api/internal/message"
+	"github.com/maxwellzp/golang-chat-api/internal/room"
+	"github.com/maxwellzp/golang-chat-api/internal/user"
+	"strings"
+	"time"
+)
+
+var ErrInvalidHookToken = apperr.NotFound("invalid_hook_token", "invalid or revoked webhook token")
+
+type IncomingWebhookService struct {
+	incomingRepository *IncomingWebhookRepository
+	roomService        *room.RoomService
+	messageService     *message.MessageService
+	baseURL            string
+}
+
+func NewIncomingWebhookService(
+	incomingRepository *IncomingWebhookRepository,
+	roomService *room.RoomService,
+	messageService *message.MessageService,
+	baseURL string,
+) *IncomingWebhookService {
+	return &IncomingWebhookService{
+		incomingRepository: incomingRepository,
+		roomService:        roomService,
+		messageService:     messageService,
+		baseURL:            strings.TrimRight(baseURL, "/"),
+	}
+}
+
+// Create registers a hook for the room together with the bot user its
+// messages are attributed to. The token is returned once and never stored.
+func (s *IncomingWebhookService) Create(ctx context.Context, roomID int64, userID int64, req CreateIncomingWebhookRequest) (*IncomingWebhook, string, error) {
+	if err := s.roomService.RequireRole(ctx, roomID, userID, room.RoleAdmin); err != nil {
+		return nil, "", err
+	}
+
+	suffix, err := randomHex(4)
+	if err != nil {
+		return nil, "", err
+	}
+	username := "hook-" + suffix
+	bot := &user.User{
+		Username: username,
+		Email:    username + "@bot.invalid",
+		IsBot:    true,
+		OwnerID:  &userID,
+	}
+	token, err := randomHex(32)
+	if err != nil {
+		return nil, "", err
+	}
+	hook := &IncomingWebhook{
+		RoomID:    roomID,
+		Name:      strings.TrimSpace(req.Name),
+		TokenHash: hashToken(token),
+		CreatedBy: &userID,
+	}
+	if err := s.incomingRepository.Create(ctx, hook, bot); err != nil {
+		return nil, "", err
+	}
+	s.roomService.MemberAdded(ctx, roomID, userID, bot.ID)
+	return hook, token, nil
+}
+
+func (s *IncomingWebhookService) List(ctx context.Context, roomID int64, userID int64) ([]*IncomingWebhook, error) {
+	if err := s.roomService.RequireRole(ctx, roomID, userID, room.RoleAdmin); err != nil {
+		return nil, err
+	}
+	return s.incomingRepository.ListByRoom(ctx, roomID)
+}
+
+// Rotate issues a new token for the hook; the previous token stops working
+// immediately.
+func (s *IncomingWebhookService) Rotate(ctx context.Context, roomID int64, id int64, userID int64) (*IncomingWebhook, string, error) {
+	if err := s.roomService.RequireRole(ctx, roomID, userID, room.RoleAdmin); err != nil {
+		return nil, "", err
+	}
+
+	token, err := randomHex(32)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := s.incomingRepository.RotateToken(ctx, roomID, id, hashToken(token), time.Now().UTC()); err != nil {
+		return nil, "", err
+	}
+	hook, err := s.incomingRepository.GetByID(ctx, roomID, id)
+	if err != nil {
+		return nil, "", err
+	}
+	if hook == nil {
+		return nil, "", ErrWebhookNotFound
+	}
+	return hook, token, nil
+}
+
+func (s *IncomingWebhookService) Revoke(ctx context.Context, roomID int64, id int64, userID int64) error {
+	if err := s.roomService.RequireRole(ctx, roomID, userID, room.RoleAdmin); err != nil {
+		return err
+	}
+	return s.incomingRepository.Revoke(ctx, roomID, id, time.Now().UTC())
+}
+
+// Post creates a message in the hook's room as its bot user. The display
+// name defaults to the hook name.
+func (s *IncomingWebhookService) Post(ctx context.Context, token string, req PostIncomingWebhookRequest) (*message.Message, error) {
+	hook, err := s.incomingRepository.FindActiveByTokenHash(ctx, hashToken(token))
+	if err != nil {
+		return nil, err
+	}
+	if hook == nil {
+		return nil, ErrInvalidHookToken
+	}
+
+	displayName := hook.Name
+	if req.Username != nil {
+		displayName = strings.TrimSpace(*req.Username)
+	}
+	return s.messageService.Create(ctx, hook.BotUserID, message.CreateMessageRequest{
+		RoomID:      &hook.RoomID,
+		Content:     req.Content,
+		DisplayName: &displayName,
+		IconURL:     req.IconURL,
+		Verbatim:    true,
+	})
+}
+
+// URL is the public endpoint for a hook token.
+func (s *IncomingWebhookService) URL(token string) string {
+	return s.baseURL + "/hooks/" + token
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,76 @@
+package webhook
+
+import (
+	"encoding/json"
+	"github.com/maxwellzp/golang-chat-api/internal/event"
+	"time"
+)
+
+const (
+	StatusPending   = "pending"
+	StatusDelivered = "delivered"
+	StatusDead      = "dead"
+)
+
+// SupportedEvents are the event types a webhook can subscribe to.
+var SupportedEvents = map[string]bool{
+	event.MessageCreated:  true,
+	event.MessageUpdated:  true,
+	event.MessageDeleted:  true,
+	event.MessagePinned:   true,
+	event.MessageUnpinned: true,
+	event.MemberJoined:    true,
+}
+
+type Webhook struct {
+	ID        int64     `json:"id"`
+	RoomID    int64     `json:"room_id"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"-"`
+	Events    []string  `json:"events"`
+	Active    bool      `json:"active"`
+	CreatedBy *int64    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Delivery is one event queued for one webhook (an outbox row).
+type Delivery struct {
+	ID             int64           `json:"id"`
+	WebhookID      int64           `json:"webhook_id"`
+	EventType      string          `json:"event_type"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  time.Time       `json:"next_attempt_at"`
+	LastStatusCode *int            `json:"last_status_code"`
+	LastError      *string         `json:"last_error"`
+	DeliveredAt    *time.Time      `json:"delivered_at"`
+	CreatedAt      time.Time       `json:"created_at"`
+	AttemptLog     []*Attempt      `json:"attempt_log"`
+
+	// Filled in when the delivery is claimed for sending.
+	url    string
+	secret string
+}
+
+type Attempt struct {
+	Attempt     int       `json:"attempt"`
+	StatusCode  *int      `json:"status_code"`
+	Error       *string   `json:"error"`
+	DurationMs  int       `json:"duration_ms"`
+	AttemptedAt time.Time `json:"attempted_at"`
+}
+
+// IncomingWebhook lets an external tool post into a room as a bot user by
+// calling POST /hooks/{token}.
+type IncomingWebhook struct {
+	ID        int64      `json:"id"`
+	RoomID    int64      `json:"room_id"`
+	BotUserID int64      `json:"bot_user_id"`
+	Name      string     `json:"name"`
+	TokenHash string     `json:"-"`
+	CreatedBy *int64     `json:"created_by"`
+	CreatedAt time.Time  `json:"created_at"`
+	RotatedAt *time.Time `json:"rotated_at"`
+	RevokedAt *time.Time `json:"revoked_at"`
+}
@@ -0,0 +1,52 @@
+package webhook
+
+import (
+	"github.com/maxwellzp/golang-chat-api/internal/httpx"
+	"net/url"
+)
+
+type CreateWebhookRequest struct {
+	URL    string   `json:"url" validate:"required,url,max=2000"`
+	Events []string `json:"events" validate:"required,min=1"`
+}
+
+func (r *CreateWebhookRequest) Validate() error {
+	u, err := url.Parse(r.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return httpx.ValidationErrorMap{
+			"url": "url must be an absolute http or https URL",
+		}
+	}
+	for _, e := range r.Events {
+		if !SupportedEvents[e] {
+			return httpx.ValidationErrorMap{
+				"events": "unsupported event " + e,
+			}
+		}
+	}
+	return nil
+}
+
+// CreateWebhookResponse is the only time the signing secret is returned.
+type CreateWebhookResponse struct {
+	*Webhook
+	Secret string `json:"secret"`
+}
+
+type CreateIncomingWebhookRequest struct {
+	Name string `json:"name" validate:"required,min=1,max=50"`
+}
+
+// IncomingWebhookTokenResponse is returned on create and rotate, the only
+// times the token is shown.
+type IncomingWebhookTokenResponse struct {
+	*IncomingWebhook
+	Token string `json:"token"`
+	URL   string `json:"url"`
+}
+
+type PostIncomingWebhookRequest struct {
+	Content  string  `json:"content" validate:"required,min=1"`
+	Username *string `json:"username,omitempty" validate:"omitempty,min=1,max=50"`
+	IconURL  *string `json:"icon_url,omitempty" validate:"omitempty,url,max=2000"`
+}
@@ -0,0 +1,244 @@
+package webhook
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/lib/pq"
+	"github.com/maxwellzp/golang-chat-api/internal/db"
+	"time"
+)
+
+type WebhookRepository struct {
+	database *db.Db
+}
+
+func NewWebhookRepository(database *db.Db) *WebhookRepository {
+	return &WebhookRepository{database: database}
+}
+
+func (r *WebhookRepository) Create(ctx context.Context, hook *Webhook) error {
+	query := `INSERT INTO room_webhooks (room_id, url, secret, events, created_by, created_at)
+				VALUES ($1, $2, $3, $4, $5, $6)
+				RETURNING id, active, created_at`
+
+	row := r.database.QueryRowContext(ctx, query, hook.RoomID, hook.URL, hook.Secret,
+		pq.Array(hook.Events), hook.CreatedBy, time.Now())
+	return row.Scan(&hook.ID, &hook.Active, &hook.CreatedAt)
+}
+
+func (r *WebhookRepository) GetByID(ctx context.Context, roomID int64, id int64) (*Webhook, error) {
+	query := `SELECT id, room_id, url, secret, events, active, created_by, created_at
+			  FROM room_webhooks WHERE id = $1 AND room_id = $2`
+	row := r.database.QueryRowContext(ctx, query, id, roomID)
+
+	var hook Webhook
+	err := row.Scan(&hook.ID, &hook.RoomID, &hook.URL, &hook.Secret, pq.Array(&hook.Events),
+		&hook.Active, &hook.CreatedBy, &hook.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &hook, nil
+}
+
+func (r *WebhookRepository) ListByRoom(ctx context.Context, roomID int64) ([]*Webhook, error) {
+	return r.list(ctx, `SELECT id, room_id, url, secret, events, active, created_by, created_at
+			  FROM room_webhooks WHERE room_id = $1 ORDER BY id`, roomID)
+}
+
+// ListSubscribed returns the room's active webhooks subscribed to eventType.
+func (r *WebhookRepository) ListSubscribed(ctx context.Context, roomID int64, eventType string) ([]*Webhook, error) {
+	return r.list(ctx, `SELECT id, room_id, url, secret, events, active, created_by, created_at
+			  FROM room_webhooks WHERE room_id = $1 AND active AND $2 = ANY(events)`, roomID, eventType)
+}
+
+func (r *WebhookRepository) list(ctx context.Context, query string, args ...any) ([]*Webhook, error) {
+	rows, err := r.database.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hooks []*Webhook
+	for rows.Next() {
+		var hook Webhook
+		err := rows.Scan(&hook.ID, &hook.RoomID, &hook.URL, &hook.Secret, pq.Array(&hook.Events),
+			&hook.Active, &hook.CreatedBy, &hook.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		hooks = append(hooks, &hook)
+	}
+	return hooks, rows.Err()
+}
+
+func (r *WebhookRepository) Delete(ctx context.Context, roomID int64, id int64) error {
+	res, err := r.database.ExecContext(ctx,
+		"DELETE FROM room_webhooks WHERE id = $1 AND room_id = $2", id, roomID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrWebhookNotFound
+	}
+	return nil
+}
+
+func (r *WebhookRepository) Enqueue(ctx context.Context, webhookID int64, eventType string, payload []byte) error {
+	_, err := r.database.ExecContext(ctx,
+		`INSERT INTO webhook_deliveries (webhook_id, event_type, payload, next_attempt_at, created_at)
+		 VALUES ($1, $2, $3, $4, $4)`,
+		webhookID, eventType, payload, time.Now().UTC())
+	return err
+}
+
+// ClaimDue locks up to limit due deliveries and pushes their next attempt
+// out by lease, so other dispatchers (on other replicas) skip them while
+// they're being sent. A crashed dispatcher's claims become due again once
+// the lease runs out.
+func (r *WebhookRepository) ClaimDue(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]*Delivery, error) {
+	tx, err := r.database.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT d.id, d.webhook_id, d.event_type, d.payload, d.attempts, w.url, w.secret
+		FROM webhook_deliveries d
+		JOIN room_webhooks w ON w.id = d.webhook_id
+		WHERE d.status = 'pending' AND d.next_attempt_at <= $1 AND w.active
+		ORDER BY d.next_attempt_at
+		LIMIT $2
+		FOR UPDATE OF d SKIP LOCKED`, now, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	var deliveries []*Delivery
+	var ids []int64
+	for rows.Next() {
+		var d Delivery
+		if err := rows.Scan(&d.ID, &d.WebhookID, &d.EventType, &d.Payload, &d.Attempts, &d.url, &d.secret); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		deliveries = append(deliveries, &d)
+		ids = append(ids, d.ID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"UPDATE webhook_deliveries SET next_attempt_at = $1 WHERE id = ANY($2)",
+		now.Add(lease), pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	return deliveries, tx.Commit()
+}
+
+// RecordAttempt logs an attempt and moves the delivery to its next state:
+// delivered, dead, or pending again at nextAttemptAt.
+func (r *WebhookRepository) RecordAttempt(ctx context.Context, d *Delivery, a *Attempt, status string, nextAttemptAt time.Time) error {
+	tx, err := r.database.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO webhook_delivery_attempts (delivery_id, attempt, status_code, error, duration_ms, attempted_at)
+		 VALUES ($1, $2, $3, $4, $5, $6)`,
+		d.ID, a.Attempt, a.StatusCode, a.Error, a.DurationMs, a.AttemptedAt)
+	if err != nil {
+		return err
+	}
+
+	var deliveredAt *time.Time
+	if status == StatusDelivered {
+		deliveredAt = &a.AttemptedAt
+	}
+	_, err = tx.ExecContext(ctx,
+		`UPDATE webhook_deliveries
+		 SET status = $1, attempts = $2, next_attempt_at = $3,
+		     last_status_code = $4, last_error = $5, delivered_at = $6
+		 WHERE id = $7`,
+		status, a.Attempt, nextAttemptAt, a.StatusCode, a.Error, deliveredAt, d.ID)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ListDeliveries returns the webhook's most recent deliveries, newest first,
+// with their attempt log.
+func (r *WebhookRepository) ListDeliveries(ctx context.Context, webhookID int64, status string, limit int) ([]*Delivery, error) {
+	rows, err := r.database.QueryContext(ctx, `
+		SELECT id, webhook_id, event_type, payload, status, attempts, next_attempt_at,
+			last_status_code, last_error, delivered_at, created_at
+		FROM webhook_deliveries
+		WHERE webhook_id = $1 AND ($2::text = '' OR status = $2)
+		ORDER BY created_at DESC, id DESC
+		LIMIT $3`, webhookID, status, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []*Delivery
+	byID := make(map[int64]*Delivery)
+	var ids []int64
+	for rows.Next() {
+		var d Delivery
+		err := rows.Scan(&d.ID, &d.WebhookID, &d.EventType, &d.Payload, &d.Status, &d.Attempts,
+			&d.NextAttemptAt, &d.LastStatusCode, &d.LastError, &d.DeliveredAt, &d.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		d.AttemptLog = []*Attempt{}
+		deliveries = append(deliveries, &d)
+		byID[d.ID] = &d
+		ids = append(ids, d.ID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return deliveries, nil
+	}
+
+	attemptRows, err := r.database.QueryContext(ctx, `
+		SELECT delivery_id, attempt, status_code, error, duration_ms, attempted_at
+		FROM webhook_delivery_attempts
+		WHERE delivery_id = ANY($1)
+		ORDER BY attempt`, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer attemptRows.Close()
+
+	for attemptRows.Next() {
+		var deliveryID int64
+		var a Attempt
+		if err := attemptRows.Scan(&deliveryID, &a.Attempt, &a.StatusCode, &a.Error, &a.DurationMs, &a.AttemptedAt); err != nil {
+			return nil, err
+		}
+		if d, ok := byID[deliveryID]; ok {
+			d.AttemptLog = append(d.AttemptLog, &a)
+		}
+	}
+	return deliveries, attemptRows.Err()
+}
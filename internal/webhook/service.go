@@ -0,0 +1,124 @@
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/maxwellzp/golang-chat-api/internal/apperr"
+	"github.com/maxwellzp/golang-chat-api/internal/event"
+	"github.com/maxwellzp/golang-chat-api/internal/httpx"
+	"github.com/maxwellzp/golang-chat-api/internal/logger"
+	"github.com/maxwellzp/golang-chat-api/internal/netguard"
+	"github.com/maxwellzp/golang-chat-api/internal/room"
+)
+
+var ErrWebhookNotFound = apperr.NotFound("webhook_not_found", "webhook not found")
+
+type WebhookService struct {
+	webhookRepository *WebhookRepository
+	roomService       *room.RoomService
+	guard             *netguard.Guard
+	logger            *logger.Logger
+}
+
+func NewWebhookService(webhookRepository *WebhookRepository, roomService *room.RoomService, guard *netguard.Guard, logger *logger.Logger) *WebhookService {
+	return &WebhookService{
+		webhookRepository: webhookRepository,
+		roomService:       roomService,
+		guard:             guard,
+		logger:            logger,
+	}
+}
+
+func (s *WebhookService) Create(ctx context.Context, roomID int64, userID int64, req CreateWebhookRequest) (*Webhook, error) {
+	if err := s.roomService.RequireRole(ctx, roomID, userID, room.RoleAdmin); err != nil {
+		return nil, err
+	}
+	if err := s.guard.CheckURL(ctx, req.URL); err != nil {
+		return nil, httpx.ValidationErrorMap{"url": err.Error()}
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	hook := &Webhook{
+		RoomID:    roomID,
+		URL:       req.URL,
+		Secret:    hex.EncodeToString(secret),
+		Events:    req.Events,
+		CreatedBy: &userID,
+	}
+	if err := s.webhookRepository.Create(ctx, hook); err != nil {
+		return nil, err
+	}
+	return hook, nil
+}
+
+func (s *WebhookService) List(ctx context.Context, roomID int64, userID int64) ([]*Webhook, error) {
+	if err := s.roomService.RequireRole(ctx, roomID, userID, room.RoleAdmin); err != nil {
+		return nil, err
+	}
+	return s.webhookRepository.ListByRoom(ctx, roomID)
+}
+
+func (s *WebhookService) Delete(ctx context.Context, roomID int64, id int64, userID int64) error {
+	if err := s.roomService.RequireRole(ctx, roomID, userID, room.RoleAdmin); err != nil {
+		return err
+	}
+	return s.webhookRepository.Delete(ctx, roomID, id)
+}
+
+func (s *WebhookService) Deliveries(ctx context.Context, roomID int64, id int64, userID int64, status string) ([]*Delivery, error) {
+	if err := s.roomService.RequireRole(ctx, roomID, userID, room.RoleAdmin); err != nil {
+		return nil, err
+	}
+	hook, err := s.webhookRepository.GetByID(ctx, roomID, id)
+	if err != nil {
+		return nil, err
+	}
+	if hook == nil {
+		return nil, ErrWebhookNotFound
+	}
+	return s.webhookRepository.ListDeliveries(ctx, id, status, 100)
+}
+
+// HandleEvent queues a delivery for every room webhook subscribed to the
+// event. It is registered on the event bus; the Dispatcher sends the queue.
+func (s *WebhookService) HandleEvent(ctx context.Context, e event.Event) {
+	if e.RoomID == nil || !SupportedEvents[e.Type] {
+		return
+	}
+
+	hooks, err := s.webhookRepository.ListSubscribed(ctx, *e.RoomID, e.Type)
+	if err != nil {
+		s.logger.Errorw("Failed to load webhooks for event",
+			"event", e.Type,
+			"room_id", *e.RoomID,
+			"error", err,
+		)
+		return
+	}
+	if len(hooks) == 0 {
+		return
+	}
+
+	payload, err := json.Marshal(e)
+	if err != nil {
+		s.logger.Errorw("Failed to encode webhook payload",
+			"event", e.Type,
+			"error", err,
+		)
+		return
+	}
+	for _, hook := range hooks {
+		if err := s.webhookRepository.Enqueue(ctx, hook.ID, e.Type, payload); err != nil {
+			s.logger.Errorw("Failed to enqueue webhook delivery",
+				"webhook_id", hook.ID,
+				"event", e.Type,
+				"error", err,
+			)
+		}
+	}
+}
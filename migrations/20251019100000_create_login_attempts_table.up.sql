@@ -0,0 +1,7 @@
+CREATE TABLE login_attempts
+(
+    key             VARCHAR(300) PRIMARY KEY,
+    failures        INT          NOT NULL DEFAULT 0,
+    last_failure_at TIMESTAMP    NOT NULL,
+    locked_until    TIMESTAMP
+);
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_messages_room_sender_created_at;
+
+ALTER TABLE rooms
+    DROP COLUMN IF EXISTS slow_mode_seconds,
+    DROP COLUMN IF EXISTS max_message_length,
+    DROP COLUMN IF EXISTS allow_links;
@@ -0,0 +1,6 @@
+ALTER TABLE rooms
+    ADD COLUMN slow_mode_seconds  INT     NOT NULL DEFAULT 0,
+    ADD COLUMN max_message_length INT     NOT NULL DEFAULT 0,
+    ADD COLUMN allow_links        BOOLEAN NOT NULL DEFAULT TRUE;
+
+CREATE INDEX idx_messages_room_sender_created_at ON messages (room_id, sender_id, created_at);
@@ -0,0 +1,21 @@
+ALTER TABLE users
+    ADD COLUMN is_bot   BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN owner_id INT REFERENCES users (id) ON DELETE CASCADE;
+
+CREATE TABLE api_keys
+(
+    id           SERIAL PRIMARY KEY,
+    user_id      INT          NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    created_by   INT          NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    name         VARCHAR(100) NOT NULL,
+    prefix       VARCHAR(16)  NOT NULL UNIQUE,
+    key_hash     VARCHAR(64)  NOT NULL,
+    scopes       TEXT[]       NOT NULL DEFAULT '{}',
+    expires_at   TIMESTAMP,
+    last_used_at TIMESTAMP,
+    revoked_at   TIMESTAMP,
+    created_at   TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_api_keys_user_id ON api_keys (user_id);
+CREATE INDEX idx_api_keys_created_by ON api_keys (created_by);
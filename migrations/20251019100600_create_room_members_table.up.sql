@@ -0,0 +1,17 @@
+CREATE TABLE room_members
+(
+    room_id   INT         NOT NULL REFERENCES rooms (id) ON DELETE CASCADE,
+    user_id   INT         NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    role      VARCHAR(20) NOT NULL DEFAULT 'member',
+    joined_at TIMESTAMP   NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (room_id, user_id),
+    CHECK (role IN ('owner', 'admin', 'moderator', 'member'))
+);
+
+CREATE INDEX idx_room_members_user_id ON room_members (user_id);
+
+INSERT INTO room_members (room_id, user_id, role, joined_at)
+SELECT id, created_by, 'owner', created_at
+FROM rooms
+WHERE created_by IS NOT NULL;
@@ -0,0 +1,46 @@
+CREATE TABLE room_webhooks
+(
+    id         SERIAL PRIMARY KEY,
+    room_id    INT         NOT NULL REFERENCES rooms (id) ON DELETE CASCADE,
+    url        TEXT        NOT NULL,
+    secret     VARCHAR(64) NOT NULL,
+    events     TEXT[]      NOT NULL,
+    active     BOOLEAN     NOT NULL DEFAULT TRUE,
+    created_by INT         REFERENCES users (id) ON DELETE SET NULL,
+    created_at TIMESTAMP   NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_room_webhooks_room_id ON room_webhooks (room_id);
+
+CREATE TABLE webhook_deliveries
+(
+    id               SERIAL PRIMARY KEY,
+    webhook_id       INT         NOT NULL REFERENCES room_webhooks (id) ON DELETE CASCADE,
+    event_type       VARCHAR(50) NOT NULL,
+    payload          JSONB       NOT NULL,
+    status           VARCHAR(20) NOT NULL DEFAULT 'pending',
+    attempts         INT         NOT NULL DEFAULT 0,
+    next_attempt_at  TIMESTAMP   NOT NULL,
+    last_status_code INT,
+    last_error       TEXT,
+    delivered_at     TIMESTAMP,
+    created_at       TIMESTAMP   NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    CHECK (status IN ('pending', 'delivered', 'dead'))
+);
+
+CREATE INDEX idx_webhook_deliveries_due ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX idx_webhook_deliveries_webhook_id ON webhook_deliveries (webhook_id, created_at DESC);
+
+CREATE TABLE webhook_delivery_attempts
+(
+    id           SERIAL PRIMARY KEY,
+    delivery_id  INT       NOT NULL REFERENCES webhook_deliveries (id) ON DELETE CASCADE,
+    attempt      INT       NOT NULL,
+    status_code  INT,
+    error        TEXT,
+    duration_ms  INT       NOT NULL,
+    attempted_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_webhook_delivery_attempts_delivery_id ON webhook_delivery_attempts (delivery_id);
@@ -0,0 +1,5 @@
+ALTER TABLE messages
+    DROP COLUMN IF EXISTS icon_url,
+    DROP COLUMN IF EXISTS display_name;
+
+DROP TABLE IF EXISTS incoming_webhooks;
@@ -0,0 +1,18 @@
+CREATE TABLE incoming_webhooks
+(
+    id          SERIAL PRIMARY KEY,
+    room_id     INT          NOT NULL REFERENCES rooms (id) ON DELETE CASCADE,
+    bot_user_id INT          NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    name        VARCHAR(50)  NOT NULL,
+    token_hash  VARCHAR(64)  NOT NULL UNIQUE,
+    created_by  INT          REFERENCES users (id) ON DELETE SET NULL,
+    created_at  TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    rotated_at  TIMESTAMP,
+    revoked_at  TIMESTAMP
+);
+
+CREATE INDEX idx_incoming_webhooks_room_id ON incoming_webhooks (room_id);
+
+ALTER TABLE messages
+    ADD COLUMN display_name VARCHAR(50),
+    ADD COLUMN icon_url     TEXT;
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS slash_commands;
+
+ALTER TABLE room_members
+    DROP COLUMN IF EXISTS muted_until;
+
+ALTER TABLE rooms
+    DROP COLUMN IF EXISTS topic;
@@ -0,0 +1,19 @@
+ALTER TABLE rooms
+    ADD COLUMN topic VARCHAR(250) NOT NULL DEFAULT '';
+
+ALTER TABLE room_members
+    ADD COLUMN muted_until TIMESTAMP;
+
+CREATE TABLE slash_commands
+(
+    id          SERIAL PRIMARY KEY,
+    room_id     INT          NOT NULL REFERENCES rooms (id) ON DELETE CASCADE,
+    name        VARCHAR(32)  NOT NULL,
+    description VARCHAR(200) NOT NULL DEFAULT '',
+    usage       VARCHAR(100) NOT NULL DEFAULT '',
+    url         TEXT         NOT NULL,
+    secret      VARCHAR(64)  NOT NULL,
+    created_by  INT          REFERENCES users (id) ON DELETE SET NULL,
+    created_at  TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (room_id, name)
+);
@@ -0,0 +1,26 @@
+-- Resolved mention spans, as they appear in the message content
+CREATE TABLE message_mentions
+(
+    id          SERIAL PRIMARY KEY,
+    message_id  INT         NOT NULL REFERENCES messages (id) ON DELETE CASCADE,
+    kind        VARCHAR(10) NOT NULL,
+    user_id     INT         REFERENCES users (id) ON DELETE SET NULL,
+    username    VARCHAR(50),
+    start_pos   INT         NOT NULL,
+    end_pos     INT         NOT NULL
+);
+
+CREATE INDEX idx_message_mentions_message_id ON message_mentions (message_id);
+
+-- One row per user notified by a message, with their read state
+CREATE TABLE user_mentions
+(
+    message_id INT         NOT NULL REFERENCES messages (id) ON DELETE CASCADE,
+    user_id    INT         NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    kind       VARCHAR(10) NOT NULL,
+    read_at    TIMESTAMP,
+    created_at TIMESTAMP   NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (message_id, user_id)
+);
+
+CREATE INDEX idx_user_mentions_user_id_created_at ON user_mentions (user_id, created_at DESC);
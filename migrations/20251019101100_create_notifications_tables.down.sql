@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS push_subscriptions;
+DROP TABLE IF EXISTS notification_room_preferences;
+DROP TABLE IF EXISTS notification_preferences;
+DROP TABLE IF EXISTS notifications;
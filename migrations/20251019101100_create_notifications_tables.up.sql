@@ -0,0 +1,54 @@
+CREATE TABLE notifications
+(
+    id            SERIAL PRIMARY KEY,
+    user_id       INT          NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    type          VARCHAR(20)  NOT NULL,
+    room_id       INT          REFERENCES rooms (id) ON DELETE CASCADE,
+    message_id    INT          REFERENCES messages (id) ON DELETE CASCADE,
+    actor_id      INT          REFERENCES users (id) ON DELETE SET NULL,
+    title         VARCHAR(200) NOT NULL,
+    body          TEXT         NOT NULL DEFAULT '',
+    read_at       TIMESTAMP,
+    deliver_after TIMESTAMP    NOT NULL,
+    dispatched_at TIMESTAMP,
+    created_at    TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_notifications_user_id_created_at ON notifications (user_id, created_at DESC);
+CREATE INDEX idx_notifications_pending ON notifications (deliver_after) WHERE dispatched_at IS NULL;
+-- A message notifies a user at most once (a mention wins over "all messages")
+CREATE UNIQUE INDEX idx_notifications_user_message ON notifications (user_id, message_id) WHERE message_id IS NOT NULL;
+
+CREATE TABLE notification_preferences
+(
+    user_id           INT PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+    timezone          VARCHAR(64) NOT NULL DEFAULT 'UTC',
+    quiet_hours_start SMALLINT,
+    quiet_hours_end   SMALLINT,
+    digest_minutes    INT         NOT NULL DEFAULT 0,
+    email_enabled     BOOLEAN     NOT NULL DEFAULT FALSE,
+    push_enabled      BOOLEAN     NOT NULL DEFAULT TRUE,
+    webhook_url       TEXT,
+    webhook_secret    VARCHAR(64),
+    updated_at        TIMESTAMP   NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE notification_room_preferences
+(
+    user_id INT         NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    room_id INT         NOT NULL REFERENCES rooms (id) ON DELETE CASCADE,
+    level   VARCHAR(10) NOT NULL,
+    PRIMARY KEY (user_id, room_id)
+);
+
+CREATE TABLE push_subscriptions
+(
+    id         SERIAL PRIMARY KEY,
+    user_id    INT       NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    endpoint   TEXT      NOT NULL UNIQUE,
+    p256dh     TEXT      NOT NULL,
+    auth       TEXT      NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_push_subscriptions_user_id ON push_subscriptions (user_id);
@@ -0,0 +1,10 @@
+CREATE TABLE user_presence
+(
+    user_id           INT PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+    last_seen_at      TIMESTAMP,
+    idle              BOOLEAN   NOT NULL DEFAULT FALSE,
+    manual_away       BOOLEAN   NOT NULL DEFAULT FALSE,
+    status_text       VARCHAR(100),
+    status_emoji      VARCHAR(32),
+    status_expires_at TIMESTAMP
+);
@@ -0,0 +1,9 @@
+CREATE TABLE pinned_messages
+(
+    message_id INT PRIMARY KEY REFERENCES messages (id) ON DELETE CASCADE,
+    room_id    INT       NOT NULL REFERENCES rooms (id) ON DELETE CASCADE,
+    pinned_by  INT       REFERENCES users (id) ON DELETE SET NULL,
+    pinned_at  TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_pinned_messages_room_id_pinned_at ON pinned_messages (room_id, pinned_at DESC);
@@ -0,0 +1,5 @@
+DELETE FROM notifications WHERE type = 'reminder';
+DROP INDEX idx_notifications_user_message;
+CREATE UNIQUE INDEX idx_notifications_user_message ON notifications (user_id, message_id) WHERE message_id IS NOT NULL;
+
+DROP TABLE IF EXISTS saved_messages;
@@ -0,0 +1,18 @@
+CREATE TABLE saved_messages
+(
+    user_id     INT       NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    message_id  INT       NOT NULL REFERENCES messages (id) ON DELETE CASCADE,
+    note        VARCHAR(500),
+    remind_at   TIMESTAMP,
+    reminded_at TIMESTAMP,
+    created_at  TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, message_id)
+);
+
+CREATE INDEX idx_saved_messages_user_id_created_at ON saved_messages (user_id, created_at DESC);
+CREATE INDEX idx_saved_messages_due ON saved_messages (remind_at) WHERE reminded_at IS NULL;
+
+-- Reminders may point at a message the user was already notified about
+DROP INDEX idx_notifications_user_message;
+CREATE UNIQUE INDEX idx_notifications_user_message ON notifications (user_id, message_id)
+    WHERE message_id IS NOT NULL AND type <> 'reminder';
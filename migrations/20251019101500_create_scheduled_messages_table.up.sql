@@ -0,0 +1,19 @@
+CREATE TABLE scheduled_messages
+(
+    id          SERIAL PRIMARY KEY,
+    user_id     INT         NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    kind        VARCHAR(10) NOT NULL DEFAULT 'message',
+    room_id     INT REFERENCES rooms (id) ON DELETE CASCADE,
+    receiver_id INT REFERENCES users (id) ON DELETE CASCADE,
+    content     TEXT        NOT NULL,
+    send_at     TIMESTAMP   NOT NULL,
+    status      VARCHAR(10) NOT NULL DEFAULT 'pending',
+    attempts    INT         NOT NULL DEFAULT 0,
+    last_error  TEXT,
+    message_id  INT REFERENCES messages (id) ON DELETE SET NULL,
+    created_at  TIMESTAMP   NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at  TIMESTAMP   NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_scheduled_messages_user_id_send_at ON scheduled_messages (user_id, send_at);
+CREATE INDEX idx_scheduled_messages_due ON scheduled_messages (send_at) WHERE status = 'pending';
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_messages_expires_at;
+
+ALTER TABLE rooms DROP COLUMN IF EXISTS message_ttl_seconds;
+ALTER TABLE messages DROP COLUMN IF EXISTS expires_at;
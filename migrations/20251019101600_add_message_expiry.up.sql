@@ -0,0 +1,4 @@
+ALTER TABLE messages ADD COLUMN expires_at TIMESTAMP;
+ALTER TABLE rooms ADD COLUMN message_ttl_seconds INT NOT NULL DEFAULT 0;
+
+CREATE INDEX idx_messages_expires_at ON messages (expires_at) WHERE expires_at IS NOT NULL;
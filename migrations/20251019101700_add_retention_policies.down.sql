@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_messages_created_at;
+
+ALTER TABLE users DROP COLUMN IF EXISTS legal_hold;
+ALTER TABLE rooms DROP COLUMN IF EXISTS legal_hold;
+ALTER TABLE rooms DROP COLUMN IF EXISTS retention_days;
@@ -0,0 +1,6 @@
+-- NULL retention_days falls back to the global default.
+ALTER TABLE rooms ADD COLUMN retention_days INT;
+ALTER TABLE rooms ADD COLUMN legal_hold BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE users ADD COLUMN legal_hold BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX idx_messages_created_at ON messages (created_at);
@@ -0,0 +1,21 @@
+CREATE TABLE exports
+(
+    id            SERIAL PRIMARY KEY,
+    user_id       INT         NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    room_id       INT REFERENCES rooms (id) ON DELETE CASCADE,
+    peer_id       INT REFERENCES users (id) ON DELETE CASCADE,
+    format        VARCHAR(8)  NOT NULL,
+    status        VARCHAR(10) NOT NULL DEFAULT 'pending',
+    error         TEXT,
+    file_path     TEXT,
+    size_bytes    BIGINT      NOT NULL DEFAULT 0,
+    message_count INT         NOT NULL DEFAULT 0,
+    created_at    TIMESTAMP   NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    started_at    TIMESTAMP,
+    finished_at   TIMESTAMP,
+    expires_at    TIMESTAMP,
+    CHECK ((room_id IS NULL) <> (peer_id IS NULL))
+);
+
+CREATE INDEX idx_exports_user_id_created_at ON exports (user_id, created_at DESC);
+CREATE INDEX idx_exports_queue ON exports (created_at) WHERE status IN ('pending', 'running');
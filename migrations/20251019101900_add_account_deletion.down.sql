@@ -0,0 +1,8 @@
+DELETE FROM exports WHERE room_id IS NULL AND peer_id IS NULL;
+ALTER TABLE exports DROP CONSTRAINT exports_check;
+ALTER TABLE exports ADD CONSTRAINT exports_check CHECK ((room_id IS NULL) <> (peer_id IS NULL));
+
+DELETE FROM users WHERE username = 'deleted user' AND deleted_at IS NOT NULL;
+
+ALTER TABLE users DROP COLUMN IF EXISTS sessions_revoked_at;
+ALTER TABLE users DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,11 @@
+ALTER TABLE users ADD COLUMN deleted_at TIMESTAMP;
+ALTER TABLE users ADD COLUMN sessions_revoked_at TIMESTAMP;
+
+-- Messages of deleted accounts are reassigned to this placeholder. The space
+-- keeps the username from being registered.
+INSERT INTO users (username, email, password, deleted_at)
+VALUES ('deleted user', 'deleted-user@invalid', '', CURRENT_TIMESTAMP);
+
+-- Account exports have neither a room nor a peer
+ALTER TABLE exports DROP CONSTRAINT exports_check;
+ALTER TABLE exports ADD CONSTRAINT exports_check CHECK (room_id IS NULL OR peer_id IS NULL);
@@ -0,0 +1,12 @@
+-- Maps objects of an imported Slack or Discord export to the rows created
+-- for them, so running an import again skips what is already there.
+CREATE TABLE import_mappings
+(
+    source      VARCHAR(20)  NOT NULL,
+    kind        VARCHAR(20)  NOT NULL,
+    external_id VARCHAR(200) NOT NULL,
+    local_id    INT          NOT NULL,
+    created_at  TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (source, kind, external_id)
+);
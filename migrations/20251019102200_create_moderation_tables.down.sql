@@ -0,0 +1,5 @@
+DROP TRIGGER IF EXISTS moderation_actions_append_only ON moderation_actions;
+DROP FUNCTION IF EXISTS moderation_actions_append_only();
+DROP TABLE IF EXISTS moderation_actions;
+DROP TABLE IF EXISTS reports;
+DROP TABLE IF EXISTS room_bans;
@@ -0,0 +1,62 @@
+CREATE TABLE room_bans
+(
+    room_id    INT       NOT NULL REFERENCES rooms (id) ON DELETE CASCADE,
+    user_id    INT       NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    banned_by  INT       REFERENCES users (id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (room_id, user_id)
+);
+
+-- message_id has no foreign key: a report outlives the message it is about,
+-- and content keeps a copy of what was reported.
+CREATE TABLE reports
+(
+    id          SERIAL PRIMARY KEY,
+    message_id  INT          NOT NULL,
+    room_id     INT REFERENCES rooms (id) ON DELETE CASCADE,
+    sender_id   INT REFERENCES users (id) ON DELETE SET NULL,
+    reporter_id INT REFERENCES users (id) ON DELETE SET NULL,
+    reason      VARCHAR(20)  NOT NULL CHECK (reason IN ('spam', 'harassment', 'hate', 'violence', 'sexual', 'self_harm', 'other')),
+    details     VARCHAR(1000),
+    content     TEXT         NOT NULL,
+    status      VARCHAR(20)  NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'dismissed', 'actioned')),
+    created_at  TIMESTAMP    NOT NULL DEFAULT NOW(),
+    resolved_by INT REFERENCES users (id) ON DELETE SET NULL,
+    resolved_at TIMESTAMP,
+    UNIQUE (message_id, reporter_id)
+);
+
+CREATE INDEX idx_reports_status ON reports (status, created_at);
+CREATE INDEX idx_reports_room_id ON reports (room_id);
+
+-- moderation_actions is append-only. It has no foreign keys, so deleting a
+-- room or user never rewrites it, and a trigger rejects updates and deletes.
+CREATE TABLE moderation_actions
+(
+    id             SERIAL PRIMARY KEY,
+    report_id      INT,
+    room_id        INT,
+    moderator_id   INT         NOT NULL,
+    action         VARCHAR(20) NOT NULL,
+    target_user_id INT,
+    message_id     INT,
+    note           VARCHAR(500),
+    muted_until    TIMESTAMP,
+    created_at     TIMESTAMP   NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_moderation_actions_room_id ON moderation_actions (room_id, created_at);
+CREATE INDEX idx_moderation_actions_report_id ON moderation_actions (report_id);
+
+CREATE FUNCTION moderation_actions_append_only() RETURNS trigger AS
+$$
+BEGIN
+    RAISE EXCEPTION 'moderation_actions is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER moderation_actions_append_only
+    BEFORE UPDATE OR DELETE
+    ON moderation_actions
+    FOR EACH ROW
+EXECUTE FUNCTION moderation_actions_append_only();
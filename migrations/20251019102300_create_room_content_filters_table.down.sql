@@ -0,0 +1,6 @@
+DELETE FROM reports WHERE reason = 'filter';
+ALTER TABLE reports DROP CONSTRAINT IF EXISTS reports_reason_check;
+ALTER TABLE reports ADD CONSTRAINT reports_reason_check
+    CHECK (reason IN ('spam', 'harassment', 'hate', 'violence', 'sexual', 'self_harm', 'other'));
+
+DROP TABLE IF EXISTS room_content_filters;
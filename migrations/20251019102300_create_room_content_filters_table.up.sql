@@ -0,0 +1,17 @@
+-- terms holds words, patterns or denied domains depending on the filter.
+CREATE TABLE room_content_filters
+(
+    room_id         INT         NOT NULL REFERENCES rooms (id) ON DELETE CASCADE,
+    filter          VARCHAR(20) NOT NULL,
+    action          VARCHAR(10) NOT NULL CHECK (action IN ('reject', 'mask', 'flag')),
+    terms           TEXT[]      NOT NULL DEFAULT '{}',
+    allowed_domains TEXT[]      NOT NULL DEFAULT '{}',
+    updated_at      TIMESTAMP   NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (room_id, filter)
+);
+
+-- Messages flagged by a content filter are reported with reason 'filter'
+-- and no reporter.
+ALTER TABLE reports DROP CONSTRAINT reports_reason_check;
+ALTER TABLE reports ADD CONSTRAINT reports_reason_check
+    CHECK (reason IN ('spam', 'harassment', 'hate', 'violence', 'sexual', 'self_harm', 'other', 'filter'));
@@ -0,0 +1,46 @@
+-- audit_events is append-only and hash-chained: each row's hash covers its
+-- own fields and the previous row's hash, so editing or removing a row
+-- breaks every hash after it. No foreign keys, the log outlives the users,
+-- rooms and messages it mentions. before and after are JSON rather than
+-- JSONB so the stored text is exactly what was hashed.
+CREATE TABLE audit_events
+(
+    id              BIGSERIAL    PRIMARY KEY,
+    occurred_at     TIMESTAMP    NOT NULL,
+    actor_id        INT,
+    impersonator_id INT,
+    action          VARCHAR(50)  NOT NULL,
+    target_type     VARCHAR(20)  NOT NULL DEFAULT '',
+    target_id       INT,
+    ip              VARCHAR(45)  NOT NULL DEFAULT '',
+    user_agent      VARCHAR(500) NOT NULL DEFAULT '',
+    request_id      VARCHAR(100) NOT NULL DEFAULT '',
+    before          JSON,
+    after           JSON,
+    prev_hash       CHAR(64)     NOT NULL,
+    hash            CHAR(64)     NOT NULL
+);
+
+CREATE INDEX idx_audit_events_occurred_at ON audit_events (occurred_at);
+CREATE INDEX idx_audit_events_actor_id ON audit_events (actor_id, occurred_at);
+CREATE INDEX idx_audit_events_target ON audit_events (target_type, target_id, occurred_at);
+CREATE INDEX idx_audit_events_action ON audit_events (action, occurred_at);
+
+CREATE FUNCTION audit_events_append_only() RETURNS trigger AS
+$$
+BEGIN
+    RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_events_append_only
+    BEFORE UPDATE OR DELETE
+    ON audit_events
+    FOR EACH ROW
+EXECUTE FUNCTION audit_events_append_only();
+
+CREATE TRIGGER audit_events_no_truncate
+    BEFORE TRUNCATE
+    ON audit_events
+    FOR EACH STATEMENT
+EXECUTE FUNCTION audit_events_append_only();
@@ -0,0 +1,5 @@
+-- email_verified_at is set once the user has shown they control the address,
+-- by completing a password reset or by signing up through an identity
+-- provider that verified it. Only verified accounts are linked to an
+-- external identity by email.
+ALTER TABLE users ADD COLUMN email_verified_at TIMESTAMP;
@@ -0,0 +1,13 @@
+-- room_post_cooldowns holds when each user last posted in a room for slow
+-- mode. It is separate from messages, so deleting a message doesn't reset
+-- the cooldown, and from room_members, since anyone can post in a public
+-- room. A post claims the row before its message is inserted.
+CREATE TABLE room_post_cooldowns
+(
+    room_id        INT       NOT NULL REFERENCES rooms (id) ON DELETE CASCADE,
+    user_id        INT       NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    last_posted_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (room_id, user_id)
+);
+
+CREATE INDEX idx_room_post_cooldowns_user_id ON room_post_cooldowns (user_id);
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS message_reactions;
+
+DROP INDEX IF EXISTS idx_messages_parent_id;
+
+ALTER TABLE messages DROP COLUMN IF EXISTS parent_id;
@@ -0,0 +1,17 @@
+-- parent_id makes a message a reply in its parent's thread. Imported Slack
+-- threads and Discord replies use it.
+ALTER TABLE messages ADD COLUMN parent_id INT REFERENCES messages (id) ON DELETE SET NULL;
+
+CREATE INDEX idx_messages_parent_id ON messages (parent_id) WHERE parent_id IS NOT NULL;
+
+-- emoji is the Unicode emoji or, for custom emoji, its name.
+CREATE TABLE message_reactions
+(
+    message_id INT          NOT NULL REFERENCES messages (id) ON DELETE CASCADE,
+    user_id    INT          NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    emoji      VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP    NOT NULL,
+    PRIMARY KEY (message_id, user_id, emoji)
+);
+
+CREATE INDEX idx_message_reactions_user_id ON message_reactions (user_id);
@@ -0,0 +1,14 @@
+-- Fails if unsealed events are left; let the sealer catch up first.
+CREATE OR REPLACE FUNCTION audit_events_append_only() RETURNS trigger AS
+$$
+BEGIN
+    RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP INDEX IF EXISTS idx_audit_events_unsealed;
+
+ALTER TABLE audit_events
+    DROP COLUMN IF EXISTS seq,
+    ALTER COLUMN prev_hash SET NOT NULL,
+    ALTER COLUMN hash SET NOT NULL;
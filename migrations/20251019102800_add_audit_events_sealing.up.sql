@@ -0,0 +1,33 @@
+-- Events are inserted without hashes and chained later by the sealer, so
+-- writing one doesn't wait on a global lock. seq is the position in the
+-- chain, assigned at sealing; ids can commit out of order.
+ALTER TABLE audit_events
+    ALTER COLUMN prev_hash DROP NOT NULL,
+    ALTER COLUMN hash DROP NOT NULL,
+    ADD COLUMN seq BIGINT UNIQUE;
+
+CREATE INDEX idx_audit_events_unsealed ON audit_events (id) WHERE seq IS NULL;
+
+-- Existing events were hashed without a key. They are unsealed here so the
+-- sealer chains them again with the keyed hash.
+ALTER TABLE audit_events DISABLE TRIGGER audit_events_append_only;
+UPDATE audit_events SET prev_hash = NULL, hash = NULL;
+ALTER TABLE audit_events ENABLE TRIGGER audit_events_append_only;
+
+-- The only change allowed is sealing: setting seq and the hashes of an
+-- unsealed event, once, without touching anything else. JSON has no
+-- equality operator, so before and after are compared as text.
+CREATE OR REPLACE FUNCTION audit_events_append_only() RETURNS trigger AS
+$$
+BEGIN
+    IF TG_OP = 'UPDATE' AND OLD.seq IS NULL AND NEW.seq IS NOT NULL AND NEW.hash IS NOT NULL
+        AND ROW (NEW.id, NEW.occurred_at, NEW.actor_id, NEW.impersonator_id, NEW.action, NEW.target_type,
+                 NEW.target_id, NEW.ip, NEW.user_agent, NEW.request_id, NEW.before::text, NEW.after::text)
+            IS NOT DISTINCT FROM
+            ROW (OLD.id, OLD.occurred_at, OLD.actor_id, OLD.impersonator_id, OLD.action, OLD.target_type,
+                 OLD.target_id, OLD.ip, OLD.user_agent, OLD.request_id, OLD.before::text, OLD.after::text) THEN
+        RETURN NEW;
+    END IF;
+    RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
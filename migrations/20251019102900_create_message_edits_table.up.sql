@@ -0,0 +1,11 @@
+-- message_edits keeps earlier versions of edited messages: content is the
+-- text the message had until edited_at, when it was replaced.
+CREATE TABLE message_edits
+(
+    id         BIGSERIAL PRIMARY KEY,
+    message_id INT       NOT NULL REFERENCES messages (id) ON DELETE CASCADE,
+    content    TEXT      NOT NULL,
+    edited_at  TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_message_edits_message_id ON message_edits (message_id, edited_at);